@@ -0,0 +1,87 @@
+// Command fakeembeddings runs a local stand-in for an OpenAI-compatible
+// embeddings endpoint, for developing questions over report history
+// without an embedding model. Point the agent at it with:
+//
+//	EMBEDDING_BASE_URL=http://127.0.0.1:5004/v1 EMBEDDING_MODEL=fake-embedding go run .
+//
+// The embeddings hash the words of each text, so texts sharing words are
+// similar; they are good enough to try retrieval, not to judge it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+
+	"agents_go/services/llm"
+)
+
+// embeddingRequest is the wire format of an embeddings request
+type embeddingRequest struct {
+	Model string `json:"model"`
+	// Input is a string or a list of strings
+	Input json.RawMessage `json:"input"`
+}
+
+// embeddingData is one embedding in a response
+type embeddingData struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// embeddingResponse is the wire format of an embeddings response
+type embeddingResponse struct {
+	Object string          `json:"object"`
+	Model  string          `json:"model"`
+	Data   []embeddingData `json:"data"`
+	Usage  llm.Usage       `json:"usage"`
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:5004", "address to listen on")
+	dims := flag.Int("dimensions", llm.HashEmbeddingDimensions, "length of the embeddings")
+	flag.Parse()
+
+	http.HandleFunc("/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var inputs []string
+		if err := json.Unmarshal(req.Input, &inputs); err != nil {
+			var input string
+			if err := json.Unmarshal(req.Input, &input); err != nil {
+				http.Error(w, "input must be a string or a list of strings", http.StatusBadRequest)
+				return
+			}
+			inputs = []string{input}
+		}
+
+		resp := embeddingResponse{Object: "list", Model: req.Model, Data: []embeddingData{}}
+		if resp.Model == "" {
+			resp.Model = "fake-embedding"
+		}
+		for i, input := range inputs {
+			resp.Data = append(resp.Data, embeddingData{
+				Object:    "embedding",
+				Index:     i,
+				Embedding: llm.HashEmbedding(input, *dims),
+			})
+			resp.Usage.PromptTokens += llm.EstimateTokens(input)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	log.Printf("Fake embeddings listening on http://%s/v1/embeddings (%d dimensions)", *addr, *dims)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,28 @@
+// Command fakes3 runs the s3test fake S3 server as a standalone server for
+// local development. Point the report store at it with:
+//
+//	REPORT_STORE=s3 REPORT_STORE_PATH=reports/dev S3_ENDPOINT=http://127.0.0.1:5003 S3_PATH_STYLE=true \
+//	S3_ACCESS_KEY_ID=fake-access-key S3_SECRET_ACCESS_KEY=fake-secret-key go run .
+//
+// Objects are kept in memory and lost when the server stops.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"agents_go/services/s3/s3test"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:5003", "address to listen on")
+	bucket := flag.String("bucket", s3test.DefaultBucket, "name of the bucket to serve")
+	flag.Parse()
+
+	server := s3test.NewHandler()
+	server.Bucket = *bucket
+
+	log.Printf("Fake S3 listening on http://%s (bucket %q, access key %q)", *addr, server.Bucket, server.AccessKey)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
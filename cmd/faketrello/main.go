@@ -0,0 +1,41 @@
+// Command faketrello runs the trellotest fake Trello API as a standalone
+// server for local development. Point the app at it with:
+//
+//	TRELLO_API_URL=http://127.0.0.1:5002/1 TRELLO_OAUTH_URL=http://127.0.0.1:5002/1 go run .
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"agents_go/services/trello/trellotest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:5002", "address to listen on")
+	fixturePath := flag.String("fixture", "", "path to a JSON board fixture (defaults to a built-in sample board)")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	rateLimit := flag.Int("rate-limit", 0, "maximum API requests per 10 seconds (0 disables)")
+	flag.Parse()
+
+	// Load the fixture
+	fixture := trellotest.DefaultFixture()
+	if *fixturePath != "" {
+		var err error
+		fixture, err = trellotest.LoadFixture(*fixturePath)
+		if err != nil {
+			log.Fatalf("Error loading fixture: %v", err)
+		}
+	}
+
+	server := trellotest.NewHandler(fixture)
+	server.SetLatency(*latency)
+	if *rateLimit > 0 {
+		server.SetRateLimit(*rateLimit, 10*time.Second)
+	}
+
+	log.Printf("Fake Trello API listening on http://%s/1 (access token %q)", *addr, fixture.AccessToken)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,91 @@
+// Command migratereports copies reports from one report store to another,
+// e.g. from the JSON files in data/reports into a SQLite database. Reports
+// already in the destination are replaced, so it can be run again after new
+// reports were saved.
+//
+//	go run ./cmd/migratereports -to-backend sqlite -to data/reports.db
+//	go run ./cmd/migratereports -from-backend sqlite -from data/reports.db -to-backend file -to data/reports
+//	S3_ENDPOINT=http://localhost:9000 S3_PATH_STYLE=true go run ./cmd/migratereports -to-backend s3 -to reports/prod
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/reportstore"
+)
+
+func main() {
+	fromBackend := flag.String("from-backend", models.ReportStoreFile, "backend to read reports from: file, sqlite or s3")
+	from := flag.String("from", "data/reports", "report directory, database or S3 bucket/prefix to read")
+	toBackend := flag.String("to-backend", models.ReportStoreSQLite, "backend to write reports to: file, sqlite or s3")
+	to := flag.String("to", "data/reports.db", "report directory, database or S3 bucket/prefix to write")
+	dryRun := flag.Bool("dry-run", false, "list the reports without copying them")
+	verbose := flag.Bool("v", false, "log skipped files and print every report copied")
+	flag.Parse()
+
+	// Read the S3 settings from the environment
+	config.Init()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	if *fromBackend == *toBackend && *from == *to {
+		fail("The source and destination are the same store")
+	}
+
+	// Read every report from the source
+	source, err := reportstore.Open(*fromBackend, *from)
+	if err != nil {
+		fail("Error opening %s: %v", *from, err)
+	}
+	defer source.Close()
+	reports, err := source.ListReports(models.ReportFilter{})
+	if err != nil {
+		fail("Error reading reports from %s: %v", *from, err)
+	}
+
+	if *dryRun {
+		for _, report := range reports {
+			fmt.Printf("%s (%s, %s)\n", report.ID, report.BoardName, report.GeneratedAt.Format("2006-01-02"))
+		}
+		fmt.Printf("%d reports would be copied to %s\n", len(reports), *to)
+		return
+	}
+
+	// Write them to the destination, oldest first
+	destination, err := reportstore.Open(*toBackend, *to)
+	if err != nil {
+		fail("Error opening %s: %v", *to, err)
+	}
+	defer destination.Close()
+
+	copied := 0
+	for i := len(reports) - 1; i >= 0; i-- {
+		report := reports[i]
+		if err := destination.SaveReport(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error copying report %s: %v\n", report.ID, err)
+			continue
+		}
+		copied++
+		if *verbose {
+			fmt.Printf("Copied %s\n", report.ID)
+		}
+	}
+
+	fmt.Printf("Copied %d of %d reports from %s to %s\n", copied, len(reports), *from, *to)
+	if copied < len(reports) {
+		os.Exit(1)
+	}
+}
+
+// fail prints an error and exits
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
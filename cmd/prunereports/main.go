@@ -0,0 +1,115 @@
+// Command prunereports applies the report retention policies once, as the
+// server does in the background: the default policy from the REPORT_KEEP_*,
+// REPORT_CANONICAL_ONLY_DAYS and REPORT_SNAPSHOT_DAYS variables and each
+// board's own policy from its settings. Pinned reports are always kept.
+//
+//	REPORT_KEEP_WEEKLY=12 REPORT_KEEP_MONTHLY=24 go run ./cmd/prunereports -dry-run
+//	go run ./cmd/prunereports -history 5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/reportstore"
+	"agents_go/services/retention"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list what would be deleted without deleting it")
+	history := flag.Int("history", 0, "print the newest audit records instead of pruning")
+	verbose := flag.Bool("v", false, "log skipped report files")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	config.Init()
+
+	audit, err := models.NewPruneAuditStore("./data/retention")
+	if err != nil {
+		fail("Error opening the audit records: %v", err)
+	}
+	if *history > 0 {
+		printHistory(audit, *history)
+		return
+	}
+
+	store, err := reportstore.OpenConfigured()
+	if err != nil {
+		fail("Error opening the report store: %v", err)
+	}
+	defer store.Close()
+	settings, err := models.NewBoardSettingsStore("./data/boards")
+	if err != nil {
+		fail("Error opening the board settings: %v", err)
+	}
+
+	record, err := retention.NewPruner(store, settings, audit).Prune(time.Now(), *dryRun)
+	if err != nil {
+		fail("Error pruning reports: %v", err)
+	}
+
+	failed := 0
+	for _, action := range record.Actions {
+		printAction(action)
+		if action.Error != "" {
+			failed++
+		}
+	}
+	switch {
+	case len(record.Actions) == 0:
+		fmt.Println("Nothing to prune")
+	case *dryRun:
+		fmt.Printf("%d would be deleted (audit record %s)\n", len(record.Actions), record.ID)
+	default:
+		fmt.Printf("Deleted %d of %d (audit record %s)\n", len(record.Actions)-failed, len(record.Actions), record.ID)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printAction prints one deletion
+func printAction(action models.PruneAction) {
+	fmt.Printf("%s %s (%s, revision %d, %s): %s",
+		action.Kind, action.ReportID, action.Period, action.Revision, action.GeneratedAt.Format("2006-01-02"), action.Reason)
+	if action.Error != "" {
+		fmt.Printf(" FAILED: %s", action.Error)
+	}
+	fmt.Println()
+}
+
+// printHistory prints the newest audit records
+func printHistory(audit *models.PruneAuditStore, count int) {
+	records, err := audit.List()
+	if err != nil {
+		fail("Error reading the audit records: %v", err)
+	}
+	if len(records) > count {
+		records = records[:count]
+	}
+	for _, record := range records {
+		mode := ""
+		if record.DryRun {
+			mode = " (dry run)"
+		}
+		fmt.Printf("%s%s: %d deletion(s)\n", record.StartedAt.Format("2006-01-02 15:04:05"), mode, len(record.Actions))
+		for _, action := range record.Actions {
+			fmt.Print("  ")
+			printAction(action)
+		}
+	}
+}
+
+// fail prints an error and exits
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,363 @@
+// Command reporteval compares two versions of the report prompt and model
+// on recorded boards. Each fixture board is served by the fake Trello API,
+// formatted as in production and sent with each version's prompt to its
+// provider. The reports are scored automatically and, with a judge
+// configured, against a rubric by a model, and the scores are written as a
+// comparison table. Versions and the judge can use scripted responses, so
+// the whole run works offline:
+//
+//	go run ./cmd/reporteval
+//	go run ./cmd/reporteval -config eval/config.json -out eval/results.md
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/prompts"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+// Config describes an evaluation. Paths are relative to the config file.
+type Config struct {
+	// ReportType is "weekly" (default) or "monthly"
+	ReportType string `json:"report_type"`
+	// AsOf is the date the fixtures were recorded, as YYYY-MM-DD; the
+	// report period ends on it. Empty uses today.
+	AsOf string `json:"as_of"`
+	// Fixtures are glob patterns of recorded board fixtures; none uses the
+	// default fixture
+	Fixtures []string `json:"fixtures"`
+	// PromptDir holds saved prompt templates; empty uses data/prompts
+	PromptDir string `json:"prompt_dir"`
+	// Versions are the two versions compared, the baseline first
+	Versions []Version `json:"versions"`
+	// Judge scores the reports against a rubric; nil skips it
+	Judge *Judge `json:"judge,omitempty"`
+	// MinWords and MaxWords bound a report's length; zero doesn't check
+	MinWords int `json:"min_words"`
+	MaxWords int `json:"max_words"`
+}
+
+// Version is a prompt and model that reports are generated with
+type Version struct {
+	Name string `json:"name"`
+	// Provider and Model select the model; empty uses the default ones
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Prompt and PromptVersion select a saved template; PromptFile reads
+	// the template text from a file instead. Neither uses the built-in
+	// template for the report type.
+	Prompt        string `json:"prompt"`
+	PromptVersion int    `json:"prompt_version"`
+	PromptFile    string `json:"prompt_file"`
+	// Script is a directory of recorded responses, one per case; setting
+	// it answers from the files instead of calling the provider
+	Script   string          `json:"script"`
+	Audience models.Audience `json:"audience"`
+	Language models.Language `json:"language"`
+}
+
+// Judge is the model that scores reports against the rubric
+type Judge struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Script is a directory of recorded verdicts, one per version and case
+	Script string `json:"script"`
+}
+
+// Case is one board to report on
+type Case struct {
+	// Name identifies the case as <fixture>/<board ID>
+	Name      string
+	BoardData map[string]interface{}
+}
+
+func main() {
+	configPath := flag.String("config", "eval/config.json", "path to the evaluation config")
+	outPath := flag.String("out", "", "also write the results to this file")
+	verbose := flag.Bool("v", false, "log report generation")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fail("Error loading config: %v", err)
+	}
+	start, end, err := cfg.period()
+	if err != nil {
+		fail("Error in config: %v", err)
+	}
+
+	// Record the board data of every case; this reconfigures the Trello
+	// client, so it is done before the models are set up
+	cases, err := loadCases(cfg, start)
+	if err != nil {
+		fail("Error loading fixtures: %v", err)
+	}
+	if len(cases) == 0 {
+		fail("No boards found in the fixtures")
+	}
+
+	// Keep the run free of side effects: no cache, and no fallback to
+	// another model, which would make the versions incomparable
+	config.LLMCacheTTL = 0
+	config.LLMFallbacks = nil
+
+	var current cursor
+	registry, err := newRegistry(cfg, &current)
+	if err != nil {
+		fail("Error setting up models: %v", err)
+	}
+	client, err := aifoundry.NewClientWithRegistry(registry)
+	if err != nil {
+		fail("Error creating LLM client: %v", err)
+	}
+
+	var judge *judge
+	if cfg.Judge != nil {
+		judge, err = newJudge(cfg.Judge, registry, &current)
+		if err != nil {
+			fail("Error setting up judge: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	var results []*Result
+	for i := range cfg.Versions {
+		version := &cfg.Versions[i]
+		for _, c := range cases {
+			current = cursor{Version: version.Name, Case: c.Name}
+			result := evaluate(ctx, cfg, version, client, c, start, end)
+			if judge != nil && result.Err == "" {
+				if err := judge.score(ctx, c, result); err != nil {
+					result.Err = fmt.Sprintf("judge: %v", err)
+				}
+			}
+			results = append(results, result)
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", version.Name, c.Name, result.summary())
+		}
+	}
+
+	table := writeTable(cfg, cases, results)
+	fmt.Print(table)
+	if *outPath != "" {
+		if err := ioutil.WriteFile(*outPath, []byte(table), 0644); err != nil {
+			fail("Error writing results: %v", err)
+		}
+	}
+}
+
+// fail prints an error and exits
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+// loadConfig reads and validates the config, resolving its paths
+func loadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	if cfg.ReportType == "" {
+		cfg.ReportType = string(models.Weekly)
+	}
+	if cfg.ReportType != string(models.Weekly) && cfg.ReportType != string(models.Monthly) {
+		return nil, fmt.Errorf("unknown report type %q", cfg.ReportType)
+	}
+	if len(cfg.Versions) != 2 {
+		return nil, fmt.Errorf("the config must have two versions to compare, found %d", len(cfg.Versions))
+	}
+	if cfg.Versions[0].Name == "" || cfg.Versions[1].Name == "" || cfg.Versions[0].Name == cfg.Versions[1].Name {
+		return nil, fmt.Errorf("the versions need distinct names")
+	}
+
+	// Resolve paths against the config file's directory
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i := range cfg.Fixtures {
+		cfg.Fixtures[i] = resolve(cfg.Fixtures[i])
+	}
+	cfg.PromptDir = resolve(cfg.PromptDir)
+	for i := range cfg.Versions {
+		version := &cfg.Versions[i]
+		version.PromptFile = resolve(version.PromptFile)
+		version.Script = resolve(version.Script)
+		if _, err := models.ParseAudience(string(version.Audience)); err != nil {
+			return nil, fmt.Errorf("version %s: %v", version.Name, err)
+		}
+		lang, err := models.ParseLanguage(string(version.Language))
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %v", version.Name, err)
+		}
+		version.Language = lang
+	}
+	if cfg.Judge != nil {
+		cfg.Judge.Script = resolve(cfg.Judge.Script)
+	}
+
+	return &cfg, nil
+}
+
+// period returns the period the reports cover, ending on the as-of date
+func (cfg *Config) period() (time.Time, time.Time, error) {
+	end := time.Now().UTC()
+	if cfg.AsOf != "" {
+		asOf, err := time.Parse("2006-01-02", cfg.AsOf)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid as_of date %q", cfg.AsOf)
+		}
+		// Include the whole day
+		end = asOf.Add(24*time.Hour - time.Second)
+	}
+	if cfg.ReportType == string(models.Monthly) {
+		return end.AddDate(0, -1, 0), end, nil
+	}
+	return end.AddDate(0, 0, -7), end, nil
+}
+
+// loadCases serves each fixture from the fake Trello API and records the
+// board data of each of its boards since start
+func loadCases(cfg *Config, start time.Time) ([]Case, error) {
+	var paths []string
+	for _, pattern := range cfg.Fixtures {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no fixtures match %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	fixtures := make(map[string]*trellotest.Fixture)
+	var names []string
+	if len(paths) == 0 {
+		fixtures["default"] = trellotest.DefaultFixture()
+		names = append(names, "default")
+	}
+	for _, path := range paths {
+		fixture, err := trellotest.LoadFixture(path)
+		if err != nil {
+			return nil, err
+		}
+		name := trimExt(filepath.Base(path))
+		fixtures[name] = fixture
+		names = append(names, name)
+	}
+
+	var cases []Case
+	for _, name := range names {
+		fixture := fixtures[name]
+		server := trellotest.NewServer(fixture)
+		server.Configure()
+		client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+		for _, board := range fixture.Boards {
+			boardData, err := client.GetBoardData(board.Board.ID, start)
+			if err != nil {
+				server.Close()
+				return nil, fmt.Errorf("error getting board %s from %s: %v", board.Board.ID, name, err)
+			}
+			cases = append(cases, Case{Name: name + "/" + board.Board.ID, BoardData: boardData})
+		}
+		server.Close()
+	}
+	return cases, nil
+}
+
+// promptText returns the version's prompt template text
+func promptText(cfg *Config, version *Version) (string, error) {
+	if version.PromptFile != "" {
+		data, err := ioutil.ReadFile(version.PromptFile)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	if version.Prompt == "" {
+		return prompts.Builtin(cfg.ReportType).Text, nil
+	}
+
+	dir := cfg.PromptDir
+	if dir == "" {
+		dir = "data/prompts"
+	}
+	store, err := prompts.NewStore(dir)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := store.Get(version.Prompt, version.PromptVersion)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Text, nil
+}
+
+// evaluate generates a case's report with a version and scores it
+func evaluate(ctx context.Context, cfg *Config, version *Version, client *aifoundry.AIFoundryClient, c Case, start, end time.Time) *Result {
+	result := &Result{Version: version.Name, Case: c.Name}
+
+	text, err := promptText(cfg, version)
+	if err != nil {
+		result.Err = fmt.Sprintf("prompt: %v", err)
+		return result
+	}
+	systemPrompt, err := prompts.Render(text, prompts.NewData(c.BoardData, cfg.ReportType, start, end))
+	if err != nil {
+		result.Err = fmt.Sprintf("prompt: %v", err)
+		return result
+	}
+
+	versionClient, err := client.WithProvider(providerName(version), version.Model)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	report, err := versionClient.GenerateReport(ctx, c.BoardData, cfg.ReportType, aifoundry.ReportOptions{
+		SystemPrompt: systemPrompt,
+		StartDate:    start,
+		EndDate:      end,
+		Language:     version.Language,
+		Audience:     version.Audience,
+	})
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	score(cfg, version, c, report, result)
+	return result
+}
+
+// trimExt returns a file name without its extension
+func trimExt(name string) string {
+	return name[:len(name)-len(filepath.Ext(name))]
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"agents_go/config"
+	"agents_go/services/llm"
+)
+
+// reportFormat is the response format name of structured report requests
+const reportFormat = "trello_report"
+
+// newRegistry registers the configured providers, and a scripted provider
+// for each version with recorded responses: <script>/<case>.json for each
+// case being evaluated.
+func newRegistry(cfg *Config, current *cursor) (*llm.Registry, error) {
+	registry := llm.NewRegistry(providerName(&cfg.Versions[0]))
+
+	// Providers that can't be created, e.g. for lack of credentials, are
+	// skipped; versions that use them fail to resolve them
+	for _, pc := range config.LLMProviders {
+		provider, err := llm.NewProvider(pc)
+		if err != nil {
+			log.Printf("Skipping LLM provider %q: %v", pc.Name, err)
+			continue
+		}
+		registry.Register(provider)
+	}
+
+	for i := range cfg.Versions {
+		version := &cfg.Versions[i]
+		if version.Script == "" {
+			continue
+		}
+		if _, err := os.Stat(version.Script); err != nil {
+			return nil, fmt.Errorf("version %s: %v", version.Name, err)
+		}
+		script := version.Script
+		path := func(c cursor) string { return filepath.Join(script, c.Case+".json") }
+		registry.Register(scriptedProvider(providerName(version), current, path, func(req llm.Request) error {
+			if req.ResponseFormat == nil || req.ResponseFormat.Name != reportFormat {
+				return fmt.Errorf("scripted versions only answer structured report requests; boards that need summarizing in chunks must use a real provider")
+			}
+			return nil
+		}))
+	}
+
+	return registry, nil
+}
+
+// providerName returns the name of the provider a version uses
+func providerName(version *Version) string {
+	if version.Script != "" {
+		return "script-" + version.Name
+	}
+	if version.Provider != "" {
+		return version.Provider
+	}
+	return config.DefaultLLMProvider
+}
+
+// cursor is the version and case being evaluated, which scripted
+// providers answer for
+type cursor struct {
+	Version string
+	Case    string
+}
+
+// scriptedProvider answers every request with a recorded response, read
+// from the file path returns for the current cursor. accept rejects requests
+// the script can't answer.
+func scriptedProvider(name string, current *cursor, path func(cursor) string, accept func(req llm.Request) error) *llm.FakeProvider {
+	fake := llm.NewFakeProvider(name)
+	fake.SetContextWindow(128000)
+	fake.SetHandler(func(req llm.Request) (*llm.Response, error) {
+		if err := accept(req); err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(path(*current))
+		if err != nil {
+			return nil, fmt.Errorf("no recorded response: %v", err)
+		}
+		return &llm.Response{Content: string(data)}, nil
+	})
+	return fake
+}
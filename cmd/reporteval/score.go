@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/pdf"
+)
+
+// Result holds the scores of one version's report for one case
+type Result struct {
+	Version string
+	Case    string
+	// Err is set if the report couldn't be generated or judged
+	Err string
+
+	// Sections is the number of the audience's sections that have
+	// content, out of Required; data limitations aren't required
+	Sections int
+	Required int
+	// PDFSections is the number of the report's section titles the PDF
+	// parser recognizes in its content, out of Titles
+	PDFSections int
+	Titles      int
+	// Cited is the number of distinct cards the report cites, of which
+	// Valid are on the board
+	Cited int
+	Valid int
+	// Claims is the number of claims the fact check couldn't verify
+	Claims int
+	// Words is the length of the report's content, and InRange whether
+	// it is within the configured bounds
+	Words   int
+	InRange bool
+	// Judgement is the judge's rubric scores, if a judge is configured
+	Judgement *Judgement
+
+	// content is the report, for the judge
+	content string
+}
+
+// score fills in the automatic scores of a report
+func score(cfg *Config, version *Version, c Case, report *aifoundry.ReportResult, result *Result) {
+	reportType := models.ReportType(cfg.ReportType)
+	result.content = report.Content
+
+	// Required sections: every section the audience sees must have content
+	if report.Structured != nil {
+		for _, section := range report.Structured.Sections(reportType, version.Language) {
+			if section.Key == models.SectionDataLimitations {
+				continue
+			}
+			result.Required++
+			if len(section.Paragraphs) > 0 || len(section.Items) > 0 {
+				result.Sections++
+			}
+		}
+	}
+
+	// PDF coverage: the PDF parser must find every section title, or the
+	// section's content ends up under the wrong heading
+	if report.Structured != nil {
+		parsed := make(map[string]bool)
+		for _, section := range pdf.NewGenerator().ParseSections(report.Content) {
+			parsed[strings.ToLower(section.Title)] = true
+		}
+		for _, section := range report.Structured.Sections(reportType, version.Language) {
+			result.Titles++
+			if parsed[strings.ToLower(section.Title)] {
+				result.PDFSections++
+			}
+		}
+	}
+
+	// Card citations: every card the report names must be on the board
+	if report.Structured != nil {
+		cards := boardCards(c.BoardData)
+		for name := range citedCards(report.Structured) {
+			result.Cited++
+			if cards[name] {
+				result.Valid++
+			}
+		}
+	}
+
+	result.Claims = len(report.UnverifiedClaims)
+
+	result.Words = len(strings.Fields(report.Content))
+	result.InRange = (cfg.MinWords == 0 || result.Words >= cfg.MinWords) &&
+		(cfg.MaxWords == 0 || result.Words <= cfg.MaxWords)
+}
+
+// summary describes the result in one line
+func (r *Result) summary() string {
+	if r.Err != "" {
+		return "error: " + r.Err
+	}
+	s := fmt.Sprintf("sections %d/%d, pdf %d/%d, cards %d/%d, claims %d, words %d",
+		r.Sections, r.Required, r.PDFSections, r.Titles, r.Valid, r.Cited, r.Claims, r.Words)
+	if r.Judgement != nil {
+		s += fmt.Sprintf(", judge %.1f", r.Judgement.Mean())
+	}
+	return s
+}
+
+// citedCards returns the normalized names of the cards a report cites
+func citedCards(s *models.StructuredReport) map[string]bool {
+	cited := make(map[string]bool)
+	add := func(cards []string) {
+		for _, card := range cards {
+			if name := normalizeName(card); name != "" {
+				cited[name] = true
+			}
+		}
+	}
+	for _, item := range s.Progress {
+		add(item.Cards)
+	}
+	for _, p := range s.Priorities {
+		add(p.Cards)
+	}
+	for _, risk := range s.Risks {
+		add(risk.Cards)
+	}
+	for _, contribution := range s.Contributions {
+		add(contribution.Cards)
+	}
+	return cited
+}
+
+// boardCards returns the normalized names of the board's cards, including
+// cards only seen in the activity
+func boardCards(boardData map[string]interface{}) map[string]bool {
+	cards := make(map[string]bool)
+	for _, card := range items(boardData["cards"]) {
+		name, _ := card["name"].(string)
+		cards[normalizeName(name)] = true
+	}
+	for _, action := range items(boardData["activities"]) {
+		data, _ := action["data"].(map[string]interface{})
+		if card, ok := data["card"].(map[string]interface{}); ok {
+			name, _ := card["name"].(string)
+			cards[normalizeName(name)] = true
+		}
+	}
+	return cards
+}
+
+// items returns the objects in a board data list
+func items(data interface{}) []map[string]interface{} {
+	if dataMap, ok := data.(map[string]interface{}); ok {
+		data = dataMap["items"]
+	}
+	switch v := data.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		result := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// normalizeName lowercases a name and collapses its whitespace and quotes
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.Trim(strings.ToLower(name), `"'“”`)), " ")
+}
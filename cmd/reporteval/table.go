@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// totals sums a version's results over the cases
+type totals struct {
+	cases, errors                   int
+	sections, required              int
+	pdfSections, titles             int
+	cited, valid                    int
+	claims, words, inRange          int
+	judged                          int
+	accuracy, completeness, clarity int
+	actionability                   int
+}
+
+// add counts a result
+func (t *totals) add(r *Result) {
+	t.cases++
+	if r.Err != "" {
+		t.errors++
+		return
+	}
+	t.sections += r.Sections
+	t.required += r.Required
+	t.pdfSections += r.PDFSections
+	t.titles += r.Titles
+	t.cited += r.Cited
+	t.valid += r.Valid
+	t.claims += r.Claims
+	t.words += r.Words
+	if r.InRange {
+		t.inRange++
+	}
+	if j := r.Judgement; j != nil {
+		t.judged++
+		t.accuracy += j.Accuracy
+		t.completeness += j.Completeness
+		t.clarity += j.Clarity
+		t.actionability += j.Actionability
+	}
+}
+
+// metric is a row of the comparison table
+type metric struct {
+	name string
+	// value returns the metric for a version's totals, and false if it
+	// can't be computed
+	value func(t *totals) (float64, bool)
+	// percent metrics are shown as percentages and their change in
+	// points; others with this many decimals
+	percent  bool
+	decimals int
+}
+
+// ratio returns a/b, or false if b is zero
+func ratio(a, b int) (float64, bool) {
+	if b == 0 {
+		return 0, false
+	}
+	return float64(a) / float64(b), true
+}
+
+// metrics returns the rows of the comparison table
+func metrics(cfg *Config) []metric {
+	rows := []metric{
+		{name: "Required sections present", percent: true, value: func(t *totals) (float64, bool) { return ratio(t.sections, t.required) }},
+		{name: "PDF section coverage", percent: true, value: func(t *totals) (float64, bool) { return ratio(t.pdfSections, t.titles) }},
+		{name: "Cards cited correctly", percent: true, value: func(t *totals) (float64, bool) { return ratio(t.valid, t.cited) }},
+		{name: "Unverified claims per report", decimals: 2, value: func(t *totals) (float64, bool) { return ratio(t.claims, t.cases-t.errors) }},
+		{name: "Words per report", value: func(t *totals) (float64, bool) { return ratio(t.words, t.cases-t.errors) }},
+	}
+	if cfg.MinWords > 0 || cfg.MaxWords > 0 {
+		rows = append(rows, metric{name: fmt.Sprintf("Length within %s", wordBounds(cfg)), percent: true, value: func(t *totals) (float64, bool) { return ratio(t.inRange, t.cases-t.errors) }})
+	}
+	if cfg.Judge != nil {
+		rows = append(rows,
+			metric{name: "Judge: accuracy", decimals: 2, value: func(t *totals) (float64, bool) { return ratio(t.accuracy, t.judged) }},
+			metric{name: "Judge: completeness", decimals: 2, value: func(t *totals) (float64, bool) { return ratio(t.completeness, t.judged) }},
+			metric{name: "Judge: clarity", decimals: 2, value: func(t *totals) (float64, bool) { return ratio(t.clarity, t.judged) }},
+			metric{name: "Judge: actionability", decimals: 2, value: func(t *totals) (float64, bool) { return ratio(t.actionability, t.judged) }},
+			metric{name: "Judge: overall", decimals: 2, value: func(t *totals) (float64, bool) {
+				return ratio(t.accuracy+t.completeness+t.clarity+t.actionability, 4*t.judged)
+			}},
+		)
+	}
+	rows = append(rows, metric{name: "Errors", value: func(t *totals) (float64, bool) { return float64(t.errors), true }})
+	return rows
+}
+
+// wordBounds describes the configured report length
+func wordBounds(cfg *Config) string {
+	switch {
+	case cfg.MinWords > 0 && cfg.MaxWords > 0:
+		return fmt.Sprintf("%d-%d words", cfg.MinWords, cfg.MaxWords)
+	case cfg.MinWords > 0:
+		return fmt.Sprintf("at least %d words", cfg.MinWords)
+	default:
+		return fmt.Sprintf("at most %d words", cfg.MaxWords)
+	}
+}
+
+// writeTable writes the results as markdown: a table comparing the two
+// versions over all cases, then the scores of each case
+func writeTable(cfg *Config, cases []Case, results []*Result) string {
+	var sb strings.Builder
+	a, b := &cfg.Versions[0], &cfg.Versions[1]
+
+	sb.WriteString("# Report Evaluation\n\n")
+	asOf := cfg.AsOf
+	if asOf == "" {
+		asOf = "today"
+	}
+	sb.WriteString(fmt.Sprintf("%s reports on %d boards, as of %s.\n\n", strings.Title(cfg.ReportType), len(cases), asOf))
+	for _, version := range []*Version{a, b} {
+		sb.WriteString(fmt.Sprintf("- **%s**: %s\n", version.Name, describe(version)))
+	}
+	sb.WriteString("\n")
+
+	// Sum the results of each version
+	sums := map[string]*totals{a.Name: {}, b.Name: {}}
+	for _, r := range results {
+		sums[r.Version].add(r)
+	}
+
+	sb.WriteString(fmt.Sprintf("| Metric | %s | %s | Change |\n", a.Name, b.Name))
+	sb.WriteString("|---|---:|---:|---:|\n")
+	for _, m := range metrics(cfg) {
+		va, okA := m.value(sums[a.Name])
+		vb, okB := m.value(sums[b.Name])
+		change := "–"
+		if okA && okB {
+			change = formatChange(vb-va, m)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", m.name, formatValue(va, okA, m), formatValue(vb, okB, m), change))
+	}
+
+	sb.WriteString("\n## Cases\n\n")
+	sb.WriteString("| Case | Version | Sections | PDF | Cards | Claims | Words | Judge | Error |\n")
+	sb.WriteString("|---|---|---:|---:|---:|---:|---:|---:|---|\n")
+	for _, c := range cases {
+		for _, r := range results {
+			if r.Case != c.Name {
+				continue
+			}
+			if r.Err != "" {
+				sb.WriteString(fmt.Sprintf("| %s | %s | | | | | | | %s |\n", r.Case, r.Version, escapeCell(r.Err)))
+				continue
+			}
+			judge := ""
+			if r.Judgement != nil {
+				judge = fmt.Sprintf("%.2f", r.Judgement.Mean())
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %d/%d | %d/%d | %d/%d | %d | %d | %s | |\n",
+				r.Case, r.Version, r.Sections, r.Required, r.PDFSections, r.Titles, r.Valid, r.Cited, r.Claims, r.Words, judge))
+		}
+	}
+
+	return sb.String()
+}
+
+// describe summarizes a version's model and prompt
+func describe(version *Version) string {
+	model := version.Provider
+	if version.Script != "" {
+		model = "recorded responses"
+	} else if model == "" {
+		model = "default provider"
+	}
+	if version.Model != "" {
+		model += " " + version.Model
+	}
+
+	prompt := "built-in prompt"
+	switch {
+	case version.PromptFile != "":
+		prompt = "prompt " + version.PromptFile
+	case version.Prompt != "" && version.PromptVersion > 0:
+		prompt = fmt.Sprintf("prompt %s@v%d", version.Prompt, version.PromptVersion)
+	case version.Prompt != "":
+		prompt = "prompt " + version.Prompt + " (latest)"
+	}
+
+	parts := []string{model, prompt}
+	if version.Audience != "" {
+		parts = append(parts, version.Audience.Name()+" audience")
+	}
+	if version.Language != "" {
+		parts = append(parts, version.Language.Name())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatValue formats a metric's value
+func formatValue(v float64, ok bool, m metric) string {
+	if !ok {
+		return "–"
+	}
+	if m.percent {
+		return fmt.Sprintf("%.0f%%", v*100)
+	}
+	return fmt.Sprintf("%.*f", m.decimals, v)
+}
+
+// formatChange formats the difference between the versions' values
+func formatChange(d float64, m metric) string {
+	if m.percent {
+		return fmt.Sprintf("%+.0f pts", d*100)
+	}
+	return fmt.Sprintf("%+.*f", m.decimals, d)
+}
+
+// escapeCell keeps text from breaking a table row
+func escapeCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
@@ -0,0 +1,103 @@
+// Command verifyreports checks the report files in a file report store for
+// problems a crash or an older version may have left: temporary files of
+// interrupted writes, files that aren't readable reports, reports stored in
+// more than one file and reports not named after their ID. With -repair it
+// fixes them; unreadable files and superseded copies are moved to the
+// store's quarantine directory rather than deleted.
+//
+//	go run ./cmd/verifyreports
+//	go run ./cmd/verifyreports -repair
+//	go run ./cmd/verifyreports -path data/reports -json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"agents_go/config"
+	"agents_go/models"
+)
+
+func main() {
+	path := flag.String("path", "", "report directory to verify (default: the configured file store)")
+	repair := flag.Bool("repair", false, "repair the problems found")
+	asJSON := flag.Bool("json", false, "print the result as JSON")
+	verbose := flag.Bool("v", false, "log quarantined files")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	config.Init()
+
+	// Only the file backend writes files that can be torn; SQLite and S3
+	// write each report atomically
+	location := *path
+	if location == "" {
+		if config.ReportStoreBackend != "" && config.ReportStoreBackend != models.ReportStoreFile {
+			fail("The report store uses the %s backend; only file stores can be verified (use -path to verify a directory)", config.ReportStoreBackend)
+		}
+		location = config.ReportStoreLocation()
+	}
+	if _, err := os.Stat(location); err != nil {
+		fail("Error opening the report store: %v", err)
+	}
+
+	result, err := models.VerifyReportFiles(location, *repair)
+	if err != nil {
+		fail("Error verifying reports: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	} else {
+		printResult(result, *repair)
+	}
+
+	// Fail if problems remain, so scripts can tell
+	for _, issue := range result.Issues {
+		if !issue.Repaired {
+			os.Exit(1)
+		}
+	}
+}
+
+// printResult prints the problems found and how they were repaired
+func printResult(result *models.VerifyResult, repair bool) {
+	for _, issue := range result.Issues {
+		fmt.Printf("%s: %s", issue.File, issue.Problem)
+		switch {
+		case issue.Error != "":
+			fmt.Printf(" (couldn't %s: %s)", issue.Repair, issue.Error)
+		case issue.Repaired:
+			fmt.Printf(" (repaired: %s)", issue.Repair)
+		case issue.Repair != "":
+			fmt.Printf(" (-repair would %s)", issue.Repair)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Checked %d file(s): %d report(s), %d problem(s)", result.Files, result.Reports, len(result.Issues))
+	if repair {
+		repaired := 0
+		for _, issue := range result.Issues {
+			if issue.Repaired {
+				repaired++
+			}
+		}
+		fmt.Printf(", %d repaired", repaired)
+	}
+	fmt.Println()
+}
+
+// fail prints an error and exits
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -1,17 +1,22 @@
 package config
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gorilla/sessions"
 	"github.com/mrjones/oauth"
 )
 
 const (
-	TrelloKey       = "a2f217e66e60163384df3e891fd329a8"
-	TrelloSecret    = "904e785848d1994523d17337b16a4473da7a9747690587d76f1b78e1dfa3779f"
-	CallbackURL     = "http://127.0.0.1:5001/callback"
-	RequestTokenURL = "https://trello.com/1/OAuthGetRequestToken"
-	AuthorizeURL    = "https://trello.com/1/OAuthAuthorizeToken"
-	AccessTokenURL  = "https://trello.com/1/OAuthGetAccessToken"
+	TrelloKey    = "a2f217e66e60163384df3e891fd329a8"
+	TrelloSecret = "904e785848d1994523d17337b16a4473da7a9747690587d76f1b78e1dfa3779f"
+	CallbackURL  = "http://127.0.0.1:5001/callback"
 
 	// AI Foundry API configuration
 	AIFoundryAPIKey     = "5A0S6uhOLsoYHEwSnTzsB9UhNo6WSjE6OCwePK1ze4mhc5soCiKCJQQJ99BEACHYHv6XJ3w3AAAAACOGtT4T"
@@ -20,6 +25,165 @@ const (
 	AIFoundryAPIVersion = "2024-05-01-preview"
 )
 
+// Trello API configuration. These can be overridden with the TRELLO_API_URL
+// and TRELLO_OAUTH_URL environment variables, e.g. to point the app at a
+// local fake Trello server.
+var (
+	// TrelloAPIURL is the base URL of the Trello REST API
+	TrelloAPIURL = "https://api.trello.com/1"
+	// TrelloOAuthURL is the base URL of the Trello OAuth 1.0a endpoints
+	TrelloOAuthURL = "https://trello.com/1"
+
+	RequestTokenURL string
+	AuthorizeURL    string
+	AccessTokenURL  string
+)
+
+// LLMProviderConfig describes a configured LLM backend
+type LLMProviderConfig struct {
+	// Name is how boards and settings refer to this provider
+	Name string `json:"name"`
+	// Type is one of "azure", "openai" (any OpenAI-compatible endpoint) or "fake"
+	Type string `json:"type"`
+	// BaseURL is the endpoint, e.g. http://localhost:11434/v1 for Ollama
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+	// Model is the default model or deployment for this provider
+	Model string `json:"model"`
+	// ContextWindow is the model's context size in tokens; zero uses a default
+	ContextWindow int `json:"context_window"`
+	// EmbeddingModel is the model or deployment used to embed text for
+	// semantic search, if the provider can
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+}
+
+// LLM configuration. LLMProviders lists every backend available to this
+// deployment and DefaultLLMProvider names the one used unless a board
+// overrides it. Additional providers can be loaded from the JSON file named
+// by LLM_PROVIDERS_FILE, and an OpenAI-compatible endpoint can be added with
+// OPENAI_BASE_URL, OPENAI_API_KEY and OPENAI_MODEL.
+var (
+	LLMProviders = []LLMProviderConfig{
+		{
+			Name:    "azure",
+			Type:    "azure",
+			BaseURL: AIFoundryAPIURL,
+			APIKey:  AIFoundryAPIKey,
+			Model:   AIFoundryModel,
+		},
+	}
+	DefaultLLMProvider = "azure"
+)
+
+// LLM retry and fallback configuration. Requests that fail with a transient
+// error (rate limits, timeouts, 5xx responses, network errors) are retried up
+// to LLMRetryAttempts times with exponential backoff, and each attempt is
+// cut off after LLMRequestTimeout. If a model still fails, the models in
+// LLMFallbacks are tried in order. Set them with LLM_RETRY_ATTEMPTS,
+// LLM_REQUEST_TIMEOUT (e.g. "90s") and LLM_FALLBACKS, a comma-separated list
+// of provider names with an optional model, e.g. "openai:gpt-4o-mini,azure".
+var (
+	LLMRetryAttempts  = 3
+	LLMRetryBaseDelay = time.Second
+	LLMRetryMaxDelay  = 20 * time.Second
+	LLMRequestTimeout = 2 * time.Minute
+	LLMFallbacks      []string
+)
+
+// Completion cache configuration. Report completions are cached on disk for
+// LLMCacheTTL and the oldest entries are evicted past LLMCacheMaxBytes; the
+// limits can be set with LLM_CACHE_TTL (e.g. "24h", "0" disables the cache)
+// and LLM_CACHE_MAX_MB.
+var (
+	LLMCachePath           = "./data/cache"
+	LLMCacheTTL            = 7 * 24 * time.Hour
+	LLMCacheMaxBytes int64 = 100 << 20
+)
+
+// ModelPrice is a model's price in US dollars per million tokens
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// LLMPrices is used to estimate the cost of completions, keyed by model.
+// Prices can be added or replaced with the JSON file named by
+// LLM_PRICES_FILE, e.g. {"gpt-4o": {"prompt": 2.5, "completion": 10}}.
+// Models without a price are counted but cost nothing.
+var LLMPrices = map[string]ModelPrice{
+	AIFoundryModel: {Prompt: 0.25, Completion: 1.00},
+	"gpt-4o":       {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-mini":  {Prompt: 0.15, Completion: 0.60},
+	"gpt-4.1":      {Prompt: 2.00, Completion: 8.00},
+	"gpt-4.1-mini": {Prompt: 0.40, Completion: 1.60},
+}
+
+// Monthly LLM budgets per user in US dollars, set with LLM_BUDGET_SOFT and
+// LLM_BUDGET_HARD. Users over the soft budget are warned; users over the
+// hard budget can't generate reports or chat until the next month. Zero
+// means no limit.
+var (
+	LLMBudgetSoft float64
+	LLMBudgetHard float64
+)
+
+// FactCheckRounds is how many times a report is regenerated when the fact
+// check finds claims the board data doesn't support, for boards that turn
+// corrections on. Set it with LLM_FACTCHECK_ROUNDS.
+var FactCheckRounds = 1
+
+// Report storage. ReportStoreBackend is "file" for one JSON file per report
+// in a directory, "sqlite" for an embedded database or "s3" for an
+// S3-compatible bucket; ReportStorePath is the directory, the database file
+// or "bucket/prefix", defaulting to ./data/reports or ./data/reports.db.
+// Set them with REPORT_STORE and REPORT_STORE_PATH.
+var (
+	ReportStoreBackend = "file"
+	ReportStorePath    string
+)
+
+// Default report retention, for boards without their own policy. Zero keeps
+// everything, which is the default. RetentionKeepWeekly and
+// RetentionKeepMonthly are how many periods of each type are kept
+// (REPORT_KEEP_WEEKLY, REPORT_KEEP_MONTHLY); revisions that aren't canonical
+// are deleted after RetentionCanonicalOnlyDays (REPORT_CANONICAL_ONLY_DAYS)
+// and board snapshots after RetentionSnapshotDays (REPORT_SNAPSHOT_DAYS).
+// The pruner runs every RetentionInterval (REPORT_PRUNE_INTERVAL); with
+// RetentionDryRun (REPORT_PRUNE_DRY_RUN) it only records what it would
+// delete.
+var (
+	RetentionKeepWeekly        int
+	RetentionKeepMonthly       int
+	RetentionCanonicalOnlyDays int
+	RetentionSnapshotDays      int
+	RetentionInterval          = 24 * time.Hour
+	RetentionDryRun            bool
+)
+
+// S3-compatible object storage for the "s3" report store. S3PathStyle puts
+// the bucket in the URL path, as MinIO expects. S3Layout is where objects
+// are written under the prefix; see reportstore.DefaultLayout.
+var (
+	S3Endpoint        = "https://s3.amazonaws.com"
+	S3Region          = "us-east-1"
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+	S3Layout          string
+)
+
+// Semantic questions over report history. Report sections are embedded by
+// the provider named by EmbeddingProvider (EMBEDDING_PROVIDER), using its
+// embedding model; empty uses the default LLM provider. EMBEDDING_BASE_URL
+// registers an OpenAI-compatible "embeddings" provider for a separate
+// endpoint, e.g. a local one, with EMBEDDING_API_KEY and EMBEDDING_MODEL,
+// and selects it. QuestionSections is how many of the most relevant
+// sections a question is answered from (QUESTION_SECTIONS).
+var (
+	EmbeddingProvider string
+	QuestionSections  = 8
+)
+
 // Store will hold all session data
 var Store = sessions.NewCookieStore([]byte("trello-oauth-secret-key"))
 
@@ -28,6 +192,29 @@ var Consumer *oauth.Consumer
 
 // Init initializes the OAuth consumer
 func Init() {
+	// Load overrides from the environment
+	if url := os.Getenv("TRELLO_API_URL"); url != "" {
+		TrelloAPIURL = strings.TrimSuffix(url, "/")
+	}
+	if url := os.Getenv("TRELLO_OAUTH_URL"); url != "" {
+		TrelloOAuthURL = strings.TrimSuffix(url, "/")
+	}
+
+	loadLLMProviders()
+	loadEmbeddings()
+	loadLLMCache()
+	loadLLMRetries()
+	loadLLMPrices()
+	loadLLMBudgets()
+	loadFactCheck()
+	loadReportStore()
+	loadS3()
+	loadRetention()
+
+	RequestTokenURL = TrelloOAuthURL + "/OAuthGetRequestToken"
+	AuthorizeURL = TrelloOAuthURL + "/OAuthAuthorizeToken"
+	AccessTokenURL = TrelloOAuthURL + "/OAuthGetAccessToken"
+
 	Consumer = oauth.NewConsumer(
 		TrelloKey,
 		TrelloSecret,
@@ -47,3 +234,263 @@ func Init() {
 	// Set the app name
 	Consumer.AdditionalAuthorizationUrlParams["name"] = "Trello AI Foundry Integration"
 }
+
+// loadLLMProviders adds LLM providers configured in the environment
+func loadLLMProviders() {
+	if path := os.Getenv("LLM_PROVIDERS_FILE"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Printf("Error reading LLM providers file: %v", err)
+		} else {
+			var providers []LLMProviderConfig
+			if err := json.Unmarshal(data, &providers); err != nil {
+				log.Printf("Error parsing LLM providers file: %v", err)
+			} else {
+				for _, p := range providers {
+					addLLMProvider(p)
+				}
+			}
+		}
+	}
+
+	if url := os.Getenv("OPENAI_BASE_URL"); url != "" {
+		addLLMProvider(LLMProviderConfig{
+			Name:    "openai",
+			Type:    "openai",
+			BaseURL: strings.TrimSuffix(url, "/"),
+			APIKey:  os.Getenv("OPENAI_API_KEY"),
+			Model:   os.Getenv("OPENAI_MODEL"),
+			// Set for semantic questions over report history
+			EmbeddingModel: os.Getenv("OPENAI_EMBEDDING_MODEL"),
+		})
+	}
+
+	if name := os.Getenv("LLM_PROVIDER"); name != "" {
+		DefaultLLMProvider = name
+		// The fake provider needs no configuration, so allow selecting it directly
+		if name == "fake" {
+			addLLMProvider(LLMProviderConfig{Name: "fake", Type: "fake", Model: "fake"})
+		}
+	}
+}
+
+// loadLLMRetries reads the retry and fallback settings from the environment
+func loadLLMRetries() {
+	if value := os.Getenv("LLM_RETRY_ATTEMPTS"); value != "" {
+		if attempts, err := strconv.Atoi(value); err == nil && attempts > 0 {
+			LLMRetryAttempts = attempts
+		} else {
+			log.Printf("Invalid LLM_RETRY_ATTEMPTS %q", value)
+		}
+	}
+	if value := os.Getenv("LLM_REQUEST_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			LLMRequestTimeout = timeout
+		} else {
+			log.Printf("Invalid LLM_REQUEST_TIMEOUT %q", value)
+		}
+	}
+	if value := os.Getenv("LLM_FALLBACKS"); value != "" {
+		LLMFallbacks = nil
+		for _, fallback := range strings.Split(value, ",") {
+			if fallback = strings.TrimSpace(fallback); fallback != "" {
+				LLMFallbacks = append(LLMFallbacks, fallback)
+			}
+		}
+	}
+}
+
+// loadLLMCache reads the completion cache limits from the environment
+func loadLLMCache() {
+	if value := os.Getenv("LLM_CACHE_TTL"); value != "" {
+		if value == "0" {
+			LLMCacheTTL = 0
+		} else if ttl, err := time.ParseDuration(value); err == nil {
+			LLMCacheTTL = ttl
+		} else {
+			log.Printf("Invalid LLM_CACHE_TTL %q: %v", value, err)
+		}
+	}
+	if value := os.Getenv("LLM_CACHE_MAX_MB"); value != "" {
+		if mb, err := strconv.ParseInt(value, 10, 64); err == nil && mb > 0 {
+			LLMCacheMaxBytes = mb << 20
+		} else {
+			log.Printf("Invalid LLM_CACHE_MAX_MB %q", value)
+		}
+	}
+}
+
+// loadLLMPrices adds model prices from the file named by LLM_PRICES_FILE
+func loadLLMPrices() {
+	path := os.Getenv("LLM_PRICES_FILE")
+	if path == "" {
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Printf("Error reading LLM prices file: %v", err)
+		return
+	}
+	var prices map[string]ModelPrice
+	if err := json.Unmarshal(data, &prices); err != nil {
+		log.Printf("Error parsing LLM prices file: %v", err)
+		return
+	}
+	for model, price := range prices {
+		LLMPrices[model] = price
+	}
+}
+
+// loadLLMBudgets reads the monthly budgets from the environment
+func loadLLMBudgets() {
+	for name, budget := range map[string]*float64{
+		"LLM_BUDGET_SOFT": &LLMBudgetSoft,
+		"LLM_BUDGET_HARD": &LLMBudgetHard,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if amount, err := strconv.ParseFloat(value, 64); err == nil && amount >= 0 {
+			*budget = amount
+		} else {
+			log.Printf("Invalid %s %q", name, value)
+		}
+	}
+}
+
+// addLLMProvider adds or replaces a provider by name
+func addLLMProvider(provider LLMProviderConfig) {
+	for i, p := range LLMProviders {
+		if p.Name == provider.Name {
+			LLMProviders[i] = provider
+			return
+		}
+	}
+	LLMProviders = append(LLMProviders, provider)
+}
+
+// loadFactCheck reads the number of correction rounds from the environment
+func loadFactCheck() {
+	if value := os.Getenv("LLM_FACTCHECK_ROUNDS"); value != "" {
+		if rounds, err := strconv.Atoi(value); err == nil && rounds >= 0 {
+			FactCheckRounds = rounds
+		} else {
+			log.Printf("Invalid LLM_FACTCHECK_ROUNDS %q", value)
+		}
+	}
+}
+
+// loadReportStore reads the report store backend from the environment
+func loadReportStore() {
+	if value := os.Getenv("REPORT_STORE"); value != "" {
+		ReportStoreBackend = value
+	}
+	if value := os.Getenv("REPORT_STORE_PATH"); value != "" {
+		ReportStorePath = value
+	}
+}
+
+// loadS3 reads the S3 settings from the environment, falling back to the
+// standard AWS credential variables
+func loadS3() {
+	if value := os.Getenv("S3_ENDPOINT"); value != "" {
+		S3Endpoint = strings.TrimSuffix(value, "/")
+	}
+	if value := os.Getenv("S3_REGION"); value != "" {
+		S3Region = value
+	}
+	for _, name := range []string{"S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID"} {
+		if value := os.Getenv(name); value != "" {
+			S3AccessKeyID = value
+			break
+		}
+	}
+	for _, name := range []string{"S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY"} {
+		if value := os.Getenv(name); value != "" {
+			S3SecretAccessKey = value
+			break
+		}
+	}
+	if value, err := strconv.ParseBool(os.Getenv("S3_PATH_STYLE")); err == nil {
+		S3PathStyle = value
+	}
+	if value := os.Getenv("S3_LAYOUT"); value != "" {
+		S3Layout = value
+	}
+}
+
+// loadEmbeddings reads the embedding provider and the number of sections
+// used to answer questions from the environment
+func loadEmbeddings() {
+	if url := os.Getenv("EMBEDDING_BASE_URL"); url != "" {
+		addLLMProvider(LLMProviderConfig{
+			Name:           "embeddings",
+			Type:           "openai",
+			BaseURL:        strings.TrimSuffix(url, "/"),
+			APIKey:         os.Getenv("EMBEDDING_API_KEY"),
+			EmbeddingModel: os.Getenv("EMBEDDING_MODEL"),
+		})
+		EmbeddingProvider = "embeddings"
+	}
+	if name := os.Getenv("EMBEDDING_PROVIDER"); name != "" {
+		EmbeddingProvider = name
+	}
+
+	if value := os.Getenv("QUESTION_SECTIONS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			QuestionSections = n
+		} else {
+			log.Printf("Invalid QUESTION_SECTIONS %q", value)
+		}
+	}
+}
+
+// loadRetention reads the default retention policy and the pruner's
+// settings from the environment
+func loadRetention() {
+	for name, setting := range map[string]*int{
+		"REPORT_KEEP_WEEKLY":         &RetentionKeepWeekly,
+		"REPORT_KEEP_MONTHLY":        &RetentionKeepMonthly,
+		"REPORT_CANONICAL_ONLY_DAYS": &RetentionCanonicalOnlyDays,
+		"REPORT_SNAPSHOT_DAYS":       &RetentionSnapshotDays,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			*setting = n
+		} else {
+			log.Printf("Invalid %s %q", name, value)
+		}
+	}
+
+	if value := os.Getenv("REPORT_PRUNE_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			RetentionInterval = interval
+		} else {
+			log.Printf("Invalid REPORT_PRUNE_INTERVAL %q", value)
+		}
+	}
+	if value, err := strconv.ParseBool(os.Getenv("REPORT_PRUNE_DRY_RUN")); err == nil {
+		RetentionDryRun = value
+	}
+}
+
+// ReportStoreLocation returns the path of the report store, or its default
+// for the backend
+func ReportStoreLocation() string {
+	if ReportStorePath != "" {
+		return ReportStorePath
+	}
+	if ReportStoreBackend == "sqlite" {
+		return "./data/reports.db"
+	}
+	if ReportStoreBackend == "s3" {
+		// The bucket has no default
+		return ""
+	}
+	return "./data/reports"
+}
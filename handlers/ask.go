@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"agents_go/models"
+	"agents_go/services/agent"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AskRequest represents a question about a board's report history
+type AskRequest struct {
+	BoardID  string `json:"board_id"`
+	Question string `json:"question"`
+	// From and To limit the reports searched by when they were generated,
+	// as YYYY-MM-DD; either may be empty
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// AskResponse represents an answer with the report sections it cites
+type AskResponse struct {
+	*agent.Answer
+	// BudgetWarning is set when the user is over their soft monthly budget
+	BudgetWarning string `json:"budget_warning,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// AskHandler answers a question about a board's report history from its
+// most relevant report sections, citing them
+func AskHandler(w http.ResponseWriter, r *http.Request) {
+	// Set content type
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse request
+	var askReq AskRequest
+	if err := json.NewDecoder(r.Body).Decode(&askReq); err != nil || askReq.BoardID == "" || strings.TrimSpace(askReq.Question) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(AskResponse{
+			Error: "Invalid request format, expected board_id and question",
+		})
+		return
+	}
+
+	// Bound the time the reports were generated, including the whole of
+	// the last day
+	filter := models.ReportFilter{BoardID: askReq.BoardID}
+	if askReq.From != "" {
+		date, err := time.Parse("2006-01-02", askReq.From)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(AskResponse{Error: "invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = date
+	}
+	if askReq.To != "" {
+		date, err := time.Parse("2006-01-02", askReq.To)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(AskResponse{Error: "invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.To = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	answer, err := a.AskReports(r.Context(), userID, client, askReq.BoardID, askReq.Question, filter)
+	if err != nil {
+		log.Printf("Error answering question: %v", err)
+		w.WriteHeader(generationErrorStatus(err))
+		json.NewEncoder(w).Encode(AskResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(AskResponse{
+		Answer:        answer,
+		BudgetWarning: budgetWarning(a, userID),
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"agents_go/services/agent"
+
+	"github.com/gorilla/mux"
+)
+
+// ApplyChangeSetRequest selects the changes of a change set to apply
+type ApplyChangeSetRequest struct {
+	// Changes holds the indexes of the approved changes; omitted applies all
+	Changes []int `json:"changes"`
+}
+
+// UndoChangeSetRequest identifies the board whose last change set to undo
+type UndoChangeSetRequest struct {
+	BoardID string `json:"board_id"`
+}
+
+// ChangeSetsHandler lists the user's change sets, optionally for one board
+func ChangeSetsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	changeSets, err := a.ChangeSets(userID, r.URL.Query().Get("board_id"))
+	if err != nil {
+		log.Printf("Error listing change sets: %v", err)
+		http.Error(w, "Error listing change sets", http.StatusInternalServerError)
+		return
+	}
+
+	writeChangeSetJSON(w, changeSets)
+}
+
+// ChangeSetHandler returns one of the user's change sets
+func ChangeSetHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	changeSet, err := a.GetChangeSet(userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Change set not found", http.StatusNotFound)
+		return
+	}
+
+	writeChangeSetJSON(w, changeSet)
+}
+
+// ApplyChangeSetHandler applies the approved changes of a proposed change set
+func ApplyChangeSetHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	// An empty body applies every change
+	var applyReq ApplyChangeSetRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&applyReq); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	changeSet, err := a.ApplyChangeSet(userID, client, mux.Vars(r)["id"], applyReq.Changes)
+	if err != nil {
+		log.Printf("Error applying change set: %v", err)
+		http.Error(w, err.Error(), changeSetErrorStatus(err))
+		return
+	}
+
+	writeChangeSetJSON(w, changeSet)
+}
+
+// RejectChangeSetHandler discards a proposed change set
+func RejectChangeSetHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	changeSet, err := a.RejectChangeSet(userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeChangeSetJSON(w, changeSet)
+}
+
+// UndoChangeSetHandler reverts the user's last applied change set on a board
+func UndoChangeSetHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	var undoReq UndoChangeSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&undoReq); err != nil || undoReq.BoardID == "" {
+		http.Error(w, "Board ID is required", http.StatusBadRequest)
+		return
+	}
+
+	changeSet, err := a.UndoLastChangeSet(userID, client, undoReq.BoardID)
+	if err != nil {
+		log.Printf("Error undoing change set: %v", err)
+		if changeSet == nil {
+			http.Error(w, err.Error(), changeSetErrorStatus(err))
+			return
+		}
+		// Some changes couldn't be reverted; report which ones
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(changeSet)
+		return
+	}
+
+	writeChangeSetJSON(w, changeSet)
+}
+
+// changeSetErrorStatus returns the HTTP status for a change set that
+// couldn't be applied or undone
+func changeSetErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrBoardAccess) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// writeChangeSetJSON writes a change set response
+func writeChangeSetJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -1,24 +1,40 @@
 package handlers
 
 import (
-	"agents_go/services/aifoundry"
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/agent"
+	"agents_go/services/trello"
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"github.com/gorilla/mux"
 )
 
 // ChatRequest represents a request to the chat endpoint
 type ChatRequest struct {
 	Message string `json:"message"`
+	// SessionID continues an existing conversation; empty starts a new one
+	SessionID string `json:"session_id,omitempty"`
+	// BoardID grounds a new conversation in a board's data
+	BoardID string `json:"board_id,omitempty"`
 }
 
 // ChatResponse represents a response from the chat endpoint
 type ChatResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	Response  string `json:"response"`
+	SessionID string `json:"session_id,omitempty"`
+	// ChangeSet holds board changes proposed in the reply, waiting for approval
+	ChangeSet *models.ChangeSet `json:"change_set,omitempty"`
+	// Usage is the tokens and estimated cost of the reply
+	Usage *models.Usage `json:"usage,omitempty"`
+	// BudgetWarning is set when the user is over their soft monthly budget
+	BudgetWarning string `json:"budget_warning,omitempty"`
+	Error         string `json:"error,omitempty"`
 }
 
-// ChatHandler handles chat requests to test the Mistral model
+// ChatHandler answers a chat message, continuing or starting a session
 func ChatHandler(w http.ResponseWriter, r *http.Request) {
 	// Set content type
 	w.Header().Set("Content-Type", "application/json")
@@ -34,7 +50,7 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request
 	var chatReq ChatRequest
-	if err := json.NewDecoder(r.Body).Decode(&chatReq); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&chatReq); err != nil || chatReq.Message == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Error: "Invalid request format",
@@ -42,21 +58,146 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create Mistral client
-	client := aifoundry.NewClient()
-	// Send message to Mistral
-	response, err := client.SendChatMessage(chatReq.Message)
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	// Send the message with the session's history and board context
+	session, err := a.Chat(userID, client, chatReq.SessionID, chatReq.BoardID, chatReq.Message)
 	if err != nil {
 		log.Printf("Error sending chat message: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(generationErrorStatus(err))
 		json.NewEncoder(w).Encode(ChatResponse{
-			Error: "Error generating response: " + err.Error(),
+			Error: err.Error(),
 		})
 		return
 	}
 
-	// Return response
-	json.NewEncoder(w).Encode(ChatResponse{
-		Response: response,
-	})
+	// Return the reply with any proposed changes
+	json.NewEncoder(w).Encode(chatResponse(a, userID, session))
+}
+
+// chatResponse builds the response for the last reply in a session
+func chatResponse(a *agent.Agent, userID string, session *models.ChatSession) ChatResponse {
+	reply := session.Messages[len(session.Messages)-1]
+	chatResp := ChatResponse{
+		Response:      reply.Content,
+		SessionID:     session.ID,
+		Usage:         reply.Usage,
+		BudgetWarning: budgetWarning(a, userID),
+	}
+	if reply.ChangeSetID != "" {
+		var err error
+		chatResp.ChangeSet, err = a.GetChangeSet(userID, reply.ChangeSetID)
+		if err != nil {
+			log.Printf("Error getting change set: %v", err)
+		}
+	}
+	return chatResp
+}
+
+// ChatSessionsHandler lists the user's chat sessions, optionally for one board
+func ChatSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	sessions, err := a.ChatSessions(userID, r.URL.Query().Get("board_id"))
+	if err != nil {
+		log.Printf("Error listing chat sessions: %v", err)
+		http.Error(w, "Error listing chat sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// ChatSessionHandler returns or deletes one of the user's chat sessions
+func ChatSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	sessionID := mux.Vars(r)["id"]
+
+	if r.Method == http.MethodDelete {
+		if err := a.DeleteChatSession(userID, sessionID); err != nil {
+			http.Error(w, "Chat session not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	session, err := a.GetChatSession(userID, sessionID)
+	if err != nil {
+		http.Error(w, "Chat session not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// currentUserID returns the Trello member ID of the logged in user, looking
+// it up once and caching it in the session. It writes an error response and
+// returns an empty string if the user is not authenticated.
+func currentUserID(w http.ResponseWriter, r *http.Request) string {
+	session, _ := config.Store.Get(r, "trello-oauth")
+	if memberID, ok := session.Values["memberID"].(string); ok && memberID != "" {
+		return memberID
+	}
+
+	accessToken, ok1 := session.Values["accessToken"].(string)
+	accessSecret, ok2 := session.Values["accessSecret"].(string)
+	if !ok1 || !ok2 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return ""
+	}
+
+	member, err := trello.NewClient(accessToken, accessSecret).GetMember()
+	if err != nil || member.ID == "" {
+		log.Printf("Error getting member: %v", err)
+		http.Error(w, "Error getting user information", http.StatusInternalServerError)
+		return ""
+	}
+
+	session.Values["memberID"] = member.ID
+	session.Save(r, w)
+
+	return member.ID
+}
+
+// userTrelloClient returns a Trello client acting as the logged in user,
+// rather than as the agent's owner. It writes an error response and returns
+// nil if the user is not authenticated.
+func userTrelloClient(w http.ResponseWriter, r *http.Request) *trello.Client {
+	session, _ := config.Store.Get(r, "trello-oauth")
+	accessToken, ok1 := session.Values["accessToken"].(string)
+	accessSecret, ok2 := session.Values["accessSecret"].(string)
+	if !ok1 || !ok2 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	return trello.NewClient(accessToken, accessSecret)
 }
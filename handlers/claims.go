@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"html/template"
+	"sort"
+	"strings"
+
+	"agents_go/models"
+)
+
+// highlightedSection is a report section with the unverified claims marked
+type highlightedSection struct {
+	Title      string
+	Paragraphs []template.HTML
+	Items      []template.HTML
+}
+
+// highlightSections marks the unverified claims in each section's text
+func highlightSections(sections []models.ReportSection, claims []models.Claim) []highlightedSection {
+	result := make([]highlightedSection, 0, len(sections))
+	for _, section := range sections {
+		highlighted := highlightedSection{Title: section.Title}
+		for _, paragraph := range section.Paragraphs {
+			highlighted.Paragraphs = append(highlighted.Paragraphs, highlightClaims(paragraph, claims))
+		}
+		for _, item := range section.Items {
+			highlighted.Items = append(highlighted.Items, highlightClaims(item, claims))
+		}
+		result = append(result, highlighted)
+	}
+	return result
+}
+
+// highlightClaims escapes text and wraps each occurrence of a claim in a
+// <mark> with the reason as its title. Overlapping claims keep the first.
+func highlightClaims(text string, claims []models.Claim) template.HTML {
+	type span struct {
+		start, end int
+		reason     string
+	}
+
+	// Find every occurrence of every claim
+	var spans []span
+	lower := strings.ToLower(text)
+	for _, claim := range claims {
+		needle := strings.ToLower(claim.Text)
+		if needle == "" {
+			continue
+		}
+		for offset := 0; ; {
+			i := strings.Index(lower[offset:], needle)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			spans = append(spans, span{start: start, end: start + len(needle), reason: claim.Reason})
+			offset = start + len(needle)
+		}
+	}
+	if len(spans) == 0 {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		sb.WriteString(template.HTMLEscapeString(text[last:s.start]))
+		sb.WriteString(`<mark class="unverified-claim" title="`)
+		sb.WriteString(template.HTMLEscapeString(s.reason))
+		sb.WriteString(`">`)
+		sb.WriteString(template.HTMLEscapeString(text[s.start:s.end]))
+		sb.WriteString(`</mark>`)
+		last = s.end
+	}
+	sb.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(sb.String())
+}
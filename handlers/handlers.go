@@ -21,7 +21,7 @@ func InitTemplates() {
 	baseTemplate := filepath.Join("templates", "base.html")
 	
 	// Parse each template with the base template
-	templateFiles := []string{"home.html", "dashboard.html", "reports.html", "view_report.html"}
+	templateFiles := []string{"home.html", "dashboard.html", "reports.html", "view_report.html", "prompts.html", "usage.html", "search.html"}
 	for _, file := range templateFiles {
 		templatePath := filepath.Join("templates", file)
 		tmpl, err := template.ParseFiles(baseTemplate, templatePath)
@@ -90,9 +90,11 @@ func CallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store the access token in the session
+	// Store the access token in the session, dropping the member ID cached
+	// for an earlier login so currentUserID looks up the new user's
 	session.Values["accessToken"] = accessToken.Token
 	session.Values["accessSecret"] = accessToken.Secret
+	delete(session.Values, "memberID")
 	session.Save(r, w)
 
 	// Redirect to the dashboard
@@ -119,7 +121,7 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Make a request to get user information
 	userResp, err := config.Consumer.Get(
-		"https://api.trello.com/1/members/me",
+		config.TrelloAPIURL+"/members/me",
 		map[string]string{},
 		token,
 	)
@@ -140,7 +142,7 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get the user's boards
 	boardsResp, err := config.Consumer.Get(
-		"https://api.trello.com/1/members/me/boards",
+		config.TrelloAPIURL+"/members/me/boards",
 		map[string]string{"fields": "name,url,desc,shortUrl"},
 		token,
 	)
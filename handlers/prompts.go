@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"agents_go/models"
+	"agents_go/services/agent"
+	"agents_go/services/prompts"
+)
+
+// promptsPage holds the state of the prompt template editor
+type promptsPage struct {
+	BoardID      string
+	ReportType   models.ReportType
+	Template     string
+	Text         string
+	Note         string
+	Preview      string
+	PreviewError string
+}
+
+// PromptsHandler displays the prompt template editor
+func PromptsHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	page := promptsPage{
+		BoardID:    query.Get("board_id"),
+		ReportType: models.ReportType(query.Get("report_type")),
+		Template:   query.Get("template"),
+	}
+	if page.ReportType != models.Monthly {
+		page.ReportType = models.Weekly
+	}
+
+	// Start from the board's selected template if none was requested
+	if page.Template == "" {
+		page.Template = prompts.DefaultTemplateName(string(page.ReportType))
+		if page.BoardID != "" {
+			if selection, err := a.SelectedPrompt(page.BoardID, page.ReportType); err == nil {
+				page.Template = selection.Template
+			}
+		}
+	}
+
+	version, _ := strconv.Atoi(query.Get("version"))
+	tmpl, err := a.GetPromptTemplate(page.Template, version)
+	if err != nil {
+		log.Printf("Error getting prompt template: %v", err)
+		http.Error(w, "Prompt template not found", http.StatusNotFound)
+		return
+	}
+	page.Text = tmpl.Text
+
+	renderPromptsPage(w, a, page)
+}
+
+// PromptPreviewHandler renders the submitted template text with the board's
+// current data without saving it
+func PromptPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	page := promptsPage{
+		BoardID:    r.FormValue("board_id"),
+		ReportType: models.ReportType(r.FormValue("report_type")),
+		Template:   r.FormValue("template"),
+		Text:       r.FormValue("text"),
+		Note:       r.FormValue("note"),
+	}
+
+	// Render with live board data when previewing for a board, otherwise
+	// with sample data
+	var preview string
+	var err error
+	if page.BoardID != "" {
+		preview, err = a.PreviewPrompt(page.BoardID, page.ReportType, page.Text)
+	} else {
+		preview, err = prompts.Render(page.Text, prompts.SampleData(string(page.ReportType)))
+	}
+	if err != nil {
+		page.PreviewError = err.Error()
+	} else {
+		page.Preview = preview
+	}
+
+	renderPromptsPage(w, a, page)
+}
+
+// PromptSaveHandler saves the submitted template text as a new version
+func PromptSaveHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	page := promptsPage{
+		BoardID:    r.FormValue("board_id"),
+		ReportType: models.ReportType(r.FormValue("report_type")),
+		Template:   r.FormValue("template"),
+		Text:       r.FormValue("text"),
+		Note:       r.FormValue("note"),
+	}
+
+	tmpl, err := a.SavePromptTemplate(page.Template, page.Text, page.Note)
+	if err != nil {
+		// Show the error in the editor so the text isn't lost
+		page.PreviewError = err.Error()
+		renderPromptsPage(w, a, page)
+		return
+	}
+
+	http.Redirect(w, r, promptsURL(page.BoardID, page.ReportType, tmpl.Name), http.StatusSeeOther)
+}
+
+// PromptSelectHandler selects the prompt template a board uses for a report type
+func PromptSelectHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	boardID := r.FormValue("board_id")
+	reportType := models.ReportType(r.FormValue("report_type"))
+	if boardID == "" || (reportType != models.Weekly && reportType != models.Monthly) {
+		http.Error(w, "Missing parameters", http.StatusBadRequest)
+		return
+	}
+
+	version, _ := strconv.Atoi(r.FormValue("version"))
+	selection := models.PromptSelection{
+		Template: r.FormValue("template"),
+		Version:  version,
+	}
+
+	if err := a.SelectPrompt(boardID, reportType, selection); err != nil {
+		log.Printf("Error selecting prompt template: %v", err)
+		http.Error(w, "Invalid prompt template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, promptsURL(boardID, reportType, selection.Template), http.StatusSeeOther)
+}
+
+// renderPromptsPage renders the prompt editor with the template list, the
+// version history of the current template and the board's selections
+func renderPromptsPage(w http.ResponseWriter, a *agent.Agent, page promptsPage) {
+	names, err := a.PromptTemplates()
+	if err != nil {
+		log.Printf("Error listing prompt templates: %v", err)
+		http.Error(w, "Error listing prompt templates", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := a.PromptHistory(page.Template)
+	if err != nil {
+		log.Printf("Error getting prompt history: %v", err)
+	}
+
+	selections := map[models.ReportType]models.PromptSelection{}
+	if page.BoardID != "" {
+		for _, reportType := range []models.ReportType{models.Weekly, models.Monthly} {
+			if selection, err := a.SelectedPrompt(page.BoardID, reportType); err == nil {
+				selections[reportType] = selection
+			}
+		}
+	}
+
+	data := map[string]interface{}{
+		"Title":      "Prompt Templates",
+		"Page":       page,
+		"Templates":  names,
+		"History":    history,
+		"Selections": selections,
+	}
+	Templates["prompts.html"].Execute(w, data)
+}
+
+// promptsURL returns the editor URL for a template
+func promptsURL(boardID string, reportType models.ReportType, template string) string {
+	query := url.Values{}
+	if boardID != "" {
+		query.Set("board_id", boardID)
+	}
+	query.Set("report_type", string(reportType))
+	query.Set("template", template)
+	return fmt.Sprintf("/prompts?%s", query.Encode())
+}
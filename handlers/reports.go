@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"agents_go/config"
 	"agents_go/models"
@@ -70,11 +74,11 @@ func ReportsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get reports for the board
-	reports, err := reportAgent.GetReportsByBoard(boardID)
+	// Get the board's reports, grouped into the revisions of each period
+	reports, err := reportAgent.GetReportGroups(boardID)
 	if err != nil {
 		log.Printf("Error getting reports: %v", err)
-		reports = []*models.Report{} // Set to empty if error
+		reports = []*models.ReportRevisions{} // Set to empty if error
 	}
 
 	// Create a token for API calls
@@ -85,7 +89,7 @@ func ReportsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get board details
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s", boardID),
+		fmt.Sprintf("%s/boards/%s", config.TrelloAPIURL, boardID),
 		map[string]string{"fields": "name,desc"},
 		token,
 	)
@@ -103,11 +107,30 @@ func ReportsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get the board's settings
+	settings, err := reportAgent.GetBoardSettings(boardID)
+	if err != nil {
+		log.Printf("Error getting board settings: %v", err)
+		settings = &models.BoardSettings{BoardID: boardID}
+	}
+
+	// Warn users who are over their soft budget
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+
 	// Render the reports template
 	data := map[string]interface{}{
-		"Title":   "Trello Reports",
-		"Board":   board,
-		"Reports": reports,
+		"Title":         "Trello Reports",
+		"Board":         board,
+		"Reports":       reports,
+		"Settings":      settings,
+		"Providers":     reportAgent.LLMProviders(),
+		"Languages":     models.Languages,
+		"Audiences":     models.Audiences,
+		"BudgetWarning": budgetWarning(reportAgent, userID),
+		"Retention":     reportAgent.RetentionPolicy(settings),
 	}
 	Templates["reports.html"].Execute(w, data)
 }
@@ -156,8 +179,21 @@ func GenerateReportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An empty language uses the board's setting
+	language, err := parseLanguage(r.FormValue("language"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Several audiences produce linked reports; none uses the board's
+	audiences, err := parseAudiences(r.Form["audience"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Create agent if not already created
-	var err error
 	if reportAgent == nil {
 		reportAgent, err = agent.NewAgent(accessToken, accessSecret, agent.ReportSchedule{
 			Weekly:  true,
@@ -171,9 +207,22 @@ func GenerateReportHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate report
-	report, err := reportAgent.GenerateReportOnDemand(boardID, rType)
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	report, err := reportAgent.GenerateReportStream(r.Context(), boardID, rType, agent.GenerateOptions{
+		UserID:          userID,
+		ForceRegenerate: r.FormValue("force") != "",
+		Language:        language,
+		Audiences:       audiences,
+	})
 	if err != nil {
 		log.Printf("Error generating report: %v", err)
+		if errors.Is(err, agent.ErrBudgetExceeded) {
+			http.Error(w, err.Error(), http.StatusPaymentRequired)
+			return
+		}
 		http.Error(w, "Error generating report", http.StatusInternalServerError)
 		return
 	}
@@ -228,6 +277,23 @@ func ViewReportHandler(w http.ResponseWriter, r *http.Request) {
 		"Title":  fmt.Sprintf("%s Report - %s", report.Type, report.BoardName),
 		"Report": report,
 	}
+	// Link the reports written for other audiences from the same data
+	var variants []*models.Report
+	for _, id := range report.Variants {
+		if variant, err := reportAgent.GetReport(id); err == nil {
+			variants = append(variants, variant)
+		}
+	}
+	data["Variants"] = variants
+	// List the other revisions of the report's period
+	if revisions, err := reportAgent.GetReportRevisions(report.ID); err == nil {
+		data["Revisions"] = revisions
+	} else {
+		log.Printf("Error getting report revisions: %v", err)
+	}
+	if report.Structured != nil {
+		data["Sections"] = highlightSections(report.Structured.Sections(report.Type, report.Language), report.UnverifiedClaims)
+	}
 	Templates["view_report.html"].Execute(w, data)
 }
 
@@ -272,21 +338,17 @@ func DownloadReportPDFHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create PDF generator
-	pdfGenerator := pdf.NewGenerator()
-
-	// Generate PDF from report content
-	pdfBuffer, err := pdfGenerator.GenerateReport(
-		report.Content,
-		report.BoardName,
-		string(report.Type),
-		report.StartDate,
-		report.EndDate,
-	)
-	if err != nil {
-		log.Printf("Error generating PDF: %v", err)
-		http.Error(w, "Error generating PDF", http.StatusInternalServerError)
-		return
+	// Serve the stored PDF if the report store keeps them, otherwise
+	// render it and store it for next time
+	pdfBytes := reportAgent.GetReportPDF(report.ID)
+	if pdfBytes == nil {
+		pdfBytes, err = renderReportPDF(report)
+		if err != nil {
+			log.Printf("Error generating PDF: %v", err)
+			http.Error(w, "Error generating PDF", http.StatusInternalServerError)
+			return
+		}
+		reportAgent.SaveReportPDF(report, pdfBytes)
 	}
 
 	// Set response headers for PDF download
@@ -297,9 +359,269 @@ func DownloadReportPDFHandler(w http.ResponseWriter, r *http.Request) {
 		report.GeneratedAt.Format("2006-01-02")))
 
 	// Write PDF buffer to response
-	if _, err := w.Write(pdfBuffer.Bytes()); err != nil {
+	if _, err := w.Write(pdfBytes); err != nil {
 		log.Printf("Error writing PDF to response: %v", err)
 		http.Error(w, "Error serving PDF", http.StatusInternalServerError)
 		return
 	}
 }
+
+// requireAgent returns the report agent, creating it from the session's
+// Trello credentials if needed. It writes an error response and returns nil
+// if the user is not authenticated or the agent can't be created.
+func requireAgent(w http.ResponseWriter, r *http.Request) *agent.Agent {
+	// Check if the user is authenticated
+	session, _ := config.Store.Get(r, "trello-oauth")
+	accessToken, ok1 := session.Values["accessToken"].(string)
+	accessSecret, ok2 := session.Values["accessSecret"].(string)
+
+	if !ok1 || !ok2 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	// Create agent if not already created
+	if reportAgent == nil {
+		var err error
+		reportAgent, err = agent.NewAgent(accessToken, accessSecret, agent.ReportSchedule{
+			Weekly:  true,
+			Monthly: true,
+		})
+		if err != nil {
+			log.Printf("Error creating agent: %v", err)
+			http.Error(w, "Error creating agent", http.StatusInternalServerError)
+			return nil
+		}
+	}
+
+	return reportAgent
+}
+
+// BoardSettingsHandler saves the per-board report settings
+func BoardSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	boardID := r.FormValue("board_id")
+	if boardID == "" {
+		http.Error(w, "Missing board ID", http.StatusBadRequest)
+		return
+	}
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	settings, err := a.GetBoardSettings(boardID)
+	if err != nil {
+		log.Printf("Error getting board settings: %v", err)
+		http.Error(w, "Error getting board settings", http.StatusInternalServerError)
+		return
+	}
+
+	settings.LLMProvider = r.FormValue("llm_provider")
+	settings.LLMModel = r.FormValue("llm_model")
+	settings.UseTools = r.FormValue("use_tools") == "on"
+	settings.CorrectClaims = r.FormValue("correct_claims") == "on"
+	if settings.Language, err = models.ParseLanguage(r.FormValue("language")); err != nil {
+		http.Error(w, "Invalid board settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if settings.Audiences, err = parseAudiences(r.Form["audiences"]); err != nil {
+		http.Error(w, "Invalid board settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	settings.Redaction = &models.RedactionRules{
+		Members:   r.FormValue("redact_members") == "on",
+		Emails:    r.FormValue("redact_emails") == "on",
+		Phones:    r.FormValue("redact_phones") == "on",
+		TokenURLs: r.FormValue("redact_urls") == "on",
+	}
+	for _, pattern := range strings.Split(r.FormValue("redact_patterns"), "\n") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			settings.Redaction.Patterns = append(settings.Redaction.Patterns, pattern)
+		}
+	}
+	if settings.Retention, err = parseRetention(r); err != nil {
+		http.Error(w, "Invalid board settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.SaveBoardSettings(client, settings); err != nil {
+		log.Printf("Error saving board settings: %v", err)
+		if errors.Is(err, agent.ErrBoardAccess) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Invalid board settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/reports?board_id=%s", boardID), http.StatusSeeOther)
+}
+
+// ReportAPIHandler returns a report as JSON, including its structured form
+func ReportAPIHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	// Get a report by ID, or the canonical revision of a period's report
+	query := r.URL.Query()
+	reportID := query.Get("id")
+	period := query.Get("period")
+	if reportID == "" && period == "" {
+		http.Error(w, "Missing report ID or period", http.StatusBadRequest)
+		return
+	}
+
+	var report *models.Report
+	var err error
+	if reportID != "" {
+		report, err = a.GetReport(reportID)
+	} else {
+		var audience models.Audience
+		if audience, err = models.ParseAudience(query.Get("audience")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err = a.GetCanonicalReport(period, audience)
+	}
+	if err != nil {
+		log.Printf("Error getting report: %v", err)
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// parseRetention reads a board's retention policy from the settings form;
+// nil uses the default policy
+func parseRetention(r *http.Request) (*models.RetentionPolicy, error) {
+	if r.FormValue("custom_retention") != "on" {
+		return nil, nil
+	}
+
+	values := make(map[string]int)
+	for _, name := range []string{"keep_weekly", "keep_monthly", "canonical_only_days", "snapshot_days"} {
+		value := strings.TrimSpace(r.FormValue(name))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s must be a whole number of zero or more", strings.ReplaceAll(name, "_", " "))
+		}
+		values[name] = n
+	}
+
+	return &models.RetentionPolicy{
+		Keep: map[models.ReportType]int{
+			models.Weekly:  values["keep_weekly"],
+			models.Monthly: values["keep_monthly"],
+		},
+		CanonicalOnlyAfterDays: values["canonical_only_days"],
+		SnapshotDays:           values["snapshot_days"],
+	}, nil
+}
+
+// PinReportHandler pins a report so retention never deletes it, or unpins
+// it
+func PinReportHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	reportID := r.FormValue("id")
+	if reportID == "" {
+		http.Error(w, "Missing report ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.SetReportPinned(reportID, r.FormValue("pinned") == "true"); err != nil {
+		log.Printf("Error pinning report: %v", err)
+		if errors.Is(err, models.ErrReportNotFound) {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error pinning report", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/view-report?id=%s", reportID), http.StatusSeeOther)
+}
+
+// CanonicalRevisionHandler marks a report revision as the canonical one of
+// its period
+func CanonicalRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	reportID := r.FormValue("id")
+	if reportID == "" {
+		http.Error(w, "Missing report ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.SetCanonicalRevision(reportID); err != nil {
+		log.Printf("Error marking canonical revision: %v", err)
+		if errors.Is(err, models.ErrReportNotFound) {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error marking canonical revision", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/view-report?id=%s", reportID), http.StatusSeeOther)
+}
+
+// renderReportPDF renders a report as a PDF
+func renderReportPDF(report *models.Report) ([]byte, error) {
+	// Create PDF generator
+	pdfGenerator := pdf.NewGenerator()
+	pdfGenerator.Language = report.Language
+	if report.WithoutAI {
+		pdfGenerator.Notice = "Generated without AI: no model was available, so this report was rendered from board statistics."
+	}
+
+	// Generate PDF from the structured report if there is one, otherwise
+	// from the report content
+	var pdfBuffer *bytes.Buffer
+	var err error
+	if report.Structured != nil {
+		pdfBuffer, err = pdfGenerator.GenerateStructuredReport(
+			report.Structured,
+			report.BoardName,
+			string(report.Type),
+			report.StartDate,
+			report.EndDate,
+		)
+	} else {
+		pdfBuffer, err = pdfGenerator.GenerateReport(
+			report.Content,
+			report.BoardName,
+			string(report.Type),
+			report.StartDate,
+			report.EndDate,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pdfBuffer.Bytes(), nil
+}
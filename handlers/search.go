@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/agent"
+	"agents_go/services/search"
+)
+
+const (
+	// defaultSearchLimit is the number of search results on a page
+	defaultSearchLimit = 20
+	// maxSearchLimit caps the results on a page of /api/search
+	maxSearchLimit = 100
+)
+
+// SearchHandler shows the search page and, with a query, its results
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	query, err := parseSearchQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only search the boards the user can read
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+	results, err := a.SearchReports(client, query)
+	if err != nil {
+		log.Printf("Error searching reports: %v", err)
+		http.Error(w, "Error searching reports", searchErrorStatus(err))
+		return
+	}
+
+	// Link the previous and next pages
+	params := r.URL.Query()
+	page := func(offset int) string {
+		params.Set("offset", strconv.Itoa(offset))
+		return "/search?" + params.Encode()
+	}
+	data := map[string]interface{}{
+		"Title":   "Search Reports",
+		"Params":  r.URL.Query(),
+		"Boards":  a.SearchBoards(client),
+		"Results": results,
+		"From":    query.Filter.Offset + 1,
+		"To":      query.Filter.Offset + len(results.Results),
+	}
+	if query.Filter.Offset > 0 {
+		previous := query.Filter.Offset - query.Filter.Limit
+		if previous < 0 {
+			previous = 0
+		}
+		data["PreviousPage"] = page(previous)
+	}
+	if query.Filter.Offset+len(results.Results) < results.Total {
+		data["NextPage"] = page(query.Filter.Offset + query.Filter.Limit)
+	}
+	Templates["search.html"].Execute(w, data)
+}
+
+// SearchAPIHandler returns the results of a search as JSON
+func SearchAPIHandler(w http.ResponseWriter, r *http.Request) {
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+	query, err := parseSearchQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only search the boards the user can read
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+	results, err := a.SearchReports(client, query)
+	if err != nil {
+		log.Printf("Error searching reports: %v", err)
+		http.Error(w, "Error searching reports", searchErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// searchErrorStatus returns the HTTP status for a failed search
+func searchErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrBoardAccess) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// parseSearchQuery reads a search from the query parameters: q, board_id,
+// type, from and to (YYYY-MM-DD, inclusive), sort, all_revisions, offset
+// and limit
+func parseSearchQuery(r *http.Request) (search.Query, error) {
+	params := r.URL.Query()
+	query := search.Query{
+		Text: params.Get("q"),
+		Sort: params.Get("sort"),
+		Filter: models.ReportFilter{
+			BoardID: params.Get("board_id"),
+			Type:    models.ReportType(params.Get("type")),
+			Limit:   defaultSearchLimit,
+		},
+	}
+
+	switch query.Filter.Type {
+	case "", models.Weekly, models.Monthly:
+	default:
+		return query, fmt.Errorf("invalid report type %q", query.Filter.Type)
+	}
+	switch query.Sort {
+	case "", search.SortRelevance, search.SortNewest, search.SortOldest:
+	default:
+		return query, fmt.Errorf("invalid sort %q, expected relevance, newest or oldest", query.Sort)
+	}
+
+	// Bound the time the reports were generated, including the whole of
+	// the last day
+	if from := params.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return query, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		query.Filter.From = date
+	}
+	if to := params.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return query, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		query.Filter.To = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	all := params.Get("all_revisions")
+	query.AllRevisions = all == "true" || all == "on"
+
+	if offset := params.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("invalid offset")
+		}
+		query.Filter.Offset = n
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return query, fmt.Errorf("invalid limit, expected 1 to %d", maxSearchLimit)
+		}
+		query.Filter.Limit = n
+	}
+	return query, nil
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/agent"
+	"agents_go/services/aifoundry"
+)
+
+// sseKeepAlive is how often a comment is sent on an idle stream so proxies
+// don't close it while the model is working
+const sseKeepAlive = 15 * time.Second
+
+// sseWriter writes server-sent events to a response
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	mutex   sync.Mutex
+	stop    chan struct{}
+	// closed is set once the handler is done with the response, after
+	// which nothing may be written to it
+	closed bool
+}
+
+// newSSEWriter starts an event stream, writing an error response and
+// returning nil if the connection doesn't support streaming
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return nil
+	}
+
+	// Streams outlive the server's write timeout, so lift it for this response
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing write deadline for stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s := &sseWriter{w: w, flusher: flusher, stop: make(chan struct{})}
+	go s.keepAlive()
+	return s
+}
+
+// send writes an event with a JSON payload
+func (s *sseWriter) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("stream is closed")
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// keepAlive sends a comment periodically until the stream is closed
+func (s *sseWriter) keepAlive() {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// A tick can race with close, so check under the lock
+			s.mutex.Lock()
+			if !s.closed {
+				fmt.Fprint(s.w, ": keep-alive\n\n")
+				s.flusher.Flush()
+			}
+			s.mutex.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// close stops the keep-alive and any further writes; the response ends when
+// the handler returns. It waits for a write in progress, so nothing touches
+// the response after the handler is done with it.
+func (s *sseWriter) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.stop)
+}
+
+// progress returns a Progress that forwards status updates, reply deltas and
+// report previews to the stream
+func (s *sseWriter) progress() *aifoundry.Progress {
+	return &aifoundry.Progress{
+		Status: func(message string) {
+			s.send("status", map[string]string{"message": message})
+		},
+		Delta: func(text string) error {
+			return s.send("delta", map[string]string{"text": text})
+		},
+		Preview: func(content string) error {
+			return s.send("preview", map[string]string{"content": content})
+		},
+	}
+}
+
+// ChatStreamHandler answers a chat message like ChatHandler, streaming the
+// reply as server-sent events: "delta" events carry chunks of the reply,
+// "status" events the tools being called, and a final "done" event the
+// ChatResponse. Errors are sent as an "error" event. The session is only
+// saved if the reply completes.
+func ChatStreamHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var chatReq ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&chatReq); err != nil || chatReq.Message == "" {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	client := userTrelloClient(w, r)
+	if client == nil {
+		return
+	}
+
+	stream := newSSEWriter(w)
+	if stream == nil {
+		return
+	}
+	defer stream.close()
+
+	// The request context is canceled if the browser disconnects
+	session, err := a.ChatStream(r.Context(), userID, client, chatReq.SessionID, chatReq.BoardID, chatReq.Message, stream.progress())
+	if err != nil {
+		log.Printf("Error streaming chat message: %v", err)
+		stream.send("error", ChatResponse{Error: err.Error()})
+		return
+	}
+
+	// Send the complete reply with any proposed changes
+	stream.send("done", chatResponse(a, userID, session))
+}
+
+// ReportStreamHandler generates a report, streaming "status" events for each
+// step and "preview" events with the report written so far. The report is
+// saved only if generation completes, and the final "done" event carries
+// its ID and URL. It is a GET endpoint so browsers can use EventSource.
+func ReportStreamHandler(w http.ResponseWriter, r *http.Request) {
+	boardID := r.URL.Query().Get("board_id")
+	reportType, ok := parseReportType(r.URL.Query().Get("report_type"))
+	if boardID == "" || !ok {
+		http.Error(w, "Missing or invalid parameters", http.StatusBadRequest)
+		return
+	}
+	language, err := parseLanguage(r.URL.Query().Get("language"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audiences, err := parseAudiences(r.URL.Query()["audience"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	stream := newSSEWriter(w)
+	if stream == nil {
+		return
+	}
+	defer stream.close()
+
+	stream.send("status", map[string]string{"message": "Getting board data"})
+
+	report, err := a.GenerateReportStream(r.Context(), boardID, reportType, agent.GenerateOptions{
+		UserID:          userID,
+		ForceRegenerate: r.URL.Query().Get("force") != "",
+		Progress:        stream.progress(),
+		Language:        language,
+		Audiences:       audiences,
+	})
+	if err != nil {
+		log.Printf("Error streaming report: %v", err)
+		stream.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+
+	stream.send("done", map[string]string{
+		"report_id": report.ID,
+		"url":       "/view-report?id=" + report.ID,
+	})
+}
+
+// LiveReportHandler shows the report view for a report that is being
+// generated; the page streams it from ReportStreamHandler
+func LiveReportHandler(w http.ResponseWriter, r *http.Request) {
+	boardID := r.URL.Query().Get("board_id")
+	reportType, ok := parseReportType(r.URL.Query().Get("report_type"))
+	if boardID == "" || !ok {
+		http.Error(w, "Missing or invalid parameters", http.StatusBadRequest)
+		return
+	}
+	language, err := parseLanguage(r.URL.Query().Get("language"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audiences, err := parseAudiences(r.URL.Query()["audience"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a := requireAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	boardName := boardID
+	if board, err := a.GetBoardDetails(boardID); err == nil {
+		boardName = board.Name
+	}
+
+	// Show the header of the report being generated
+	report := &models.Report{
+		BoardID:   boardID,
+		BoardName: boardName,
+		Type:      reportType,
+		Language:  language,
+	}
+
+	data := map[string]interface{}{
+		"Title":     fmt.Sprintf("Generating %s Report - %s", reportType, boardName),
+		"Report":    report,
+		"Live":      true,
+		"Force":     r.URL.Query().Get("force") != "",
+		"Audiences": audiences,
+	}
+	Templates["view_report.html"].Execute(w, data)
+}
+
+// parseLanguage validates a report language parameter; an empty value is
+// left empty so the board's setting is used
+func parseLanguage(value string) (models.Language, error) {
+	if value == "" {
+		return "", nil
+	}
+	return models.ParseLanguage(value)
+}
+
+// parseAudiences validates the audience parameters; none leaves the choice
+// to the board's settings
+func parseAudiences(values []string) ([]models.Audience, error) {
+	var audiences []models.Audience
+	for _, value := range values {
+		audience, err := models.ParseAudience(value)
+		if err != nil {
+			return nil, err
+		}
+		audiences = append(audiences, audience)
+	}
+	return audiences, nil
+}
+
+// parseReportType validates a report type parameter
+func parseReportType(value string) (models.ReportType, bool) {
+	switch value {
+	case string(models.Weekly):
+		return models.Weekly, true
+	case string(models.Monthly):
+		return models.Monthly, true
+	default:
+		return "", false
+	}
+}
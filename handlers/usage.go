@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/agent"
+)
+
+// UsageResponse is a user's LLM usage for a month and their budget
+type UsageResponse struct {
+	Summary *models.UsageSummary `json:"summary"`
+	Budget  *agent.BudgetStatus  `json:"budget"`
+	// Months lists the months with usage, newest first
+	Months []string `json:"months"`
+}
+
+// UsageHandler shows the user's token usage and estimated cost for a month,
+// in total and per board
+func UsageHandler(w http.ResponseWriter, r *http.Request) {
+	usage := usageResponse(w, r)
+	if usage == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "LLM Usage",
+		"Usage": usage,
+	}
+	Templates["usage.html"].Execute(w, data)
+}
+
+// UsageAPIHandler returns the user's usage for a month as JSON
+func UsageAPIHandler(w http.ResponseWriter, r *http.Request) {
+	usage := usageResponse(w, r)
+	if usage == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// usageResponse gets the usage for the month in the "month" parameter,
+// defaulting to the current month. It writes an error response and returns
+// nil if that fails.
+func usageResponse(w http.ResponseWriter, r *http.Request) *UsageResponse {
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = models.UsageMonth(time.Now())
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+		return nil
+	}
+
+	userID := currentUserID(w, r)
+	if userID == "" {
+		return nil
+	}
+	a := requireAgent(w, r)
+	if a == nil {
+		return nil
+	}
+
+	summary, err := a.UsageSummary(userID, month)
+	if err != nil {
+		log.Printf("Error getting usage: %v", err)
+		http.Error(w, "Error getting usage", http.StatusInternalServerError)
+		return nil
+	}
+	budget, err := a.Budget(userID)
+	if err != nil {
+		log.Printf("Error getting budget: %v", err)
+		http.Error(w, "Error getting budget", http.StatusInternalServerError)
+		return nil
+	}
+	months, err := a.UsageMonths(userID)
+	if err != nil {
+		log.Printf("Error listing usage months: %v", err)
+	}
+
+	return &UsageResponse{
+		Summary: summary,
+		Budget:  budget,
+		Months:  months,
+	}
+}
+
+// budgetWarning returns a warning if the user is over their soft budget
+func budgetWarning(a *agent.Agent, userID string) string {
+	budget, err := a.Budget(userID)
+	if err != nil {
+		log.Printf("Error getting budget: %v", err)
+		return ""
+	}
+	if budget.HardExceeded || !budget.SoftExceeded {
+		return ""
+	}
+	return fmt.Sprintf("You have spent $%.2f of your $%.2f monthly budget for AI features.", budget.Spent, budget.Soft)
+}
+
+// generationErrorStatus returns the HTTP status for a failed report or
+// chat reply
+func generationErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrBudgetExceeded) {
+		return http.StatusPaymentRequired
+	}
+	if errors.Is(err, agent.ErrBoardAccess) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// tempFilePrefix starts the names of the temporary files WriteFileAtomic
+// writes, so ones left behind by a crash can be told apart and cleaned up
+const tempFilePrefix = ".tmp-"
+
+// WriteFileAtomic writes data to a file so that readers, and the file
+// after a crash, see either the old contents or the new ones, never a
+// partial write: the data is written to a temporary file in the same
+// directory, synced to disk and renamed over the file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, tempFilePrefix+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temporary file unless it was renamed into place
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing temporary file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %v", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("error setting file permissions: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error renaming temporary file: %v", err)
+	}
+	renamed = true
+
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform can sync a directory, so this is best effort.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// isTempFile reports whether a file name is one of WriteFileAtomic's
+// temporary files
+func isTempFile(name string) bool {
+	return strings.HasPrefix(filepath.Base(name), tempFilePrefix)
+}
+
+// keyLocks hands out a mutex per key, e.g. per report ID, so writes of the
+// same key are serialized while writes of different keys run in parallel.
+// The zero value is ready to use.
+type keyLocks struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is a key's mutex and the number of callers holding or waiting
+// for it, so it can be dropped when nobody needs it
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks the key's mutex and returns the function that unlocks it
+func (k *keyLocks) Lock(key string) func() {
+	k.mutex.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		k.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mutex.Unlock()
+	}
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name string
+		// existing is the file's contents before the write; nil means no file
+		existing []byte
+		// subdir writes into a missing directory
+		subdir  string
+		data    []byte
+		perm    os.FileMode
+		wantErr bool
+	}{
+		{name: "new file", data: []byte(`{"id":"1"}`), perm: 0644},
+		{name: "replaces file", existing: []byte(`{"id":"old","content":"a longer old version"}`), data: []byte(`{"id":"1"}`), perm: 0644},
+		{name: "empty data", existing: []byte("old"), data: []byte{}, perm: 0644},
+		{name: "permissions", data: []byte("secret"), perm: 0600},
+		{name: "missing directory", subdir: "missing", data: []byte("x"), perm: 0644, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.subdir, "report.json")
+			if tt.existing != nil {
+				if err := ioutil.WriteFile(path, tt.existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := WriteFileAtomic(path, tt.data, tt.perm)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("WriteFileAtomic succeeded, want an error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("WriteFileAtomic: %v", err)
+				}
+				got, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, tt.data) {
+					t.Errorf("file holds %q, want %q", got, tt.data)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != tt.perm {
+					t.Errorf("file mode is %v, want %v", info.Mode().Perm(), tt.perm)
+				}
+			}
+
+			// No temporary file may be left behind, whatever the outcome
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range files {
+				if isTempFile(file.Name()) {
+					t.Errorf("temporary file %s left behind", file.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	contents := make([][]byte, 20)
+	for i := range contents {
+		contents[i] = bytes.Repeat([]byte(fmt.Sprintf("writer %d;", i)), 1000*(i+1))
+	}
+
+	// Readers must only ever see one writer's complete contents
+	var wg sync.WaitGroup
+	for _, data := range contents {
+		wg.Add(1)
+		go func(data []byte) {
+			defer wg.Done()
+			if err := WriteFileAtomic(path, data, 0644); err != nil {
+				t.Errorf("WriteFileAtomic: %v", err)
+			}
+		}(data)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		got, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		complete := false
+		for _, data := range contents {
+			if bytes.Equal(got, data) {
+				complete = true
+			}
+		}
+		if !complete {
+			t.Fatalf("read a partial write of %d bytes", len(got))
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Audience is who a report is written for. Each audience has its own
+// prompt, sections, level of detail and rules for what is hidden, so one
+// board snapshot can be reported to several audiences.
+type Audience string
+
+const (
+	// AudienceStandard is the general report written for every reader
+	AudienceStandard Audience = ""
+	// AudienceExecutive is a short report on outcomes and delivery risk
+	AudienceExecutive Audience = "executive"
+	// AudienceTeam is a detailed report for the people working on the board
+	AudienceTeam Audience = "team"
+	// AudienceClient is a report that can be shared outside the team
+	AudienceClient Audience = "client"
+)
+
+// Audiences lists the audiences a report variant can be written for
+var Audiences = []Audience{AudienceExecutive, AudienceTeam, AudienceClient}
+
+// Section keys identify report sections independently of their titles
+const (
+	SectionSummary         = "executive_summary"
+	SectionProgress        = "progress"
+	SectionStatus          = "current_status"
+	SectionPriorities      = "priorities"
+	SectionRisks           = "risks"
+	SectionContributions   = "contributions"
+	SectionDataLimitations = "data_limitations"
+)
+
+// allSections are the section keys in display order
+var allSections = []string{SectionSummary, SectionProgress, SectionStatus, SectionPriorities, SectionRisks, SectionContributions, SectionDataLimitations}
+
+// DetailLevel is how much detail a report goes into
+type DetailLevel string
+
+const (
+	// DetailBrief keeps the most important items of each list
+	DetailBrief DetailLevel = "brief"
+	// DetailStandard keeps a handful of items per list
+	DetailStandard DetailLevel = "standard"
+	// DetailFull keeps every item
+	DetailFull DetailLevel = "detailed"
+)
+
+// maxItems is the number of items kept per list at each detail level; zero
+// keeps them all
+var maxItems = map[DetailLevel]int{
+	DetailBrief:    3,
+	DetailStandard: 6,
+}
+
+// AudienceProfile describes how a report is written for an audience
+type AudienceProfile struct {
+	Audience Audience
+	// Name is shown to users, e.g. "Executive"
+	Name string
+	// Prompt is added to the system prompt to write for the audience
+	Prompt string
+	// Sections are the keys of the sections the audience sees
+	Sections []string
+	Detail   DetailLevel
+	// HideMembers removes member names: owners and contributions are
+	// dropped and names in the text are replaced with "the team"
+	HideMembers bool
+	// HideBlockerDetails drops mitigations, the cards behind risks and the
+	// details of blocked work, leaving only high-level descriptions
+	HideBlockerDetails bool
+}
+
+var audienceProfiles = map[Audience]AudienceProfile{
+	AudienceStandard: {
+		Audience: AudienceStandard,
+		Name:     "Standard",
+		Sections: allSections,
+		Detail:   DetailFull,
+	},
+	AudienceExecutive: {
+		Audience: AudienceExecutive,
+		Name:     "Executive",
+		Prompt:   "Write this report for executives and stakeholders who don't follow the board day to day. Lead with outcomes, the schedule and risks to delivery. Leave out task-level detail, card-by-card updates and individual contributions.",
+		Sections: []string{SectionSummary, SectionStatus, SectionPriorities, SectionRisks},
+		Detail:   DetailBrief,
+	},
+	AudienceTeam: {
+		Audience: AudienceTeam,
+		Name:     "Team",
+		Prompt:   "Write this report for the team working on the board. Be specific: name the cards, owners and due dates, say what each blocker is waiting on, and cover every active member's contributions.",
+		Sections: allSections,
+		Detail:   DetailFull,
+	},
+	AudienceClient: {
+		Audience:           AudienceClient,
+		Name:               "Client",
+		Prompt:             "Write this report for an external client. Describe progress and upcoming milestones as deliverables. Don't name team members or refer to individuals. Describe risks only at a high level, without internal blocker details or mitigations. Keep a professional tone, but don't hide delays that affect the client.",
+		Sections:           []string{SectionSummary, SectionProgress, SectionStatus, SectionPriorities, SectionRisks},
+		Detail:             DetailStandard,
+		HideMembers:        true,
+		HideBlockerDetails: true,
+	},
+}
+
+// ParseAudience validates an audience name; an empty name is the standard
+// report
+func ParseAudience(name string) (Audience, error) {
+	audience := Audience(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := audienceProfiles[audience]; !ok {
+		return "", fmt.Errorf("unknown audience %q", name)
+	}
+	return audience, nil
+}
+
+// Profile returns the audience's profile; unknown audiences get the
+// standard one
+func (a Audience) Profile() AudienceProfile {
+	if profile, ok := audienceProfiles[a]; ok {
+		return profile
+	}
+	return audienceProfiles[AudienceStandard]
+}
+
+// Name returns the audience's display name
+func (a Audience) Name() string {
+	return a.Profile().Name
+}
+
+// Shows reports whether the audience sees a section
+func (p AudienceProfile) Shows(section string) bool {
+	return contains(p.Sections, section)
+}
+
+// MaxItems returns the number of items kept per list, or zero for all
+func (p AudienceProfile) MaxItems() int {
+	return maxItems[p.Detail]
+}
+
+// severityRank orders risks from most to least severe
+var severityRank = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+
+// ApplyAudience makes the report fit an audience: hidden sections are
+// emptied, lists are shortened to the audience's detail level (keeping the
+// most severe risks) and member names and blocker details are removed if
+// the audience mustn't see them. members are the board's member names and
+// usernames; lang is the report's language, for the text names are
+// replaced with.
+func (s *StructuredReport) ApplyAudience(audience Audience, members []string, lang Language) {
+	profile := audience.Profile()
+	s.Audience = audience
+
+	// Empty the sections the audience doesn't see
+	if !profile.Shows(SectionSummary) {
+		s.ExecutiveSummary = ""
+	}
+	if !profile.Shows(SectionProgress) {
+		s.Progress = nil
+	}
+	if !profile.Shows(SectionStatus) {
+		s.CurrentStatus = ""
+	}
+	if !profile.Shows(SectionPriorities) {
+		s.Priorities = nil
+	}
+	if !profile.Shows(SectionRisks) {
+		s.Risks = nil
+	}
+	if !profile.Shows(SectionContributions) {
+		s.Contributions = nil
+	}
+	if !profile.Shows(SectionDataLimitations) {
+		s.DataLimitations = nil
+	}
+
+	// Keep the most important items at lower detail levels
+	if n := profile.MaxItems(); n > 0 {
+		sort.SliceStable(s.Risks, func(i, j int) bool {
+			return severityRank[s.Risks[i].Severity] < severityRank[s.Risks[j].Severity]
+		})
+		if len(s.Progress) > n {
+			s.Progress = s.Progress[:n]
+		}
+		if len(s.Priorities) > n {
+			s.Priorities = s.Priorities[:n]
+		}
+		if len(s.Risks) > n {
+			s.Risks = s.Risks[:n]
+		}
+		if len(s.Contributions) > n {
+			s.Contributions = s.Contributions[:n]
+		}
+	}
+
+	if profile.HideBlockerDetails {
+		for i := range s.Risks {
+			s.Risks[i].Mitigation = ""
+			s.Risks[i].Cards = nil
+		}
+		for i := range s.Progress {
+			if s.Progress[i].Status == "blocked" {
+				s.Progress[i].Detail = ""
+			}
+		}
+	}
+
+	if profile.HideMembers {
+		s.Contributions = nil
+		scrub := nameScrubber(members, lang.T("the team"))
+		s.ExecutiveSummary = scrub(s.ExecutiveSummary)
+		s.CurrentStatus = scrub(s.CurrentStatus)
+		for i := range s.Progress {
+			s.Progress[i].Title = scrub(s.Progress[i].Title)
+			s.Progress[i].Detail = scrub(s.Progress[i].Detail)
+		}
+		for i := range s.Priorities {
+			s.Priorities[i].Title = scrub(s.Priorities[i].Title)
+			s.Priorities[i].Owner = ""
+		}
+		for i := range s.Risks {
+			s.Risks[i].Description = scrub(s.Risks[i].Description)
+			s.Risks[i].Mitigation = scrub(s.Risks[i].Mitigation)
+		}
+		for i := range s.DataLimitations {
+			s.DataLimitations[i] = scrub(s.DataLimitations[i])
+		}
+	}
+}
+
+// nameScrubber returns a function that replaces the names, as whole words
+// and with any @ before them, with replacement. Longer names are replaced
+// first so a full name isn't left half replaced.
+func nameScrubber(names []string, replacement string) func(string) string {
+	var quoted []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	if len(quoted) == 0 {
+		return func(text string) string { return text }
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+	pattern := regexp.MustCompile(`@?\b(` + strings.Join(quoted, "|") + `)\b('s)?`)
+	return func(text string) string {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			sb.WriteString(text[last:loc[0]])
+			name := replacement
+			if sentenceStart(text[:loc[0]]) {
+				name = strings.ToUpper(name[:1]) + name[1:]
+			}
+			if strings.HasSuffix(text[loc[0]:loc[1]], "'s") {
+				name += "'s"
+			}
+			sb.WriteString(name)
+			last = loc[1]
+		}
+		sb.WriteString(text[last:])
+		return sb.String()
+	}
+}
+
+// sentenceStart reports whether text following before starts a sentence
+func sentenceStart(before string) bool {
+	before = strings.TrimRight(before, " \t")
+	return before == "" || strings.ContainsAny(before[len(before)-1:], ".!?\n")
+}
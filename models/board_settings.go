@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// BoardSettings holds per-board preferences for report generation
+type BoardSettings struct {
+	BoardID string `json:"board_id"`
+	// LLMProvider names one of the configured LLM providers; empty uses the default
+	LLMProvider string `json:"llm_provider,omitempty"`
+	// LLMModel overrides the provider's default model
+	LLMModel string `json:"llm_model,omitempty"`
+	// Prompts selects the prompt template used for each report type, keyed by
+	// report type; report types without a selection use the default template
+	Prompts map[ReportType]PromptSelection `json:"prompts,omitempty"`
+	// UseTools lets the model look up cards, comments and activity while
+	// writing reports and answering chat messages
+	UseTools bool `json:"use_tools,omitempty"`
+	// Redaction controls what is removed from board content before it is
+	// sent to the model; nil uses DefaultRedactionRules
+	Redaction *RedactionRules `json:"redaction,omitempty"`
+	// CorrectClaims regenerates reports whose fact check finds claims the
+	// board data doesn't support
+	CorrectClaims bool `json:"correct_claims,omitempty"`
+	// Language is the language reports are written in; empty is English.
+	// A report request can ask for a different language.
+	Language Language `json:"language,omitempty"`
+	// Audiences are the audiences a report is written for each time one is
+	// generated, each stored as a linked report; empty writes the standard
+	// report only
+	Audiences []Audience `json:"audiences,omitempty"`
+	// Retention decides which of the board's reports are pruned; nil uses
+	// the default policy
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+}
+
+// RedactionRules selects the personal data that is redacted from board
+// content before it is sent to the model
+type RedactionRules struct {
+	// Members replaces member names and usernames with stable pseudonyms,
+	// which are mapped back to the real names in the reply
+	Members bool `json:"members"`
+	// Emails, Phones and TokenURLs remove email addresses, phone numbers
+	// and the query strings of URLs that carry tokens or keys
+	Emails    bool `json:"emails"`
+	Phones    bool `json:"phones"`
+	TokenURLs bool `json:"token_urls"`
+	// Patterns are extra regular expressions whose matches are removed
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// DefaultRedactionRules redacts everything except custom patterns
+func DefaultRedactionRules() RedactionRules {
+	return RedactionRules{
+		Members:   true,
+		Emails:    true,
+		Phones:    true,
+		TokenURLs: true,
+	}
+}
+
+// HasAudience reports whether reports are written for the audience
+func (s *BoardSettings) HasAudience(audience Audience) bool {
+	for _, a := range s.Audiences {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactionRules returns the board's redaction rules, or the defaults if
+// none are saved
+func (s *BoardSettings) RedactionRules() RedactionRules {
+	if s.Redaction == nil {
+		return DefaultRedactionRules()
+	}
+	return *s.Redaction
+}
+
+// PromptSelection names a prompt template and version. Version 0 follows the
+// latest version of the template.
+type PromptSelection struct {
+	Template string `json:"template"`
+	Version  int    `json:"version,omitempty"`
+}
+
+// BoardSettingsStore handles storage and retrieval of board settings
+type BoardSettingsStore struct {
+	StoragePath string
+}
+
+// NewBoardSettingsStore creates a new board settings store
+func NewBoardSettingsStore(storagePath string) (*BoardSettingsStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &BoardSettingsStore{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// GetSettings returns the settings for a board, or defaults if none are saved
+func (s *BoardSettingsStore) GetSettings(boardID string) (*BoardSettings, error) {
+	data, err := ioutil.ReadFile(s.path(boardID))
+	if os.IsNotExist(err) {
+		return &BoardSettings{BoardID: boardID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading board settings: %v", err)
+	}
+
+	var settings BoardSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error unmarshaling board settings: %v", err)
+	}
+	settings.BoardID = boardID
+
+	return &settings, nil
+}
+
+// SaveSettings saves the settings for a board
+func (s *BoardSettingsStore) SaveSettings(settings *BoardSettings) error {
+	if settings.BoardID == "" {
+		return fmt.Errorf("board ID is required")
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling board settings: %v", err)
+	}
+
+	if err := WriteFileAtomic(s.path(settings.BoardID), data, 0644); err != nil {
+		return fmt.Errorf("error writing board settings file: %v", err)
+	}
+
+	return nil
+}
+
+// path returns the settings file for a board
+func (s *BoardSettingsStore) path(boardID string) string {
+	return filepath.Join(s.StoragePath, filepath.Base(boardID)+".json")
+}
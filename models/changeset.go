@@ -0,0 +1,275 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChangeType is a kind of board change the assistant can propose
+type ChangeType string
+
+const (
+	// ChangeMoveCard moves a card to another list
+	ChangeMoveCard ChangeType = "move_card"
+	// ChangeAssignMember adds a member to a card
+	ChangeAssignMember ChangeType = "assign_member"
+	// ChangeUnassignMember removes a member from a card
+	ChangeUnassignMember ChangeType = "unassign_member"
+	// ChangeSetDue sets or clears a card's due date
+	ChangeSetDue ChangeType = "set_due"
+)
+
+// ChangeStatus is the state of a single change
+type ChangeStatus string
+
+const (
+	ChangePending ChangeStatus = "pending"
+	ChangeApplied ChangeStatus = "applied"
+	ChangeFailed  ChangeStatus = "failed"
+	// ChangeSkipped is a change the user left out when approving
+	ChangeSkipped ChangeStatus = "skipped"
+	ChangeUndone  ChangeStatus = "undone"
+	// ChangeUndoFailed is an applied change that could not be reverted
+	ChangeUndoFailed ChangeStatus = "undo_failed"
+)
+
+// Change is one mutation of a card. Names are resolved when the change is
+// proposed so the user can review it without looking up IDs.
+type Change struct {
+	Type     ChangeType `json:"type"`
+	CardID   string     `json:"card_id"`
+	CardName string     `json:"card_name"`
+	// ListID and ListName are the target list of a move
+	ListID   string `json:"list_id,omitempty"`
+	ListName string `json:"list_name,omitempty"`
+	// MemberID and MemberName are the member to assign or unassign
+	MemberID   string `json:"member_id,omitempty"`
+	MemberName string `json:"member_name,omitempty"`
+	// Due is the new due date in RFC 3339; empty clears it
+	Due string `json:"due,omitempty"`
+
+	Status ChangeStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+
+	// Previous state, recorded when the change is applied so it can be undone
+	PreviousListID   string `json:"previous_list_id,omitempty"`
+	PreviousListName string `json:"previous_list_name,omitempty"`
+	PreviousDue      string `json:"previous_due,omitempty"`
+	// NoOp marks a change that didn't modify the card, e.g. assigning a
+	// member who was already on it; undo leaves it alone
+	NoOp bool `json:"no_op,omitempty"`
+}
+
+// Describe returns a one-line description of the change
+func (c *Change) Describe() string {
+	switch c.Type {
+	case ChangeMoveCard:
+		return fmt.Sprintf("Move '%s' to '%s'", c.CardName, c.ListName)
+	case ChangeAssignMember:
+		return fmt.Sprintf("Assign %s to '%s'", c.MemberName, c.CardName)
+	case ChangeUnassignMember:
+		return fmt.Sprintf("Remove %s from '%s'", c.MemberName, c.CardName)
+	case ChangeSetDue:
+		if c.Due == "" {
+			return fmt.Sprintf("Clear the due date of '%s'", c.CardName)
+		}
+		if due, err := time.Parse(time.RFC3339, c.Due); err == nil {
+			return fmt.Sprintf("Set the due date of '%s' to %s", c.CardName, due.Format("Jan 2, 2006 15:04"))
+		}
+		return fmt.Sprintf("Set the due date of '%s' to %s", c.CardName, c.Due)
+	default:
+		return fmt.Sprintf("Unknown change %q", c.Type)
+	}
+}
+
+// ChangeSetStatus is the state of a change set
+type ChangeSetStatus string
+
+const (
+	// ChangeSetProposed is waiting for the user's approval
+	ChangeSetProposed ChangeSetStatus = "proposed"
+	ChangeSetApplied  ChangeSetStatus = "applied"
+	// ChangeSetPartial had some changes fail
+	ChangeSetPartial  ChangeSetStatus = "partially_applied"
+	ChangeSetFailed   ChangeSetStatus = "failed"
+	ChangeSetRejected ChangeSetStatus = "rejected"
+	ChangeSetUndone   ChangeSetStatus = "undone"
+)
+
+// ChangeSet is a group of board changes proposed by the assistant. Nothing
+// is written to Trello until the user approves it.
+type ChangeSet struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	BoardID   string          `json:"board_id"`
+	SessionID string          `json:"session_id,omitempty"`
+	Summary   string          `json:"summary"`
+	Status    ChangeSetStatus `json:"status"`
+	Changes   []Change        `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+	AppliedAt *time.Time      `json:"applied_at,omitempty"`
+	UndoneAt  *time.Time      `json:"undone_at,omitempty"`
+}
+
+// NewChangeSet creates a proposed change set with a random ID
+func NewChangeSet(userID, boardID, summary string, changes []Change) (*ChangeSet, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("error generating change set ID: %v", err)
+	}
+
+	for i := range changes {
+		changes[i].Status = ChangePending
+	}
+
+	return &ChangeSet{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		BoardID:   boardID,
+		Summary:   summary,
+		Status:    ChangeSetProposed,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// ChangeSetStore handles storage of change sets, one directory per user
+type ChangeSetStore struct {
+	StoragePath string
+	mutex       sync.Mutex
+}
+
+// NewChangeSetStore creates a new change set store
+func NewChangeSetStore(storagePath string) (*ChangeSetStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &ChangeSetStore{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// SaveChangeSet saves a change set
+func (s *ChangeSetStore) SaveChangeSet(changeSet *ChangeSet) error {
+	if changeSet.UserID == "" || changeSet.ID == "" {
+		return fmt.Errorf("user ID and change set ID are required")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.userPath(changeSet.UserID), 0755); err != nil {
+		return fmt.Errorf("error creating change set directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(changeSet, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling change set: %v", err)
+	}
+
+	if err := WriteFileAtomic(s.path(changeSet.UserID, changeSet.ID), data, 0644); err != nil {
+		return fmt.Errorf("error writing change set file: %v", err)
+	}
+
+	return nil
+}
+
+// GetChangeSet retrieves a user's change set by ID
+func (s *ChangeSetStore) GetChangeSet(userID, id string) (*ChangeSet, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readChangeSet(s.path(userID, id))
+}
+
+// ListChangeSets returns a user's change sets for a board, newest first
+func (s *ChangeSetStore) ListChangeSets(userID, boardID string) ([]*ChangeSet, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	matches, err := filepath.Glob(filepath.Join(s.userPath(userID), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding change sets: %v", err)
+	}
+
+	changeSets := make([]*ChangeSet, 0, len(matches))
+	for _, match := range matches {
+		changeSet, err := s.readChangeSet(match)
+		if err != nil {
+			return nil, err
+		}
+		if boardID != "" && changeSet.BoardID != boardID {
+			continue
+		}
+		changeSets = append(changeSets, changeSet)
+	}
+
+	sort.Slice(changeSets, func(i, j int) bool {
+		return changeSets[i].CreatedAt.After(changeSets[j].CreatedAt)
+	})
+
+	return changeSets, nil
+}
+
+// LastApplied returns the user's most recently applied change set for a
+// board that hasn't been undone
+func (s *ChangeSetStore) LastApplied(userID, boardID string) (*ChangeSet, error) {
+	changeSets, err := s.ListChangeSets(userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	var last *ChangeSet
+	for _, changeSet := range changeSets {
+		if changeSet.AppliedAt == nil {
+			continue
+		}
+		if last == nil || changeSet.AppliedAt.After(*last.AppliedAt) {
+			last = changeSet
+		}
+	}
+
+	if last == nil || (last.Status != ChangeSetApplied && last.Status != ChangeSetPartial) {
+		return nil, fmt.Errorf("no applied change set to undo")
+	}
+
+	return last, nil
+}
+
+// readChangeSet reads a change set file
+func (s *ChangeSetStore) readChangeSet(path string) (*ChangeSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("change set not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading change set file: %v", err)
+	}
+
+	var changeSet ChangeSet
+	if err := json.Unmarshal(data, &changeSet); err != nil {
+		return nil, fmt.Errorf("error unmarshaling change set: %v", err)
+	}
+
+	return &changeSet, nil
+}
+
+// userPath returns the directory holding a user's change sets
+func (s *ChangeSetStore) userPath(userID string) string {
+	return filepath.Join(s.StoragePath, filepath.Base(userID))
+}
+
+// path returns the file for a change set
+func (s *ChangeSetStore) path(userID, id string) string {
+	return filepath.Join(s.userPath(userID), filepath.Base(id)+".json")
+}
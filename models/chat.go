@@ -0,0 +1,204 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChatMessage is one message in a chat session
+type ChatMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	// ToolCalls records the tools the assistant called for this reply
+	ToolCalls []ToolCallRecord `json:"tool_calls,omitempty"`
+	// ChangeSetID is the board change set the assistant proposed in this reply
+	ChangeSetID string `json:"change_set_id,omitempty"`
+	// Usage records the tokens and estimated cost of an assistant reply
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// ChatSession is a conversation between a user and the assistant, optionally
+// about a specific board
+type ChatSession struct {
+	ID        string        `json:"id"`
+	UserID    string        `json:"user_id"`
+	BoardID   string        `json:"board_id,omitempty"`
+	BoardName string        `json:"board_name,omitempty"`
+	Title     string        `json:"title"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Messages  []ChatMessage `json:"messages,omitempty"`
+}
+
+// chatTitleLength is the maximum length of a session title
+const chatTitleLength = 60
+
+// NewChatSession creates an empty chat session with a random ID
+func NewChatSession(userID, boardID, boardName string) (*ChatSession, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("error generating session ID: %v", err)
+	}
+
+	now := time.Now()
+	return &ChatSession{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		BoardID:   boardID,
+		BoardName: boardName,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// AddMessage appends a message to the session. The first user message
+// becomes the session title.
+func (s *ChatSession) AddMessage(role, content string) {
+	now := time.Now()
+	s.Messages = append(s.Messages, ChatMessage{Role: role, Content: content, CreatedAt: now})
+	s.UpdatedAt = now
+
+	if s.Title == "" && role == "user" {
+		title := strings.Join(strings.Fields(content), " ")
+		if len(title) > chatTitleLength {
+			title = strings.TrimSpace(title[:chatTitleLength]) + "..."
+		}
+		s.Title = title
+	}
+}
+
+// ChatStore handles storage of chat sessions, one directory per user
+type ChatStore struct {
+	StoragePath string
+	mutex       sync.Mutex
+}
+
+// NewChatStore creates a new chat store
+func NewChatStore(storagePath string) (*ChatStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &ChatStore{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// SaveSession saves a chat session
+func (s *ChatStore) SaveSession(session *ChatSession) error {
+	if session.UserID == "" || session.ID == "" {
+		return fmt.Errorf("user ID and session ID are required")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.userPath(session.UserID), 0755); err != nil {
+		return fmt.Errorf("error creating chat directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling chat session: %v", err)
+	}
+
+	if err := WriteFileAtomic(s.path(session.UserID, session.ID), data, 0644); err != nil {
+		return fmt.Errorf("error writing chat session file: %v", err)
+	}
+
+	return nil
+}
+
+// GetSession retrieves a user's chat session by ID
+func (s *ChatStore) GetSession(userID, id string) (*ChatSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readSession(s.path(userID, id))
+}
+
+// ListSessions returns a user's chat sessions without their messages, most
+// recently updated first. A non-empty board ID limits the list to that board.
+func (s *ChatStore) ListSessions(userID, boardID string) ([]*ChatSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	matches, err := filepath.Glob(filepath.Join(s.userPath(userID), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding chat sessions: %v", err)
+	}
+
+	sessions := make([]*ChatSession, 0, len(matches))
+	for _, match := range matches {
+		session, err := s.readSession(match)
+		if err != nil {
+			return nil, err
+		}
+		if boardID != "" && session.BoardID != boardID {
+			continue
+		}
+		session.Messages = nil
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+
+	return sessions, nil
+}
+
+// DeleteSession deletes a user's chat session
+func (s *ChatStore) DeleteSession(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.Remove(s.path(userID, id))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("chat session not found")
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting chat session file: %v", err)
+	}
+
+	return nil
+}
+
+// readSession reads a chat session file
+func (s *ChatStore) readSession(path string) (*ChatSession, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("chat session not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading chat session file: %v", err)
+	}
+
+	var session ChatSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("error unmarshaling chat session: %v", err)
+	}
+
+	return &session, nil
+}
+
+// userPath returns the directory holding a user's sessions
+func (s *ChatStore) userPath(userID string) string {
+	return filepath.Join(s.StoragePath, filepath.Base(userID))
+}
+
+// path returns the file for a chat session
+func (s *ChatStore) path(userID, id string) string {
+	return filepath.Join(s.userPath(userID), filepath.Base(id)+".json")
+}
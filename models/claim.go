@@ -0,0 +1,34 @@
+package models
+
+// Kinds of claims checked against the board data
+const (
+	ClaimCard   = "card"
+	ClaimMember = "member"
+	ClaimDate   = "date"
+	ClaimCount  = "count"
+)
+
+// Claim is a statement in a report that the fact check couldn't verify
+// against the board data the report was generated from
+type Claim struct {
+	// Kind is ClaimCard, ClaimMember, ClaimDate or ClaimCount
+	Kind string `json:"kind"`
+	// Text is the claim as written in the report
+	Text string `json:"text"`
+	// Section is the report section the claim appears in
+	Section string `json:"section"`
+	// Reason says why the claim couldn't be verified
+	Reason string `json:"reason"`
+}
+
+// InjectionFlag records board content that looks like instructions to the
+// model, such as a comment saying "ignore previous instructions". Flagged
+// content is still sent, quoted and marked as untrusted.
+type InjectionFlag struct {
+	// Where describes the content, e.g. `comment on card "Launch"`
+	Where string `json:"where"`
+	// Excerpt is the start of the flagged text
+	Excerpt string `json:"excerpt"`
+	// Patterns names the heuristics the text matched
+	Patterns []string `json:"patterns"`
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Language is the language reports are written in, as an ISO 639-1 code
+type Language string
+
+const (
+	// English is the default language
+	English Language = "en"
+	// German report language
+	German Language = "de"
+	// Spanish report language
+	Spanish Language = "es"
+)
+
+// Languages lists the supported report languages
+var Languages = []Language{English, German, Spanish}
+
+// locale holds what is needed to present a report in a language
+type locale struct {
+	// Name is the English name of the language, used in prompts
+	Name string
+	// NativeName is the name of the language in the language itself
+	NativeName string
+	// Months are the month names, January first
+	Months [12]string
+	// Date and DateTime format a date and a date with time of day, given
+	// the month's name in the language
+	Date     func(t time.Time, month string) string
+	DateTime func(t time.Time, month string) string
+	// Text translates the report's section titles and labels, keyed by
+	// the English text
+	Text map[string]string
+}
+
+var locales = map[Language]*locale{
+	English: {
+		Name:       "English",
+		NativeName: "English",
+		Months:     [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		Date:       func(t time.Time, _ string) string { return t.Format("Jan 2, 2006") },
+		DateTime:   func(t time.Time, _ string) string { return t.Format("January 2, 2006 at 3:04 PM") },
+	},
+	German: {
+		Name:       "German",
+		NativeName: "Deutsch",
+		Months:     [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		Date: func(t time.Time, month string) string {
+			return fmt.Sprintf("%d. %s %d", t.Day(), month, t.Year())
+		},
+		DateTime: func(t time.Time, month string) string {
+			return fmt.Sprintf("%d. %s %d um %s", t.Day(), month, t.Year(), t.Format("15:04"))
+		},
+		Text: map[string]string{
+			"Executive Summary":                     "Zusammenfassung",
+			"Progress This Week":                    "Fortschritt diese Woche",
+			"Progress This Month":                   "Fortschritt diesen Monat",
+			"Current Project Status":                "Aktueller Projektstatus",
+			"Priorities & Deadlines for Next Week":  "Prioritäten & Fristen für nächste Woche",
+			"Priorities & Deadlines for Next Month": "Prioritäten & Fristen für nächsten Monat",
+			"Risks, Blockers & Issues":              "Risiken, Blocker & Probleme",
+			"Team Focus & Contributions":            "Teamfokus & Beiträge",
+			"Data Limitations":                      "Einschränkungen der Daten",
+			"None.":                                 "Keine.",
+			"completed":                             "abgeschlossen",
+			"in progress":                           "in Arbeit",
+			"blocked":                               "blockiert",
+			"due %s":                                "fällig am %s",
+			"owner: %s":                             "verantwortlich: %s",
+			"mitigation: %s":                        "Gegenmaßnahme: %s",
+			"%s Weekly Report":                      "%s – Wochenbericht",
+			"%s Monthly Report":                     "%s – Monatsbericht",
+			"Period":                                "Zeitraum",
+			"Generated":                             "Erstellt",
+			"%s to %s":                              "%s bis %s",
+			"the team":                              "das Team",
+		},
+	},
+	Spanish: {
+		Name:       "Spanish",
+		NativeName: "Español",
+		Months:     [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		Date: func(t time.Time, month string) string {
+			return fmt.Sprintf("%d de %s de %d", t.Day(), month, t.Year())
+		},
+		DateTime: func(t time.Time, month string) string {
+			return fmt.Sprintf("%d de %s de %d, %s", t.Day(), month, t.Year(), t.Format("15:04"))
+		},
+		Text: map[string]string{
+			"Executive Summary":                     "Resumen ejecutivo",
+			"Progress This Week":                    "Progreso de esta semana",
+			"Progress This Month":                   "Progreso de este mes",
+			"Current Project Status":                "Estado actual del proyecto",
+			"Priorities & Deadlines for Next Week":  "Prioridades y plazos para la próxima semana",
+			"Priorities & Deadlines for Next Month": "Prioridades y plazos para el próximo mes",
+			"Risks, Blockers & Issues":              "Riesgos, bloqueos y problemas",
+			"Team Focus & Contributions":            "Enfoque y contribuciones del equipo",
+			"Data Limitations":                      "Limitaciones de los datos",
+			"None.":                                 "Ninguno.",
+			"completed":                             "completado",
+			"in progress":                           "en curso",
+			"blocked":                               "bloqueado",
+			"due %s":                                "vence el %s",
+			"owner: %s":                             "responsable: %s",
+			"mitigation: %s":                        "mitigación: %s",
+			"%s Weekly Report":                      "%s – Informe semanal",
+			"%s Monthly Report":                     "%s – Informe mensual",
+			"Period":                                "Periodo",
+			"Generated":                             "Generado",
+			"%s to %s":                              "%s a %s",
+			"the team":                              "el equipo",
+		},
+	},
+}
+
+// ParseLanguage validates a language code; an empty code is English
+func ParseLanguage(code string) (Language, error) {
+	lang := Language(strings.ToLower(strings.TrimSpace(code)))
+	if lang == "" {
+		return English, nil
+	}
+	if _, ok := locales[lang]; !ok {
+		return "", fmt.Errorf("unsupported language %q", code)
+	}
+	return lang, nil
+}
+
+// locale returns the language's locale, falling back to English
+func (l Language) locale() *locale {
+	if loc, ok := locales[l]; ok {
+		return loc
+	}
+	return locales[English]
+}
+
+// Name returns the English name of the language, e.g. "German"
+func (l Language) Name() string {
+	return l.locale().Name
+}
+
+// NativeName returns the name of the language in the language itself
+func (l Language) NativeName() string {
+	return l.locale().NativeName
+}
+
+// T translates English report text, formatting it with args if any. Text
+// without a translation is returned in English.
+func (l Language) T(text string, args ...interface{}) string {
+	if translated, ok := l.locale().Text[text]; ok {
+		text = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// FormatDate formats a date for the language, e.g. "Oct 18, 2026" or
+// "18. Oktober 2026"
+func (l Language) FormatDate(t time.Time) string {
+	loc := l.locale()
+	return loc.Date(t, loc.Months[t.Month()-1])
+}
+
+// FormatDateTime formats a date and time of day for the language
+func (l Language) FormatDateTime(t time.Time) string {
+	loc := l.locale()
+	return loc.DateTime(t, loc.Months[t.Month()-1])
+}
+
+// FormatPeriod formats a date range for the language
+func (l Language) FormatPeriod(start, end time.Time) string {
+	return l.T("%s to %s", l.FormatDate(start), l.FormatDate(end))
+}
+
+// ReportTitle returns the title of a board's report in the language
+func (l Language) ReportTitle(boardName string, reportType ReportType) string {
+	if reportType == Monthly {
+		return l.T("%s Monthly Report", boardName)
+	}
+	if reportType == Weekly {
+		return l.T("%s Weekly Report", boardName)
+	}
+	return fmt.Sprintf("%s %s Report", boardName, strings.Title(string(reportType)))
+}
+
+// SectionTitles returns the report section titles in every language, for
+// recognizing headings in report content
+func SectionTitles() []string {
+	var titles []string
+	for _, lang := range Languages {
+		for _, reportType := range []ReportType{Weekly, Monthly} {
+			for _, section := range (&StructuredReport{}).Sections(reportType, lang) {
+				if !contains(titles, section.Title) {
+					titles = append(titles, section.Title)
+				}
+			}
+		}
+	}
+	return titles
+}
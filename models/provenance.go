@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ToolCallRecord is one tool call the model made while generating a report
+// or answering a chat message. Together the records show which data the
+// model looked at to reach its conclusions.
+type ToolCallRecord struct {
+	// Step is the model turn the call was made in, starting at 1
+	Step      int    `json:"step"`
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments"`
+	// Result is the tool output as given to the model, truncated for storage
+	Result     string    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CalledAt   time.Time `json:"called_at"`
+}
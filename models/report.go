@@ -1,11 +1,6 @@
 package models
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"time"
 )
 
@@ -21,6 +16,9 @@ const (
 
 // Report represents a project report
 type Report struct {
+	// ID is a ULID, unique and sortable by the time the report was
+	// generated. Reports generated before revisions were kept have IDs of
+	// the form {board}_{type}_{YYYY-MM-DD}.
 	ID          string     `json:"id"`
 	BoardID     string     `json:"board_id"`
 	BoardName   string     `json:"board_name"`
@@ -29,148 +27,61 @@ type Report struct {
 	GeneratedAt time.Time  `json:"generated_at"`
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     time.Time  `json:"end_date"`
-}
-
-// ReportStore handles storage and retrieval of reports
-type ReportStore struct {
-	StoragePath string
-}
-
-// NewReportStore creates a new report store
-func NewReportStore(storagePath string) (*ReportStore, error) {
-	// Create storage directory if it doesn't exist
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create storage directory: %v", err)
-	}
-
-	return &ReportStore{
-		StoragePath: storagePath,
-	}, nil
-}
-
-// SaveReport saves a report to storage
-func (s *ReportStore) SaveReport(report *Report) error {
-	// Create filename based on report properties
-	filename := fmt.Sprintf("%s_%s_%s.json", 
-		report.BoardID, 
-		report.Type, 
-		report.GeneratedAt.Format("2006-01-02"))
-	
-	filepath := filepath.Join(s.StoragePath, filename)
-
-	// Convert report to JSON
-	data, err := json.MarshalIndent(report, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error marshaling report: %v", err)
-	}
-
-	// Write to file
-	if err := ioutil.WriteFile(filepath, data, 0644); err != nil {
-		return fmt.Errorf("error writing report file: %v", err)
-	}
-
-	return nil
-}
-
-// GetReportsByBoard retrieves all reports for a specific board
-func (s *ReportStore) GetReportsByBoard(boardID string) ([]*Report, error) {
-	pattern := fmt.Sprintf("%s_*.json", boardID)
-	matches, err := filepath.Glob(filepath.Join(s.StoragePath, pattern))
-	if err != nil {
-		return nil, fmt.Errorf("error finding reports: %v", err)
-	}
-
-	reports := make([]*Report, 0, len(matches))
-	for _, match := range matches {
-		data, err := ioutil.ReadFile(match)
-		if err != nil {
-			return nil, fmt.Errorf("error reading report file: %v", err)
-		}
-
-		var report Report
-		if err := json.Unmarshal(data, &report); err != nil {
-			return nil, fmt.Errorf("error unmarshaling report: %v", err)
-		}
-
-		reports = append(reports, &report)
-	}
-
-	return reports, nil
-}
-
-// GetReportsByType retrieves all reports of a specific type
-func (s *ReportStore) GetReportsByType(reportType ReportType) ([]*Report, error) {
-	pattern := fmt.Sprintf("*_%s_*.json", reportType)
-	matches, err := filepath.Glob(filepath.Join(s.StoragePath, pattern))
-	if err != nil {
-		return nil, fmt.Errorf("error finding reports: %v", err)
-	}
-
-	reports := make([]*Report, 0, len(matches))
-	for _, match := range matches {
-		data, err := ioutil.ReadFile(match)
-		if err != nil {
-			return nil, fmt.Errorf("error reading report file: %v", err)
-		}
-
-		var report Report
-		if err := json.Unmarshal(data, &report); err != nil {
-			return nil, fmt.Errorf("error unmarshaling report: %v", err)
-		}
-
-		reports = append(reports, &report)
-	}
-
-	return reports, nil
-}
-
-// GetReport retrieves a specific report by ID
-func (s *ReportStore) GetReport(id string) (*Report, error) {
-	// List all files in the directory
-	files, err := ioutil.ReadDir(s.StoragePath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading storage directory: %v", err)
-	}
-
-	// Look for a file that contains the report ID
-	for _, file := range files {
-		if !file.IsDir() {
-			filePath := filepath.Join(s.StoragePath, file.Name())
-			data, err := ioutil.ReadFile(filePath)
-			if err != nil {
-				continue // Skip files we can't read
-			}
-
-			var report Report
-			if err := json.Unmarshal(data, &report); err != nil {
-				continue // Skip files that aren't valid reports
-			}
-
-			// Check if this is the report we're looking for
-			if report.ID == id {
-				return &report, nil
-			}
-		}
-	}
-
-	return nil, fmt.Errorf("report not found")
-}
-
-// DeleteReport deletes a report by ID
-func (s *ReportStore) DeleteReport(id string) error {
-	pattern := fmt.Sprintf("*_%s_*.json", id)
-	matches, err := filepath.Glob(filepath.Join(s.StoragePath, pattern))
-	if err != nil {
-		return fmt.Errorf("error finding report: %v", err)
-	}
-
-	if len(matches) == 0 {
-		return fmt.Errorf("report not found")
-	}
-
-	if err := os.Remove(matches[0]); err != nil {
-		return fmt.Errorf("error deleting report file: %v", err)
-	}
-
-	return nil
+	// Provider and Model record which LLM generated the report
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// Strategy records how the board data was fed to the model: "single"
+	// for one prompt or "map_reduce" for chunked summarization
+	Strategy string `json:"strategy,omitempty"`
+	// PromptTemplate and PromptVersion record the prompt template used;
+	// version 0 is the built-in default
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	PromptVersion  int    `json:"prompt_version,omitempty"`
+	// Structured holds the validated JSON form of the report. Content is
+	// rendered from it; reports generated before structured output have
+	// only Content.
+	Structured *StructuredReport `json:"structured,omitempty"`
+	// Provenance records the tool calls the model made while researching
+	// the board for this report
+	Provenance []ToolCallRecord `json:"provenance,omitempty"`
+	// Cache records whether the completions came from the completion cache:
+	// "hit", "miss", "partial" or "bypassed" when regeneration was forced
+	Cache       string `json:"cache,omitempty"`
+	CacheHits   int    `json:"cache_hits,omitempty"`
+	CacheMisses int    `json:"cache_misses,omitempty"`
+	// UserID is the user the report's usage was charged to
+	UserID string `json:"user_id,omitempty"`
+	// Usage records the tokens and estimated cost of generating the report
+	Usage *Usage `json:"usage,omitempty"`
+	// WithoutAI is set when no model could generate the report and it was
+	// rendered from board statistics instead
+	WithoutAI bool `json:"generated_without_ai,omitempty"`
+	// Redactions counts the distinct values of each kind of personal data,
+	// e.g. "email" or "member", that were redacted before prompting the model
+	Redactions map[string]int `json:"redactions,omitempty"`
+	// FactChecked is set when the report was checked against the board
+	// data; UnverifiedClaims lists what couldn't be verified and
+	// Corrections counts the times the report was regenerated to fix it
+	FactChecked      bool    `json:"fact_checked,omitempty"`
+	UnverifiedClaims []Claim `json:"unverified_claims,omitempty"`
+	Corrections      int     `json:"corrections,omitempty"`
+	// InjectionFlags records board content that looked like instructions
+	// to the model; it was sent quoted and marked as untrusted
+	InjectionFlags []InjectionFlag `json:"injection_flags,omitempty"`
+	// Language is the language the report was written in; empty is English
+	Language Language `json:"language,omitempty"`
+	// Audience is who the report was written for; empty is the standard
+	// report. Variants lists the IDs of the reports for other audiences
+	// generated from the same board data.
+	Audience Audience `json:"audience,omitempty"`
+	Variants []string `json:"variants,omitempty"`
+	// Period groups the revisions of a board's report of one type for one
+	// period, and Revision numbers them from 1. Canonical marks the
+	// revision chosen to be distributed; if none is marked, the latest is.
+	Period    string `json:"period,omitempty"`
+	Revision  int    `json:"revision,omitempty"`
+	Canonical bool   `json:"canonical,omitempty"`
+	// Pinned keeps the report and its snapshot forever, whatever the
+	// board's retention policy
+	Pinned bool `json:"pinned,omitempty"`
 }
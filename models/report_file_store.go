@@ -0,0 +1,315 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// quarantineDir is the directory under the storage path that corrupt
+// report files are moved to
+const quarantineDir = "quarantine"
+
+// errCorruptReportFile is wrapped by the errors of report files that can't
+// be decoded, e.g. ones truncated by a crash mid-write
+var errCorruptReportFile = errors.New("report file is corrupt")
+
+// FileReportStore keeps one JSON file per report in a directory. An
+// in-memory index maps report IDs to their files and holds the fields
+// reports are filtered on, so lookups and listings only read the files of
+// the reports returned.
+//
+// Files are written atomically, so a crash never leaves a partial report,
+// and writes of the same report are serialized. Files that are corrupt
+// anyway, e.g. written by an older version, are moved to the quarantine
+// directory with a warning instead of failing listings.
+type FileReportStore struct {
+	StoragePath string
+
+	mutex sync.RWMutex
+	index map[string]*fileIndexEntry
+	// locks serializes writing, deleting and quarantining each report's
+	// file
+	locks keyLocks
+}
+
+// fileIndexEntry is what the index knows about a report file
+type fileIndexEntry struct {
+	path        string
+	boardID     string
+	reportType  ReportType
+	period      string
+	generatedAt time.Time
+}
+
+// NewFileReportStore creates a file report store and indexes the reports
+// already in the directory
+func NewFileReportStore(storagePath string) (*FileReportStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	s := &FileReportStore{
+		StoragePath: storagePath,
+		index:       make(map[string]*fileIndexEntry),
+	}
+	if err := s.buildIndex(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// buildIndex reads every report file once. Files written before reports
+// were named after their ID keep their names; files that aren't reports
+// are skipped.
+func (s *FileReportStore) buildIndex() error {
+	matches, err := filepath.Glob(filepath.Join(s.StoragePath, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error finding reports: %v", err)
+	}
+
+	for _, match := range matches {
+		report, err := readReportFile(match)
+		if errors.Is(err, errCorruptReportFile) {
+			s.quarantineFile(match, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Skipping report file %s: %v", match, err)
+			continue
+		}
+		if report.ID == "" {
+			log.Printf("Skipping report file %s: no report ID", match)
+			continue
+		}
+		s.index[report.ID] = newFileIndexEntry(match, report)
+	}
+
+	return nil
+}
+
+// newFileIndexEntry indexes a report stored at path
+func newFileIndexEntry(path string, report *Report) *fileIndexEntry {
+	return &fileIndexEntry{
+		path:        path,
+		boardID:     report.BoardID,
+		reportType:  report.Type,
+		period:      report.PeriodKey(),
+		generatedAt: report.GeneratedAt,
+	}
+}
+
+// readReportFile reads and decodes a report file
+func readReportFile(path string) (*Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading report file: %w", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptReportFile, err)
+	}
+
+	return &report, nil
+}
+
+// SaveReport saves a report to <ID>.json
+func (s *FileReportStore) SaveReport(report *Report) error {
+	if err := ValidReportID(report.ID); err != nil {
+		return err
+	}
+	path := filepath.Join(s.StoragePath, report.ID+".json")
+
+	// Convert report to JSON
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling report: %v", err)
+	}
+
+	// Serialize writers of this report, e.g. the scheduler and a user
+	// regenerating it, without blocking readers or other reports
+	unlock := s.locks.Lock(report.ID)
+	defer unlock()
+
+	// Write to file
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing report file: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Remove the report's old file if it was stored under another name
+	if old, ok := s.index[report.ID]; ok && old.path != path {
+		if err := os.Remove(old.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing old report file %s: %v", old.path, err)
+		}
+	}
+	s.index[report.ID] = newFileIndexEntry(path, report)
+
+	return nil
+}
+
+// GetReport retrieves a specific report by ID
+func (s *FileReportStore) GetReport(id string) (*Report, error) {
+	s.mutex.RLock()
+	entry, ok := s.index[id]
+	s.mutex.RUnlock()
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+
+	report, err := readReportFile(entry.path)
+	if os.IsNotExist(errors.Unwrap(err)) {
+		return nil, ErrReportNotFound
+	}
+	if errors.Is(err, errCorruptReportFile) {
+		// The report is lost; keep its file for inspection
+		if s.quarantineReport(id, entry.path, err) {
+			return nil, ErrReportNotFound
+		}
+		return s.GetReport(id)
+	}
+	return report, err
+}
+
+// ListReports retrieves the reports matching the filter, newest first
+func (s *FileReportStore) ListReports(filter ReportFilter) ([]*Report, error) {
+	// Filter and page on the index, then read only the reports returned
+	var candidates []*Report
+	paths := make(map[*Report]string)
+	s.mutex.RLock()
+	for id, entry := range s.index {
+		summary := &Report{ID: id, BoardID: entry.boardID, Type: entry.reportType, Period: entry.period, GeneratedAt: entry.generatedAt}
+		if filter.Matches(summary) {
+			candidates = append(candidates, summary)
+			paths[summary] = entry.path
+		}
+	}
+	s.mutex.RUnlock()
+
+	page := filter.Page(candidates)
+	reports := make([]*Report, 0, len(page))
+	for _, summary := range page {
+		report, err := readReportFile(paths[summary])
+		if os.IsNotExist(errors.Unwrap(err)) {
+			// Another process, e.g. the prune command, deleted the report
+			continue
+		}
+		if errors.Is(err, errCorruptReportFile) {
+			// Leave the corrupt report out rather than fail the listing;
+			// if it was rewritten meanwhile, read the new file
+			if s.quarantineReport(summary.ID, paths[summary], err) {
+				continue
+			}
+			report, err = s.GetReport(summary.ID)
+			if err == ErrReportNotFound {
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// SearchReports retrieves the reports matching the filter whose board name
+// or content contains every word of the query, newest first
+func (s *FileReportStore) SearchReports(query string, filter ReportFilter) ([]*Report, error) {
+	// Search every matching report, then page the results
+	all := filter
+	all.Offset, all.Limit = 0, 0
+	reports, err := s.ListReports(all)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*Report
+	for _, report := range reports {
+		if MatchesQuery(report, query) {
+			found = append(found, report)
+		}
+	}
+	return filter.Page(found), nil
+}
+
+// DeleteReport deletes a report by ID
+func (s *FileReportStore) DeleteReport(id string) error {
+	unlock := s.locks.Lock(id)
+	defer unlock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.index[id]
+	if !ok {
+		return ErrReportNotFound
+	}
+
+	if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting report file: %v", err)
+	}
+	delete(s.index, id)
+
+	return nil
+}
+
+// quarantineReport moves a report's corrupt file to the quarantine
+// directory and drops the report from the index. It returns false, leaving
+// the file alone, if the file was meanwhile rewritten and can be read.
+func (s *FileReportStore) quarantineReport(id, path string, readErr error) bool {
+	unlock := s.locks.Lock(id)
+	defer unlock()
+
+	// A writer holding the lock may have replaced the file since it was
+	// read; only quarantine it if it's still corrupt
+	if _, err := readReportFile(path); !errors.Is(err, errCorruptReportFile) {
+		return false
+	}
+
+	s.quarantineFile(path, readErr)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if entry, ok := s.index[id]; ok && entry.path == path {
+		delete(s.index, id)
+	}
+	return true
+}
+
+// quarantineFile moves a file to the quarantine directory, named with the
+// time it was moved so files of the same name don't collide, and logs a
+// warning. It returns the file's new path.
+func (s *FileReportStore) quarantineFile(path string, reason error) string {
+	dir := filepath.Join(s.StoragePath, quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Warning: report file %s is unreadable (%v) and couldn't be quarantined: %v", path, reason, err)
+		return ""
+	}
+
+	target := filepath.Join(dir, time.Now().UTC().Format("20060102T150405.000000000")+"-"+filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: report file %s is unreadable (%v) and couldn't be quarantined: %v", path, reason, err)
+		}
+		return ""
+	}
+	log.Printf("Warning: quarantined report file %s as %s: %v", path, target, reason)
+	return target
+}
+
+// Close does nothing; the file store holds no open resources
+func (s *FileReportStore) Close() error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// truncateFile cuts a file in half, as a crash mid-write would
+func truncateFile(t *testing.T, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReportStoreQuarantine(t *testing.T) {
+	tests := []struct {
+		name string
+		// corrupt damages the report's file and reads the store as the
+		// case requires, returning the store to check
+		corrupt func(t *testing.T, s *FileReportStore, path string) *FileReportStore
+	}{
+		{
+			name: "corrupt when the store opens",
+			corrupt: func(t *testing.T, s *FileReportStore, path string) *FileReportStore {
+				truncateFile(t, path)
+				reopened, err := NewFileReportStore(s.StoragePath)
+				if err != nil {
+					t.Fatalf("NewFileReportStore: %v", err)
+				}
+				return reopened
+			},
+		},
+		{
+			name: "corrupt when the report is read",
+			corrupt: func(t *testing.T, s *FileReportStore, path string) *FileReportStore {
+				truncateFile(t, path)
+				id := strings.TrimSuffix(filepath.Base(path), ".json")
+				if _, err := s.GetReport(id); err != ErrReportNotFound {
+					t.Errorf("GetReport returned %v, want ErrReportNotFound", err)
+				}
+				return s
+			},
+		},
+		{
+			name: "corrupt when reports are listed",
+			corrupt: func(t *testing.T, s *FileReportStore, path string) *FileReportStore {
+				truncateFile(t, path)
+				if _, err := s.ListReports(ReportFilter{}); err != nil {
+					t.Errorf("ListReports: %v", err)
+				}
+				return s
+			},
+		},
+		{
+			name: "not JSON",
+			corrupt: func(t *testing.T, s *FileReportStore, path string) *FileReportStore {
+				if err := ioutil.WriteFile(path, []byte("<html>"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := s.ListReports(ReportFilter{}); err != nil {
+					t.Errorf("ListReports: %v", err)
+				}
+				return s
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewFileReportStore(dir)
+			if err != nil {
+				t.Fatalf("NewFileReportStore: %v", err)
+			}
+			now := time.Now().UTC()
+			good := &Report{ID: NewReportID(now), BoardID: "board-1", Type: Weekly, GeneratedAt: now, Content: "good"}
+			bad := &Report{ID: NewReportID(now.Add(time.Second)), BoardID: "board-1", Type: Weekly, GeneratedAt: now.Add(time.Second), Content: "bad"}
+			for _, report := range []*Report{good, bad} {
+				if err := s.SaveReport(report); err != nil {
+					t.Fatalf("SaveReport: %v", err)
+				}
+			}
+			badPath := filepath.Join(dir, bad.ID+".json")
+
+			s = tt.corrupt(t, s, badPath)
+
+			// The corrupt file is moved aside, not deleted
+			if _, err := os.Stat(badPath); !os.IsNotExist(err) {
+				t.Errorf("corrupt file is still in the store: %v", err)
+			}
+			quarantined, err := ioutil.ReadDir(filepath.Join(dir, quarantineDir))
+			if err != nil {
+				t.Fatalf("reading quarantine directory: %v", err)
+			}
+			if len(quarantined) != 1 {
+				t.Fatalf("quarantined %d files, want 1", len(quarantined))
+			}
+
+			// The other reports are unaffected
+			reports, err := s.ListReports(ReportFilter{})
+			if err != nil {
+				t.Fatalf("ListReports: %v", err)
+			}
+			if len(reports) != 1 || reports[0].ID != good.ID {
+				t.Errorf("listed %d reports, want only %s", len(reports), good.ID)
+			}
+			if _, err := s.GetReport(bad.ID); err != ErrReportNotFound {
+				t.Errorf("GetReport of the corrupt report returned %v, want ErrReportNotFound", err)
+			}
+
+			// Saving the report again replaces the lost file
+			if err := s.SaveReport(bad); err != nil {
+				t.Fatalf("SaveReport: %v", err)
+			}
+			if got, err := s.GetReport(bad.ID); err != nil || got.Content != "bad" {
+				t.Errorf("GetReport after saving again returned %v, %v", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// staleTempFileAge is how old a temporary file must be before Verify
+// takes it for one left behind by a crash rather than a write in progress
+const staleTempFileAge = time.Minute
+
+// VerifyIssue is a problem Verify found with a file in a file report store
+type VerifyIssue struct {
+	File     string `json:"file"`
+	ReportID string `json:"report_id,omitempty"`
+	Problem  string `json:"problem"`
+	// Repair says what repairing the problem does, or did; it is empty if
+	// the problem can't be repaired
+	Repair   string `json:"repair,omitempty"`
+	Repaired bool   `json:"repaired"`
+	// Error is why the repair failed
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyResult is the outcome of verifying a file report store
+type VerifyResult struct {
+	// Files is the number of report files checked
+	Files int `json:"files"`
+	// Reports is the number of readable reports
+	Reports int           `json:"reports"`
+	Issues  []VerifyIssue `json:"issues"`
+}
+
+// verifiedFile is a readable report file found by Verify
+type verifiedFile struct {
+	path   string
+	report *Report
+}
+
+// Verify checks every file in the store's directory for temporary files
+// left by interrupted writes, files that aren't readable reports, reports
+// stored in more than one file and reports not named after their ID. With
+// repair, it deletes the temporary files, moves the unreadable files and
+// the superseded copies to the quarantine directory, renames misnamed
+// files and reindexes the store.
+func (s *FileReportStore) Verify(repair bool) (*VerifyResult, error) {
+	files, err := ioutil.ReadDir(s.StoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading report directory: %v", err)
+	}
+
+	result := &VerifyResult{Issues: []VerifyIssue{}}
+	byID := make(map[string][]verifiedFile)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.StoragePath, file.Name())
+
+		// Delete temporary files old enough that no write is using them
+		if isTempFile(path) {
+			if time.Since(file.ModTime()) < staleTempFileAge {
+				continue
+			}
+			issue := VerifyIssue{File: path, Problem: "temporary file left by an interrupted write", Repair: "delete it"}
+			if repair {
+				s.repair(&issue, "", func() error { return os.Remove(path) })
+			}
+			result.Issues = append(result.Issues, issue)
+			continue
+		}
+		if !strings.HasSuffix(path, ".json") {
+			continue
+		}
+		result.Files++
+
+		report, err := readReportFile(path)
+		problem := ""
+		switch {
+		case errors.Is(err, errCorruptReportFile):
+			problem = err.Error()
+		case err != nil:
+			// Reading failed, e.g. for permissions; there's nothing to repair
+			result.Issues = append(result.Issues, VerifyIssue{File: path, Problem: err.Error()})
+			continue
+		case report.ID == "":
+			problem = "not a report: no report ID"
+		default:
+			if err := ValidReportID(report.ID); err != nil {
+				problem = err.Error()
+			}
+		}
+		if problem != "" {
+			issue := VerifyIssue{File: path, Problem: problem, Repair: "quarantine it"}
+			if repair {
+				s.repair(&issue, "", func() error { return s.quarantine(path, problem) })
+			}
+			result.Issues = append(result.Issues, issue)
+			continue
+		}
+
+		result.Reports++
+		byID[report.ID] = append(byID[report.ID], verifiedFile{path: path, report: report})
+	}
+
+	// Keep one file per report, named after its ID
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		copies := byID[id]
+		want := filepath.Join(s.StoragePath, id+".json")
+		keep := keptCopy(copies, want)
+
+		for _, candidate := range copies {
+			if candidate.path == keep.path {
+				continue
+			}
+			result.Reports--
+			path := candidate.path
+			issue := VerifyIssue{
+				File:     path,
+				ReportID: id,
+				Problem:  fmt.Sprintf("duplicate of report %s, which is kept in %s", id, keep.path),
+				Repair:   "quarantine it",
+			}
+			if repair {
+				s.repair(&issue, id, func() error { return s.quarantine(path, issue.Problem) })
+			}
+			result.Issues = append(result.Issues, issue)
+		}
+
+		if keep.path != want {
+			path := keep.path
+			issue := VerifyIssue{
+				File:     path,
+				ReportID: id,
+				Problem:  fmt.Sprintf("report %s is not in %s", id, filepath.Base(want)),
+				Repair:   "rename it to " + filepath.Base(want),
+			}
+			if repair {
+				s.repair(&issue, id, func() error { return os.Rename(path, want) })
+			}
+			result.Issues = append(result.Issues, issue)
+		}
+	}
+
+	// Pick up the repaired files
+	if repair {
+		s.mutex.Lock()
+		s.index = make(map[string]*fileIndexEntry)
+		err := s.buildIndex()
+		s.mutex.Unlock()
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// VerifyReportFiles verifies the report files in a directory without
+// opening it as a store, which would quarantine corrupt files, so nothing
+// changes unless repair is set
+func VerifyReportFiles(storagePath string, repair bool) (*VerifyResult, error) {
+	s := &FileReportStore{
+		StoragePath: storagePath,
+		index:       make(map[string]*fileIndexEntry),
+	}
+	return s.Verify(repair)
+}
+
+// keptCopy picks the file of a report to keep: the one named after its ID,
+// which SaveReport writes, or else the most recently generated
+func keptCopy(copies []verifiedFile, want string) verifiedFile {
+	keep := copies[0]
+	for _, candidate := range copies[1:] {
+		switch {
+		case keep.path == want:
+		case candidate.path == want, candidate.report.GeneratedAt.After(keep.report.GeneratedAt):
+			keep = candidate
+		}
+	}
+	return keep
+}
+
+// repair runs a repair, holding the report's lock if it has an ID, and
+// records its outcome in the issue
+func (s *FileReportStore) repair(issue *VerifyIssue, id string, fix func() error) {
+	if id != "" {
+		unlock := s.locks.Lock(id)
+		defer unlock()
+	}
+	if err := fix(); err != nil {
+		issue.Error = err.Error()
+		return
+	}
+	issue.Repaired = true
+}
+
+// quarantine moves a file to the quarantine directory, returning an error
+// if it couldn't be moved
+func (s *FileReportStore) quarantine(path, reason string) error {
+	if s.quarantineFile(path, errors.New(reason)) == "" {
+		return fmt.Errorf("couldn't quarantine %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// NewReportID returns a new report ID for a report generated at t. IDs are
+// ULIDs, so they sort by time; IDs made in the same millisecond still
+// differ and keep their order.
+func NewReportID(t time.Time) string {
+	return ulid.MustNew(ulid.Timestamp(t), ulid.DefaultEntropy()).String()
+}
+
+// ReportPeriod identifies the period of a board's report of one type: the
+// board, the type and the first day of the week or month the report ends
+// in, so reports generated on different days of a week or month are
+// revisions of the same report
+func ReportPeriod(boardID string, reportType ReportType, endDate time.Time) string {
+	return fmt.Sprintf("%s_%s_%s", boardID, reportType, PeriodStart(reportType, endDate).Format("2006-01-02"))
+}
+
+// PeriodStart returns the first day, in UTC, of the week starting Monday or
+// the month a report ends in. A report ending at midnight ends in the day
+// before, so the scheduled report generated on a Monday or the first of the
+// month covers the same period as those generated on demand during the
+// week or month before it.
+func PeriodStart(reportType ReportType, endDate time.Time) time.Time {
+	last := endDate.UTC().Add(-time.Nanosecond)
+	day := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+	if reportType == Monthly {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	// Weekdays count from Sunday, weeks from Monday
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// PeriodKey returns the report's period. Reports saved before periods were
+// recorded get it from their board, type and end date, which their ID was
+// made of.
+func (r *Report) PeriodKey() string {
+	if r.Period != "" {
+		return r.Period
+	}
+	return ReportPeriod(r.BoardID, r.Type, r.EndDate)
+}
+
+// RevisionNumber returns the report's revision; reports saved before
+// revisions were kept are the first revision of their period
+func (r *Report) RevisionNumber() int {
+	if r.Revision > 0 {
+		return r.Revision
+	}
+	return 1
+}
+
+// ReportRevisions are the revisions of a period's report for one audience
+type ReportRevisions struct {
+	Period   string
+	Audience Audience
+	// Revisions are newest first
+	Revisions []*Report
+	// Canonical is the revision that gets distributed
+	Canonical *Report
+}
+
+// GroupRevisions groups reports by period and audience. The groups are
+// ordered by their newest revision, newest first.
+func GroupRevisions(reports []*Report) []*ReportRevisions {
+	var groups []*ReportRevisions
+	byKey := make(map[string]*ReportRevisions)
+	for _, report := range reports {
+		key := report.PeriodKey() + "/" + string(report.Audience)
+		group, ok := byKey[key]
+		if !ok {
+			group = &ReportRevisions{Period: report.PeriodKey(), Audience: report.Audience}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.Revisions = append(group.Revisions, report)
+	}
+
+	for _, group := range groups {
+		sortRevisions(group.Revisions)
+		group.Canonical = CanonicalRevision(group.Revisions)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Revisions[0].GeneratedAt.After(groups[j].Revisions[0].GeneratedAt)
+	})
+	return groups
+}
+
+// CanonicalRevision returns the revision marked canonical, or the latest
+// revision if none is
+func CanonicalRevision(revisions []*Report) *Report {
+	var latest *Report
+	for _, report := range revisions {
+		if report.Canonical {
+			return report
+		}
+		if latest == nil || report.RevisionNumber() > latest.RevisionNumber() ||
+			(report.RevisionNumber() == latest.RevisionNumber() && report.GeneratedAt.After(latest.GeneratedAt)) {
+			latest = report
+		}
+	}
+	return latest
+}
+
+// sortRevisions sorts revisions newest first
+func sortRevisions(revisions []*Report) {
+	sort.SliceStable(revisions, func(i, j int) bool {
+		if revisions[i].RevisionNumber() != revisions[j].RevisionNumber() {
+			return revisions[i].RevisionNumber() > revisions[j].RevisionNumber()
+		}
+		return revisions[i].GeneratedAt.After(revisions[j].GeneratedAt)
+	})
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupRevisions(t *testing.T) {
+	base := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	report := func(id, period string, audience Audience, revision, hour int, canonical bool) *Report {
+		return &Report{
+			ID: id, BoardID: "board-1", Type: Weekly, Period: period, Audience: audience,
+			Revision: revision, GeneratedAt: base.Add(time.Duration(hour) * time.Hour), Canonical: canonical,
+		}
+	}
+	legacy := func(id string, endDate time.Time, hour int) *Report {
+		return &Report{ID: id, BoardID: "board-1", Type: Weekly, EndDate: endDate, GeneratedAt: base.Add(time.Duration(hour) * time.Hour)}
+	}
+
+	tests := []struct {
+		name    string
+		reports []*Report
+		// want describes each group as "period/audience: revisions newest
+		// first -> canonical"
+		want []string
+	}{
+		{
+			name: "no reports",
+		},
+		{
+			name:    "one report",
+			reports: []*Report{report("a", "p1", "", 1, 0, false)},
+			want:    []string{"p1/: a -> a"},
+		},
+		{
+			name: "latest revision is canonical",
+			reports: []*Report{
+				report("a1", "p1", "", 1, 0, false),
+				report("a3", "p1", "", 3, 2, false),
+				report("a2", "p1", "", 2, 1, false),
+			},
+			want: []string{"p1/: a3 a2 a1 -> a3"},
+		},
+		{
+			name: "marked revision is canonical",
+			reports: []*Report{
+				report("a1", "p1", "", 1, 0, false),
+				report("a2", "p1", "", 2, 1, true),
+				report("a3", "p1", "", 3, 2, false),
+			},
+			want: []string{"p1/: a3 a2 a1 -> a2"},
+		},
+		{
+			name: "audiences are grouped separately",
+			reports: []*Report{
+				report("a1", "p1", "", 1, 0, false),
+				report("e1", "p1", "executive", 1, 1, false),
+				report("a2", "p1", "", 2, 2, false),
+			},
+			want: []string{"p1/: a2 a1 -> a2", "p1/executive: e1 -> e1"},
+		},
+		{
+			name: "groups are ordered by their newest revision",
+			reports: []*Report{
+				report("old2", "p1", "", 2, 5, false),
+				report("new1", "p2", "", 1, 3, false),
+				report("old1", "p1", "", 1, 0, false),
+			},
+			want: []string{"p1/: old2 old1 -> old2", "p2/: new1 -> new1"},
+		},
+		{
+			name: "equal revision numbers are ordered by time",
+			reports: []*Report{
+				report("early", "p1", "", 1, 0, false),
+				report("late", "p1", "", 1, 1, false),
+			},
+			want: []string{"p1/: late early -> late"},
+		},
+		{
+			name: "reports saved before periods and revisions",
+			reports: []*Report{
+				legacy("l1", base, 0),
+				report("r2", ReportPeriod("board-1", Weekly, base), "", 2, 1, false),
+				legacy("l0", base.AddDate(0, 0, -7), -168),
+			},
+			want: []string{
+				"board-1_weekly_2026-10-12/: r2 l1 -> r2",
+				"board-1_weekly_2026-10-05/: l0 -> l0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, group := range GroupRevisions(tt.reports) {
+				var ids []string
+				for _, revision := range group.Revisions {
+					ids = append(ids, revision.ID)
+				}
+				got = append(got, fmt.Sprintf("%s/%s: %s -> %s", group.Period, group.Audience, strings.Join(ids, " "), group.Canonical.ID))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got groups %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportPeriod(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		name       string
+		reportType ReportType
+		endDate    time.Time
+		want       string
+	}{
+		{name: "weekly, midweek", reportType: Weekly, endDate: time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC), want: "board-1_weekly_2026-10-12"},
+		{name: "weekly, Sunday", reportType: Weekly, endDate: time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), want: "board-1_weekly_2026-10-12"},
+		{name: "weekly, Monday morning", reportType: Weekly, endDate: time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC), want: "board-1_weekly_2026-10-12"},
+		{name: "weekly, scheduled at Monday midnight", reportType: Weekly, endDate: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), want: "board-1_weekly_2026-10-12"},
+		{name: "weekly, across months", reportType: Weekly, endDate: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), want: "board-1_weekly_2026-09-28"},
+		{name: "weekly, in UTC", reportType: Weekly, endDate: time.Date(2026, 10, 19, 1, 0, 0, 0, berlin), want: "board-1_weekly_2026-10-12"},
+		{name: "monthly, mid-month", reportType: Monthly, endDate: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), want: "board-1_monthly_2026-10-01"},
+		{name: "monthly, first of the month", reportType: Monthly, endDate: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), want: "board-1_monthly_2026-10-01"},
+		{name: "monthly, scheduled at midnight on the first", reportType: Monthly, endDate: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), want: "board-1_monthly_2026-10-01"},
+		{name: "monthly, across years", reportType: Monthly, endDate: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), want: "board-1_monthly_2026-12-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReportPeriod("board-1", tt.reportType, tt.endDate); got != tt.want {
+				t.Errorf("got period %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
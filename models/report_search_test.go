@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// searchStores opens an empty store of each kind that can search reports
+func searchStores(t *testing.T) map[string]ReportStore {
+	t.Helper()
+	files, err := NewFileReportStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileReportStore: %v", err)
+	}
+	db, err := NewSQLiteReportStore(filepath.Join(t.TempDir(), "reports.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteReportStore: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]ReportStore{"file": files, "sqlite": db}
+}
+
+func TestSearchReports(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Reports are saved oldest first, report-0 the oldest
+	reports := []struct {
+		boardName, content string
+	}{
+		{"Website", "The landing page redesign is blocked on copy."},
+		{"Überprüfung", "Die ÜBERPRÜFUNG der Zahlungen ist abgeschlossen."},
+		{"Équipe produit", "L'équipe a livré la page de tarification."},
+		{"Отчёт", "ЗАДАЧИ по оплате выполнены."},
+		{"Website", "Payments: 100% of webhooks retried; see card_42."},
+		{"Mobile", "Ελέγχουμε την ΟΘΟΝΗ σύνδεσης."},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter ReportFilter
+		// want are the indexes of the matching reports, newest first
+		want []int
+	}{
+		{name: "ASCII, any case", query: "WEBSITE", want: []int{4, 0}},
+		{name: "every word must match", query: "website blocked", want: []int{0}},
+		{name: "German umlauts", query: "überprüfung", want: []int{1}},
+		{name: "upper-case umlauts in the query", query: "ÜBERPRÜFUNG zahlungen", want: []int{1}},
+		{name: "French accents", query: "ÉQUIPE", want: []int{2}},
+		{name: "Cyrillic", query: "задачи", want: []int{3}},
+		{name: "Greek", query: "οθονη", want: []int{5}},
+		{name: "LIKE wildcards are literal", query: "100%", want: []int{4}},
+		{name: "underscore is literal", query: "card_42", want: []int{4}},
+		{name: "percent alone matches nothing else", query: "%", want: []int{4}},
+		{name: "no match", query: "kubernetes", want: []int{}},
+		{name: "board filter", query: "page", filter: ReportFilter{BoardID: "board-0"}, want: []int{0}},
+		{name: "first page", query: "e", filter: ReportFilter{Limit: 2}, want: []int{5, 4}},
+		{name: "second page", query: "e", filter: ReportFilter{Offset: 2, Limit: 2}, want: []int{2, 1}},
+		{name: "past the last page", query: "e", filter: ReportFilter{Offset: 10, Limit: 2}, want: []int{}},
+	}
+
+	for name, store := range searchStores(t) {
+		ids := make([]string, len(reports))
+		for i, r := range reports {
+			generated := now.Add(time.Duration(i-len(reports)) * time.Hour)
+			report := &Report{
+				ID:          fmt.Sprintf("%s-report-%d", NewReportID(generated), i),
+				BoardID:     fmt.Sprintf("board-%d", i),
+				BoardName:   r.boardName,
+				Type:        Weekly,
+				Content:     r.content,
+				GeneratedAt: generated,
+			}
+			if err := store.SaveReport(report); err != nil {
+				t.Fatalf("%s: SaveReport: %v", name, err)
+			}
+			ids[i] = report.ID
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				found, err := store.SearchReports(tt.query, tt.filter)
+				if err != nil {
+					t.Fatalf("SearchReports: %v", err)
+				}
+				got := []int{}
+				for _, report := range found {
+					for i, id := range ids {
+						if report.ID == id {
+							got = append(got, i)
+						}
+					}
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("found reports %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Register the pure Go SQLite driver
+	"modernc.org/sqlite"
+)
+
+// sqliteTimeFormat stores times in UTC with a fixed width, so they sort as
+// text
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteSchema creates the reports table. The full report is kept as JSON;
+// the other columns are copies of the fields reports are filtered on.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS reports (
+	id           TEXT PRIMARY KEY,
+	board_id     TEXT NOT NULL,
+	board_name   TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	generated_at TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	data         TEXT NOT NULL,
+	period       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS reports_board ON reports (board_id, generated_at);
+CREATE INDEX IF NOT EXISTS reports_type ON reports (type, generated_at);
+`
+
+// sqlitePeriodIndex indexes the period column, which databases created
+// before revisions were kept get from migrate
+const sqlitePeriodIndex = `CREATE INDEX IF NOT EXISTS reports_period ON reports (period, generated_at)`
+
+// SQLite's lower() only folds ASCII, so searches use fold_case, which
+// folds like the other stores
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("fold_case", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return foldCase(value), nil
+		case []byte:
+			return foldCase(string(value)), nil
+		default:
+			return value, nil
+		}
+	})
+}
+
+// SQLiteReportStore keeps reports in an embedded SQLite database
+type SQLiteReportStore struct {
+	Path string
+	db   *sql.DB
+}
+
+// NewSQLiteReportStore opens or creates a SQLite report database
+func NewSQLiteReportStore(path string) (*SQLiteReportStore, error) {
+	// Create the database's directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	// Wait for locks held by other connections instead of failing, and use
+	// a write-ahead log so reads don't block writes
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("error opening report database: %v", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating report database: %v", err)
+	}
+
+	s := &SQLiteReportStore{Path: path, db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate adds the columns of later versions to an existing database and
+// fills them in from the stored reports
+func (s *SQLiteReportStore) migrate() error {
+	// Add the period column if the table predates it
+	var hasPeriod bool
+	if err := s.db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('reports') WHERE name = 'period'`).Scan(&hasPeriod); err != nil {
+		return fmt.Errorf("error reading report database schema: %v", err)
+	}
+	if !hasPeriod {
+		if _, err := s.db.Exec(`ALTER TABLE reports ADD COLUMN period TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("error adding report periods: %v", err)
+		}
+	}
+	if _, err := s.db.Exec(sqlitePeriodIndex); err != nil {
+		return fmt.Errorf("error indexing report periods: %v", err)
+	}
+
+	// Fill in the period of reports saved before it was recorded
+	rows, err := s.db.Query(`SELECT data FROM reports WHERE period = ''`)
+	if err != nil {
+		return fmt.Errorf("error finding reports without a period: %v", err)
+	}
+	var reports []*Report
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading report: %v", err)
+		}
+		report, err := decodeReport(data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		reports = append(reports, report)
+	}
+	rows.Close()
+
+	for _, report := range reports {
+		if _, err := s.db.Exec(`UPDATE reports SET period = ? WHERE id = ?`, report.PeriodKey(), report.ID); err != nil {
+			return fmt.Errorf("error saving report period: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveReport saves a report, replacing any report with the same ID
+func (s *SQLiteReportStore) SaveReport(report *Report) error {
+	if err := ValidReportID(report.ID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error marshaling report: %v", err)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO reports (id, board_id, board_name, type, generated_at, content, data, period)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			board_id = excluded.board_id,
+			board_name = excluded.board_name,
+			type = excluded.type,
+			generated_at = excluded.generated_at,
+			content = excluded.content,
+			data = excluded.data,
+			period = excluded.period`,
+		report.ID, report.BoardID, report.BoardName, string(report.Type),
+		report.GeneratedAt.UTC().Format(sqliteTimeFormat), report.Content, string(data), report.PeriodKey())
+	if err != nil {
+		return fmt.Errorf("error saving report: %v", err)
+	}
+
+	return nil
+}
+
+// GetReport retrieves a specific report by ID
+func (s *SQLiteReportStore) GetReport(id string) (*Report, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM reports WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading report: %v", err)
+	}
+
+	return decodeReport(data)
+}
+
+// ListReports retrieves the reports matching the filter, newest first
+func (s *SQLiteReportStore) ListReports(filter ReportFilter) ([]*Report, error) {
+	return s.query(nil, nil, filter)
+}
+
+// SearchReports retrieves the reports matching the filter whose board name
+// or content contains every word of the query, newest first
+func (s *SQLiteReportStore) SearchReports(query string, filter ReportFilter) ([]*Report, error) {
+	var conditions []string
+	var args []interface{}
+	for _, term := range queryTerms(query) {
+		conditions = append(conditions, `(fold_case(board_name) LIKE ? ESCAPE '\' OR fold_case(content) LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(term) + "%"
+		args = append(args, pattern, pattern)
+	}
+	return s.query(conditions, args, filter)
+}
+
+// query selects the reports matching the filter and any extra conditions
+func (s *SQLiteReportStore) query(conditions []string, args []interface{}, filter ReportFilter) ([]*Report, error) {
+	if filter.BoardID != "" {
+		conditions = append(conditions, "board_id = ?")
+		args = append(args, filter.BoardID)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, string(filter.Type))
+	}
+	if filter.Period != "" {
+		conditions = append(conditions, "period = ?")
+		args = append(args, filter.Period)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "generated_at >= ?")
+		args = append(args, filter.From.UTC().Format(sqliteTimeFormat))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "generated_at <= ?")
+		args = append(args, filter.To.UTC().Format(sqliteTimeFormat))
+	}
+
+	query := "SELECT data FROM reports"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY generated_at DESC, id"
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error finding reports: %v", err)
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("error reading report: %v", err)
+		}
+		report, err := decodeReport(data)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding reports: %v", err)
+	}
+
+	return reports, nil
+}
+
+// DeleteReport deletes a report by ID
+func (s *SQLiteReportStore) DeleteReport(id string) error {
+	result, err := s.db.Exec(`DELETE FROM reports WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting report: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrReportNotFound
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteReportStore) Close() error {
+	return s.db.Close()
+}
+
+// decodeReport decodes a report stored as JSON
+func decodeReport(data string) (*Report, error) {
+	var report Report
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("error unmarshaling report: %v", err)
+	}
+	return &report, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSQLiteStore opens a report database, closing it when the test ends
+func newTestSQLiteStore(t *testing.T, path string) *SQLiteReportStore {
+	t.Helper()
+	store, err := NewSQLiteReportStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteReportStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteReportStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "reports.db")
+	store := newTestSQLiteStore(t, path)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newReport := func(boardID string, reportType ReportType, age time.Duration) *Report {
+		generated := now.Add(-age)
+		return &Report{
+			ID:          NewReportID(generated),
+			Period:      ReportPeriod(boardID, reportType, generated),
+			BoardID:     boardID,
+			BoardName:   "Board " + boardID,
+			Type:        reportType,
+			Content:     "Report for " + boardID,
+			GeneratedAt: generated,
+			EndDate:     generated,
+		}
+	}
+	oldest := newReport("board-1", Weekly, 21*24*time.Hour)
+	monthly := newReport("board-1", Monthly, 48*time.Hour)
+	other := newReport("board-2", Weekly, 24*time.Hour)
+	newest := newReport("board-1", Weekly, time.Hour)
+	for _, report := range []*Report{oldest, monthly, other, newest} {
+		if err := store.SaveReport(report); err != nil {
+			t.Fatalf("SaveReport: %v", err)
+		}
+	}
+
+	// Saving again replaces the report
+	newest.Content = "Revised report"
+	if err := store.SaveReport(newest); err != nil {
+		t.Fatalf("SaveReport: %v", err)
+	}
+	got, err := store.GetReport(newest.ID)
+	if err != nil {
+		t.Fatalf("GetReport: %v", err)
+	}
+	if got.Content != "Revised report" || got.BoardName != newest.BoardName || !got.GeneratedAt.Equal(newest.GeneratedAt) {
+		t.Errorf("got %+v, want %+v", got, newest)
+	}
+
+	if _, err := store.GetReport(NewReportID(now)); err != ErrReportNotFound {
+		t.Errorf("GetReport of a missing report returned %v, want ErrReportNotFound", err)
+	}
+	if err := store.SaveReport(&Report{ID: "../escape"}); err == nil {
+		t.Error("saved a report with an invalid ID")
+	}
+
+	tests := []struct {
+		name   string
+		filter ReportFilter
+		want   []*Report
+	}{
+		{name: "all, newest first", want: []*Report{newest, other, monthly, oldest}},
+		{name: "board", filter: ReportFilter{BoardID: "board-1"}, want: []*Report{newest, monthly, oldest}},
+		{name: "type", filter: ReportFilter{Type: Monthly}, want: []*Report{monthly}},
+		{name: "period", filter: ReportFilter{Period: oldest.Period}, want: []*Report{oldest}},
+		{name: "time range", filter: ReportFilter{From: now.Add(-50 * time.Hour), To: now.Add(-12 * time.Hour)}, want: []*Report{other, monthly}},
+		{name: "page", filter: ReportFilter{Offset: 1, Limit: 2}, want: []*Report{other, monthly}},
+		{name: "offset only", filter: ReportFilter{Offset: 3}, want: []*Report{oldest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := store.ListReports(tt.filter)
+			if err != nil {
+				t.Fatalf("ListReports: %v", err)
+			}
+			if len(reports) != len(tt.want) {
+				t.Fatalf("got %d reports, want %d", len(reports), len(tt.want))
+			}
+			for i := range reports {
+				if reports[i].ID != tt.want[i].ID {
+					t.Errorf("report %d is %s (%s), want %s (%s)", i, reports[i].ID, reports[i].Content, tt.want[i].ID, tt.want[i].Content)
+				}
+			}
+		})
+	}
+
+	// Deleting removes the report, once
+	if err := store.DeleteReport(oldest.ID); err != nil {
+		t.Fatalf("DeleteReport: %v", err)
+	}
+	if err := store.DeleteReport(oldest.ID); err != ErrReportNotFound {
+		t.Errorf("deleting twice returned %v, want ErrReportNotFound", err)
+	}
+
+	// The reports outlive the connection
+	store.Close()
+	reopened := newTestSQLiteStore(t, path)
+	reports, err := reopened.ListReports(ReportFilter{})
+	if err != nil {
+		t.Fatalf("ListReports: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Errorf("got %d reports after reopening, want 3", len(reports))
+	}
+}
+
+func TestSQLiteReportStoreMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.db")
+	end := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	report := &Report{
+		ID:          NewReportID(end),
+		BoardID:     "board-1",
+		BoardName:   "Launch",
+		Type:        Weekly,
+		Content:     "Saved before periods were recorded",
+		GeneratedAt: end,
+		EndDate:     end,
+	}
+
+	// Create a database without the period column, as older versions did
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE reports (
+		id TEXT PRIMARY KEY, board_id TEXT NOT NULL, board_name TEXT NOT NULL, type TEXT NOT NULL,
+		generated_at TEXT NOT NULL, content TEXT NOT NULL, data TEXT NOT NULL)`)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO reports VALUES (?, ?, ?, ?, ?, ?, ?)`, report.ID, report.BoardID, report.BoardName,
+			string(report.Type), report.GeneratedAt.Format(sqliteTimeFormat), report.Content, string(data))
+	}
+	db.Close()
+	if err != nil {
+		t.Fatalf("creating old database: %v", err)
+	}
+
+	// Opening it adds the period, so the old report is found by period
+	store := newTestSQLiteStore(t, path)
+	reports, err := store.ListReports(ReportFilter{Period: ReportPeriod("board-1", Weekly, end)})
+	if err != nil {
+		t.Fatalf("ListReports: %v", err)
+	}
+	if len(reports) != 1 || reports[0].ID != report.ID {
+		t.Errorf("got %d reports for the period, want the old report", len(reports))
+	}
+}
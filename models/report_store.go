@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrReportNotFound is returned when no report has the requested ID
+	ErrReportNotFound = errors.New("report not found")
+	// ErrReportConflict is returned when a report was changed by another
+	// writer since this store last read it
+	ErrReportConflict = errors.New("report was changed by another writer")
+)
+
+// ReportStore stores reports. Implementations must be safe for concurrent
+// use.
+type ReportStore interface {
+	// SaveReport saves a report, replacing any report with the same ID
+	SaveReport(report *Report) error
+	// GetReport returns the report with the given ID, or ErrReportNotFound
+	GetReport(id string) (*Report, error)
+	// ListReports returns the reports matching the filter, newest first
+	ListReports(filter ReportFilter) ([]*Report, error)
+	// DeleteReport deletes the report with the given ID, or returns
+	// ErrReportNotFound
+	DeleteReport(id string) error
+	// SearchReports returns the reports matching the filter whose board
+	// name or content contains every word of the query, newest first
+	SearchReports(query string, filter ReportFilter) ([]*Report, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+// ReportArtifacts is implemented by report stores that also keep the board
+// snapshot a report was written from and its rendered PDF. The getters
+// return ErrReportNotFound if there is no artifact.
+type ReportArtifacts interface {
+	SaveSnapshot(report *Report, data []byte) error
+	GetSnapshot(id string) ([]byte, error)
+	HasSnapshot(id string) (bool, error)
+	DeleteSnapshot(id string) error
+	SavePDF(report *Report, pdf []byte) error
+	GetPDF(id string) ([]byte, error)
+}
+
+// Report store backends
+const (
+	// ReportStoreFile keeps one JSON file per report in a directory
+	ReportStoreFile = "file"
+	// ReportStoreSQLite keeps reports in an embedded SQLite database
+	ReportStoreSQLite = "sqlite"
+	// ReportStoreS3 keeps reports, snapshots and PDFs in an S3-compatible
+	// bucket
+	ReportStoreS3 = "s3"
+)
+
+// ReportFilter selects reports. Zero fields match every report.
+type ReportFilter struct {
+	BoardID string
+	Type    ReportType
+	// Period selects the revisions of one period's report
+	Period string
+	// From and To bound the time the reports were generated
+	From time.Time
+	To   time.Time
+	// Offset skips the newest reports and Limit caps the number returned;
+	// zero returns them all
+	Offset int
+	Limit  int
+}
+
+// Matches reports whether a report passes the filter, ignoring pagination
+func (f ReportFilter) Matches(report *Report) bool {
+	if f.BoardID != "" && report.BoardID != f.BoardID {
+		return false
+	}
+	if f.Type != "" && report.Type != f.Type {
+		return false
+	}
+	if f.Period != "" && report.PeriodKey() != f.Period {
+		return false
+	}
+	if !f.From.IsZero() && report.GeneratedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && report.GeneratedAt.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// Page sorts reports newest first and returns the filter's page of them
+func (f ReportFilter) Page(reports []*Report) []*Report {
+	sortReports(reports)
+	if f.Offset > 0 {
+		if f.Offset >= len(reports) {
+			return []*Report{}
+		}
+		reports = reports[f.Offset:]
+	}
+	if f.Limit > 0 && len(reports) > f.Limit {
+		reports = reports[:f.Limit]
+	}
+	return reports
+}
+
+// sortReports sorts reports newest first, by ID for reports generated at
+// the same time
+func sortReports(reports []*Report) {
+	sort.SliceStable(reports, func(i, j int) bool {
+		if !reports[i].GeneratedAt.Equal(reports[j].GeneratedAt) {
+			return reports[i].GeneratedAt.After(reports[j].GeneratedAt)
+		}
+		return reports[i].ID < reports[j].ID
+	})
+}
+
+// queryTerms splits a search query into lowercase words
+func queryTerms(query string) []string {
+	return strings.Fields(foldCase(query))
+}
+
+// foldCase lower-cases text for case-insensitive search in any script.
+// Every store folds with it, so they match the same reports.
+func foldCase(text string) string {
+	return strings.ToLower(text)
+}
+
+// MatchesQuery reports whether the report's board name or content
+// contains every word of the query, ignoring case
+func MatchesQuery(report *Report, query string) bool {
+	text := foldCase(report.BoardName + "\n" + report.Content)
+	for _, term := range queryTerms(query) {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidReportID checks that a report ID can be used as a file or object
+// name
+func ValidReportID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return fmt.Errorf("invalid report ID %q", id)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy decides which of a board's reports are kept. Zero fields
+// keep everything; pinned reports are always kept.
+type RetentionPolicy struct {
+	// Keep is how many periods of each report type are kept, e.g. 12
+	// weekly and 24 monthly; all revisions of older periods are deleted
+	Keep map[ReportType]int `json:"keep,omitempty"`
+	// CanonicalOnlyAfterDays deletes revisions that aren't their period's
+	// canonical one once they are this many days old
+	CanonicalOnlyAfterDays int `json:"canonical_only_after_days,omitempty"`
+	// SnapshotDays deletes the board snapshots reports were written from
+	// once they are this many days old, keeping the reports
+	SnapshotDays int `json:"snapshot_days,omitempty"`
+}
+
+// Enabled reports whether the policy deletes anything
+func (p RetentionPolicy) Enabled() bool {
+	for _, keep := range p.Keep {
+		if keep > 0 {
+			return true
+		}
+	}
+	return p.CanonicalOnlyAfterDays > 0 || p.SnapshotDays > 0
+}
+
+// KeepCount returns how many periods of a report type are kept; zero keeps
+// them all
+func (p RetentionPolicy) KeepCount(reportType ReportType) int {
+	return p.Keep[reportType]
+}
+
+// What the pruner deletes
+const (
+	PruneReport   = "report"
+	PruneSnapshot = "snapshot"
+)
+
+// PruneAction is a report or snapshot the pruner deleted, or would have in
+// a dry run
+type PruneAction struct {
+	Kind        string     `json:"kind"`
+	ReportID    string     `json:"report_id"`
+	BoardID     string     `json:"board_id"`
+	BoardName   string     `json:"board_name,omitempty"`
+	Type        ReportType `json:"type"`
+	Period      string     `json:"period"`
+	Revision    int        `json:"revision"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	// Reason names the rule that selected it
+	Reason string `json:"reason"`
+	// Error is set if deleting failed
+	Error string `json:"error,omitempty"`
+}
+
+// PruneRecord is the audit record of one pruner run
+type PruneRecord struct {
+	ID         string        `json:"id"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	DryRun     bool          `json:"dry_run"`
+	Actions    []PruneAction `json:"actions"`
+}
+
+// PruneAuditStore keeps one JSON file per pruner run
+type PruneAuditStore struct {
+	StoragePath string
+}
+
+// NewPruneAuditStore creates a new prune audit store
+func NewPruneAuditStore(storagePath string) (*PruneAuditStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &PruneAuditStore{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// Save writes a run's audit record, named after the time it started
+func (s *PruneAuditStore) Save(record *PruneRecord) error {
+	if record.ID == "" {
+		record.ID = record.StartedAt.UTC().Format("20060102T150405.000000000Z")
+		if record.DryRun {
+			record.ID += "-dry-run"
+		}
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling prune record: %v", err)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(s.StoragePath, record.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("error writing prune record: %v", err)
+	}
+
+	return nil
+}
+
+// List returns the audit records, newest first
+func (s *PruneAuditStore) List() ([]*PruneRecord, error) {
+	matches, err := filepath.Glob(filepath.Join(s.StoragePath, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding prune records: %v", err)
+	}
+
+	records := make([]*PruneRecord, 0, len(matches))
+	for _, match := range matches {
+		data, err := ioutil.ReadFile(match)
+		if err != nil {
+			return nil, fmt.Errorf("error reading prune record: %v", err)
+		}
+		var record PruneRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("error unmarshaling prune record: %v", err)
+		}
+		records = append(records, &record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	return records, nil
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StructuredReport is the machine-readable form of a report. The model is
+// asked to return JSON matching StructuredReportSchema, so renderers can
+// work from fields instead of parsing prose.
+type StructuredReport struct {
+	ExecutiveSummary string               `json:"executive_summary"`
+	Progress         []ProgressItem       `json:"progress"`
+	CurrentStatus    string               `json:"current_status"`
+	Priorities       []Priority           `json:"priorities"`
+	Risks            []Risk               `json:"risks"`
+	Contributions    []MemberContribution `json:"contributions"`
+	DataLimitations  []string             `json:"data_limitations"`
+	// Audience is who the report was written for; it is set by
+	// ApplyAudience, not by the model, and limits the sections shown
+	Audience Audience `json:"audience,omitempty"`
+}
+
+// ProgressItem is a piece of work that moved forward during the period
+type ProgressItem struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	// Status is one of "completed", "in_progress" or "blocked"
+	Status string   `json:"status"`
+	Cards  []string `json:"cards"`
+}
+
+// Priority is an upcoming priority or deadline
+type Priority struct {
+	Title string   `json:"title"`
+	Due   string   `json:"due"`
+	Owner string   `json:"owner"`
+	Cards []string `json:"cards"`
+}
+
+// Risk is a risk, blocker or issue
+type Risk struct {
+	Description string `json:"description"`
+	// Severity is one of "low", "medium", "high" or "critical"
+	Severity   string   `json:"severity"`
+	Mitigation string   `json:"mitigation"`
+	Cards      []string `json:"cards"`
+}
+
+// MemberContribution summarizes what a board member worked on
+type MemberContribution struct {
+	Member  string   `json:"member"`
+	Summary string   `json:"summary"`
+	Cards   []string `json:"cards"`
+}
+
+// Allowed enum values
+var (
+	ProgressStatuses = []string{"completed", "in_progress", "blocked"}
+	RiskSeverities   = []string{"low", "medium", "high", "critical"}
+)
+
+// StructuredReportSchema is the JSON schema sent to the model
+const StructuredReportSchema = `{
+  "type": "object",
+  "properties": {
+    "executive_summary": {"type": "string", "description": "Two to four sentences on the overall state of the project"},
+    "progress": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "detail": {"type": "string"},
+          "status": {"type": "string", "enum": ["completed", "in_progress", "blocked"]},
+          "cards": {"type": "array", "items": {"type": "string"}}
+        },
+        "required": ["title", "detail", "status", "cards"],
+        "additionalProperties": false
+      }
+    },
+    "current_status": {"type": "string", "description": "Where the project stands now, e.g. cards per list and overall health"},
+    "priorities": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "due": {"type": "string", "description": "Due date as YYYY-MM-DD, or empty"},
+          "owner": {"type": "string"},
+          "cards": {"type": "array", "items": {"type": "string"}}
+        },
+        "required": ["title", "due", "owner", "cards"],
+        "additionalProperties": false
+      }
+    },
+    "risks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "description": {"type": "string"},
+          "severity": {"type": "string", "enum": ["low", "medium", "high", "critical"]},
+          "mitigation": {"type": "string"},
+          "cards": {"type": "array", "items": {"type": "string"}}
+        },
+        "required": ["description", "severity", "mitigation", "cards"],
+        "additionalProperties": false
+      }
+    },
+    "contributions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "member": {"type": "string"},
+          "summary": {"type": "string"},
+          "cards": {"type": "array", "items": {"type": "string"}}
+        },
+        "required": ["member", "summary", "cards"],
+        "additionalProperties": false
+      }
+    },
+    "data_limitations": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["executive_summary", "progress", "current_status", "priorities", "risks", "contributions", "data_limitations"],
+  "additionalProperties": false
+}`
+
+// Validate checks the fields the schema can't fully express. It returns
+// every problem found so they can be sent back to the model in one go.
+func (s *StructuredReport) Validate() []string {
+	var problems []string
+
+	if strings.TrimSpace(s.ExecutiveSummary) == "" {
+		problems = append(problems, "executive_summary must not be empty")
+	}
+
+	for i, item := range s.Progress {
+		if strings.TrimSpace(item.Title) == "" {
+			problems = append(problems, fmt.Sprintf("progress[%d].title must not be empty", i))
+		}
+		if !contains(ProgressStatuses, item.Status) {
+			problems = append(problems, fmt.Sprintf("progress[%d].status must be one of %s, got %q", i, strings.Join(ProgressStatuses, ", "), item.Status))
+		}
+	}
+
+	for i, priority := range s.Priorities {
+		if strings.TrimSpace(priority.Title) == "" {
+			problems = append(problems, fmt.Sprintf("priorities[%d].title must not be empty", i))
+		}
+	}
+
+	for i, risk := range s.Risks {
+		if strings.TrimSpace(risk.Description) == "" {
+			problems = append(problems, fmt.Sprintf("risks[%d].description must not be empty", i))
+		}
+		if !contains(RiskSeverities, risk.Severity) {
+			problems = append(problems, fmt.Sprintf("risks[%d].severity must be one of %s, got %q", i, strings.Join(RiskSeverities, ", "), risk.Severity))
+		}
+	}
+
+	for i, contribution := range s.Contributions {
+		if strings.TrimSpace(contribution.Member) == "" {
+			problems = append(problems, fmt.Sprintf("contributions[%d].member must not be empty", i))
+		}
+	}
+
+	return problems
+}
+
+// ReportSection is a titled section of a structured report, in a form every
+// renderer (HTML, PDF, markdown) can use directly
+type ReportSection struct {
+	// Key identifies the section, e.g. SectionRisks
+	Key        string
+	Title      string
+	Paragraphs []string
+	Items      []string
+}
+
+// Sections returns the sections the report's audience sees, in display
+// order, with the titles and labels in the given language
+func (s *StructuredReport) Sections(reportType ReportType, lang Language) []ReportSection {
+	profile := s.Audience.Profile()
+	var shown []ReportSection
+	for _, section := range s.allSections(reportType, lang) {
+		if profile.Shows(section.Key) {
+			shown = append(shown, section)
+		}
+	}
+	return shown
+}
+
+// allSections returns every section of the report in display order
+func (s *StructuredReport) allSections(reportType ReportType, lang Language) []ReportSection {
+	period, next := "This Week", "Next Week"
+	if reportType == Monthly {
+		period, next = "This Month", "Next Month"
+	}
+
+	sections := []ReportSection{
+		{Key: SectionSummary, Title: lang.T("Executive Summary"), Paragraphs: paragraphs(s.ExecutiveSummary)},
+	}
+
+	progress := ReportSection{Key: SectionProgress, Title: lang.T("Progress " + period)}
+	for _, item := range s.Progress {
+		progress.Items = append(progress.Items, joinNonEmpty(" - ", item.Title, item.Detail)+statusSuffix(item.Status, lang))
+	}
+	sections = append(sections, progress)
+
+	sections = append(sections, ReportSection{Key: SectionStatus, Title: lang.T("Current Project Status"), Paragraphs: paragraphs(s.CurrentStatus)})
+
+	priorities := ReportSection{Key: SectionPriorities, Title: lang.T("Priorities & Deadlines for " + next)}
+	for _, p := range s.Priorities {
+		var details []string
+		if p.Due != "" {
+			details = append(details, lang.T("due %s", p.Due))
+		}
+		if p.Owner != "" {
+			details = append(details, lang.T("owner: %s", p.Owner))
+		}
+		item := p.Title
+		if len(details) > 0 {
+			item += " (" + strings.Join(details, ", ") + ")"
+		}
+		priorities.Items = append(priorities.Items, item)
+	}
+	sections = append(sections, priorities)
+
+	risks := ReportSection{Key: SectionRisks, Title: lang.T("Risks, Blockers & Issues")}
+	for _, r := range s.Risks {
+		item := fmt.Sprintf("[%s] %s", strings.ToUpper(r.Severity), r.Description)
+		if r.Mitigation != "" {
+			item += " (" + lang.T("mitigation: %s", r.Mitigation) + ")"
+		}
+		risks.Items = append(risks.Items, item)
+	}
+	sections = append(sections, risks)
+
+	team := ReportSection{Key: SectionContributions, Title: lang.T("Team Focus & Contributions")}
+	for _, c := range s.Contributions {
+		team.Items = append(team.Items, joinNonEmpty(": ", c.Member, c.Summary))
+	}
+	sections = append(sections, team)
+
+	sections = append(sections, ReportSection{Key: SectionDataLimitations, Title: lang.T("Data Limitations"), Items: s.DataLimitations})
+
+	return sections
+}
+
+// Markdown renders the report as markdown, for views that display Content
+func (s *StructuredReport) Markdown(reportType ReportType, lang Language) string {
+	var sb strings.Builder
+	for _, section := range s.Sections(reportType, lang) {
+		sb.WriteString("## " + section.Title + "\n\n")
+		for _, p := range section.Paragraphs {
+			sb.WriteString(p + "\n\n")
+		}
+		for _, item := range section.Items {
+			sb.WriteString("- " + item + "\n")
+		}
+		if len(section.Items) > 0 {
+			sb.WriteString("\n")
+		}
+		if len(section.Paragraphs) == 0 && len(section.Items) == 0 {
+			sb.WriteString(lang.T("None.") + "\n\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// paragraphs splits text on blank lines
+func paragraphs(text string) []string {
+	var result []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// joinNonEmpty joins the non-empty parts with sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// statusSuffix describes a progress status for display
+func statusSuffix(status string, lang Language) string {
+	switch status {
+	case "completed":
+		return " (" + lang.T("completed") + ")"
+	case "in_progress":
+		return " (" + lang.T("in progress") + ")"
+	case "blocked":
+		return " (" + lang.T("blocked") + ")"
+	default:
+		return ""
+	}
+}
+
+// contains reports whether list contains value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
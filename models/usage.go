@@ -0,0 +1,239 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage records the LLM tokens, time and estimated cost of a report or chat
+// reply. Completions served from the cache aren't counted.
+type Usage struct {
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	LatencyMs        int64   `json:"latency_ms"`
+	Cost             float64 `json:"cost"`
+}
+
+// UsageKind is what the tokens were used for
+type UsageKind string
+
+const (
+	UsageReport   UsageKind = "report"
+	UsageChat     UsageKind = "chat"
+	UsageQuestion UsageKind = "question"
+	// UsageEmbedding is embedding report sections and questions for
+	// semantic search
+	UsageEmbedding UsageKind = "embedding"
+)
+
+// UsageRecord is one entry in a user's usage ledger
+type UsageRecord struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	BoardID   string    `json:"board_id,omitempty"`
+	BoardName string    `json:"board_name,omitempty"`
+	Kind      UsageKind `json:"kind"`
+	ReportID  string    `json:"report_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Usage
+}
+
+// UsageTotals sums usage records
+type UsageTotals struct {
+	Reports          int     `json:"reports"`
+	Chats            int     `json:"chats"`
+	Questions        int     `json:"questions"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	LatencyMs        int64   `json:"latency_ms"`
+	Cost             float64 `json:"cost"`
+}
+
+// add adds a record to the totals
+func (t *UsageTotals) add(record *UsageRecord) {
+	switch record.Kind {
+	case UsageReport:
+		t.Reports++
+	case UsageChat:
+		t.Chats++
+	case UsageQuestion:
+		t.Questions++
+	}
+	t.Requests += record.Requests
+	t.PromptTokens += record.PromptTokens
+	t.CompletionTokens += record.CompletionTokens
+	t.LatencyMs += record.LatencyMs
+	t.Cost += record.Cost
+}
+
+// BoardUsage is a board's share of a month's usage
+type BoardUsage struct {
+	BoardID   string `json:"board_id"`
+	BoardName string `json:"board_name,omitempty"`
+	UsageTotals
+}
+
+// UsageSummary is a user's usage for a month, in total and per board
+type UsageSummary struct {
+	UserID string       `json:"user_id"`
+	Month  string       `json:"month"`
+	Total  UsageTotals  `json:"total"`
+	Boards []BoardUsage `json:"boards"`
+}
+
+// UsageMonth formats the month a time falls in, e.g. "2026-10"
+func UsageMonth(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// UsageStore keeps a ledger of usage records per user and month
+type UsageStore struct {
+	StoragePath string
+	mutex       sync.Mutex
+}
+
+// NewUsageStore creates a new usage store
+func NewUsageStore(storagePath string) (*UsageStore, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &UsageStore{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// Record adds a record to the user's ledger for the month it was made in
+func (s *UsageStore) Record(record *UsageRecord) error {
+	if record.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	month := UsageMonth(record.Time)
+	records, err := s.readRecords(record.UserID, month)
+	if err != nil {
+		return err
+	}
+	records = append(records, record)
+
+	if err := os.MkdirAll(s.userPath(record.UserID), 0755); err != nil {
+		return fmt.Errorf("error creating usage directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling usage records: %v", err)
+	}
+
+	if err := WriteFileAtomic(s.path(record.UserID, month), data, 0644); err != nil {
+		return fmt.Errorf("error writing usage file: %v", err)
+	}
+
+	return nil
+}
+
+// Records returns a user's records for a month, oldest first
+func (s *UsageStore) Records(userID, month string) ([]*UsageRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readRecords(userID, month)
+}
+
+// Summary totals a user's usage for a month, per board with the most
+// expensive board first
+func (s *UsageStore) Summary(userID, month string) (*UsageSummary, error) {
+	records, err := s.Records(userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &UsageSummary{UserID: userID, Month: month, Boards: []BoardUsage{}}
+	boards := make(map[string]*BoardUsage)
+	var order []string
+	for _, record := range records {
+		summary.Total.add(record)
+
+		board, ok := boards[record.BoardID]
+		if !ok {
+			board = &BoardUsage{BoardID: record.BoardID}
+			boards[record.BoardID] = board
+			order = append(order, record.BoardID)
+		}
+		if record.BoardName != "" {
+			board.BoardName = record.BoardName
+		}
+		board.add(record)
+	}
+
+	for _, boardID := range order {
+		summary.Boards = append(summary.Boards, *boards[boardID])
+	}
+	sort.SliceStable(summary.Boards, func(i, j int) bool {
+		return summary.Boards[i].Cost > summary.Boards[j].Cost
+	})
+
+	return summary, nil
+}
+
+// Months returns the months a user has usage for, newest first
+func (s *UsageStore) Months(userID string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.userPath(userID), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding usage files: %v", err)
+	}
+
+	months := make([]string, 0, len(matches))
+	for _, match := range matches {
+		months = append(months, strings.TrimSuffix(filepath.Base(match), ".json"))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(months)))
+
+	return months, nil
+}
+
+// readRecords reads a user's records for a month
+func (s *UsageStore) readRecords(userID, month string) ([]*UsageRecord, error) {
+	data, err := ioutil.ReadFile(s.path(userID, month))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading usage file: %v", err)
+	}
+
+	var records []*UsageRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("error unmarshaling usage records: %v", err)
+	}
+
+	return records, nil
+}
+
+// userPath returns the directory holding a user's usage
+func (s *UsageStore) userPath(userID string) string {
+	return filepath.Join(s.StoragePath, filepath.Base(userID))
+}
+
+// path returns the file for a user's usage in a month
+func (s *UsageStore) path(userID, month string) string {
+	return filepath.Join(s.userPath(userID), filepath.Base(month)+".json")
+}
@@ -20,11 +20,40 @@ func SetupRoutes() *mux.Router {
 	// Report routes
 	r.HandleFunc("/reports", handlers.ReportsHandler).Methods("GET")
 	r.HandleFunc("/generate-report", handlers.GenerateReportHandler).Methods("POST")
+	r.HandleFunc("/generate-report/live", handlers.LiveReportHandler).Methods("GET")
 	r.HandleFunc("/view-report", handlers.ViewReportHandler).Methods("GET")
 	r.HandleFunc("/download-report-pdf", handlers.DownloadReportPDFHandler).Methods("GET")
+	r.HandleFunc("/reports/canonical", handlers.CanonicalRevisionHandler).Methods("POST")
+	r.HandleFunc("/reports/pin", handlers.PinReportHandler).Methods("POST")
+	r.HandleFunc("/board-settings", handlers.BoardSettingsHandler).Methods("POST")
+	r.HandleFunc("/usage", handlers.UsageHandler).Methods("GET")
+	r.HandleFunc("/search", handlers.SearchHandler).Methods("GET")
+
+	// Prompt template routes
+	r.HandleFunc("/prompts", handlers.PromptsHandler).Methods("GET")
+	r.HandleFunc("/prompts/preview", handlers.PromptPreviewHandler).Methods("POST")
+	r.HandleFunc("/prompts/save", handlers.PromptSaveHandler).Methods("POST")
+	r.HandleFunc("/prompts/select", handlers.PromptSelectHandler).Methods("POST")
 	
-	// Chat endpoint for testing the model
+	// Report API
+	r.HandleFunc("/api/report", handlers.ReportAPIHandler).Methods("GET")
+	r.HandleFunc("/api/reports/stream", handlers.ReportStreamHandler).Methods("GET")
+	r.HandleFunc("/api/usage", handlers.UsageAPIHandler).Methods("GET")
+	r.HandleFunc("/api/search", handlers.SearchAPIHandler).Methods("GET")
+	r.HandleFunc("/api/ask", handlers.AskHandler).Methods("POST")
+
+	// Chat endpoints
 	r.HandleFunc("/api/chat", handlers.ChatHandler).Methods("POST")
+	r.HandleFunc("/api/chat/stream", handlers.ChatStreamHandler).Methods("POST")
+	r.HandleFunc("/api/chat/sessions", handlers.ChatSessionsHandler).Methods("GET")
+	r.HandleFunc("/api/chat/sessions/{id}", handlers.ChatSessionHandler).Methods("GET", "DELETE")
+
+	// Board change sets proposed in chat
+	r.HandleFunc("/api/changesets", handlers.ChangeSetsHandler).Methods("GET")
+	r.HandleFunc("/api/changesets/undo", handlers.UndoChangeSetHandler).Methods("POST")
+	r.HandleFunc("/api/changesets/{id}", handlers.ChangeSetHandler).Methods("GET")
+	r.HandleFunc("/api/changesets/{id}/apply", handlers.ApplyChangeSetHandler).Methods("POST")
+	r.HandleFunc("/api/changesets/{id}/reject", handlers.RejectChangeSetHandler).Methods("POST")
 
 	// Serve static files if needed
 	// r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
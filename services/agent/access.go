@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"errors"
+	"log"
+
+	"agents_go/services/trello"
+)
+
+// ErrBoardAccess is returned when a user's Trello token can't read a board
+var ErrBoardAccess = errors.New("you don't have access to this board")
+
+// checkBoardAccess checks that a user's Trello client can read a board and
+// returns the board. The agent's own client belongs to whoever logged in
+// first, so anything done on a user's behalf must go through theirs.
+func checkBoardAccess(client *trello.Client, boardID string) (*trello.Board, error) {
+	if client == nil {
+		return nil, ErrBoardAccess
+	}
+	board, err := client.GetBoardDetails(boardID)
+	if err != nil {
+		log.Printf("Error checking access to board %s: %v", boardID, err)
+		return nil, ErrBoardAccess
+	}
+	return board, nil
+}
+
+// userBoards returns the IDs of the boards a user's Trello client can read
+func userBoards(client *trello.Client) (map[string]bool, error) {
+	if client == nil {
+		return nil, ErrBoardAccess
+	}
+	boards, err := client.GetBoards()
+	if err != nil {
+		log.Printf("Error listing the user's boards: %v", err)
+		return nil, ErrBoardAccess
+	}
+	ids := make(map[string]bool, len(boards))
+	for _, board := range boards {
+		ids[board.ID] = true
+	}
+	return ids, nil
+}
@@ -1,13 +1,24 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"agents_go/config"
 	"agents_go/models"
 	"agents_go/services/aifoundry"
+	"agents_go/services/embeddings"
+	"agents_go/services/llm"
+	"agents_go/services/prompts"
+	"agents_go/services/redact"
+	"agents_go/services/reportstore"
+	"agents_go/services/retention"
+	"agents_go/services/search"
+	"agents_go/services/tools"
 	"agents_go/services/trello"
 )
 
@@ -21,12 +32,35 @@ type ReportSchedule struct {
 type Agent struct {
 	trelloClient   *trello.Client
 	aifoundryClient *aifoundry.AIFoundryClient
-	reportStore    *models.ReportStore
+	reportStore    models.ReportStore
+	boardSettings  *models.BoardSettingsStore
+	prompts        *prompts.Store
+	chats          *models.ChatStore
+	changeSets     *models.ChangeSetStore
+	usage          *models.UsageStore
 	schedule       ReportSchedule
+	// owner is the Trello member ID the agent runs as, once looked up
+	owner          string
+	ownerMutex     sync.Mutex
 	stop           chan struct{}
 	wg             sync.WaitGroup
 	running        bool
 	mutex          sync.Mutex
+	// changeSetMutex serializes applying and undoing change sets
+	changeSetMutex sync.Mutex
+	// revisionMutex serializes numbering report revisions and choosing
+	// the canonical one
+	revisionMutex sync.Mutex
+	// searchIndex indexes the reports in reportStore, which keeps it up to
+	// date
+	searchIndex *search.Index
+	// embeddings indexes report sections for answering questions
+	embeddings *embeddings.Index
+	// pruneStop stops the background report pruning when closed, and
+	// pruneWG waits for it to finish
+	pruneStop chan struct{}
+	pruneWG   sync.WaitGroup
+	closeOnce sync.Once
 }
 
 // NewAgent creates a new agent
@@ -34,18 +68,80 @@ func NewAgent(accessToken, accessSecret string, schedule ReportSchedule) (*Agent
 	trelloClient := trello.NewClient(accessToken, accessSecret)
 	aifoundryClient := aifoundry.NewClient()
 
-	reportStore, err := models.NewReportStore("./data/reports")
+	reportStore, err := reportstore.OpenConfigured()
 	if err != nil {
 		return nil, fmt.Errorf("error creating report store: %v", err)
 	}
 
+	boardSettings, err := models.NewBoardSettingsStore("./data/boards")
+	if err != nil {
+		return nil, fmt.Errorf("error creating board settings store: %v", err)
+	}
+
+	promptStore, err := prompts.NewStore("./data/prompts")
+	if err != nil {
+		return nil, fmt.Errorf("error creating prompt store: %v", err)
+	}
+
+	chatStore, err := models.NewChatStore("./data/chats")
+	if err != nil {
+		return nil, fmt.Errorf("error creating chat store: %v", err)
+	}
+
+	changeSetStore, err := models.NewChangeSetStore("./data/changesets")
+	if err != nil {
+		return nil, fmt.Errorf("error creating change set store: %v", err)
+	}
+
+	usageStore, err := models.NewUsageStore("./data/usage")
+	if err != nil {
+		return nil, fmt.Errorf("error creating usage store: %v", err)
+	}
+
+	embeddingsIndex, err := embeddings.NewIndex("./data/embeddings")
+	if err != nil {
+		return nil, fmt.Errorf("error creating embeddings index: %v", err)
+	}
+
+	pruneAudit, err := models.NewPruneAuditStore("./data/retention")
+	if err != nil {
+		return nil, fmt.Errorf("error creating prune audit store: %v", err)
+	}
+
+	agent := NewAgentWithClients(trelloClient, aifoundryClient, reportStore, boardSettings, promptStore, chatStore, changeSetStore, usageStore, embeddingsIndex, schedule)
+
+	// Prune old reports in the background until the agent is closed,
+	// sharing the agent's report store so its search index stays up to date
+	agent.startPruning(retention.NewPruner(agent.reportStore, boardSettings, pruneAudit))
+
+	return agent, nil
+}
+
+// NewAgentWithClients creates an agent from already constructed clients and
+// stores, e.g. a fake Trello server and LLM provider
+func NewAgentWithClients(trelloClient *trello.Client, aifoundryClient *aifoundry.AIFoundryClient, reportStore models.ReportStore, boardSettings *models.BoardSettingsStore, promptStore *prompts.Store, chatStore *models.ChatStore, changeSetStore *models.ChangeSetStore, usageStore *models.UsageStore, embeddingsIndex *embeddings.Index, schedule ReportSchedule) *Agent {
+	// Index the stored reports for search, and keep the index up to date
+	// as reports are saved and deleted
+	searchIndex := search.NewIndex()
+	if err := searchIndex.Build(reportStore); err != nil {
+		log.Printf("Error indexing reports for search: %v", err)
+	}
+
 	return &Agent{
-		trelloClient:   trelloClient,
+		trelloClient:    trelloClient,
 		aifoundryClient: aifoundryClient,
-		reportStore:    reportStore,
-		schedule:       schedule,
-		stop:           make(chan struct{}),
-	}, nil
+		reportStore:     search.Indexed(reportStore, searchIndex),
+		searchIndex:     searchIndex,
+		boardSettings:   boardSettings,
+		prompts:         promptStore,
+		chats:           chatStore,
+		changeSets:      changeSetStore,
+		usage:           usageStore,
+		embeddings:      embeddingsIndex,
+		schedule:        schedule,
+		stop:            make(chan struct{}),
+		pruneStop:       make(chan struct{}),
+	}
 }
 
 // Start starts the agent
@@ -84,6 +180,27 @@ func (a *Agent) Stop() error {
 	return nil
 }
 
+// startPruning runs the pruner in the background until the agent is closed
+func (a *Agent) startPruning(pruner *retention.Pruner) {
+	a.pruneWG.Add(1)
+	go func() {
+		defer a.pruneWG.Done()
+		pruner.Run(config.RetentionInterval, config.RetentionDryRun, a.pruneStop)
+	}()
+}
+
+// Close stops the agent if it's running and the background report pruning,
+// waiting for both to finish. Closing again does nothing.
+func (a *Agent) Close() {
+	a.closeOnce.Do(func() {
+		// Stop only fails if the agent isn't running
+		a.Stop()
+
+		close(a.pruneStop)
+		a.pruneWG.Wait()
+	})
+}
+
 // run is the main loop of the agent
 func (a *Agent) run() {
 	defer a.wg.Done()
@@ -166,6 +283,17 @@ func (a *Agent) generateMonthlyReports(now time.Time) {
 func (a *Agent) generateReport(boardID, boardName string, reportType models.ReportType, startDate, endDate time.Time) {
 	log.Printf("Generating %s report for board %s (%s)", reportType, boardName, boardID)
 
+	// Scheduled reports count against the budget of the user the agent runs as
+	userID, err := a.ownerID()
+	if err != nil {
+		log.Printf("Error getting report owner: %v", err)
+		return
+	}
+	if _, err := a.checkBudget(userID); err != nil {
+		log.Printf("Skipping %s report for board %s: %v", reportType, boardName, err)
+		return
+	}
+
 	// Get board data
 	boardData, err := a.trelloClient.GetBoardData(boardID, startDate)
 	if err != nil {
@@ -173,27 +301,44 @@ func (a *Agent) generateReport(boardID, boardName string, reportType models.Repo
 		return
 	}
 
-	// Generate report using AI Foundry
-	reportContent, err := a.aifoundryClient.GenerateReport(boardData, string(reportType))
+	// Pick the LLM provider configured for this board
+	aiClient, err := a.aiClientForBoard(boardID)
 	if err != nil {
-		log.Printf("Error generating report: %v", err)
+		log.Printf("Error selecting LLM provider: %v", err)
 		return
 	}
 
-	// Create report
-	report := &models.Report{
-		ID:          fmt.Sprintf("%s_%s_%s", boardID, reportType, endDate.Format("2006-01-02")),
-		BoardID:     boardID,
-		BoardName:   boardName,
-		Type:        reportType,
-		Content:     reportContent,
-		GeneratedAt: time.Now(),
-		StartDate:   startDate,
-		EndDate:     endDate,
+	// Render the prompt template selected for this board
+	systemPrompt, tmpl, err := a.reportPrompt(boardID, reportType, boardData, startDate, endDate)
+	if err != nil {
+		log.Printf("Error rendering prompt template: %v", err)
+		return
 	}
 
-	// Save report
-	if err := a.reportStore.SaveReport(report); err != nil {
+	// Write a report for each of the board's audiences
+	opts := a.reportOptions(boardID, systemPrompt)
+	opts.StartDate, opts.EndDate = startDate, endDate
+	run := &reportRun{
+		boardID:     boardID,
+		boardName:   boardName,
+		reportType:  reportType,
+		startDate:   startDate,
+		endDate:     endDate,
+		generatedAt: time.Now(),
+		userID:      userID,
+		boardData:   boardData,
+		aiClient:    aiClient,
+		tmpl:        tmpl,
+		opts:        opts,
+	}
+	reports, err := a.generateVariants(context.Background(), run, a.reportAudiences(boardID, nil))
+	if err != nil {
+		log.Printf("Error generating report: %v", err)
+		return
+	}
+
+	// Save reports
+	if err := a.saveReports(run, reports); err != nil {
 		log.Printf("Error saving report: %v", err)
 		return
 	}
@@ -201,8 +346,56 @@ func (a *Agent) generateReport(boardID, boardName string, reportType models.Repo
 	log.Printf("Successfully generated %s report for board %s", reportType, boardName)
 }
 
+// GenerateOptions customizes on-demand report generation
+type GenerateOptions struct {
+	// UserID is the user the report's usage is charged to
+	UserID string
+	// ForceRegenerate ignores cached completions
+	ForceRegenerate bool
+	// Progress receives status updates and previews of the report as it is
+	// written
+	Progress *aifoundry.Progress
+	// Language overrides the board's report language; empty uses the
+	// board's setting
+	Language models.Language
+	// Audiences are the audiences to write reports for; empty uses the
+	// board's audiences
+	Audiences []models.Audience
+}
+
 // GenerateReportOnDemand generates a report on demand
 func (a *Agent) GenerateReportOnDemand(boardID string, reportType models.ReportType) (*models.Report, error) {
+	return a.GenerateReportStream(context.Background(), boardID, reportType, GenerateOptions{})
+}
+
+// GenerateReportStream generates a report on demand, sending progress and
+// previews of the report as it is written. The report is saved only if
+// generation completes; canceling ctx stops it. If several audiences are
+// requested, the first audience's report is returned and links to the
+// others.
+func (a *Agent) GenerateReportStream(ctx context.Context, boardID string, reportType models.ReportType, genOpts GenerateOptions) (*models.Report, error) {
+	reports, err := a.GenerateReportVariants(ctx, boardID, reportType, genOpts)
+	if err != nil {
+		return nil, err
+	}
+	return reports[0], nil
+}
+
+// GenerateReportVariants generates a report for each requested audience, or
+// for the board's audiences, from one snapshot of the board. The reports
+// are saved only if every one of them is generated.
+func (a *Agent) GenerateReportVariants(ctx context.Context, boardID string, reportType models.ReportType, genOpts GenerateOptions) ([]*models.Report, error) {
+	// Refuse to generate reports for users over their hard budget
+	if genOpts.UserID == "" {
+		var err error
+		if genOpts.UserID, err = a.ownerID(); err != nil {
+			return nil, fmt.Errorf("error getting report owner: %v", err)
+		}
+	}
+	if _, err := a.checkBudget(genOpts.UserID); err != nil {
+		return nil, err
+	}
+
 	// Get board details
 	board, err := a.trelloClient.GetBoardDetails(boardID)
 	if err != nil {
@@ -225,43 +418,462 @@ func (a *Agent) GenerateReportOnDemand(boardID string, reportType models.ReportT
 		return nil, fmt.Errorf("error getting board data: %v", err)
 	}
 
-	// Generate report using AI Foundry
-	reportContent, err := a.aifoundryClient.GenerateReport(boardData, string(reportType))
+	// Pick the LLM provider configured for this board
+	aiClient, err := a.aiClientForBoard(boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting LLM provider: %v", err)
+	}
+
+	// Render the prompt template selected for this board
+	systemPrompt, tmpl, err := a.reportPrompt(boardID, reportType, boardData, startDate, now)
 	if err != nil {
-		return nil, fmt.Errorf("error generating report: %v", err)
+		return nil, fmt.Errorf("error rendering prompt template: %v", err)
 	}
 
-	// Create report
-	report := &models.Report{
-		ID:          fmt.Sprintf("%s_%s_%s", boardID, reportType, now.Format("2006-01-02")),
-		BoardID:     boardID,
-		BoardName:   board.Name,
-		Type:        reportType,
-		Content:     reportContent,
-		GeneratedAt: now,
-		StartDate:   startDate,
-		EndDate:     now,
+	// Write a report for each audience from the same board data
+	opts := a.reportOptions(boardID, systemPrompt)
+	opts.Progress = genOpts.Progress
+	opts.ForceRegenerate = genOpts.ForceRegenerate
+	opts.StartDate, opts.EndDate = startDate, now
+	if genOpts.Language != "" {
+		opts.Language = genOpts.Language
+	}
+	run := &reportRun{
+		boardID:     boardID,
+		boardName:   board.Name,
+		reportType:  reportType,
+		startDate:   startDate,
+		endDate:     now,
+		generatedAt: now,
+		userID:      genOpts.UserID,
+		boardData:   boardData,
+		aiClient:    aiClient,
+		tmpl:        tmpl,
+		opts:        opts,
+	}
+	reports, err := a.generateVariants(ctx, run, a.reportAudiences(boardID, genOpts.Audiences))
+	if err != nil {
+		return nil, err
 	}
 
-	// Save report
-	if err := a.reportStore.SaveReport(report); err != nil {
+	// Save reports
+	if err := a.saveReports(run, reports); err != nil {
 		return nil, fmt.Errorf("error saving report: %v", err)
 	}
 
-	return report, nil
+	return reports, nil
+}
+
+// fallbackReasonLength limits the model error quoted in a report generated
+// without AI
+const fallbackReasonLength = 300
+
+// generateReportResult generates a report with the LLM. If every model
+// fails, the report is rendered from board statistics instead, so there is
+// still a report, marked as generated without AI.
+func generateReportResult(ctx context.Context, aiClient *aifoundry.AIFoundryClient, boardData map[string]interface{}, reportType models.ReportType, opts aifoundry.ReportOptions) (*aifoundry.ReportResult, error) {
+	result, err := aiClient.GenerateReport(ctx, boardData, string(reportType), opts)
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+
+	log.Printf("Error generating report, rendering it from board statistics instead: %v", err)
+	if opts.Progress != nil && opts.Progress.Status != nil {
+		opts.Progress.Status("No model is available, generating the report without AI")
+	}
+
+	reason := err.Error()
+	if len(reason) > fallbackReasonLength {
+		reason = reason[:fallbackReasonLength] + "…"
+	}
+	fallback, fallbackErr := aifoundry.StatisticsReport(boardData, string(reportType), time.Now(), reason, opts.Language)
+	if fallbackErr != nil {
+		log.Printf("Error rendering report from board statistics: %v", fallbackErr)
+		return nil, err
+	}
+	fallback.ForAudience(opts.Audience, boardData, string(reportType), opts.Language)
+
+	// The failed attempts, retries and repairs still used tokens
+	if result != nil {
+		fallback.Usage = result.Usage
+	}
+
+	return fallback, nil
 }
 
 // GetReportsByBoard gets all reports for a specific board
 func (a *Agent) GetReportsByBoard(boardID string) ([]*models.Report, error) {
-	return a.reportStore.GetReportsByBoard(boardID)
+	return a.reportStore.ListReports(models.ReportFilter{BoardID: boardID})
 }
 
 // GetReportsByType gets all reports of a specific type
 func (a *Agent) GetReportsByType(reportType models.ReportType) ([]*models.Report, error) {
-	return a.reportStore.GetReportsByType(reportType)
+	return a.reportStore.ListReports(models.ReportFilter{Type: reportType})
 }
 
 // GetReport gets a specific report by ID
 func (a *Agent) GetReport(id string) (*models.Report, error) {
 	return a.reportStore.GetReport(id)
 }
+
+// GetReportPDF returns a report's stored PDF, or nil if the report store
+// doesn't keep PDFs or hasn't got this one
+func (a *Agent) GetReportPDF(id string) []byte {
+	artifacts, ok := a.reportStore.(models.ReportArtifacts)
+	if !ok {
+		return nil
+	}
+	pdf, err := artifacts.GetPDF(id)
+	if err != nil {
+		if err != models.ErrReportNotFound {
+			log.Printf("Error reading PDF of report %s: %v", id, err)
+		}
+		return nil
+	}
+	return pdf
+}
+
+// SaveReportPDF stores a report's rendered PDF if the report store keeps
+// PDFs
+func (a *Agent) SaveReportPDF(report *models.Report, pdf []byte) {
+	if artifacts, ok := a.reportStore.(models.ReportArtifacts); ok {
+		if err := artifacts.SavePDF(report, pdf); err != nil {
+			log.Printf("Error saving PDF of report %s: %v", report.ID, err)
+		}
+	}
+}
+
+// aiClientForBoard returns the AI client configured for a board
+func (a *Agent) aiClientForBoard(boardID string) (*aifoundry.AIFoundryClient, error) {
+	settings, err := a.boardSettings.GetSettings(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return a.aifoundryClient.ForBoard(settings)
+}
+
+// GetBoardDetails gets a board's name and description from Trello
+func (a *Agent) GetBoardDetails(boardID string) (*trello.Board, error) {
+	return a.trelloClient.GetBoardDetails(boardID)
+}
+
+// GetBoardSettings gets the settings for a board
+func (a *Agent) GetBoardSettings(boardID string) (*models.BoardSettings, error) {
+	return a.boardSettings.GetSettings(boardID)
+}
+
+// SaveBoardSettings validates and saves the settings for a board, if the
+// user's Trello client can read it
+func (a *Agent) SaveBoardSettings(client *trello.Client, settings *models.BoardSettings) error {
+	if _, err := checkBoardAccess(client, settings.BoardID); err != nil {
+		return err
+	}
+
+	// Make sure the provider and model can be resolved before saving
+	if _, err := a.aifoundryClient.ForBoard(settings); err != nil {
+		return err
+	}
+
+	// Make sure the redaction patterns compile
+	if _, err := redact.New(settings.RedactionRules(), nil); err != nil {
+		return err
+	}
+	return a.boardSettings.SaveSettings(settings)
+}
+
+// LLMProviders returns the names of the configured LLM providers
+func (a *Agent) LLMProviders() []string {
+	return a.aifoundryClient.Providers().Names()
+}
+
+// reportOptions returns the report generation options for a board
+func (a *Agent) reportOptions(boardID, systemPrompt string) aifoundry.ReportOptions {
+	opts := aifoundry.ReportOptions{SystemPrompt: systemPrompt}
+
+	settings, err := a.boardSettings.GetSettings(boardID)
+	if err != nil {
+		log.Printf("Error getting board settings: %v", err)
+		return opts
+	}
+	if settings.UseTools {
+		opts.Tools = tools.NewTrelloTools(a.trelloClient, boardID)
+	}
+	if settings.CorrectClaims {
+		opts.CorrectionRounds = config.FactCheckRounds
+	}
+	opts.Language = settings.Language
+
+	return opts
+}
+
+// promptSelection returns the prompt template selected for a board and report
+// type, or the default template for the report type
+func (a *Agent) promptSelection(boardID string, reportType models.ReportType) (models.PromptSelection, error) {
+	settings, err := a.boardSettings.GetSettings(boardID)
+	if err != nil {
+		return models.PromptSelection{}, err
+	}
+
+	if selection, ok := settings.Prompts[reportType]; ok && selection.Template != "" {
+		return selection, nil
+	}
+	return models.PromptSelection{Template: prompts.DefaultTemplateName(string(reportType))}, nil
+}
+
+// reportPrompt renders the prompt template selected for a board with the
+// board data and report period
+func (a *Agent) reportPrompt(boardID string, reportType models.ReportType, boardData map[string]interface{}, startDate, endDate time.Time) (string, *prompts.Template, error) {
+	selection, err := a.promptSelection(boardID, reportType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmpl, err := a.prompts.Get(selection.Template, selection.Version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	text, err := prompts.Render(tmpl.Text, prompts.NewData(boardData, string(reportType), startDate, endDate))
+	if err != nil {
+		return "", nil, fmt.Errorf("error rendering %s: %v", tmpl.Ref(), err)
+	}
+
+	return text, tmpl, nil
+}
+
+// PromptTemplates returns the names of all prompt templates
+func (a *Agent) PromptTemplates() ([]string, error) {
+	return a.prompts.Names()
+}
+
+// PromptHistory returns every version of a prompt template, newest first
+func (a *Agent) PromptHistory(name string) ([]*prompts.Template, error) {
+	return a.prompts.History(name)
+}
+
+// GetPromptTemplate returns a version of a prompt template; version 0 is the latest
+func (a *Agent) GetPromptTemplate(name string, version int) (*prompts.Template, error) {
+	return a.prompts.Get(name, version)
+}
+
+// SavePromptTemplate saves a new version of a prompt template
+func (a *Agent) SavePromptTemplate(name, text, note string) (*prompts.Template, error) {
+	return a.prompts.Save(name, text, note)
+}
+
+// SelectedPrompt returns the prompt template selected for a board and report type
+func (a *Agent) SelectedPrompt(boardID string, reportType models.ReportType) (models.PromptSelection, error) {
+	return a.promptSelection(boardID, reportType)
+}
+
+// SelectPrompt selects the prompt template used for a board and report type.
+// Selecting version 0 follows the latest version.
+func (a *Agent) SelectPrompt(boardID string, reportType models.ReportType, selection models.PromptSelection) error {
+	// Make sure the template version exists before selecting it
+	if _, err := a.prompts.Get(selection.Template, selection.Version); err != nil {
+		return err
+	}
+
+	settings, err := a.boardSettings.GetSettings(boardID)
+	if err != nil {
+		return err
+	}
+
+	if settings.Prompts == nil {
+		settings.Prompts = make(map[models.ReportType]models.PromptSelection)
+	}
+	settings.Prompts[reportType] = selection
+
+	return a.boardSettings.SaveSettings(settings)
+}
+
+// PreviewPrompt renders prompt template text with the board's current data,
+// as it would be sent for a report of the given type
+func (a *Agent) PreviewPrompt(boardID string, reportType models.ReportType, text string) (string, error) {
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -7)
+	if reportType == models.Monthly {
+		startDate = endDate.AddDate(0, -1, 0)
+	}
+
+	boardData, err := a.trelloClient.GetBoardData(boardID, startDate)
+	if err != nil {
+		return "", fmt.Errorf("error getting board data: %v", err)
+	}
+
+	return prompts.Render(text, prompts.NewData(boardData, string(reportType), startDate, endDate))
+}
+
+// chatActivityDays is how far back board activity is included in chat context
+const chatActivityDays = 14
+
+// chatReportCount is how many recent reports are included in chat context
+const chatReportCount = 3
+
+// Chat adds a user message to a chat session and answers it. An empty
+// session ID starts a new session, about the given board if one is set.
+// The board is read with the user's own Trello client, which must have
+// access to it.
+func (a *Agent) Chat(userID string, client *trello.Client, sessionID, boardID, message string) (*models.ChatSession, error) {
+	return a.ChatStream(context.Background(), userID, client, sessionID, boardID, message, nil)
+}
+
+// ChatStream answers a chat message like Chat, streaming the reply to
+// progress as it is generated. The session is saved only once the reply is
+// complete; canceling ctx stops the reply and leaves the session unchanged.
+func (a *Agent) ChatStream(ctx context.Context, userID string, client *trello.Client, sessionID, boardID, message string, progress *aifoundry.Progress) (*models.ChatSession, error) {
+	// Refuse to answer users over their hard budget
+	if _, err := a.checkBudget(userID); err != nil {
+		return nil, err
+	}
+
+	var session *models.ChatSession
+	var err error
+
+	if sessionID != "" {
+		session, err = a.chats.GetSession(userID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var boardName string
+		if boardID != "" {
+			board, err := checkBoardAccess(client, boardID)
+			if err != nil {
+				return nil, err
+			}
+			boardName = board.Name
+		}
+		session, err = models.NewChatSession(userID, boardID, boardName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Ground the conversation in the board's current state
+	aiClient := a.aifoundryClient
+	var proposal *models.ChangeSet
+	chatContext := &aifoundry.ChatContext{}
+	if session.BoardID != "" {
+		// Check the user can still read the board of an existing session
+		if sessionID != "" {
+			if _, err := checkBoardAccess(client, session.BoardID); err != nil {
+				return nil, err
+			}
+		}
+
+		chatContext.BoardData, err = client.GetBoardData(session.BoardID, time.Now().AddDate(0, 0, -chatActivityDays))
+		if err != nil {
+			return nil, fmt.Errorf("error getting board data: %v", err)
+		}
+
+		chatContext.Reports, err = a.recentReports(session.BoardID, chatReportCount)
+		if err != nil {
+			log.Printf("Error getting recent reports for chat: %v", err)
+		}
+
+		// Pick the LLM provider configured for this board
+		aiClient, err = a.aiClientForBoard(session.BoardID)
+		if err != nil {
+			return nil, fmt.Errorf("error selecting LLM provider: %v", err)
+		}
+
+		// Redact personal data before it is sent to the model
+		chatContext.Redactor, err = a.redactor(session.BoardID, chatContext.BoardData)
+		if err != nil {
+			return nil, err
+		}
+
+		// The read-only tools are opt-in per board; proposing changes is
+		// always available since nothing is applied without approval
+		chatContext.Tools = tools.NewRegistry()
+		if settings, err := a.boardSettings.GetSettings(session.BoardID); err == nil && settings.UseTools {
+			chatContext.Tools = tools.NewTrelloTools(client, session.BoardID)
+		}
+		chatContext.Tools.Register(tools.NewProposeChangesTool(client, session.BoardID, func(summary string, changes []models.Change) {
+			// Merge proposals if the model calls the tool more than once
+			if proposal == nil {
+				proposal = &models.ChangeSet{}
+			}
+			proposal.Summary = strings.TrimSpace(proposal.Summary + " " + summary)
+			proposal.Changes = append(proposal.Changes, changes...)
+		}))
+	}
+
+	session.AddMessage(string(llm.RoleUser), message)
+
+	result, err := aiClient.Chat(ctx, chatContext, session.Messages, progress)
+	if chatContext.Redactor != nil {
+		logRedactions(session.BoardID, "chat", chatContext.Redactor)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error generating response: %v", err)
+	}
+
+	session.AddMessage(string(llm.RoleAssistant), result.Content)
+	reply := &session.Messages[len(session.Messages)-1]
+	reply.ToolCalls = result.ToolCalls
+	usage := usageFor(result.Provider, result.Model, result.Usage)
+	reply.Usage = &usage
+
+	// Save any proposed board changes for the user to review
+	if proposal != nil {
+		changeSet, err := models.NewChangeSet(userID, session.BoardID, proposal.Summary, proposal.Changes)
+		if err != nil {
+			return nil, err
+		}
+		changeSet.SessionID = session.ID
+		if err := a.changeSets.SaveChangeSet(changeSet); err != nil {
+			return nil, fmt.Errorf("error saving change set: %v", err)
+		}
+		reply.ChangeSetID = changeSet.ID
+	}
+
+	// Save the session only once the reply has been generated
+	if err := a.chats.SaveSession(session); err != nil {
+		return nil, fmt.Errorf("error saving chat session: %v", err)
+	}
+	a.recordUsage(&models.UsageRecord{
+		UserID:    userID,
+		BoardID:   session.BoardID,
+		BoardName: session.BoardName,
+		Kind:      models.UsageChat,
+		SessionID: session.ID,
+		Usage:     usage,
+	})
+
+	return session, nil
+}
+
+// recentReports returns the canonical revisions of a board's most recent
+// reports, newest first
+func (a *Agent) recentReports(boardID string, limit int) ([]*models.Report, error) {
+	reports, err := a.reportStore.ListReports(models.ReportFilter{BoardID: boardID})
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []*models.Report
+	for _, group := range models.GroupRevisions(reports) {
+		if len(recent) == limit {
+			break
+		}
+		recent = append(recent, group.Canonical)
+	}
+	return recent, nil
+}
+
+// ChatSessions lists a user's chat sessions, optionally for one board
+func (a *Agent) ChatSessions(userID, boardID string) ([]*models.ChatSession, error) {
+	return a.chats.ListSessions(userID, boardID)
+}
+
+// GetChatSession gets a user's chat session with its messages
+func (a *Agent) GetChatSession(userID, sessionID string) (*models.ChatSession, error) {
+	return a.chats.GetSession(userID, sessionID)
+}
+
+// DeleteChatSession deletes a user's chat session
+func (a *Agent) DeleteChatSession(userID, sessionID string) error {
+	return a.chats.DeleteSession(userID, sessionID)
+}
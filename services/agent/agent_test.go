@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/llm"
+	"agents_go/services/retention"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+func TestGenerateReportResultFallbackUsage(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+	boardData, err := trello.NewClient(fixture.AccessToken, fixture.AccessSecret).GetBoardData("board-1", time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Error getting board data: %v", err)
+	}
+
+	// Price only the backup model, which answers every request
+	defer func(fallbacks []string) { config.LLMFallbacks = fallbacks }(config.LLMFallbacks)
+	config.LLMFallbacks = []string{"backup:backup-model"}
+	defer delete(config.LLMPrices, "backup-model")
+	config.LLMPrices["backup-model"] = config.ModelPrice{Prompt: 2, Completion: 8}
+
+	newFake := func(name string) *llm.FakeProvider {
+		provider, err := llm.NewProvider(config.LLMProviderConfig{Name: name, Type: "fake", Model: name + "-model", ContextWindow: 32768})
+		if err != nil {
+			t.Fatalf("Error creating fake LLM: %v", err)
+		}
+		return provider.(*llm.FakeProvider)
+	}
+
+	// The primary model always fails and the backup never writes a valid
+	// report, so every repair fails too
+	primary, backup := newFake("primary"), newFake("backup")
+	primary.SetHandler(func(req llm.Request) (*llm.Response, error) {
+		return nil, &llm.APIError{StatusCode: http.StatusUnauthorized, Body: "invalid key"}
+	})
+	backup.SetHandler(func(req llm.Request) (*llm.Response, error) {
+		return &llm.Response{
+			Content: "not a report",
+			Usage:   llm.Usage{PromptTokens: 1000, CompletionTokens: 100},
+		}, nil
+	})
+	registry := llm.NewRegistry("primary")
+	registry.Register(primary)
+	registry.Register(backup)
+	client, err := aifoundry.NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("Error creating LLM client: %v", err)
+	}
+	client = client.WithRetryPolicy(llm.RetryPolicy{MaxAttempts: 1})
+
+	result, err := generateReportResult(context.Background(), client, boardData, models.Weekly, aifoundry.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Error generating report: %v", err)
+	}
+	if !result.WithoutAI || result.Strategy != aifoundry.StrategyStatistics {
+		t.Fatalf("report written with strategy %q, want one rendered from board statistics", result.Strategy)
+	}
+
+	// The first attempt and every repair reached the backup model
+	attempts := len(backup.Requests())
+	if attempts < 2 {
+		t.Fatalf("backup model got %d requests, want the first attempt and its repairs", attempts)
+	}
+	usage := usageFor(result.Provider, result.Model, result.Usage)
+	if usage.Requests != attempts {
+		t.Errorf("recorded %d requests, want %d", usage.Requests, attempts)
+	}
+	if usage.PromptTokens != attempts*1000 || usage.CompletionTokens != attempts*100 {
+		t.Errorf("recorded %d prompt and %d completion tokens, want %d and %d", usage.PromptTokens, usage.CompletionTokens, attempts*1000, attempts*100)
+	}
+	wantCost := float64(attempts) * (1000*2 + 100*8) / 1e6
+	if math.Abs(usage.Cost-wantCost) > 1e-9 {
+		t.Errorf("recorded a cost of %v, want %v", usage.Cost, wantCost)
+	}
+}
+
+func TestSaveBoardSettingsBoardAccess(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+
+	provider, err := llm.NewProvider(config.LLMProviderConfig{Name: "fake", Type: "fake", Model: "fake-model", ContextWindow: 32768})
+	if err != nil {
+		t.Fatalf("Error creating fake LLM: %v", err)
+	}
+	registry := llm.NewRegistry("fake")
+	registry.Register(provider)
+	aiClient, err := aifoundry.NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("Error creating LLM client: %v", err)
+	}
+	store, err := models.NewBoardSettingsStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating board settings store: %v", err)
+	}
+	a := &Agent{aifoundryClient: aiClient, boardSettings: store}
+	client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+	tests := []struct {
+		name    string
+		client  *trello.Client
+		boardID string
+		wantErr error
+	}{
+		{name: "no client", boardID: "board-1", wantErr: ErrBoardAccess},
+		{name: "unreadable board", client: client, boardID: "board-2", wantErr: ErrBoardAccess},
+		{name: "readable board", client: client, boardID: "board-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.BoardSettings{BoardID: tt.boardID, UseTools: true}
+			if err := a.SaveBoardSettings(tt.client, settings); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			// Refused settings aren't saved
+			saved, err := store.GetSettings(tt.boardID)
+			if err != nil {
+				t.Fatalf("Error getting board settings: %v", err)
+			}
+			if saved.UseTools != (tt.wantErr == nil) {
+				t.Errorf("settings saved: %v, want %v", saved.UseTools, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestCloseStopsPruning(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// Prune often, so closing interrupts the wait between runs
+	defer func(interval time.Duration) { config.RetentionInterval = interval }(config.RetentionInterval)
+	config.RetentionInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	reportStore, err := models.NewFileReportStore(dir + "/reports")
+	if err != nil {
+		t.Fatalf("Error creating report store: %v", err)
+	}
+	boardSettings, err := models.NewBoardSettingsStore(dir + "/settings")
+	if err != nil {
+		t.Fatalf("Error creating board settings store: %v", err)
+	}
+	audit, err := models.NewPruneAuditStore(dir + "/retention")
+	if err != nil {
+		t.Fatalf("Error creating prune audit store: %v", err)
+	}
+	a := NewAgentWithClients(nil, nil, reportStore, boardSettings, nil, nil, nil, nil, nil, ReportSchedule{})
+	a.startPruning(retention.NewPruner(a.reportStore, boardSettings, audit))
+	time.Sleep(30 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		a.Close()
+		a.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't stop the pruning")
+	}
+}
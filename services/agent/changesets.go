@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/trello"
+)
+
+// ChangeSets lists a user's change sets, optionally for one board
+func (a *Agent) ChangeSets(userID, boardID string) ([]*models.ChangeSet, error) {
+	return a.changeSets.ListChangeSets(userID, boardID)
+}
+
+// GetChangeSet gets one of a user's change sets
+func (a *Agent) GetChangeSet(userID, id string) (*models.ChangeSet, error) {
+	return a.changeSets.GetChangeSet(userID, id)
+}
+
+// ApplyChangeSet applies a proposed change set to the board. selected holds
+// the indexes of the changes the user approved; nil approves all of them.
+// Each change is applied on its own, so some may fail while others succeed;
+// the result of every change is recorded in the returned change set. The
+// changes are made with the approving user's Trello client, which must be
+// able to read the change set's board.
+func (a *Agent) ApplyChangeSet(userID string, client *trello.Client, id string, selected []int) (*models.ChangeSet, error) {
+	a.changeSetMutex.Lock()
+	defer a.changeSetMutex.Unlock()
+
+	changeSet, err := a.changeSets.GetChangeSet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if changeSet.Status != models.ChangeSetProposed {
+		return nil, fmt.Errorf("change set is already %s", changeSet.Status)
+	}
+	if _, err := checkBoardAccess(client, changeSet.BoardID); err != nil {
+		return nil, err
+	}
+
+	// Skip the changes the user didn't approve
+	approved := make([]bool, len(changeSet.Changes))
+	for _, i := range selected {
+		if i < 0 || i >= len(changeSet.Changes) {
+			return nil, fmt.Errorf("invalid change index %d", i)
+		}
+		approved[i] = true
+	}
+	count := 0
+	for i := range changeSet.Changes {
+		if selected == nil || approved[i] {
+			count++
+		} else {
+			changeSet.Changes[i].Status = models.ChangeSkipped
+		}
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no changes selected")
+	}
+
+	// Get list names to record where moved cards came from
+	listNames := make(map[string]string)
+	if lists, err := client.GetLists(changeSet.BoardID); err == nil {
+		for _, list := range lists {
+			listNames[list.ID] = list.Name
+		}
+	}
+
+	// Apply the approved changes in order
+	applied := 0
+	for i := range changeSet.Changes {
+		change := &changeSet.Changes[i]
+		if change.Status == models.ChangeSkipped {
+			continue
+		}
+		if err := applyChange(client, changeSet.BoardID, change, listNames); err != nil {
+			change.Status = models.ChangeFailed
+			change.Error = err.Error()
+			continue
+		}
+		change.Status = models.ChangeApplied
+		applied++
+	}
+
+	switch applied {
+	case count:
+		changeSet.Status = models.ChangeSetApplied
+	case 0:
+		changeSet.Status = models.ChangeSetFailed
+	default:
+		changeSet.Status = models.ChangeSetPartial
+	}
+	if applied > 0 {
+		now := time.Now()
+		changeSet.AppliedAt = &now
+	}
+
+	if err := a.changeSets.SaveChangeSet(changeSet); err != nil {
+		return nil, fmt.Errorf("error saving change set: %v", err)
+	}
+
+	return changeSet, nil
+}
+
+// applyChange applies one change with the user's client, recording the
+// card's previous state so the change can be undone
+func applyChange(client *trello.Client, boardID string, change *models.Change, listNames map[string]string) error {
+	// Check the card's current state
+	card, err := client.GetCard(change.CardID)
+	if err != nil {
+		return err
+	}
+	if card.BoardID != boardID {
+		return fmt.Errorf("card is no longer on this board")
+	}
+
+	switch change.Type {
+	case models.ChangeMoveCard:
+		change.PreviousListID = card.ListID
+		change.PreviousListName = listNames[card.ListID]
+		if card.ListID == change.ListID {
+			change.NoOp = true
+			return nil
+		}
+		_, err = client.UpdateCard(card.ID, map[string]string{"idList": change.ListID})
+	case models.ChangeAssignMember:
+		if hasMember(card, change.MemberID) {
+			change.NoOp = true
+			return nil
+		}
+		err = client.AddCardMember(card.ID, change.MemberID)
+	case models.ChangeUnassignMember:
+		if !hasMember(card, change.MemberID) {
+			change.NoOp = true
+			return nil
+		}
+		err = client.RemoveCardMember(card.ID, change.MemberID)
+	case models.ChangeSetDue:
+		if card.Due != nil {
+			change.PreviousDue = card.Due.UTC().Format(time.RFC3339)
+		}
+		_, err = client.UpdateCard(card.ID, map[string]string{"due": dueValue(change.Due)})
+	default:
+		return fmt.Errorf("unknown change type %q", change.Type)
+	}
+
+	return err
+}
+
+// RejectChangeSet discards a proposed change set without applying it
+func (a *Agent) RejectChangeSet(userID, id string) (*models.ChangeSet, error) {
+	a.changeSetMutex.Lock()
+	defer a.changeSetMutex.Unlock()
+
+	changeSet, err := a.changeSets.GetChangeSet(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if changeSet.Status != models.ChangeSetProposed {
+		return nil, fmt.Errorf("change set is already %s", changeSet.Status)
+	}
+
+	changeSet.Status = models.ChangeSetRejected
+	for i := range changeSet.Changes {
+		changeSet.Changes[i].Status = models.ChangeSkipped
+	}
+
+	if err := a.changeSets.SaveChangeSet(changeSet); err != nil {
+		return nil, fmt.Errorf("error saving change set: %v", err)
+	}
+
+	return changeSet, nil
+}
+
+// UndoLastChangeSet reverts the user's most recently applied change set on a
+// board, undoing its changes in reverse order. Changes that can't be undone
+// are marked and the change set stays applied, so undo can be retried. Like
+// applying, undoing uses the user's own Trello client.
+func (a *Agent) UndoLastChangeSet(userID string, client *trello.Client, boardID string) (*models.ChangeSet, error) {
+	a.changeSetMutex.Lock()
+	defer a.changeSetMutex.Unlock()
+
+	if _, err := checkBoardAccess(client, boardID); err != nil {
+		return nil, err
+	}
+
+	changeSet, err := a.changeSets.LastApplied(userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := 0
+	for i := len(changeSet.Changes) - 1; i >= 0; i-- {
+		change := &changeSet.Changes[i]
+		if change.Status != models.ChangeApplied && change.Status != models.ChangeUndoFailed {
+			continue
+		}
+		if err := undoChange(client, change); err != nil {
+			change.Status = models.ChangeUndoFailed
+			change.Error = err.Error()
+			failed++
+			continue
+		}
+		change.Status = models.ChangeUndone
+		change.Error = ""
+	}
+
+	if failed == 0 {
+		now := time.Now()
+		changeSet.Status = models.ChangeSetUndone
+		changeSet.UndoneAt = &now
+	}
+
+	if err := a.changeSets.SaveChangeSet(changeSet); err != nil {
+		return nil, fmt.Errorf("error saving change set: %v", err)
+	}
+
+	if failed > 0 {
+		return changeSet, fmt.Errorf("%d of the changes could not be undone", failed)
+	}
+	return changeSet, nil
+}
+
+// undoChange reverts an applied change to the card's previous state with
+// the user's client
+func undoChange(client *trello.Client, change *models.Change) error {
+	if change.NoOp {
+		return nil
+	}
+
+	switch change.Type {
+	case models.ChangeMoveCard:
+		_, err := client.UpdateCard(change.CardID, map[string]string{"idList": change.PreviousListID})
+		return err
+	case models.ChangeAssignMember:
+		return client.RemoveCardMember(change.CardID, change.MemberID)
+	case models.ChangeUnassignMember:
+		return client.AddCardMember(change.CardID, change.MemberID)
+	case models.ChangeSetDue:
+		_, err := client.UpdateCard(change.CardID, map[string]string{"due": dueValue(change.PreviousDue)})
+		return err
+	default:
+		return fmt.Errorf("unknown change type %q", change.Type)
+	}
+}
+
+// hasMember reports whether a member is assigned to a card
+func hasMember(card *trello.Card, memberID string) bool {
+	for _, id := range card.Members {
+		if id == memberID {
+			return true
+		}
+	}
+	return false
+}
+
+// dueValue returns the due parameter for a due date, "null" clearing it
+func dueValue(due string) string {
+	if due == "" {
+		return "null"
+	}
+	return due
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+// newChangeSetTest starts a fake Trello server with the default board and
+// returns an agent that stores change sets in a temporary directory
+func newChangeSetTest(t *testing.T) (*Agent, *trello.Client, *trellotest.Server) {
+	t.Helper()
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	t.Cleanup(server.Close)
+	server.Configure()
+
+	store, err := models.NewChangeSetStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating change set store: %v", err)
+	}
+	return &Agent{changeSets: store}, trello.NewClient(fixture.AccessToken, fixture.AccessSecret), server
+}
+
+// proposeChangeSet saves a proposed change set for a user on board-1
+func proposeChangeSet(t *testing.T, a *Agent, userID string, changes []models.Change) *models.ChangeSet {
+	t.Helper()
+	changeSet, err := models.NewChangeSet(userID, "board-1", "Tidy up the board", changes)
+	if err != nil {
+		t.Fatalf("Error creating change set: %v", err)
+	}
+	if err := a.changeSets.SaveChangeSet(changeSet); err != nil {
+		t.Fatalf("Error saving change set: %v", err)
+	}
+	return changeSet
+}
+
+// cardFields are the card fields change sets modify
+type cardFields struct {
+	ListID  string
+	Members []string
+	Due     string
+}
+
+// getCardFields reads a card's list, members and due date from Trello
+func getCardFields(t *testing.T, client *trello.Client, cardID string) cardFields {
+	t.Helper()
+	card, err := client.GetCard(cardID)
+	if err != nil {
+		t.Fatalf("Error getting card %s: %v", cardID, err)
+	}
+	fields := cardFields{ListID: card.ListID, Members: append([]string{}, card.Members...)}
+	if card.Due != nil {
+		fields.Due = card.Due.UTC().Format(time.RFC3339)
+	}
+	return fields
+}
+
+// writes returns the requests that modified the board
+func writes(server *trellotest.Server) []string {
+	var result []string
+	for _, request := range server.Requests() {
+		if !strings.HasPrefix(request, "GET ") {
+			result = append(result, request)
+		}
+	}
+	return result
+}
+
+func TestApplyAndUndoChangeSet(t *testing.T) {
+	a, client, _ := newChangeSetTest(t)
+
+	cards := []string{"card-1", "card-2", "card-3", "card-4"}
+	original := make(map[string]cardFields)
+	for _, id := range cards {
+		original[id] = getCardFields(t, client, id)
+	}
+
+	due := time.Now().AddDate(0, 0, 14).UTC().Truncate(time.Second).Format(time.RFC3339)
+	changeSet := proposeChangeSet(t, a, "member-1", []models.Change{
+		{Type: models.ChangeMoveCard, CardID: "card-1", ListID: "list-done"},
+		{Type: models.ChangeAssignMember, CardID: "card-2", MemberID: "member-3"},
+		{Type: models.ChangeUnassignMember, CardID: "card-3", MemberID: "member-3"},
+		{Type: models.ChangeSetDue, CardID: "card-4", Due: due},
+		// Already on the card, so undo must leave the member assigned
+		{Type: models.ChangeAssignMember, CardID: "card-1", MemberID: "member-1"},
+	})
+
+	applied, err := a.ApplyChangeSet("member-1", client, changeSet.ID, nil)
+	if err != nil {
+		t.Fatalf("Error applying change set: %v", err)
+	}
+	if applied.Status != models.ChangeSetApplied {
+		t.Fatalf("change set is %s, want %s", applied.Status, models.ChangeSetApplied)
+	}
+	for i, change := range applied.Changes {
+		if change.Status != models.ChangeApplied {
+			t.Errorf("change %d is %s: %s", i, change.Status, change.Error)
+		}
+	}
+	if !applied.Changes[4].NoOp {
+		t.Error("assigning a member already on the card wasn't recorded as a no-op")
+	}
+
+	// The changes reached Trello
+	if got := getCardFields(t, client, "card-1").ListID; got != "list-done" {
+		t.Errorf("card-1 is in %s, want list-done", got)
+	}
+	if got := getCardFields(t, client, "card-2").Members; !reflect.DeepEqual(got, []string{"member-2", "member-3"}) {
+		t.Errorf("card-2 members are %v, want member-2 and member-3", got)
+	}
+	if got := getCardFields(t, client, "card-3").Members; len(got) != 0 {
+		t.Errorf("card-3 members are %v, want none", got)
+	}
+	if got := getCardFields(t, client, "card-4").Due; got != due {
+		t.Errorf("card-4 is due %q, want %q", got, due)
+	}
+
+	// Undo restores every card's original fields
+	undone, err := a.UndoLastChangeSet("member-1", client, "board-1")
+	if err != nil {
+		t.Fatalf("Error undoing change set: %v", err)
+	}
+	if undone.ID != changeSet.ID || undone.Status != models.ChangeSetUndone {
+		t.Fatalf("undid change set %s, now %s; want %s undone", undone.ID, undone.Status, changeSet.ID)
+	}
+	for _, id := range cards {
+		if got := getCardFields(t, client, id); !reflect.DeepEqual(got, original[id]) {
+			t.Errorf("%s is %+v after undo, want %+v", id, got, original[id])
+		}
+	}
+
+	// There is nothing left to undo
+	if _, err := a.UndoLastChangeSet("member-1", client, "board-1"); err == nil {
+		t.Error("undid the same change set twice")
+	}
+}
+
+func TestApplyChangeSetRefused(t *testing.T) {
+	move := []models.Change{{Type: models.ChangeMoveCard, CardID: "card-2", ListID: "list-done"}}
+
+	tests := []struct {
+		name string
+		// prepare proposes the change set and brings it to the state under
+		// test, returning its ID
+		prepare func(t *testing.T, a *Agent, client *trello.Client) string
+		// userID applies the change set
+		userID string
+	}{
+		{
+			name: "already applied",
+			prepare: func(t *testing.T, a *Agent, client *trello.Client) string {
+				changeSet := proposeChangeSet(t, a, "member-1", move)
+				if _, err := a.ApplyChangeSet("member-1", client, changeSet.ID, nil); err != nil {
+					t.Fatalf("Error applying change set: %v", err)
+				}
+				return changeSet.ID
+			},
+			userID: "member-1",
+		},
+		{
+			name: "rejected",
+			prepare: func(t *testing.T, a *Agent, client *trello.Client) string {
+				changeSet := proposeChangeSet(t, a, "member-1", move)
+				if _, err := a.RejectChangeSet("member-1", changeSet.ID); err != nil {
+					t.Fatalf("Error rejecting change set: %v", err)
+				}
+				return changeSet.ID
+			},
+			userID: "member-1",
+		},
+		{
+			name: "undone",
+			prepare: func(t *testing.T, a *Agent, client *trello.Client) string {
+				changeSet := proposeChangeSet(t, a, "member-1", move)
+				if _, err := a.ApplyChangeSet("member-1", client, changeSet.ID, nil); err != nil {
+					t.Fatalf("Error applying change set: %v", err)
+				}
+				if _, err := a.UndoLastChangeSet("member-1", client, "board-1"); err != nil {
+					t.Fatalf("Error undoing change set: %v", err)
+				}
+				return changeSet.ID
+			},
+			userID: "member-1",
+		},
+		{
+			name: "another user's",
+			prepare: func(t *testing.T, a *Agent, client *trello.Client) string {
+				return proposeChangeSet(t, a, "member-1", move).ID
+			},
+			userID: "member-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, client, server := newChangeSetTest(t)
+			id := tt.prepare(t, a, client)
+			before := getCardFields(t, client, "card-2")
+			written := len(writes(server))
+
+			if _, err := a.ApplyChangeSet(tt.userID, client, id, nil); err == nil {
+				t.Fatal("change set was applied")
+			}
+			if got := writes(server)[written:]; len(got) > 0 {
+				t.Errorf("board modified: %v", got)
+			}
+			if got := getCardFields(t, client, "card-2"); !reflect.DeepEqual(got, before) {
+				t.Errorf("card-2 is %+v, want %+v", got, before)
+			}
+		})
+	}
+}
+
+func TestUndoAnotherUsersChangeSet(t *testing.T) {
+	a, client, server := newChangeSetTest(t)
+	changeSet := proposeChangeSet(t, a, "member-1", []models.Change{
+		{Type: models.ChangeMoveCard, CardID: "card-2", ListID: "list-done"},
+	})
+	if _, err := a.ApplyChangeSet("member-1", client, changeSet.ID, nil); err != nil {
+		t.Fatalf("Error applying change set: %v", err)
+	}
+	written := len(writes(server))
+
+	if _, err := a.UndoLastChangeSet("member-2", client, "board-1"); err == nil {
+		t.Fatal("another user undid the change set")
+	}
+	if got := writes(server)[written:]; len(got) > 0 {
+		t.Errorf("board modified: %v", got)
+	}
+	if got := getCardFields(t, client, "card-2").ListID; got != "list-done" {
+		t.Errorf("card-2 is in %s, want list-done", got)
+	}
+}
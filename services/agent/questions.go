@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/llm"
+	"agents_go/services/trello"
+)
+
+// Answer is an answer to a question about a board's report history
+type Answer struct {
+	Question string `json:"question"`
+	// Answer cites its sources by number, e.g. [2]
+	Answer string `json:"answer"`
+	// Sources are the report sections the answer was written from,
+	// numbered from 1; Cited marks the ones the answer cites
+	Sources []AnswerSource `json:"sources"`
+	Usage   *models.Usage  `json:"usage,omitempty"`
+}
+
+// AnswerSource is a report section an answer was written from
+type AnswerSource struct {
+	Number    int               `json:"number"`
+	ReportID  string            `json:"report_id"`
+	URL       string            `json:"url"`
+	Label     string            `json:"label"`
+	Section   string            `json:"section"`
+	Type      models.ReportType `json:"type"`
+	Period    string            `json:"period"`
+	StartDate time.Time         `json:"start_date"`
+	EndDate   time.Time         `json:"end_date"`
+	Text      string            `json:"text"`
+	// Score is the similarity of the section and the question, from -1
+	// to 1
+	Score float64 `json:"score"`
+	Cited bool    `json:"cited"`
+}
+
+// Citations returns the sources the answer cites
+func (a *Answer) Citations() []AnswerSource {
+	var cited []AnswerSource
+	for _, source := range a.Sources {
+		if source.Cited {
+			cited = append(cited, source)
+		}
+	}
+	return cited
+}
+
+// citationPattern matches citations like [2] or [2, 5]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// AskReports answers a question about a board's report history from the
+// report sections most similar to it, if the user's Trello client can read
+// the board. The board's reports are embedded first if they haven't been;
+// the filter limits the reports searched.
+func (a *Agent) AskReports(ctx context.Context, userID string, client *trello.Client, boardID, question string, filter models.ReportFilter) (*Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("no question to answer")
+	}
+
+	// Refuse to answer users over their hard budget
+	if _, err := a.checkBudget(userID); err != nil {
+		return nil, err
+	}
+
+	// Only answer from the reports of boards the user can read, as in chat
+	board, err := checkBoardAccess(client, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	embedder, embeddingModel, err := a.aifoundryClient.Providers().Embedder(config.EmbeddingProvider)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting embedding provider: %v", err)
+	}
+
+	// Redact personal data from the sections and the question before they
+	// are embedded or sent to the model, as in chat
+	boardData, err := client.GetBoardData(boardID, time.Now().AddDate(0, 0, -chatActivityDays))
+	if err != nil {
+		return nil, fmt.Errorf("error getting board data: %v", err)
+	}
+	redactor, err := a.redactor(boardID, boardData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Embed with the embedding provider, counting its usage
+	var embedUsage aifoundry.Usage
+	embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+		input := make([]string, len(texts))
+		for i, text := range texts {
+			input[i] = redactor.Redact(text)
+		}
+		start := time.Now()
+		resp, err := embedder.Embed(ctx, llm.EmbeddingRequest{Model: embeddingModel, Input: input})
+		embedUsage.Requests++
+		embedUsage.Latency += time.Since(start)
+		if err != nil {
+			return nil, err
+		}
+		embedUsage.PromptTokens += resp.Usage.PromptTokens
+		return resp.Vectors, nil
+	}
+	boardName := board.Name
+	defer func() {
+		if embedUsage.Requests > 0 {
+			a.recordUsage(&models.UsageRecord{
+				UserID:    userID,
+				BoardID:   boardID,
+				BoardName: boardName,
+				Kind:      models.UsageEmbedding,
+				Usage:     usageFor(embedder.Name(), embeddingModel, embedUsage),
+			})
+		}
+	}()
+
+	// Embed the reports that are new since the last question
+	reports, err := a.questionReports(boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting reports: %v", err)
+	}
+	if embedded, err := a.embeddings.Sync(ctx, boardID, reports, embeddingModel, embed); err != nil {
+		return nil, err
+	} else if embedded > 0 {
+		log.Printf("Embedded %d report(s) of board %s for questions", embedded, boardID)
+	}
+
+	// Find the sections most similar to the question
+	vectors, err := embed(ctx, []string{question})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding question: %v", err)
+	}
+	matches, err := a.embeddings.Search(boardID, vectors[0], embeddingModel, filter, config.QuestionSections)
+	if err != nil {
+		return nil, fmt.Errorf("error searching report sections: %v", err)
+	}
+
+	answer := &Answer{Question: question, Sources: []AnswerSource{}}
+	if len(matches) == 0 {
+		answer.Answer = "There are no reports to answer from. Generate a report first, or widen the date range."
+		return answer, nil
+	}
+
+	var sources []aifoundry.QuestionSource
+	for i, match := range matches {
+		answer.Sources = append(answer.Sources, AnswerSource{
+			Number:    i + 1,
+			ReportID:  match.ReportID,
+			URL:       "/view-report?id=" + match.ReportID,
+			Label:     match.Label(),
+			Section:   match.Title,
+			Type:      match.Type,
+			Period:    match.Period,
+			StartDate: match.StartDate,
+			EndDate:   match.EndDate,
+			Text:      match.Text,
+			Score:     match.Score,
+		})
+		sources = append(sources, aifoundry.QuestionSource{Label: match.Label(), Text: match.Text})
+	}
+
+	// Answer with the model configured for the board
+	aiClient, err := a.aiClientForBoard(boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting LLM provider: %v", err)
+	}
+	result, err := aiClient.AnswerQuestion(ctx, question, sources, redactor)
+	logRedactions(boardID, "question", redactor)
+	if err != nil {
+		return nil, fmt.Errorf("error answering question: %v", err)
+	}
+
+	answer.Answer = result.Content
+	markCitations(answer)
+	usage := usageFor(result.Provider, result.Model, result.Usage)
+	answer.Usage = &usage
+	a.recordUsage(&models.UsageRecord{
+		UserID:    userID,
+		BoardID:   boardID,
+		BoardName: boardName,
+		Kind:      models.UsageQuestion,
+		Usage:     usage,
+	})
+
+	return answer, nil
+}
+
+// markCitations marks the sources the answer cites
+func markCitations(answer *Answer) {
+	for _, match := range citationPattern.FindAllStringSubmatch(answer.Answer, -1) {
+		for _, number := range strings.Split(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(number))
+			if err == nil && n >= 1 && n <= len(answer.Sources) {
+				answer.Sources[n-1].Cited = true
+			}
+		}
+	}
+}
+
+// questionReports returns the reports questions about a board are answered
+// from: the canonical revision of each period's report, for the standard
+// audience if there is one, newest first
+func (a *Agent) questionReports(boardID string) ([]*models.Report, error) {
+	reports, err := a.reportStore.ListReports(models.ReportFilter{BoardID: boardID})
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []string
+	chosen := make(map[string]*models.Report)
+	for _, group := range models.GroupRevisions(reports) {
+		current, ok := chosen[group.Period]
+		if !ok {
+			periods = append(periods, group.Period)
+		}
+		if !ok || (group.Audience == "" && current.Audience != "") {
+			chosen[group.Period] = group.Canonical
+		}
+	}
+
+	result := make([]*models.Report, 0, len(periods))
+	for _, period := range periods {
+		result = append(result, chosen[period])
+	}
+	return result, nil
+}
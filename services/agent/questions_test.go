@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"agents_go/models"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+func TestAskReportsBoardAccess(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+
+	usage, err := models.NewUsageStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating usage store: %v", err)
+	}
+	a := &Agent{usage: usage}
+	client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+	// Questions about a board the user can't read, or without a Trello
+	// client, are refused before any board data is fetched
+	for name, client := range map[string]*trello.Client{"unreadable board": client, "no client": nil} {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.AskReports(context.Background(), "member-1", client, "board-2", "What shipped?", models.ReportFilter{BoardID: "board-2"})
+			if !errors.Is(err, ErrBoardAccess) {
+				t.Errorf("got error %v, want ErrBoardAccess", err)
+			}
+		})
+	}
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "/cards") || strings.Contains(request, "/actions") {
+			t.Errorf("fetched board data with %s", request)
+		}
+	}
+}
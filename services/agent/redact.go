@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+
+	"agents_go/models"
+	"agents_go/services/redact"
+)
+
+// redactor creates the redactor for a board's data from the board's
+// redaction rules. If the settings can't be read the default rules are used,
+// so personal data isn't sent by accident.
+func (a *Agent) redactor(boardID string, boardData map[string]interface{}) (*redact.Redactor, error) {
+	rules := models.DefaultRedactionRules()
+	settings, err := a.boardSettings.GetSettings(boardID)
+	if err != nil {
+		log.Printf("Error getting board settings, using default redaction rules: %v", err)
+	} else {
+		rules = settings.RedactionRules()
+	}
+
+	redactor, err := redact.New(rules, redact.MembersFromBoard(boardData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating redactor: %v", err)
+	}
+	return redactor, nil
+}
+
+// logRedactions logs how much personal data was redacted from the prompts;
+// the redacted values themselves are never logged
+func logRedactions(boardID, purpose string, redactor *redact.Redactor) {
+	counts := redactor.Counts()
+	if len(counts) == 0 {
+		return
+	}
+	log.Printf("Redacted %s from the %s prompts for board %s", redact.Summary(counts), purpose, boardID)
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"fmt"
+
+	"agents_go/models"
+	"agents_go/services/retention"
+)
+
+// SetReportPinned pins a report so retention never deletes it, or unpins it
+func (a *Agent) SetReportPinned(id string, pinned bool) error {
+	a.revisionMutex.Lock()
+	defer a.revisionMutex.Unlock()
+
+	err := a.updateReport(id, func(report *models.Report) bool {
+		if report.Pinned == pinned {
+			return false
+		}
+		report.Pinned = pinned
+		return true
+	})
+	if err == models.ErrReportNotFound {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error saving report: %v", err)
+	}
+	return nil
+}
+
+// RetentionPolicy returns the retention policy that applies to a board:
+// its own, or the default
+func (a *Agent) RetentionPolicy(settings *models.BoardSettings) models.RetentionPolicy {
+	if settings.Retention != nil {
+		return *settings.Retention
+	}
+	return retention.DefaultPolicy()
+}
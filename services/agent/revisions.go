@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"agents_go/models"
+)
+
+// maxConflictRetries is how many times a change to a report is applied
+// again after another writer changed the report first
+const maxConflictRetries = 3
+
+// revisions returns the saved revisions of a report's period for the
+// report's audience, newest first
+func (a *Agent) revisions(report *models.Report) ([]*models.Report, error) {
+	reports, err := a.reportStore.ListReports(models.ReportFilter{
+		BoardID: report.BoardID,
+		Type:    report.Type,
+		Period:  report.PeriodKey(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error finding report revisions: %v", err)
+	}
+
+	for _, group := range models.GroupRevisions(reports) {
+		if group.Audience == report.Audience {
+			return group.Revisions, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetReportRevisions returns every revision of the report's period for the
+// report's audience, newest first, and the canonical one
+func (a *Agent) GetReportRevisions(id string) (*models.ReportRevisions, error) {
+	report, err := a.reportStore.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := a.revisions(report)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReportRevisions{
+		Period:    report.PeriodKey(),
+		Audience:  report.Audience,
+		Revisions: revisions,
+		Canonical: models.CanonicalRevision(revisions),
+	}, nil
+}
+
+// GetReportGroups returns a board's reports grouped into the revisions of
+// each period and audience, newest first
+func (a *Agent) GetReportGroups(boardID string) ([]*models.ReportRevisions, error) {
+	reports, err := a.reportStore.ListReports(models.ReportFilter{BoardID: boardID})
+	if err != nil {
+		return nil, err
+	}
+	return models.GroupRevisions(reports), nil
+}
+
+// GetCanonicalReport returns the canonical revision of a period's report
+// for an audience
+func (a *Agent) GetCanonicalReport(period string, audience models.Audience) (*models.Report, error) {
+	reports, err := a.reportStore.ListReports(models.ReportFilter{Period: period})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range models.GroupRevisions(reports) {
+		if group.Audience == audience {
+			return group.Canonical, nil
+		}
+	}
+	return nil, models.ErrReportNotFound
+}
+
+// SetCanonicalRevision marks a revision as the canonical one of its period
+// and unmarks the others. The revision is marked first, so the period
+// always has a canonical revision; if unmarking another one fails, calling
+// this again finishes the job.
+func (a *Agent) SetCanonicalRevision(id string) error {
+	a.revisionMutex.Lock()
+	defer a.revisionMutex.Unlock()
+
+	report, err := a.reportStore.GetReport(id)
+	if err != nil {
+		return err
+	}
+	revisions, err := a.revisions(report)
+	if err != nil {
+		return err
+	}
+
+	// Put the chosen revision first
+	ordered := []*models.Report{report}
+	for _, revision := range revisions {
+		if revision.ID != id {
+			ordered = append(ordered, revision)
+		}
+	}
+
+	for _, revision := range ordered {
+		canonical := revision.ID == id
+		err := a.updateReport(revision.ID, func(stored *models.Report) bool {
+			if stored.Canonical == canonical {
+				return false
+			}
+			stored.Canonical = canonical
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("error saving revision %s: %v", revision.ID, err)
+		}
+	}
+	return nil
+}
+
+// updateReport reads a report, changes it and saves it. If another writer
+// changed the report since it was read, the save fails with
+// models.ErrReportConflict; the report is then read again and the change
+// applied to the other writer's version. change returns false if the
+// report needs no change.
+func (a *Agent) updateReport(id string, change func(report *models.Report) bool) error {
+	for attempt := 1; ; attempt++ {
+		report, err := a.reportStore.GetReport(id)
+		if err != nil {
+			return err
+		}
+		if !change(report) {
+			return nil
+		}
+
+		err = a.reportStore.SaveReport(report)
+		if errors.Is(err, models.ErrReportConflict) && attempt <= maxConflictRetries {
+			log.Printf("Report %s was changed by another writer, trying again: %v", id, err)
+			continue
+		}
+		return err
+	}
+}
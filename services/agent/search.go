@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"errors"
+
+	"agents_go/models"
+	"agents_go/services/search"
+	"agents_go/services/trello"
+)
+
+// SearchReports searches the reports of the boards the user's Trello client
+// can read. Reports deleted by another process since they were indexed are
+// dropped from the index and the results.
+func (a *Agent) SearchReports(client *trello.Client, query search.Query) (*search.Results, error) {
+	boards, err := userBoards(client)
+	if err != nil {
+		return nil, err
+	}
+	query.Boards = boards
+
+	for {
+		results := a.searchIndex.Search(query)
+
+		stale := false
+		for _, result := range results.Results {
+			if _, err := a.reportStore.GetReport(result.ReportID); errors.Is(err, models.ErrReportNotFound) {
+				a.searchIndex.Remove(result.ReportID)
+				stale = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if !stale {
+			return results, nil
+		}
+	}
+}
+
+// SearchBoards lists the boards with reports the user's Trello client can
+// search
+func (a *Agent) SearchBoards(client *trello.Client) []search.Board {
+	boards, err := userBoards(client)
+	if err != nil {
+		return []search.Board{}
+	}
+	readable := []search.Board{}
+	for _, board := range a.searchIndex.Boards() {
+		if boards[board.ID] {
+			readable = append(readable, board)
+		}
+	}
+	return readable
+}
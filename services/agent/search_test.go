@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/search"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+func TestSearchReportsReadableBoards(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+
+	store, err := models.NewFileReportStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating report store: %v", err)
+	}
+	index := search.NewIndex()
+	a := &Agent{reportStore: search.Indexed(store, index), searchIndex: index}
+
+	// The user can read board-1 only
+	now := time.Now().UTC()
+	for i, boardID := range []string{"board-1", "board-2", "board-1"} {
+		generated := now.Add(time.Duration(i-3) * time.Hour)
+		report := &models.Report{
+			ID:          fmt.Sprintf("%s-%d", models.NewReportID(generated), i),
+			Period:      fmt.Sprintf("period-%d", i),
+			BoardID:     boardID,
+			BoardName:   "Board " + boardID,
+			Type:        models.Weekly,
+			Content:     "The webhook retries were fixed.",
+			GeneratedAt: generated,
+		}
+		if err := a.reportStore.SaveReport(report); err != nil {
+			t.Fatalf("Error saving report: %v", err)
+		}
+	}
+	client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+	results, err := a.SearchReports(client, search.Query{Text: "webhook", Filter: models.ReportFilter{Limit: 1}})
+	if err != nil {
+		t.Fatalf("Error searching reports: %v", err)
+	}
+	if results.Total != 2 || len(results.Results) != 1 || results.Results[0].BoardID != "board-1" {
+		t.Errorf("got %d results of %d, want 1 of 2 on board-1: %+v", len(results.Results), results.Total, results.Results)
+	}
+
+	boards := a.SearchBoards(client)
+	if !reflect.DeepEqual(boards, []search.Board{{ID: "board-1", Name: "Board board-1"}}) {
+		t.Errorf("got boards %+v, want board-1 only", boards)
+	}
+
+	// Without a Trello client nothing is searchable
+	if _, err := a.SearchReports(nil, search.Query{Text: "webhook"}); !errors.Is(err, ErrBoardAccess) {
+		t.Errorf("searching without a client returned %v, want ErrBoardAccess", err)
+	}
+	if boards := a.SearchBoards(nil); len(boards) != 0 {
+		t.Errorf("got boards %+v without a client, want none", boards)
+	}
+}
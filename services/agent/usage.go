@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/llm"
+)
+
+// ErrBudgetExceeded is returned when a user has spent their hard monthly
+// budget
+var ErrBudgetExceeded = errors.New("monthly LLM budget exceeded")
+
+// BudgetStatus is a user's spending against the monthly budgets
+type BudgetStatus struct {
+	Month string  `json:"month"`
+	Spent float64 `json:"spent"`
+	// Soft and Hard are the budgets; zero means no limit
+	Soft         float64 `json:"soft"`
+	Hard         float64 `json:"hard"`
+	SoftExceeded bool    `json:"soft_exceeded"`
+	HardExceeded bool    `json:"hard_exceeded"`
+}
+
+// Budget returns a user's spending this month against the budgets
+func (a *Agent) Budget(userID string) (*BudgetStatus, error) {
+	month := models.UsageMonth(time.Now())
+	summary, err := a.usage.Summary(userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BudgetStatus{
+		Month: month,
+		Spent: summary.Total.Cost,
+		Soft:  config.LLMBudgetSoft,
+		Hard:  config.LLMBudgetHard,
+	}
+	status.SoftExceeded = status.Soft > 0 && status.Spent >= status.Soft
+	status.HardExceeded = status.Hard > 0 && status.Spent >= status.Hard
+
+	return status, nil
+}
+
+// checkBudget returns ErrBudgetExceeded if the user is over the hard
+// budget and logs a warning if they are over the soft one
+func (a *Agent) checkBudget(userID string) (*BudgetStatus, error) {
+	status, err := a.Budget(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking budget: %v", err)
+	}
+
+	if status.HardExceeded {
+		return status, fmt.Errorf("%w: $%.2f of $%.2f spent in %s", ErrBudgetExceeded, status.Spent, status.Hard, status.Month)
+	}
+	if status.SoftExceeded {
+		log.Printf("User %s is over the soft LLM budget: $%.2f of $%.2f spent in %s", userID, status.Spent, status.Soft, status.Month)
+	}
+
+	return status, nil
+}
+
+// UsageSummary returns a user's usage for a month, e.g. "2026-10"
+func (a *Agent) UsageSummary(userID, month string) (*models.UsageSummary, error) {
+	return a.usage.Summary(userID, month)
+}
+
+// UsageMonths returns the months a user has usage for, newest first
+func (a *Agent) UsageMonths(userID string) ([]string, error) {
+	return a.usage.Months(userID)
+}
+
+// ownerID returns the Trello member the agent's access token belongs to
+func (a *Agent) ownerID() (string, error) {
+	a.ownerMutex.Lock()
+	defer a.ownerMutex.Unlock()
+
+	if a.owner == "" {
+		member, err := a.trelloClient.GetMember()
+		if err != nil {
+			return "", err
+		}
+		a.owner = member.ID
+	}
+
+	return a.owner, nil
+}
+
+// usageFor prices the usage of a report or chat reply. Metered usage is
+// already priced per model; the rest, like embeddings, is priced for model.
+func usageFor(provider, model string, usage aifoundry.Usage) models.Usage {
+	cost := usage.Cost
+	if cost == 0 {
+		cost = llm.EstimateCost(model, llm.Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		})
+	}
+	return models.Usage{
+		Provider:         provider,
+		Model:            model,
+		Requests:         usage.Requests,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		LatencyMs:        usage.Latency.Milliseconds(),
+		Cost:             cost,
+	}
+}
+
+// recordReportUsage adds a report's usage to its user's ledger
+func (a *Agent) recordReportUsage(report *models.Report) {
+	if report.Usage == nil {
+		return
+	}
+	a.recordUsage(&models.UsageRecord{
+		Time:      report.GeneratedAt,
+		UserID:    report.UserID,
+		BoardID:   report.BoardID,
+		BoardName: report.BoardName,
+		Kind:      models.UsageReport,
+		ReportID:  report.ID,
+		Usage:     *report.Usage,
+	})
+}
+
+// recordUsage adds a record to the ledger. Failing to record usage doesn't
+// fail the report or reply it was for.
+func (a *Agent) recordUsage(record *models.UsageRecord) {
+	if err := a.usage.Record(record); err != nil {
+		log.Printf("Error recording usage: %v", err)
+	}
+}
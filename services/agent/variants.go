@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/aifoundry"
+	"agents_go/services/prompts"
+)
+
+// reportRun is one run of report generation: the board snapshot and the
+// settings shared by every variant generated from it
+type reportRun struct {
+	boardID     string
+	boardName   string
+	reportType  models.ReportType
+	startDate   time.Time
+	endDate     time.Time
+	generatedAt time.Time
+	userID      string
+	boardData   map[string]interface{}
+	aiClient    *aifoundry.AIFoundryClient
+	tmpl        *prompts.Template
+	opts        aifoundry.ReportOptions
+}
+
+// reportAudiences returns the audiences to write reports for: the
+// requested ones, or the board's if none were requested, or just the
+// standard report
+func (a *Agent) reportAudiences(boardID string, requested []models.Audience) []models.Audience {
+	audiences := requested
+	if len(audiences) == 0 {
+		settings, err := a.boardSettings.GetSettings(boardID)
+		if err != nil {
+			log.Printf("Error getting board settings: %v", err)
+		} else {
+			audiences = settings.Audiences
+		}
+	}
+	if len(audiences) == 0 {
+		return []models.Audience{models.AudienceStandard}
+	}
+
+	// Drop duplicates, keeping the order
+	var unique []models.Audience
+	seen := make(map[models.Audience]bool)
+	for _, audience := range audiences {
+		if !seen[audience] {
+			seen[audience] = true
+			unique = append(unique, audience)
+		}
+	}
+	return unique
+}
+
+// generateVariants writes a report for each audience from the run's board
+// snapshot and links the reports to each other. Nothing is saved: if any
+// variant fails, the error is returned and the caller saves none of them,
+// and saveReports saves all of them or none.
+func (a *Agent) generateVariants(ctx context.Context, run *reportRun, audiences []models.Audience) ([]*models.Report, error) {
+	reports := make([]*models.Report, 0, len(audiences))
+	for i, audience := range audiences {
+		if len(audiences) > 1 && run.opts.Progress != nil && run.opts.Progress.Status != nil {
+			run.opts.Progress.Status(fmt.Sprintf("Writing the %s report (%d of %d)", audience.Name(), i+1, len(audiences)))
+		}
+
+		// Redact personal data before it is sent to the model, with a
+		// redactor per variant so each report counts its own redactions
+		opts := run.opts
+		opts.Audience = audience
+		var err error
+		opts.Redactor, err = a.redactor(run.boardID, run.boardData)
+		if err != nil {
+			return nil, err
+		}
+
+		// Generate report using the LLM
+		result, err := generateReportResult(ctx, run.aiClient, run.boardData, run.reportType, opts)
+		logRedactions(run.boardID, string(run.reportType)+" report", opts.Redactor)
+		if err != nil {
+			return nil, fmt.Errorf("error generating %s report: %v", audience.Name(), err)
+		}
+		usage := usageFor(result.Provider, result.Model, result.Usage)
+
+		// Create report
+		reports = append(reports, &models.Report{
+			ID:          models.NewReportID(run.generatedAt),
+			Period:      models.ReportPeriod(run.boardID, run.reportType, run.endDate),
+			BoardID:     run.boardID,
+			BoardName:   run.boardName,
+			Type:        run.reportType,
+			Content:     result.Content,
+			GeneratedAt: run.generatedAt,
+			StartDate:   run.startDate,
+			EndDate:     run.endDate,
+			UserID:      run.userID,
+			Provider:    result.Provider,
+			Model:       result.Model,
+			Strategy:    result.Strategy,
+			Structured:  result.Structured,
+			Provenance:  result.Provenance,
+			WithoutAI:   result.WithoutAI,
+
+			PromptTemplate: run.tmpl.Name,
+			PromptVersion:  run.tmpl.Version,
+
+			Cache:       result.Cache.Status(),
+			CacheHits:   result.Cache.Hits,
+			CacheMisses: result.Cache.Misses,
+
+			Usage:      &usage,
+			Redactions: opts.Redactor.Counts(),
+
+			FactChecked:      result.FactChecked,
+			UnverifiedClaims: result.UnverifiedClaims,
+			Corrections:      result.Corrections,
+			InjectionFlags:   result.InjectionFlags,
+			Language:         opts.Language,
+			Audience:         audience,
+		})
+	}
+
+	// Link the variants generated from the same snapshot
+	if len(reports) > 1 {
+		for _, report := range reports {
+			for _, other := range reports {
+				if other != report {
+					report.Variants = append(report.Variants, other.ID)
+				}
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// saveReports saves the reports of a run as new revisions of their period
+// and records their usage. Stores that keep artifacts also get the board
+// snapshot the reports were written from.
+func (a *Agent) saveReports(run *reportRun, reports []*models.Report) error {
+	// Number the revisions and save them together, so two runs for the
+	// same period don't take the same number
+	a.revisionMutex.Lock()
+	defer a.revisionMutex.Unlock()
+	for _, report := range reports {
+		revisions, err := a.revisions(report)
+		if err != nil {
+			return err
+		}
+		report.Revision = 1
+		if len(revisions) > 0 {
+			report.Revision = revisions[0].RevisionNumber() + 1
+		}
+	}
+
+	artifacts, _ := a.reportStore.(models.ReportArtifacts)
+	var snapshot []byte
+	if artifacts != nil {
+		var err error
+		if snapshot, err = json.Marshal(run.boardData); err != nil {
+			log.Printf("Error marshaling board snapshot: %v", err)
+		}
+	}
+
+	// Save every variant or none: a variant links to the others by ID, so
+	// if a save fails, the variants already saved are deleted again
+	for i, report := range reports {
+		if err := a.saveNewReport(report); err != nil {
+			for _, saved := range reports[:i] {
+				if err := a.reportStore.DeleteReport(saved.ID); err != nil {
+					log.Printf("Error deleting report %s after a failed save: %v", saved.ID, err)
+				}
+			}
+			return err
+		}
+	}
+
+	for _, report := range reports {
+		a.recordReportUsage(report)
+
+		if snapshot != nil {
+			if err := artifacts.SaveSnapshot(report, snapshot); err != nil {
+				log.Printf("Error saving snapshot of report %s: %v", report.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// saveNewReport saves a report that was just generated. A conflict means
+// the store already holds a report with its ID; if that is this report,
+// e.g. saved by an earlier attempt whose response was lost, the save
+// succeeded, and otherwise the conflict is returned.
+func (a *Agent) saveNewReport(report *models.Report) error {
+	err := a.reportStore.SaveReport(report)
+	if !errors.Is(err, models.ErrReportConflict) {
+		return err
+	}
+
+	stored, getErr := a.reportStore.GetReport(report.ID)
+	if getErr != nil {
+		return err
+	}
+	want, _ := json.Marshal(report)
+	got, _ := json.Marshal(stored)
+	if !bytes.Equal(want, got) {
+		return err
+	}
+	return nil
+}
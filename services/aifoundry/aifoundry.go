@@ -3,146 +3,356 @@ package aifoundry
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"agents_go/config"
-	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"agents_go/models"
+	"agents_go/services/llm"
+	"agents_go/services/prompts"
+	"agents_go/services/redact"
+	"agents_go/services/tools"
 )
 
-// AIFoundryClient is an AI Foundry API client
+// AIFoundryClient generates chat replies and reports with an LLM provider.
+// It started out as an Azure AI Foundry client and kept the name; the
+// provider and model are now selected from the configured llm.Registry.
 type AIFoundryClient struct {
-	client       *azopenai.Client
-	deploymentID string
+	providers *llm.Registry
+	provider  llm.Provider
+	model     string
+	// cache stores report completions; nil disables caching
+	cache *llm.Cache
+	// fallbacks are tried in order when the provider fails
+	fallbacks []llm.Target
+	retry     llm.RetryPolicy
+	// language and audience are the language and audience reports are
+	// written for; they are set on the copy of the client that generates a
+	// report
+	language models.Language
+	audience models.Audience
 }
 
-// NewClient creates a new AI Foundry client
+// NewClient creates a new client using the default configured provider
 func NewClient() *AIFoundryClient {
-	// Create the API key credential
-	cred := azcore.NewKeyCredential(config.AIFoundryAPIKey)
+	registry, err := llm.NewRegistryFromConfig()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create LLM providers: %v", err))
+	}
 
-	// Create the client with API key
-	client, err := azopenai.NewClientWithKeyCredential(config.AIFoundryAPIURL, cred, nil)
+	client, err := NewClientWithRegistry(registry)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create Azure OpenAI client: %v", err))
+		panic(fmt.Sprintf("Failed to create AI client: %v", err))
 	}
 
-	return &AIFoundryClient{
-		client:       client,
-		deploymentID: config.AIFoundryModel,
+	// Cache report completions unless the TTL disables it
+	if config.LLMCacheTTL > 0 {
+		cache, err := llm.NewCache(config.LLMCachePath, config.LLMCacheTTL, config.LLMCacheMaxBytes)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create completion cache: %v", err))
+		}
+		client.cache = cache
 	}
+
+	return client
 }
 
-// SendChatMessage sends a simple chat message to the AI Foundry API
-func (c *AIFoundryClient) SendChatMessage(message string) (string, error) {
-	// Create the messages
-	systemMessage := azopenai.ChatRequestSystemMessage{
-		Content: azopenai.NewChatRequestSystemMessageContent("You are a helpful assistant for Trello users. You provide concise and accurate information."),
+// NewClientWithRegistry creates a client using the registry's default provider
+func NewClientWithRegistry(registry *llm.Registry) (*AIFoundryClient, error) {
+	provider, model, err := registry.Resolve("", "")
+	if err != nil {
+		return nil, err
 	}
 
-	userMessage := azopenai.ChatRequestUserMessage{
-		Content: azopenai.NewChatRequestUserMessageContent(message),
+	return &AIFoundryClient{
+		providers: registry,
+		provider:  provider,
+		model:     model,
+		fallbacks: resolveFallbacks(registry, config.LLMFallbacks),
+		retry:     llm.DefaultRetryPolicy(),
+	}, nil
+}
+
+// resolveFallbacks looks up the configured fallback models, given as
+// "provider" or "provider:model". Unknown providers are skipped.
+func resolveFallbacks(registry *llm.Registry, names []string) []llm.Target {
+	var targets []llm.Target
+	for _, name := range names {
+		providerName, model := name, ""
+		if i := strings.Index(name, ":"); i >= 0 {
+			providerName, model = name[:i], name[i+1:]
+		}
+
+		provider, model, err := registry.Resolve(providerName, model)
+		if err != nil {
+			log.Printf("Ignoring fallback model %q: %v", name, err)
+			continue
+		}
+		targets = append(targets, llm.Target{Provider: provider, Model: model})
 	}
+	return targets
+}
 
-	// Create the request
-	deploymentID := c.deploymentID // Create a copy to take address of
-	request := azopenai.ChatCompletionsOptions{
-		DeploymentName: &deploymentID,
-		Messages: []azopenai.ChatRequestMessageClassification{
-			&systemMessage,
-			&userMessage,
-		},
-		Temperature: floatPtr(0.8),
-		MaxTokens:   int32Ptr(2048),
+// WithProvider returns a copy of the client that uses the named provider and
+// model. An empty name keeps the current provider and an empty model uses the
+// provider's default.
+func (c *AIFoundryClient) WithProvider(name, model string) (*AIFoundryClient, error) {
+	if name == "" {
+		name = c.provider.Name()
 	}
 
-	// Send the request
-	ctx := context.Background()
-	resp, err := c.client.GetChatCompletions(ctx, request, nil)
+	provider, model, err := c.providers.Resolve(name, model)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %v", err)
+		return nil, err
 	}
 
-	// Extract the response content
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+	return &AIFoundryClient{
+		providers: c.providers,
+		provider:  provider,
+		model:     model,
+		cache:     c.cache,
+		fallbacks: c.fallbacks,
+		retry:     c.retry,
+	}, nil
+}
+
+// WithCache returns a copy of the client that caches report completions in
+// the given cache; nil disables caching
+func (c *AIFoundryClient) WithCache(cache *llm.Cache) *AIFoundryClient {
+	client := *c
+	client.cache = cache
+	return &client
+}
+
+// WithRetryPolicy returns a copy of the client that retries failed
+// completions with the given policy
+func (c *AIFoundryClient) WithRetryPolicy(policy llm.RetryPolicy) *AIFoundryClient {
+	client := *c
+	client.retry = policy
+	return &client
+}
+
+// chain returns the client's provider followed by its fallbacks, with
+// retries for transient failures
+func (c *AIFoundryClient) chain() *llm.Fallback {
+	targets := []llm.Target{{Provider: c.provider, Model: c.model}}
+	for _, fallback := range c.fallbacks {
+		if fallback.Provider.Name() == c.provider.Name() && fallback.Model == c.model {
+			continue
+		}
+		targets = append(targets, fallback)
 	}
+	return llm.NewFallback(c.retry, targets...)
+}
 
-	// Get the content as string
-	if resp.Choices[0].Message == nil || resp.Choices[0].Message.Content == nil {
-		return "", fmt.Errorf("empty response content")
+// ForBoard returns a client configured for the board's LLM settings
+func (c *AIFoundryClient) ForBoard(settings *models.BoardSettings) (*AIFoundryClient, error) {
+	if settings == nil || (settings.LLMProvider == "" && settings.LLMModel == "") {
+		return c, nil
 	}
+	return c.WithProvider(settings.LLMProvider, settings.LLMModel)
+}
 
-	return *resp.Choices[0].Message.Content, nil
+// Providers returns the registry of available providers
+func (c *AIFoundryClient) Providers() *llm.Registry {
+	return c.providers
 }
 
-// GenerateReport generates a report using the AI Foundry API
-func (c *AIFoundryClient) GenerateReport(boardData map[string]interface{}, reportType string) (string, error) {
-	// Convert board data to a more readable format for the LLM
-	boardSummary, err := formatBoardData(boardData)
+// ProviderName returns the name of the provider in use
+func (c *AIFoundryClient) ProviderName() string {
+	return c.provider.Name()
+}
+
+// Model returns the model in use
+func (c *AIFoundryClient) Model() string {
+	return c.model
+}
+
+// SendChatMessage sends a simple chat message to the model
+func (c *AIFoundryClient) SendChatMessage(message string) (string, error) {
+	resp, err := c.provider.Complete(context.Background(), llm.Request{
+		Model: c.model,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are a helpful assistant for Trello users. You provide concise and accurate information."},
+			{Role: llm.RoleUser, Content: message},
+		},
+		Temperature: 0.8,
+		MaxTokens:   2048,
+	})
 	if err != nil {
-		return "", fmt.Errorf("error formatting board data: %v", err)
+		return "", err
 	}
 
-	// Create system prompt based on report type
-	systemPrompt := getReportSystemPrompt(reportType)
+	return resp.Content, nil
+}
 
-	// Create the system message
-	systemMessage := azopenai.ChatRequestSystemMessage{
-		Content: azopenai.NewChatRequestSystemMessageContent(systemPrompt),
-	}
+// ReportOptions customizes report generation
+type ReportOptions struct {
+	// SystemPrompt replaces the built-in prompt for the report type, e.g. a
+	// rendered prompt template selected for the board
+	SystemPrompt string
+	// Tools lets the model look up details on the board before writing the
+	// report; nil skips the research step
+	Tools *tools.Registry
+	// ToolBudget bounds the research step; zero uses DefaultToolBudget
+	ToolBudget ToolBudget
+	// Progress receives status updates and previews of the report as it is
+	// written; nil generates the report without streaming
+	Progress *Progress
+	// ForceRegenerate skips cached completions; the new ones still replace
+	// them in the cache
+	ForceRegenerate bool
+	// Redactor removes personal data from the prompts and maps member
+	// pseudonyms back in the report; nil sends board content as is
+	Redactor *redact.Redactor
+	// StartDate and EndDate are the period the report covers; the fact
+	// check accepts any date in it. Zero values assume the last month.
+	StartDate time.Time
+	EndDate   time.Time
+	// CorrectionRounds regenerates a report up to this many times while
+	// the fact check finds claims the board data doesn't support
+	CorrectionRounds int
+	// Language is the language the report is written in; empty is English
+	Language models.Language
+	// Audience is who the report is written for; it selects the prompt,
+	// sections, detail and what is hidden
+	Audience models.Audience
+}
 
-	// Create the user message
-	userMessage := azopenai.ChatRequestUserMessage{
-		Content: azopenai.NewChatRequestUserMessageContent(boardSummary),
+// GenerateReport generates a report for the board data. Boards that don't
+// fit in the model's context window are summarized in chunks first. If it
+// fails after requesting completions, the result returned with the error
+// holds only the usage of the failed attempts, so it can still be charged.
+func (c *AIFoundryClient) GenerateReport(ctx context.Context, boardData map[string]interface{}, reportType string, opts ReportOptions) (*ReportResult, error) {
+	// Convert board data to a more readable format for the LLM
+	sections, err := formatBoardSections(boardData)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting board data: %v", err)
+	}
+	logInjectionFlags(sections)
+
+	// Retry failures and fall back to other models, count the tokens used
+	// by the report, serve completions from the cache where the input
+	// hasn't changed, and redact personal data. Cache hits aren't counted;
+	// the cache sits inside the redactor so it keys on the redacted request
+	// and the redactor runs, and records its redactions, on every hit.
+	chain := c.chain()
+	meter := newUsageMeter(chain, chain.Used)
+	client := *c
+	client.provider = meter
+	var cached *cachingProvider
+	if c.cache != nil {
+		cached = newCachingProvider(client.provider, c.cache, opts.ForceRegenerate, chain.Used)
+		client.provider = cached
+	}
+	if opts.Redactor != nil {
+		client.provider = newRedactingProvider(client.provider, opts.Redactor)
+	}
+	client.language = opts.Language
+	client.audience = opts.Audience
+	c = &client
+	failed := func(err error) (*ReportResult, error) {
+		return &ReportResult{Usage: meter.Usage()}, err
+	}
+
+	// Fall back to the built-in prompt for the report type
+	systemPrompt := opts.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt, err = getReportSystemPrompt(boardData, reportType)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Create the request
-	deploymentID := c.deploymentID // Create a copy to take address of
-	request := azopenai.ChatCompletionsOptions{
-		DeploymentName: &deploymentID,
-		Messages: []azopenai.ChatRequestMessageClassification{
-			&systemMessage,
-			&userMessage,
-		},
-		Temperature: floatPtr(0.7),
-		MaxTokens:   int32Ptr(4000),
+	// Let the model gather details with the tools first
+	var provenance []models.ToolCallRecord
+	if opts.Tools != nil && opts.Tools.Len() > 0 {
+		var notes string
+		opts.Progress.status("Researching the board")
+		notes, provenance, err = c.research(ctx, sections, reportType, opts.Tools, opts.ToolBudget, opts.Progress)
+		if err != nil {
+			return failed(fmt.Errorf("error researching board: %v", err))
+		}
+		sections.Header += "## Research Notes\n\n" + notes + "\n\n"
 	}
 
-	// Send the request
-	ctx := context.Background()
-	resp, err := c.client.GetChatCompletions(ctx, request, nil)
+	result, err := c.generateFromSections(ctx, sections, reportType, systemPrompt, opts.Progress)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %v", err)
+		return failed(err)
 	}
+	result.ForAudience(c.audience, boardData, reportType, c.language)
 
-	// Extract the response content
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+	// Check the report against the board data, and rewrite it with the
+	// unsupported claims pointed out if the board asks for that
+	checker, err := newFactChecker(boardData, opts.StartDate, opts.EndDate)
+	if err != nil {
+		log.Printf("Error fact-checking report for board %s: %v", sections.BoardName, err)
+	} else {
+		result.FactChecked = true
+		result.UnverifiedClaims = checker.check(result.Structured, reportType, c.language)
+		for round := 1; round <= opts.CorrectionRounds && len(result.UnverifiedClaims) > 0; round++ {
+			opts.Progress.status(fmt.Sprintf("Correcting %s", plural(len(result.UnverifiedClaims), "unverified claim", "unverified claims")))
+			corrected, err := c.generateFromSections(ctx, sections, reportType, systemPrompt+"\n\n"+correctionPrompt(result.UnverifiedClaims), opts.Progress)
+			if err != nil {
+				log.Printf("Error correcting report for board %s: %v", sections.BoardName, err)
+				break
+			}
+			corrected.ForAudience(c.audience, boardData, reportType, c.language)
+			corrected.FactChecked = true
+			corrected.UnverifiedClaims = checker.check(corrected.Structured, reportType, c.language)
+			corrected.Corrections = round
+
+			// Keep whichever draft has fewer unsupported claims
+			if len(corrected.UnverifiedClaims) < len(result.UnverifiedClaims) {
+				result = corrected
+			} else {
+				result.Corrections = round
+			}
+		}
 	}
-
-	// Get the content as string
-	if resp.Choices[0].Message == nil || resp.Choices[0].Message.Content == nil {
-		return "", fmt.Errorf("empty response content")
+	result.Provenance = provenance
+	result.InjectionFlags = sections.Flags
+	result.Usage = meter.Usage()
+	result.Provider, result.Model = c.provider.Name(), c.model
+	if used := chain.Used(); used != nil {
+		result.Provider, result.Model = used.Provider.Name(), used.Model
+	}
+	if cached != nil {
+		// The last completion may have come from the cache, written by
+		// another model than the one that answered the last request
+		if source := cached.Source(); source != nil {
+			result.Provider, result.Model = source.Provider, source.Model
+		}
+		result.Cache = cached.Stats()
 	}
 
-	return *resp.Choices[0].Message.Content, nil
-}
-
-// Helper functions for pointer types
-func floatPtr(v float32) *float32 {
-	return &v
+	return result, nil
 }
 
-func int32Ptr(v int32) *int32 {
-	return &v
+// FormatBoardData returns the board data as it is sent to the model, for
+// tools that build their own prompts around it
+func FormatBoardData(boardData map[string]interface{}) (string, error) {
+	return formatBoardData(boardData)
 }
 
 // formatBoardData converts the board data to a readable format for the LLM
 func formatBoardData(boardData map[string]interface{}) (string, error) {
+	sections, err := formatBoardSections(boardData)
+	if err != nil {
+		return "", err
+	}
+
+	return sections.String(), nil
+}
+
+// formatBoardSections formats the board data as separate sections for the
+// board header, each list and each action, so large boards can be split up
+func formatBoardSections(boardData map[string]interface{}) (*boardSections, error) {
 	// Extract board information
 	board, ok := boardData["board"].(map[string]interface{})
 	if !ok {
-		return "", fmt.Errorf("invalid board data format")
+		return nil, fmt.Errorf("invalid board data format")
 	}
 
 	boardName, _ := board["name"].(string)
@@ -178,31 +388,31 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 	// Extract lists
 	listsData, ok := boardData["lists"]
 	if !ok {
-		return "", fmt.Errorf("missing lists data")
+		return nil, fmt.Errorf("missing lists data")
 	}
 	lists, err := extractMaps(listsData)
 	if err != nil {
-		return "", fmt.Errorf("invalid lists data: %v", err)
+		return nil, fmt.Errorf("invalid lists data: %v", err)
 	}
 
 	// Extract cards
 	cardsData, ok := boardData["cards"]
 	if !ok {
-		return "", fmt.Errorf("missing cards data")
+		return nil, fmt.Errorf("missing cards data")
 	}
 	cards, err := extractMaps(cardsData)
 	if err != nil {
-		return "", fmt.Errorf("invalid cards data: %v", err)
+		return nil, fmt.Errorf("invalid cards data: %v", err)
 	}
 
 	// Extract members
 	membersData, ok := boardData["members"]
 	if !ok {
-		return "", fmt.Errorf("missing members data")
+		return nil, fmt.Errorf("missing members data")
 	}
 	members, err := extractMaps(membersData)
 	if err != nil {
-		return "", fmt.Errorf("invalid members data: %v", err)
+		return nil, fmt.Errorf("invalid members data: %v", err)
 	}
 
 	// Extract actions (activities)
@@ -215,11 +425,13 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 
 	// Build summary
 	var summary string
+	sections := &boardSections{BoardName: boardName}
+	sections.ActivityTruncated, _ = boardData["activities_truncated"].(bool)
 
 	// Board info
-	summary += fmt.Sprintf("# Board: %s\n\n", boardName)
+	summary += fmt.Sprintf("# Board: %s\n\n", sections.untrusted("board name", boardName))
 	if boardDesc != "" {
-		summary += fmt.Sprintf("Description: %s\n\n", boardDesc)
+		summary += fmt.Sprintf("Description: %s\n\n", sections.untrusted("board description", boardDesc))
 	}
 
 	// Members
@@ -227,12 +439,12 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 	for _, member := range members {
 		memberName, _ := member["fullName"].(string)
 		memberUsername, _ := member["username"].(string)
-		summary += fmt.Sprintf("- %s (@%s)\n", memberName, memberUsername)
+		summary += fmt.Sprintf("- %s (@%s)\n", sections.untrusted("member name", memberName), memberUsername)
 	}
 	summary += "\n"
+	sections.Header = summary
 
 	// Lists and cards
-	summary += fmt.Sprintf("## Lists and Cards\n\n")
 	
 	// Create a map of list IDs to names
 	listMap := make(map[string]string)
@@ -257,25 +469,29 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 		listID, _ := list["id"].(string)
 		listName, _ := list["name"].(string)
 		
-		summary += fmt.Sprintf("### List: %s\n\n", listName)
+		// The name is quoted where the list is rendered; this only records
+		// it if it looks like instructions
+		section := listSection{Name: listName}
+		sections.untrusted("list name", listName)
 		
 		// Get cards for this list
 		listCards, exists := cardsByList[listID]
 		if !exists || len(listCards) == 0 {
-			summary += "No cards in this list.\n\n"
+			sections.Lists = append(sections.Lists, section)
 			continue
 		}
 		
 		for _, card := range listCards {
+			summary := ""
 			cardName, _ := card["name"].(string)
 			cardDesc, _ := card["desc"].(string)
 			cardDue, _ := card["due"].(string)
 			cardLabels, _ := card["labels"].([]interface{})
 			
-			summary += fmt.Sprintf("#### Card: %s\n\n", cardName)
+			summary += fmt.Sprintf("#### Card: %s\n\n", sections.untrusted("card name", cardName))
 			
 			if cardDesc != "" {
-				summary += fmt.Sprintf("Description: %s\n\n", cardDesc)
+				summary += fmt.Sprintf("Description: %s\n\n", sections.untrusted(fmt.Sprintf("description of card %q", cardName), cardDesc))
 			}
 			
 			if cardDue != "" {
@@ -292,23 +508,19 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 						if i > 0 {
 							summary += ", "
 						}
-						summary += fmt.Sprintf("%s (%s)", labelName, labelColor)
+						summary += fmt.Sprintf("%s (%s)", sections.untrusted(fmt.Sprintf("label on card %q", cardName), labelName), labelColor)
 					}
 				}
 				summary += "\n\n"
 			}
+
+			section.Cards = append(section.Cards, summary)
 		}
+
+		sections.Lists = append(sections.Lists, section)
 	}
 
 	// Recent activity
-	summary += fmt.Sprintf("## Recent Activity (%d actions)\n\n", len(actions))
-	
-	// Only include the 20 most recent actions to keep the summary concise
-	maxActions := 20
-	if len(actions) > maxActions {
-		actions = actions[:maxActions]
-	}
-	
 	for _, action := range actions {
 		actionType, _ := action["type"].(string)
 		date, _ := action["date"].(string)
@@ -329,7 +541,7 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 			cardName, _ := card["name"].(string)
 			list, _ := data["list"].(map[string]interface{})
 			listName, _ := list["name"].(string)
-			actionDesc = fmt.Sprintf("Created card '%s' in list '%s'", cardName, listName)
+			actionDesc = fmt.Sprintf("Created card %s in list %s", quoteUntrusted(cardName), quoteUntrusted(listName))
 		case "updateCard":
 			card, _ := data["card"].(map[string]interface{})
 			cardName, _ := card["name"].(string)
@@ -337,69 +549,28 @@ func formatBoardData(boardData map[string]interface{}) (string, error) {
 				listAfterName, _ := listAfter["name"].(string)
 				listBefore, _ := data["listBefore"].(map[string]interface{})
 				listBeforeName, _ := listBefore["name"].(string)
-				actionDesc = fmt.Sprintf("Moved card '%s' from list '%s' to '%s'", cardName, listBeforeName, listAfterName)
+				actionDesc = fmt.Sprintf("Moved card %s from list %s to %s", quoteUntrusted(cardName), quoteUntrusted(listBeforeName), quoteUntrusted(listAfterName))
 			} else {
-				actionDesc = fmt.Sprintf("Updated card '%s'", cardName)
+				actionDesc = fmt.Sprintf("Updated card %s", quoteUntrusted(cardName))
 			}
 		case "commentCard":
 			card, _ := data["card"].(map[string]interface{})
 			cardName, _ := card["name"].(string)
 			text, _ := data["text"].(string)
-			actionDesc = fmt.Sprintf("Commented on card '%s': %s", cardName, text)
+			actionDesc = fmt.Sprintf("Commented on card %s: %s", quoteUntrusted(cardName), sections.untrusted(fmt.Sprintf("comment by %s on card %q", memberName, cardName), text))
 		default:
-			actionDesc = fmt.Sprintf("Action of type '%s'", actionType)
+			actionDesc = fmt.Sprintf("Action of type %s", quoteUntrusted(actionType))
 		}
 		
 		// Add the action to the summary
-		summary += fmt.Sprintf("- %s: %s (%s)\n", memberName, actionDesc, date)
+		sections.Activity = append(sections.Activity, fmt.Sprintf("- %s: %s (%s)\n", quoteUntrusted(memberName), actionDesc, date))
 	}
 	
-	return summary, nil
+	return sections, nil
 }
 
-// getReportSystemPrompt returns the system prompt for the specified report type
-func getReportSystemPrompt(reportType string) string {
-	switch reportType {
-	case "weekly":
-		return `You are an AI assistant that generates weekly reports for Trello boards. 
-Your task is to analyze the board data provided and create a comprehensive weekly report.
-
-The report should include:
-1. A summary of the board's current state
-2. Progress made during the week (completed tasks, moved cards)
-3. Pending tasks and their status
-4. Any blockers or issues identified
-5. Recommendations for the upcoming week
-
-Be concise but thorough. Use markdown formatting to make the report readable.
-Start with an executive summary, then break down the details by list/category.
-Highlight important metrics and trends.
-
-Your report should be professional and actionable, providing clear insights into the project's progress.`
-
-	case "monthly":
-		return `You are an AI assistant that generates monthly reports for Trello boards. 
-Your task is to analyze the board data provided and create a comprehensive monthly report.
-
-The report should include:
-1. An executive summary of the month's progress
-2. Key achievements and milestones reached
-3. Detailed analysis of completed work
-4. Current status of ongoing tasks
-5. Blockers and challenges encountered
-6. Trends and patterns observed
-7. Strategic recommendations for the next month
-
-Use markdown formatting to structure the report clearly.
-Include metrics where possible, such as completion rates, task distribution, etc.
-Compare the current state with previous periods if the data allows.
-
-Your report should be thorough, insightful, and provide strategic value to the project stakeholders.`
-
-	default:
-		return `You are an AI assistant that generates reports for Trello boards.
-Analyze the board data provided and create a comprehensive report.
-Use markdown formatting to make the report readable and well-structured.
-Focus on providing actionable insights and clear status updates.`
-	}
+// getReportSystemPrompt renders the built-in prompt template for the
+// specified report type
+func getReportSystemPrompt(boardData map[string]interface{}, reportType string) (string, error) {
+	return prompts.Render(prompts.Builtin(reportType).Text, prompts.NewData(boardData, reportType, time.Time{}, time.Time{}))
 }
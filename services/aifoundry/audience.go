@@ -0,0 +1,63 @@
+package aifoundry
+
+import (
+	"fmt"
+	"strings"
+
+	"agents_go/models"
+	"agents_go/services/redact"
+)
+
+// audiencePrompt asks the model to write for an audience: its own
+// instructions, the sections to leave empty and how many items to list. It
+// returns an empty string for the standard report.
+func audiencePrompt(audience models.Audience) string {
+	profile := audience.Profile()
+	if profile.Prompt == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Audience: " + profile.Prompt)
+	var hidden []string
+	for _, section := range (&models.StructuredReport{}).Sections(models.Weekly, models.English) {
+		if !profile.Shows(section.Key) {
+			hidden = append(hidden, section.Key)
+		}
+	}
+	if len(hidden) > 0 {
+		sb.WriteString(fmt.Sprintf("\nThis audience doesn't see these fields, so return them empty: %s.", strings.Join(hidden, ", ")))
+	}
+	if n := profile.MaxItems(); n > 0 {
+		sb.WriteString(fmt.Sprintf("\nList at most %d items in each array, the most important first.", n))
+	}
+	if profile.HideMembers {
+		sb.WriteString("\nLeave every owner empty.")
+	}
+	return sb.String()
+}
+
+// ForAudience fits the report to an audience: sections and details the
+// audience doesn't see are removed, member names too if it mustn't see
+// them, and the content is rendered again. The model is asked for this
+// already; this makes sure of it.
+func (r *ReportResult) ForAudience(audience models.Audience, boardData map[string]interface{}, reportType string, lang models.Language) {
+	if r.Structured == nil || audience == models.AudienceStandard {
+		return
+	}
+	r.Structured.ApplyAudience(audience, memberNames(boardData), lang)
+	r.Content = r.Structured.Markdown(models.ReportType(reportType), lang)
+}
+
+// memberNames returns the full names, first names and usernames of the
+// board's members and of everyone in the activity
+func memberNames(boardData map[string]interface{}) []string {
+	var names []string
+	for _, member := range redact.MembersFromBoard(boardData) {
+		names = append(names, member.FullName, member.Username)
+		if first := strings.Fields(member.FullName); len(first) > 1 {
+			names = append(names, first[0])
+		}
+	}
+	return names
+}
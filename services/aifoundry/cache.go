@@ -0,0 +1,170 @@
+package aifoundry
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"agents_go/services/llm"
+)
+
+// CacheStats counts the completions served from the cache while generating
+// a report
+type CacheStats struct {
+	Hits     int
+	Misses   int
+	Bypassed bool
+}
+
+// Status summarizes the stats as "hit", "miss", "partial" or "bypassed"
+func (s CacheStats) Status() string {
+	switch {
+	case s.Bypassed:
+		return "bypassed"
+	case s.Hits > 0 && s.Misses == 0:
+		return "hit"
+	case s.Hits > 0:
+		return "partial"
+	case s.Misses > 0:
+		return "miss"
+	}
+	return ""
+}
+
+// cachingProvider serves completions from the cache and stores the ones it
+// has to request, if acceptCompletion accepts them. With bypass set it skips
+// the lookup but still refreshes the cache with the new completions. Entries record the provider and model
+// that wrote them, as reported by used, so a hit on a fallback model's
+// completion isn't credited to the primary.
+type cachingProvider struct {
+	llm.Provider
+	cache  *llm.Cache
+	bypass bool
+	used   func() *llm.Target
+
+	mutex  sync.Mutex
+	stats  CacheStats
+	source *llm.CacheSource
+}
+
+// newCachingProvider wraps a provider with the cache; used returns the
+// target that answered the provider's last request, or nil if unknown
+func newCachingProvider(provider llm.Provider, cache *llm.Cache, bypass bool, used func() *llm.Target) *cachingProvider {
+	return &cachingProvider{
+		Provider: provider,
+		cache:    cache,
+		bypass:   bypass,
+		used:     used,
+		stats:    CacheStats{Bypassed: bypass},
+	}
+}
+
+// Complete returns the cached response or requests and caches a new one
+func (p *cachingProvider) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	key := llm.CacheKey(p.Name(), req)
+	if resp, ok := p.lookup(key, req); ok {
+		return resp, nil
+	}
+
+	resp, err := p.Provider.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.store(key, req, resp)
+
+	return resp, nil
+}
+
+// Stream replays a cached response as a single chunk or streams and caches
+// a new one
+func (p *cachingProvider) Stream(ctx context.Context, req llm.Request, onDelta func(delta string) error) (*llm.Response, error) {
+	key := llm.CacheKey(p.Name(), req)
+	if resp, ok := p.lookup(key, req); ok {
+		if resp.Content != "" {
+			if err := onDelta(resp.Content); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+
+	resp, err := p.Provider.Stream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	p.store(key, req, resp)
+
+	return resp, nil
+}
+
+// lookup returns the cached response for a request and counts the hit or
+// miss. Entries acceptCompletion rejects, cached before it checked them,
+// are misses.
+func (p *cachingProvider) lookup(key string, req llm.Request) (*llm.Response, bool) {
+	var resp *llm.Response
+	var source *llm.CacheSource
+	ok := false
+	if !p.bypass {
+		resp, source, ok = p.cache.Get(key)
+		ok = ok && acceptCompletion(req, resp)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ok {
+		p.stats.Hits++
+		p.source = source
+	} else {
+		p.stats.Misses++
+	}
+
+	return resp, ok
+}
+
+// store caches a complete response; truncated responses and ones
+// acceptCompletion rejects aren't reused
+func (p *cachingProvider) store(key string, req llm.Request, resp *llm.Response) {
+	source := llm.CacheSource{Provider: p.Name(), Model: resp.Model}
+	if p.used != nil {
+		if target := p.used(); target != nil {
+			source = llm.CacheSource{Provider: target.Provider.Name(), Model: target.Model}
+		}
+	}
+
+	p.mutex.Lock()
+	p.source = &source
+	p.mutex.Unlock()
+
+	if resp.FinishReason == "length" || !acceptCompletion(req, resp) {
+		return
+	}
+	if err := p.cache.Put(key, source, resp); err != nil {
+		log.Printf("Error caching completion: %v", err)
+	}
+}
+
+// acceptCompletion reports whether a completion may be cached. Structured
+// reports must pass the validation completeStructured applies, or a draft
+// it rejected would be served again on every attempt instead of repaired.
+func acceptCompletion(req llm.Request, resp *llm.Response) bool {
+	if req.ResponseFormat == nil || req.ResponseFormat.Name != structuredReportFormat {
+		return true
+	}
+	_, problems := parseStructuredReport(resp.Content)
+	return len(problems) == 0
+}
+
+// Stats returns the hits and misses so far
+func (p *cachingProvider) Stats() CacheStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats
+}
+
+// Source returns the provider and model that wrote the last completion,
+// whether it came from the cache or not, or nil if there was none
+func (p *cachingProvider) Source() *llm.CacheSource {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.source
+}
@@ -0,0 +1,174 @@
+package aifoundry
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/llm"
+	"agents_go/services/redact"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+func TestGenerateReportCache(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+	boardData, err := trello.NewClient(fixture.AccessToken, fixture.AccessSecret).GetBoardData("board-1", time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Error getting board data: %v", err)
+	}
+
+	// The primary model always fails, so the backup writes every report
+	newFake := func(name string) *llm.FakeProvider {
+		provider, err := llm.NewProvider(config.LLMProviderConfig{Name: name, Type: "fake", Model: name + "-model", ContextWindow: 32768})
+		if err != nil {
+			t.Fatalf("Error creating fake LLM: %v", err)
+		}
+		return provider.(*llm.FakeProvider)
+	}
+	primary, backup := newFake("primary"), newFake("backup")
+	primary.SetHandler(func(req llm.Request) (*llm.Response, error) {
+		return nil, &llm.APIError{StatusCode: http.StatusUnauthorized, Body: "invalid key"}
+	})
+	registry := llm.NewRegistry("primary")
+	registry.Register(primary)
+	registry.Register(backup)
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("Error creating LLM client: %v", err)
+	}
+	client.fallbacks = []llm.Target{{Provider: backup, Model: "backup-model"}}
+	cache, err := llm.NewCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Error creating cache: %v", err)
+	}
+	client = client.WithCache(cache).WithRetryPolicy(llm.RetryPolicy{MaxAttempts: 1})
+
+	members := redact.MembersFromBoard(boardData)
+	moreRules := models.DefaultRedactionRules()
+	moreRules.Patterns = []string{"Stripe"}
+
+	tests := []struct {
+		name            string
+		rules           models.RedactionRules
+		forceRegenerate bool
+		wantCache       string
+	}{
+		{name: "first report", rules: models.DefaultRedactionRules(), wantCache: "miss"},
+		{name: "same input", rules: models.DefaultRedactionRules(), wantCache: "hit"},
+		{name: "redaction rules changed", rules: moreRules, wantCache: "miss"},
+		{name: "same input under the new rules", rules: moreRules, wantCache: "hit"},
+		{name: "regeneration forced", rules: models.DefaultRedactionRules(), forceRegenerate: true, wantCache: "bypassed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := redact.New(tt.rules, members)
+			if err != nil {
+				t.Fatalf("Error creating redactor: %v", err)
+			}
+			requests := len(backup.Requests())
+
+			result, err := client.GenerateReport(context.Background(), boardData, "weekly", ReportOptions{
+				Redactor:        redactor,
+				ForceRegenerate: tt.forceRegenerate,
+			})
+			if err != nil {
+				t.Fatalf("Error generating report: %v", err)
+			}
+
+			if got := result.Cache.Status(); got != tt.wantCache {
+				t.Errorf("cache status is %q, want %q", got, tt.wantCache)
+			}
+			hit := tt.wantCache == "hit"
+			if sent := len(backup.Requests()) > requests; sent == hit {
+				t.Errorf("requests sent to the model: %v, want %v", sent, !hit)
+			}
+
+			// Hits name the model that wrote the cached report, and the
+			// redactor still runs on them
+			if result.Provider != "backup" || result.Model != "backup-model" {
+				t.Errorf("report written by %s/%s, want backup/backup-model", result.Provider, result.Model)
+			}
+			if len(redactor.Counts()) == 0 {
+				t.Error("nothing was redacted")
+			}
+		})
+	}
+}
+
+func TestGenerateReportCacheInvalidDraft(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+	boardData, err := trello.NewClient(fixture.AccessToken, fixture.AccessSecret).GetBoardData("board-1", time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Error getting board data: %v", err)
+	}
+
+	provider, err := llm.NewProvider(config.LLMProviderConfig{Name: "fake", Type: "fake", Model: "fake-model", ContextWindow: 32768})
+	if err != nil {
+		t.Fatalf("Error creating fake LLM: %v", err)
+	}
+	fake := provider.(*llm.FakeProvider)
+	registry := llm.NewRegistry("fake")
+	registry.Register(fake)
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("Error creating LLM client: %v", err)
+	}
+	cache, err := llm.NewCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Error creating cache: %v", err)
+	}
+	client = client.WithCache(cache)
+
+	// The first draft fails validation and is repaired; only the repaired
+	// report may be cached
+	fake.Push(llm.FakeResponse{Content: "not a report"})
+
+	tests := []struct {
+		name      string
+		wantCache string
+		// wantRequests is the number of completions requested from the model
+		wantRequests int
+	}{
+		{name: "invalid draft repaired", wantCache: "miss", wantRequests: 2},
+		{name: "invalid draft not served from the cache", wantCache: "miss", wantRequests: 1},
+		{name: "valid report served from the cache", wantCache: "hit", wantRequests: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := len(fake.Requests())
+
+			result, err := client.GenerateReport(context.Background(), boardData, "weekly", ReportOptions{})
+			if err != nil {
+				t.Fatalf("Error generating report: %v", err)
+			}
+
+			if got := result.Cache.Status(); got != tt.wantCache {
+				t.Errorf("cache status is %q, want %q", got, tt.wantCache)
+			}
+			if got := len(fake.Requests()) - requests; got != tt.wantRequests {
+				t.Errorf("model got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
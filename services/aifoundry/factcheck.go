@@ -0,0 +1,483 @@
+package aifoundry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agents_go/models"
+)
+
+// factCheckPeriodDays is the period assumed when the report's dates aren't
+// known
+const factCheckPeriodDays = 31
+
+var (
+	// quotedPattern finds quoted phrases, which reports use for card names
+	quotedPattern = regexp.MustCompile(`["“]([^"“”\n]{3,100})["”]`)
+	// mentionPattern finds @username mentions
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w.])@([A-Za-z0-9_]{2,})`)
+	// isoDatePattern finds dates such as 2026-10-18
+	isoDatePattern = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	// monthDayPattern finds dates such as "Oct 18" or "October 18, 2026"
+	monthDayPattern = regexp.MustCompile(`\b(` + monthNames + `)\.? (\d{1,2})(?:st|nd|rd|th)?(?:,? (\d{4}))?\b`)
+	// dayMonthPattern finds dates such as "18 October 2026"
+	dayMonthPattern = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)? (` + monthNames + `)\.?(?: (\d{4}))?\b`)
+	// countPattern finds counts such as "3 overdue cards" or "2 cards were
+	// completed"
+	countPattern = regexp.MustCompile(`(?i)\b(\d+|` + numberWords + `) (?:(open|overdue|finished|completed|done|new|unassigned|active) )?(cards?|tasks?|members?|comments?)(?: (?:were|was|have been|has been|are|is) (completed|finished|done|moved|created|added|overdue|open|unassigned))?\b`)
+	// ownerSeparator splits lists of owners such as "Priya and Jonas"
+	ownerSeparator = regexp.MustCompile(`\s*(?:,|&|/|\band\b)\s*`)
+	// noOwnerPattern matches owners that don't name a member
+	noOwnerPattern = regexp.MustCompile(`(?i)^(unassigned|none|nobody|no one|n/a|tbd|team|the team|everyone|all)$`)
+)
+
+const (
+	monthNames  = `Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sept?(?:ember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?`
+	numberWords = `one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve`
+)
+
+// factChecker verifies the cards, members, dates and counts a report
+// mentions against the board data it was generated from
+type factChecker struct {
+	// cards holds the normalized names of the board's cards, including
+	// cards only seen in the activity
+	cards map[string]bool
+	// names holds other names a report may quote: lists, labels and the
+	// board itself
+	names map[string]bool
+	// texts holds descriptions and comments, which may be quoted
+	texts []string
+	// members holds normalized full names, first names and usernames
+	members   map[string]bool
+	usernames map[string]bool
+	// dates holds the days of due dates and activity, as 2006-01-02
+	dates map[string]bool
+	// counts holds the numbers a count may refer to, by category
+	counts     map[string][]int
+	start, end time.Time
+}
+
+// newFactChecker collects what the board data can verify. Dates between
+// start and end, the report's period, are always accepted.
+func newFactChecker(boardData map[string]interface{}, start, end time.Time) (*factChecker, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.AddDate(0, 0, -factCheckPeriodDays)
+	}
+
+	stats, err := computeBoardStats(boardData, end)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &factChecker{
+		cards:     make(map[string]bool),
+		names:     map[string]bool{normalizeClaim(stats.BoardName): true},
+		members:   make(map[string]bool),
+		usernames: make(map[string]bool),
+		dates:     make(map[string]bool),
+		counts:    make(map[string][]int),
+		start:     start,
+		end:       end,
+	}
+
+	for _, list := range boardItems(boardData["lists"]) {
+		name, _ := list["name"].(string)
+		f.names[normalizeClaim(name)] = true
+	}
+
+	for _, member := range boardItems(boardData["members"]) {
+		fullName, _ := member["fullName"].(string)
+		username, _ := member["username"].(string)
+		f.addMember(fullName, username)
+	}
+
+	for _, card := range boardItems(boardData["cards"]) {
+		name, _ := card["name"].(string)
+		desc, _ := card["desc"].(string)
+		f.cards[normalizeClaim(name)] = true
+		if desc != "" {
+			f.texts = append(f.texts, strings.ToLower(desc))
+		}
+		for _, label := range boardItems(card["labels"]) {
+			labelName, _ := label["name"].(string)
+			f.names[normalizeClaim(labelName)] = true
+		}
+		for _, key := range []string{"due", "dateLastActivity"} {
+			f.addDate(card[key])
+		}
+	}
+
+	for _, action := range boardItems(boardData["activities"]) {
+		f.addDate(action["date"])
+		data, _ := action["data"].(map[string]interface{})
+		if card, ok := data["card"].(map[string]interface{}); ok {
+			name, _ := card["name"].(string)
+			f.cards[normalizeClaim(name)] = true
+		}
+		if text, ok := data["text"].(string); ok {
+			f.texts = append(f.texts, strings.ToLower(text))
+		}
+		// Authors of activity may have left the board
+		if creator, ok := action["memberCreator"].(map[string]interface{}); ok {
+			fullName, _ := creator["fullName"].(string)
+			username, _ := creator["username"].(string)
+			f.addMember(fullName, username)
+		}
+	}
+	delete(f.cards, "")
+	delete(f.names, "")
+
+	// Counts a report may give, by what they count
+	total := stats.OpenCards + stats.DoneCards
+	f.counts["open"] = []int{stats.OpenCards}
+	f.counts["overdue"] = []int{len(stats.Overdue)}
+	f.counts["done"] = []int{stats.DoneCards, len(stats.Completed)}
+	f.counts["new"] = []int{stats.Created}
+	f.counts["moved"] = []int{stats.Moved}
+	f.counts["unassigned"] = []int{stats.Unassigned}
+	f.counts["members"] = []int{len(stats.Members)}
+	f.counts["comments"] = []int{stats.Comments}
+	f.counts["cards"] = []int{total, stats.OpenCards, stats.DoneCards, len(stats.Completed), len(stats.Overdue), len(stats.DueSoon), stats.Created, stats.Moved, stats.Unassigned}
+	for _, list := range stats.Lists {
+		f.counts["cards"] = append(f.counts["cards"], list.Cards)
+		if !list.Done {
+			f.counts["open"] = append(f.counts["open"], list.Cards)
+		}
+	}
+	for _, member := range stats.Members {
+		f.counts["cards"] = append(f.counts["cards"], len(member.Open), len(member.Done), len(member.Open)+len(member.Done), member.Moves)
+		f.counts["open"] = append(f.counts["open"], len(member.Open))
+		f.counts["done"] = append(f.counts["done"], len(member.Done))
+	}
+
+	return f, nil
+}
+
+// addMember adds the names a member may be referred to by
+func (f *factChecker) addMember(fullName, username string) {
+	if fullName != "" {
+		f.members[normalizeClaim(fullName)] = true
+		if fields := strings.Fields(fullName); len(fields) > 1 {
+			f.members[normalizeClaim(fields[0])] = true
+		}
+	}
+	if username != "" {
+		f.members[normalizeClaim(username)] = true
+		f.usernames[strings.ToLower(username)] = true
+	}
+}
+
+// addDate adds the day of an RFC 3339 timestamp
+func (f *factChecker) addDate(value interface{}) {
+	s, _ := value.(string)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		f.dates[t.UTC().Format("2006-01-02")] = true
+	}
+}
+
+// check returns the claims in the report that the board data doesn't
+// support, in report order and without duplicates
+func (f *factChecker) check(report *models.StructuredReport, reportType string, lang models.Language) []models.Claim {
+	if report == nil {
+		return nil
+	}
+
+	var claims []models.Claim
+	seen := make(map[string]bool)
+	add := func(claim *models.Claim) {
+		if claim == nil {
+			return
+		}
+		key := claim.Kind + "\x00" + strings.ToLower(claim.Text)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		claims = append(claims, *claim)
+	}
+
+	// Use the section titles shown to readers. Sections the report's
+	// audience doesn't see have been emptied, so they have no claims.
+	titles := make(map[string]string)
+	for _, section := range (&models.StructuredReport{}).Sections(models.ReportType(reportType), lang) {
+		titles[section.Key] = section.Title
+	}
+	summary, progress, status := titles[models.SectionSummary], titles[models.SectionProgress], titles[models.SectionStatus]
+	priorities, risks, team := titles[models.SectionPriorities], titles[models.SectionRisks], titles[models.SectionContributions]
+
+	f.checkText(summary, report.ExecutiveSummary, add)
+	f.checkText(status, report.CurrentStatus, add)
+	for _, item := range report.Progress {
+		f.checkText(progress, item.Title+"\n"+item.Detail, add)
+		f.checkCards(progress, item.Cards, add)
+	}
+	for _, item := range report.Priorities {
+		f.checkText(priorities, item.Title, add)
+		f.checkCards(priorities, item.Cards, add)
+		f.checkOwners(priorities, item.Owner, add)
+		if item.Due != "" {
+			add(f.checkDue(priorities, item.Due))
+		}
+	}
+	for _, item := range report.Risks {
+		f.checkText(risks, item.Description+"\n"+item.Mitigation, add)
+		f.checkCards(risks, item.Cards, add)
+	}
+	for _, item := range report.Contributions {
+		f.checkText(team, item.Summary, add)
+		f.checkCards(team, item.Cards, add)
+		f.checkOwners(team, item.Member, add)
+	}
+
+	return claims
+}
+
+// checkCards checks the card names listed for a report item
+func (f *factChecker) checkCards(section string, cards []string, add func(*models.Claim)) {
+	for _, card := range cards {
+		if strings.TrimSpace(card) == "" || f.isCard(card) {
+			continue
+		}
+		add(&models.Claim{Kind: models.ClaimCard, Text: card, Section: section, Reason: "No card with this name on the board"})
+	}
+}
+
+// checkOwners checks the members named as an owner or contributor
+func (f *factChecker) checkOwners(section, owners string, add func(*models.Claim)) {
+	for _, owner := range ownerSeparator.Split(owners, -1) {
+		owner = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(owner), "@"))
+		if owner == "" || noOwnerPattern.MatchString(owner) || f.members[normalizeClaim(owner)] {
+			continue
+		}
+		add(&models.Claim{Kind: models.ClaimMember, Text: owner, Section: section, Reason: "No board member with this name"})
+	}
+}
+
+// checkDue checks a due date given as YYYY-MM-DD or in words
+func (f *factChecker) checkDue(section, due string) *models.Claim {
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(due)); err == nil {
+		return f.checkDate(section, due, t)
+	}
+	var claim *models.Claim
+	f.checkDates(section, due, func(c *models.Claim) {
+		if claim == nil {
+			claim = c
+		}
+	})
+	return claim
+}
+
+// checkText checks the quoted names, mentions, dates and counts in text
+func (f *factChecker) checkText(section, text string, add func(*models.Claim)) {
+	for _, match := range quotedPattern.FindAllStringSubmatch(text, -1) {
+		quoted := match[1]
+		if f.isCard(quoted) || f.names[normalizeClaim(quoted)] || f.members[normalizeClaim(quoted)] || f.isQuote(quoted) {
+			continue
+		}
+		add(&models.Claim{Kind: models.ClaimCard, Text: quoted, Section: section, Reason: "Quoted name doesn't match a card, list or label on the board"})
+	}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if f.usernames[strings.ToLower(match[1])] {
+			continue
+		}
+		add(&models.Claim{Kind: models.ClaimMember, Text: "@" + match[1], Section: section, Reason: "No board member with this username"})
+	}
+
+	f.checkDates(section, text, add)
+
+	for _, match := range countPattern.FindAllStringSubmatch(text, -1) {
+		n, ok := parseCount(match[1])
+		if !ok {
+			continue
+		}
+		category := countCategory(match[2], match[3], match[4])
+		if containsInt(f.counts[category], n) {
+			continue
+		}
+		add(&models.Claim{Kind: models.ClaimCount, Text: strings.TrimSpace(match[0]), Section: section, Reason: fmt.Sprintf("The board data gives %s", describeCounts(category, f.counts[category]))})
+	}
+}
+
+// checkDates checks the dates written in text
+func (f *factChecker) checkDates(section, text string, add func(*models.Claim)) {
+	for _, match := range isoDatePattern.FindAllString(text, -1) {
+		if t, err := time.Parse("2006-01-02", match); err == nil {
+			add(f.checkDate(section, match, t))
+		}
+	}
+	for _, match := range monthDayPattern.FindAllStringSubmatch(text, -1) {
+		add(f.checkWrittenDate(section, match[0], match[1], match[2], match[3]))
+	}
+	for _, match := range dayMonthPattern.FindAllStringSubmatch(text, -1) {
+		add(f.checkWrittenDate(section, match[0], match[2], match[1], match[3]))
+	}
+}
+
+// checkWrittenDate checks a date written with a month name. Dates without a
+// year are checked in the years of the report period.
+func (f *factChecker) checkWrittenDate(section, text, month, day, year string) *models.Claim {
+	years := []string{year}
+	if year == "" {
+		years = []string{strconv.Itoa(f.start.Year()), strconv.Itoa(f.end.Year())}
+	}
+
+	var claim *models.Claim
+	for _, y := range years {
+		t, err := time.Parse("Jan 2 2006", month[:3]+" "+day+" "+y)
+		if err != nil {
+			return nil
+		}
+		if claim = f.checkDate(section, text, t); claim == nil {
+			return nil
+		}
+	}
+	return claim
+}
+
+// checkDate accepts dates in the report period and the days of due dates
+// and activity on the board
+func (f *factChecker) checkDate(section, text string, t time.Time) *models.Claim {
+	day := t.Format("2006-01-02")
+	if f.dates[day] {
+		return nil
+	}
+	start := f.start.UTC().Format("2006-01-02")
+	end := f.end.UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	if day >= start && day <= end {
+		return nil
+	}
+	return &models.Claim{Kind: models.ClaimDate, Text: text, Section: section, Reason: "Not a due date or activity date on the board, and outside the report period"}
+}
+
+// isCard reports whether a name matches a card, allowing for the model
+// shortening or extending the card's name
+func (f *factChecker) isCard(name string) bool {
+	normalized := normalizeClaim(name)
+	if f.cards[normalized] {
+		return true
+	}
+	if len(normalized) < 5 {
+		return false
+	}
+	for card := range f.cards {
+		if len(card) >= 5 && (strings.Contains(card, normalized) || strings.Contains(normalized, card)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuote reports whether quoted text is taken from a description or comment
+func (f *factChecker) isQuote(quoted string) bool {
+	lower := strings.ToLower(strings.TrimSpace(quoted))
+	for _, text := range f.texts {
+		if strings.Contains(text, lower) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeClaim lower-cases a name and strips the punctuation and spacing
+// the model may add around it
+func normalizeClaim(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.Trim(s, ` "'“”‘’.,:;!?()[]`)
+}
+
+// countCategory picks what a count refers to from the words around it
+func countCategory(adjective, noun, verb string) string {
+	noun = strings.ToLower(noun)
+	switch {
+	case strings.HasPrefix(noun, "member"):
+		return "members"
+	case strings.HasPrefix(noun, "comment"):
+		return "comments"
+	}
+
+	word := strings.ToLower(adjective)
+	if word == "" {
+		word = strings.ToLower(verb)
+	}
+	switch word {
+	case "open", "active":
+		return "open"
+	case "overdue":
+		return "overdue"
+	case "finished", "completed", "done":
+		return "done"
+	case "new", "created", "added":
+		return "new"
+	case "moved":
+		return "moved"
+	case "unassigned":
+		return "unassigned"
+	}
+	return "cards"
+}
+
+// describeCounts lists the counts a category may refer to
+func describeCounts(category string, counts []int) string {
+	unique := make(map[int]bool)
+	var values []string
+	for _, n := range counts {
+		if !unique[n] {
+			unique[n] = true
+			values = append(values, strconv.Itoa(n))
+		}
+	}
+	sort.Strings(values)
+
+	what := category
+	if category == "cards" {
+		what = "card totals"
+	} else if category != "members" && category != "comments" {
+		what = category + " card counts"
+	}
+	if len(values) == 0 {
+		return "no " + what
+	}
+	return what + " of " + strings.Join(values, ", ")
+}
+
+// parseCount parses a count written as digits or a word
+func parseCount(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	for i, word := range strings.Split(numberWords, "|") {
+		if strings.EqualFold(s, word) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func containsInt(values []int, n int) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// correctionPrompt asks the model to rewrite a report without the claims
+// the fact check couldn't verify
+func correctionPrompt(claims []models.Claim) string {
+	var sb strings.Builder
+	sb.WriteString("A previous draft of this report made claims that could not be verified against the board data:\n")
+	for _, claim := range claims {
+		sb.WriteString(fmt.Sprintf("- %s %q in %s: %s\n", claim.Kind, claim.Text, claim.Section, claim.Reason))
+	}
+	sb.WriteString("Write the report again. Correct or leave out these claims, and use only card names, member names, dates and counts that appear in the board data.")
+	return sb.String()
+}
@@ -0,0 +1,244 @@
+package aifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/llm"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+// factCheckBoardData returns the default fixture's board data for the last
+// week
+func factCheckBoardData(t *testing.T) map[string]interface{} {
+	t.Helper()
+	fixture := trellotest.DefaultFixture()
+	server := trellotest.NewServer(fixture)
+	t.Cleanup(server.Close)
+	server.Configure()
+	boardData, err := trello.NewClient(fixture.AccessToken, fixture.AccessSecret).GetBoardData("board-1", time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Error getting board data: %v", err)
+	}
+	return boardData
+}
+
+// supportedReport only mentions cards and members on the default board
+func supportedReport() *models.StructuredReport {
+	return &models.StructuredReport{
+		ExecutiveSummary: `The team finished "Write launch blog post" and is working on "Redesign landing page".`,
+		CurrentStatus:    "Work is on track.",
+		Progress: []models.ProgressItem{
+			{Title: "Launch blog post", Detail: "Published by @lucia", Status: "completed", Cards: []string{"Write launch blog post"}},
+		},
+		Priorities: []models.Priority{
+			{Title: "Fix the payment webhook", Owner: "Jonas Weber", Cards: []string{"Fix payment webhook retries"}},
+		},
+		Risks:         []models.Risk{},
+		Contributions: []models.MemberContribution{},
+	}
+}
+
+func TestFactCheck(t *testing.T) {
+	boardData := factCheckBoardData(t)
+	end := time.Now()
+	checker, err := newFactChecker(boardData, end.AddDate(0, 0, -7), end)
+	if err != nil {
+		t.Fatalf("Error creating fact checker: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// change adds claims to a report that the board supports
+		change func(report *models.StructuredReport)
+		// want are the kinds and texts of the unverified claims
+		want []models.Claim
+	}{
+		{
+			name:   "supported",
+			change: func(report *models.StructuredReport) {},
+		},
+		{
+			name: "unknown quoted card",
+			change: func(report *models.StructuredReport) {
+				report.ExecutiveSummary += ` "Migrate billing to Paddle" is almost done.`
+			},
+			want: []models.Claim{{Kind: models.ClaimCard, Text: "Migrate billing to Paddle"}},
+		},
+		{
+			name: "unknown card listed on an item",
+			change: func(report *models.StructuredReport) {
+				report.Progress[0].Cards = append(report.Progress[0].Cards, "Hire a designer")
+			},
+			want: []models.Claim{{Kind: models.ClaimCard, Text: "Hire a designer"}},
+		},
+		{
+			name: "unknown owner and mention",
+			change: func(report *models.StructuredReport) {
+				report.Priorities[0].Owner = "Jonas Weber and Marcus"
+				report.CurrentStatus += " @marcus is reviewing."
+			},
+			want: []models.Claim{
+				{Kind: models.ClaimMember, Text: "@marcus"},
+				{Kind: models.ClaimMember, Text: "Marcus"},
+			},
+		},
+		{
+			name: "date outside the board data",
+			change: func(report *models.StructuredReport) {
+				report.Priorities[0].Due = "2019-03-14"
+			},
+			want: []models.Claim{{Kind: models.ClaimDate, Text: "2019-03-14"}},
+		},
+		{
+			name: "case and whitespace don't matter",
+			change: func(report *models.StructuredReport) {
+				report.Progress[0].Cards = []string{"  write LAUNCH blog post "}
+				report.Priorities[0].Owner = "jonas weber"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := supportedReport()
+			tt.change(report)
+
+			claims := checker.check(report, "weekly", models.English)
+			got := make(map[string]bool)
+			for _, claim := range claims {
+				if claim.Reason == "" || claim.Section == "" {
+					t.Errorf("claim %q has no reason or section", claim.Text)
+				}
+				got[claim.Kind+" "+claim.Text] = true
+			}
+			for _, claim := range tt.want {
+				if !got[claim.Kind+" "+claim.Text] {
+					t.Errorf("%s claim %q wasn't flagged", claim.Kind, claim.Text)
+				}
+			}
+			if len(claims) != len(tt.want) {
+				t.Errorf("got %d unverified claims %+v, want %d", len(claims), claims, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGenerateReportCorrections(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	boardData := factCheckBoardData(t)
+
+	unsupported := supportedReport()
+	unsupported.ExecutiveSummary += ` "Migrate billing to Paddle" is almost done.`
+	reportJSON := func(report *models.StructuredReport) string {
+		data, err := json.Marshal(report)
+		if err != nil {
+			t.Fatalf("Error encoding report: %v", err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name   string
+		rounds int
+		// drafts are the reports the model writes, in order
+		drafts []*models.StructuredReport
+		// wantClaims is the number of unverified claims in the result
+		wantClaims      int
+		wantCorrections int
+		wantSummary     string
+	}{
+		{
+			name:        "supported, nothing to correct",
+			rounds:      2,
+			drafts:      []*models.StructuredReport{supportedReport()},
+			wantSummary: supportedReport().ExecutiveSummary,
+		},
+		{
+			name:        "corrections off",
+			rounds:      0,
+			drafts:      []*models.StructuredReport{unsupported},
+			wantClaims:  1,
+			wantSummary: unsupported.ExecutiveSummary,
+		},
+		{
+			name:            "corrected",
+			rounds:          2,
+			drafts:          []*models.StructuredReport{unsupported, supportedReport()},
+			wantCorrections: 1,
+			wantSummary:     supportedReport().ExecutiveSummary,
+		},
+		{
+			name:            "not improved, first draft kept",
+			rounds:          2,
+			drafts:          []*models.StructuredReport{unsupported, unsupported, unsupported},
+			wantClaims:      1,
+			wantCorrections: 2,
+			wantSummary:     unsupported.ExecutiveSummary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := llm.NewProvider(config.LLMProviderConfig{Name: "fake", Type: "fake", Model: "fake-model", ContextWindow: 32768})
+			if err != nil {
+				t.Fatalf("Error creating fake LLM: %v", err)
+			}
+			fake := provider.(*llm.FakeProvider)
+			for _, draft := range tt.drafts {
+				fake.Push(llm.FakeResponse{Content: reportJSON(draft)})
+			}
+			registry := llm.NewRegistry("fake")
+			registry.Register(fake)
+			client, err := NewClientWithRegistry(registry)
+			if err != nil {
+				t.Fatalf("Error creating LLM client: %v", err)
+			}
+
+			result, err := client.GenerateReport(context.Background(), boardData, "weekly", ReportOptions{CorrectionRounds: tt.rounds})
+			if err != nil {
+				t.Fatalf("Error generating report: %v", err)
+			}
+
+			if !result.FactChecked {
+				t.Error("report wasn't fact-checked")
+			}
+			if len(result.UnverifiedClaims) != tt.wantClaims {
+				t.Errorf("got unverified claims %+v, want %d", result.UnverifiedClaims, tt.wantClaims)
+			}
+			if result.Corrections != tt.wantCorrections {
+				t.Errorf("got %d corrections, want %d", result.Corrections, tt.wantCorrections)
+			}
+			if result.Structured.ExecutiveSummary != tt.wantSummary {
+				t.Errorf("executive summary is %q, want %q", result.Structured.ExecutiveSummary, tt.wantSummary)
+			}
+
+			// Each draft after the first was asked for with the previous
+			// draft's unsupported claims pointed out
+			requests := fake.Requests()
+			if len(requests) != len(tt.drafts) {
+				t.Fatalf("sent %d requests, want %d", len(requests), len(tt.drafts))
+			}
+			for i, req := range requests {
+				system := req.Messages[0].Content
+				corrected := strings.Contains(system, "could not be verified")
+				if corrected != (i > 0) {
+					t.Errorf("request %d asks for a correction: %v, want %v", i+1, corrected, i > 0)
+				}
+				if corrected && !strings.Contains(system, "Migrate billing to Paddle") {
+					t.Errorf("correction request %d doesn't name the unsupported claim", i+1)
+				}
+			}
+		})
+	}
+}
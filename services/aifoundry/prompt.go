@@ -0,0 +1,385 @@
+package aifoundry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+)
+
+// Report generation strategies recorded on each report
+const (
+	// StrategySingle sends the whole board in one prompt
+	StrategySingle = "single"
+	// StrategyMapReduce summarizes chunks of the board first and combines
+	// the partial summaries into the final report
+	StrategyMapReduce = "map_reduce"
+	// StrategyStatistics renders the report from board statistics without
+	// a model, when every model failed
+	StrategyStatistics = "statistics"
+)
+
+const (
+	// reportMaxTokens is the completion budget for the final report
+	reportMaxTokens = 4000
+	// summaryMaxTokens is the completion budget for each partial summary
+	summaryMaxTokens = 1000
+	// maxPromptActions is how many actions are included in a single prompt
+	maxPromptActions = 20
+	// promptSafetyMargin leaves room for token estimation errors
+	promptSafetyMargin = 0.9
+)
+
+// boardSections is the formatted board data split into pieces that can be
+// summarized independently
+type boardSections struct {
+	BoardName string
+	// Header holds the board name, description and members
+	Header string
+	Lists  []listSection
+	// Activity holds one formatted line per action, newest first
+	Activity []string
+	// ActivityTruncated is set when Trello had more actions in the period
+	// than were fetched
+	ActivityTruncated bool
+	// Flags records untrusted content that looks like instructions
+	Flags []models.InjectionFlag
+}
+
+// listSection is a formatted list with one entry per card
+type listSection struct {
+	Name  string
+	Cards []string
+}
+
+// String renders the sections as a single prompt. Only the most recent
+// actions are included to keep the summary concise.
+func (b *boardSections) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(b.Header)
+	sb.WriteString("## Lists and Cards\n\n")
+	for _, list := range b.Lists {
+		sb.WriteString(list.String())
+	}
+
+	sb.WriteString(b.activityHeading())
+	actions := b.Activity
+	if len(actions) > maxPromptActions {
+		actions = actions[:maxPromptActions]
+	}
+	for _, line := range actions {
+		sb.WriteString(line)
+	}
+
+	return boardDataBlock(sb.String())
+}
+
+// activityHeading introduces the activity log, saying when it was capped so
+// the report doesn't present a partial count as the board's whole activity
+func (b *boardSections) activityHeading() string {
+	if b.ActivityTruncated {
+		return fmt.Sprintf("## Recent Activity (%d actions, capped; older activity in the period was not fetched)\n\n", len(b.Activity))
+	}
+	return fmt.Sprintf("## Recent Activity (%d actions)\n\n", len(b.Activity))
+}
+
+// String renders a list and its cards
+func (l listSection) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### List: %s\n\n", quoteUntrusted(l.Name)))
+	if len(l.Cards) == 0 {
+		sb.WriteString("No cards in this list.\n\n")
+	}
+	for _, card := range l.Cards {
+		sb.WriteString(card)
+	}
+	return sb.String()
+}
+
+// chunks splits the lists and the full activity log into pieces of at most
+// maxTokens each. Lists are kept whole where possible and only split between
+// cards when a single list is too large.
+func (b *boardSections) chunks(maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	add := func(text string) {
+		if current.Len() > 0 && llm.EstimateTokens(current.String()+text) > maxTokens {
+			flush()
+		}
+		current.WriteString(text)
+	}
+
+	// Group lists into chunks
+	for _, list := range b.Lists {
+		text := list.String()
+		if llm.EstimateTokens(text) <= maxTokens {
+			add(text)
+			continue
+		}
+
+		// The list alone is too large, so split it between cards
+		flush()
+		heading := fmt.Sprintf("### List: %s (continued)\n\n", quoteUntrusted(list.Name))
+		current.WriteString(fmt.Sprintf("### List: %s\n\n", quoteUntrusted(list.Name)))
+		for _, card := range list.Cards {
+			if llm.EstimateTokens(current.String()+card) > maxTokens {
+				flush()
+				current.WriteString(heading)
+			}
+			current.WriteString(card)
+		}
+		flush()
+	}
+	flush()
+
+	// Group the full activity log into chunks
+	if len(b.Activity) > 0 {
+		current.WriteString(b.activityHeading())
+		for _, line := range b.Activity {
+			if llm.EstimateTokens(current.String()+line) > maxTokens {
+				flush()
+				current.WriteString("## Recent Activity (continued)\n\n")
+			}
+			current.WriteString(line)
+		}
+		flush()
+	}
+
+	return chunks
+}
+
+// ReportResult is the outcome of generating a report
+type ReportResult struct {
+	// Content is the markdown rendering of Structured
+	Content    string
+	Structured *models.StructuredReport
+	// Provenance records the tool calls made while researching the board
+	Provenance []models.ToolCallRecord
+	// Strategy is StrategySingle or StrategyMapReduce
+	Strategy string
+	// Chunks is the number of partial summaries for map-reduce reports
+	Chunks int
+	// PromptTokens is the estimated size of the final prompt
+	PromptTokens int
+	// Cache counts the completions served from the completion cache
+	Cache CacheStats
+	// Usage totals the completions requested for the report
+	Usage Usage
+	// Provider and Model are the ones that wrote the report, which differ
+	// from the client's if it fell back to another model
+	Provider string
+	Model    string
+	// WithoutAI is set for reports rendered from board statistics because
+	// no model could generate one
+	WithoutAI bool
+	// FactChecked is set when the report was checked against the board
+	// data; UnverifiedClaims are the claims it didn't support
+	FactChecked      bool
+	UnverifiedClaims []models.Claim
+	// Corrections counts the times the report was regenerated because of
+	// unverified claims
+	Corrections int
+	// InjectionFlags records board content that looked like instructions
+	// to the model
+	InjectionFlags []models.InjectionFlag
+}
+
+// promptBudget returns the number of prompt tokens that fit in the context
+// window alongside a completion of maxTokens
+func (c *AIFoundryClient) promptBudget(maxTokens int) int {
+	return int(float64(c.provider.ContextWindow()-maxTokens) * promptSafetyMargin)
+}
+
+// generateFromSections writes the report in one call when the board fits in
+// the context window, and falls back to map-reduce summarization otherwise
+func (c *AIFoundryClient) generateFromSections(ctx context.Context, sections *boardSections, reportType, reportPrompt string, progress *Progress) (*ReportResult, error) {
+	systemPrompt := reportPrompt + "\n\n" + untrustedDataPrompt() + "\n\n" + structuredOutputPrompt()
+	if prompt := audiencePrompt(c.audience); prompt != "" {
+		systemPrompt += "\n\n" + prompt
+	}
+	if prompt := languagePrompt(c.language); prompt != "" {
+		systemPrompt += "\n\n" + prompt
+	}
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: sections.String()},
+	}
+
+	budget := c.promptBudget(reportMaxTokens)
+	promptTokens := c.provider.CountTokens(messages)
+
+	// The whole board fits, so write the report directly
+	if promptTokens <= budget {
+		progress.status("Writing the report")
+		structured, _, err := c.completeStructured(ctx, messages, 0.7, reportMaxTokens, reportType, progress)
+		if err != nil {
+			return nil, err
+		}
+		return &ReportResult{
+			Content:      structured.Markdown(models.ReportType(reportType), c.language),
+			Structured:   structured,
+			Strategy:     StrategySingle,
+			PromptTokens: promptTokens,
+		}, nil
+	}
+
+	log.Printf("Board %s needs ~%d prompt tokens (budget %d), using map-reduce summarization", sections.BoardName, promptTokens, budget)
+
+	// Map: summarize each chunk of the board independently
+	chunkBudget := c.promptBudget(summaryMaxTokens) - c.provider.CountTokens([]llm.Message{
+		{Role: llm.RoleSystem, Content: getChunkSummaryPrompt(reportType)},
+		{Role: llm.RoleUser, Content: boardDataBlock(sections.Header)},
+	})
+	if chunkBudget > budget/2 {
+		chunkBudget = budget / 2
+	}
+	if chunkBudget <= 0 {
+		return nil, fmt.Errorf("context window of %d tokens is too small to summarize the board", c.provider.ContextWindow())
+	}
+
+	chunks := sections.chunks(chunkBudget)
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		progress.status(fmt.Sprintf("Summarizing part %d of %d of the board", i+1, len(chunks)))
+		summary, err := c.complete(ctx, []llm.Message{
+			{Role: llm.RoleSystem, Content: getChunkSummaryPrompt(reportType)},
+			{Role: llm.RoleUser, Content: boardDataBlock(sections.Header + chunk)},
+		}, 0.3, summaryMaxTokens)
+		if err != nil {
+			return nil, fmt.Errorf("error summarizing chunk %d of %d: %v", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	// Reduce: combine summaries in groups until they fit in one prompt
+	for {
+		combined := combineSummaries(sections.Header, summaries)
+		messages = []llm.Message{
+			{Role: llm.RoleSystem, Content: systemPrompt},
+			{Role: llm.RoleUser, Content: combined},
+		}
+		promptTokens = c.provider.CountTokens(messages)
+		if promptTokens <= budget || len(summaries) == 1 {
+			break
+		}
+
+		progress.status("Combining the summaries")
+		reduced, err := c.reduceSummaries(ctx, sections.Header, summaries, chunkBudget, reportType)
+		if err != nil {
+			return nil, err
+		}
+		summaries = reduced
+	}
+
+	progress.status("Writing the report")
+	structured, _, err := c.completeStructured(ctx, messages, 0.7, reportMaxTokens, reportType, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReportResult{
+		Content:      structured.Markdown(models.ReportType(reportType), c.language),
+		Structured:   structured,
+		Strategy:     StrategyMapReduce,
+		Chunks:       len(chunks),
+		PromptTokens: promptTokens,
+	}, nil
+}
+
+// reduceSummaries merges groups of partial summaries into fewer, shorter
+// summaries. Every group holds at least two summaries so each pass shrinks
+// the list.
+func (c *AIFoundryClient) reduceSummaries(ctx context.Context, header string, summaries []string, maxTokens int, reportType string) ([]string, error) {
+	var reduced []string
+	var group []string
+
+	merge := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+		if len(group) == 1 {
+			reduced = append(reduced, group[0])
+			group = nil
+			return nil
+		}
+		summary, err := c.complete(ctx, []llm.Message{
+			{Role: llm.RoleSystem, Content: getChunkSummaryPrompt(reportType)},
+			{Role: llm.RoleUser, Content: combineSummaries(header, group)},
+		}, 0.3, summaryMaxTokens)
+		if err != nil {
+			return fmt.Errorf("error combining summaries: %v", err)
+		}
+		reduced = append(reduced, summary)
+		group = nil
+		return nil
+	}
+
+	for _, summary := range summaries {
+		if len(group) >= 2 && llm.EstimateTokens(strings.Join(append(group, summary), "\n\n")) > maxTokens {
+			if err := merge(); err != nil {
+				return nil, err
+			}
+		}
+		group = append(group, summary)
+	}
+	if err := merge(); err != nil {
+		return nil, err
+	}
+
+	return reduced, nil
+}
+
+// combineSummaries builds the prompt holding the partial summaries
+func combineSummaries(header string, summaries []string) string {
+	var sb strings.Builder
+	sb.WriteString(header)
+	sb.WriteString("## Partial Summaries\n\n")
+	sb.WriteString("The board was too large to include in full. Each part below summarizes a portion of its lists, cards and activity.\n\n")
+	for i, summary := range summaries {
+		sb.WriteString(fmt.Sprintf("### Part %d\n\n%s\n\n", i+1, boardDataTags.Replace(strings.TrimSpace(summary))))
+	}
+	return boardDataBlock(sb.String())
+}
+
+// complete sends messages to the provider and returns the content
+func (c *AIFoundryClient) complete(ctx context.Context, messages []llm.Message, temperature float32, maxTokens int) (string, error) {
+	resp, err := c.provider.Complete(ctx, llm.Request{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Content, nil
+}
+
+// getChunkSummaryPrompt returns the system prompt for partial summaries
+func getChunkSummaryPrompt(reportType string) string {
+	return fmt.Sprintf(`You are an AI assistant helping to write a %s report for a Trello board.
+You are given one portion of the board's data (some of its lists and cards, or part of its activity log), or several earlier partial summaries to merge.
+
+Summarize this portion factually for the report writer:
+- Work completed or moved forward, naming the cards involved
+- Work in progress and its status, including due dates
+- Blockers, overdue cards and risks
+- Who was active and on what
+
+Keep card and member names exactly as written. Do not speculate about data you were not given.
+Use concise markdown bullet points.
+
+%s`, reportType, untrustedDataPrompt())
+}
@@ -0,0 +1,101 @@
+package aifoundry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"agents_go/services/llm"
+	"agents_go/services/redact"
+)
+
+// questionMaxTokens is the completion budget for an answer
+const questionMaxTokens = 1024
+
+// QuestionSource is a report section a question is answered from
+type QuestionSource struct {
+	// Label says which report and section the text comes from
+	Label string
+	Text  string
+}
+
+// AnswerResult is an answer to a question about a board's reports
+type AnswerResult struct {
+	// Content cites the sources it used by number, e.g. [2] for the second
+	Content string
+	// Usage totals the completions requested for the answer
+	Usage Usage
+	// Provider and Model are the ones that wrote the answer
+	Provider string
+	Model    string
+}
+
+// AnswerQuestion answers a question from report sections, citing them by
+// their number in the list, starting at 1. The sections are redacted
+// before they are sent if a redactor is set.
+func (c *AIFoundryClient) AnswerQuestion(ctx context.Context, question string, sources []QuestionSource, redactor *redact.Redactor) (*AnswerResult, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no report sections to answer from")
+	}
+
+	// Number the sources, keeping as many as fit in the context window
+	budget := c.promptBudget(questionMaxTokens) - llm.EstimateTokens(getQuestionSystemPrompt()+question)
+	var sb strings.Builder
+	for i, source := range sources {
+		text := fmt.Sprintf("[%d] %s\n%s\n\n", i+1, source.Label, source.Text)
+		if llm.EstimateTokens(sb.String()+text) > budget && i > 0 {
+			break
+		}
+		sb.WriteString(text)
+	}
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: getQuestionSystemPrompt()},
+		{Role: llm.RoleUser, Content: fmt.Sprintf("Report excerpts:\n\n%s\nQuestion: %s", boardDataBlock(sb.String()), question)},
+	}
+
+	// Retry failures and fall back to other models, count the tokens used
+	// by the answer, and redact personal data
+	chain := c.chain()
+	meter := newUsageMeter(chain, chain.Used)
+	var provider llm.Provider = meter
+	if redactor != nil {
+		provider = newRedactingProvider(provider, redactor)
+	}
+
+	resp, err := provider.Complete(ctx, llm.Request{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: 0.2,
+		MaxTokens:   questionMaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnswerResult{
+		Content:  resp.Content,
+		Usage:    meter.Usage(),
+		Provider: c.provider.Name(),
+		Model:    c.model,
+	}
+	if used := chain.Used(); used != nil {
+		result.Provider, result.Model = used.Provider.Name(), used.Model
+	}
+	return result, nil
+}
+
+// getQuestionSystemPrompt returns the system prompt for answering questions
+// about a board's report history
+func getQuestionSystemPrompt() string {
+	return fmt.Sprintf(`You answer questions about a project from excerpts of its past weekly and monthly reports.
+Today is %s.
+
+Each excerpt is numbered and labeled with its report's type, board and period. Answer only from the excerpts:
+- Cite the excerpts each statement comes from by number in square brackets, e.g. "The checkout redesign shipped in March [2][5]."
+- Use the report periods to place events in time; a quarter is three calendar months, e.g. Q1 is January to March.
+- If the excerpts don't answer the question, say so instead of guessing, and mention what they do cover.
+- Be concise: a short paragraph or a few bullet points.
+
+%s`, time.Now().Format("Monday, January 2, 2006"), untrustedDataPrompt())
+}
@@ -0,0 +1,88 @@
+package aifoundry
+
+import (
+	"context"
+
+	"agents_go/services/llm"
+	"agents_go/services/redact"
+)
+
+// redactingProvider redacts every message before it is sent to the model,
+// including tool results and earlier replies, and maps pseudonyms in the
+// reply and in tool call arguments back to the real names
+type redactingProvider struct {
+	llm.Provider
+	redactor *redact.Redactor
+}
+
+// newRedactingProvider wraps a provider with the redactor
+func newRedactingProvider(provider llm.Provider, redactor *redact.Redactor) *redactingProvider {
+	return &redactingProvider{Provider: provider, redactor: redactor}
+}
+
+// Complete redacts the request and restores the response
+func (p *redactingProvider) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	resp, err := p.Provider.Complete(ctx, p.redact(req))
+	if err != nil {
+		return nil, err
+	}
+	return p.restore(req, resp), nil
+}
+
+// Stream redacts the request and restores the chunks as they arrive
+func (p *redactingProvider) Stream(ctx context.Context, req llm.Request, onDelta func(delta string) error) (*llm.Response, error) {
+	restorer := p.redactor.NewStreamRestorer()
+	resp, err := p.Provider.Stream(ctx, p.redact(req), func(delta string) error {
+		if text := restorer.Write(delta); text != "" {
+			return onDelta(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text := restorer.Flush(); text != "" {
+		if err := onDelta(text); err != nil {
+			return nil, err
+		}
+	}
+	return p.restore(req, resp), nil
+}
+
+// redact returns a copy of the request with its messages redacted
+func (p *redactingProvider) redact(req llm.Request) llm.Request {
+	messages := make([]llm.Message, len(req.Messages))
+	for i, message := range req.Messages {
+		message.Content = p.redactor.Redact(message.Content)
+		if len(message.ToolCalls) > 0 {
+			calls := make([]llm.ToolCall, len(message.ToolCalls))
+			for j, call := range message.ToolCalls {
+				call.Arguments = p.redactor.Redact(call.Arguments)
+				calls[j] = call
+			}
+			message.ToolCalls = calls
+		}
+		messages[i] = message
+	}
+	req.Messages = messages
+	return req
+}
+
+// restore maps the pseudonyms in a response back to the real names. JSON
+// replies and tool arguments get the names escaped for JSON strings.
+func (p *redactingProvider) restore(req llm.Request, resp *llm.Response) *llm.Response {
+	restored := *resp
+	if req.ResponseFormat != nil {
+		restored.Content = p.redactor.RestoreJSON(resp.Content)
+	} else {
+		restored.Content = p.redactor.Restore(resp.Content)
+	}
+	if len(resp.ToolCalls) > 0 {
+		restored.ToolCalls = make([]llm.ToolCall, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			call.Arguments = p.redactor.RestoreJSON(call.Arguments)
+			restored.ToolCalls[i] = call
+		}
+	}
+	return &restored
+}
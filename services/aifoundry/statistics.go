@@ -0,0 +1,348 @@
+package aifoundry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"agents_go/models"
+)
+
+const (
+	// statisticsDueSoonDays is how far ahead due dates count as priorities
+	statisticsDueSoonDays = 14
+	// statisticsLateDays is how overdue a card is before it is a high risk
+	statisticsLateDays = 7
+)
+
+// doneListPattern matches the names of lists that hold finished work
+var doneListPattern = regexp.MustCompile(`(?i)\b(done|complete[d]?|finished|shipped|closed|released)\b`)
+
+// boardStats are the numbers a report can be written from without a model
+type boardStats struct {
+	BoardName  string
+	Lists      []listStats
+	OpenCards  int
+	DoneCards  int
+	Unassigned int
+	Overdue    []cardStats
+	DueSoon    []cardStats
+	Completed  []string
+	Created    int
+	Moved      int
+	Comments   int
+	Members    []memberStats
+}
+
+// listStats counts the cards in a list
+type listStats struct {
+	Name  string
+	Cards int
+	Done  bool
+}
+
+// cardStats is a card with a due date
+type cardStats struct {
+	Name   string
+	Due    time.Time
+	Owners []string
+}
+
+// memberStats counts the cards assigned to a member
+type memberStats struct {
+	Name  string
+	Open  []string
+	Done  []string
+	Moves int
+}
+
+// statisticsTemplates render the paragraphs of a report without a model
+var statisticsTemplates = template.Must(template.New("statistics").Parse(`
+{{- define "summary" -}}
+{{ .BoardName }} has {{ .OpenCards }} open {{ if eq .OpenCards 1 }}card{{ else }}cards{{ end }} and {{ .DoneCards }} finished across {{ len .Lists }} lists.
+{{- if .Completed }} {{ len .Completed }} {{ if eq (len .Completed) 1 }}card was{{ else }}cards were{{ end }} completed during the period.{{ end }}
+{{- if .Overdue }} {{ len .Overdue }} {{ if eq (len .Overdue) 1 }}card is{{ else }}cards are{{ end }} overdue.{{ end }}
+{{- if .DueSoon }} {{ len .DueSoon }} {{ if eq (len .DueSoon) 1 }}card is{{ else }}cards are{{ end }} due in the next two weeks.{{ end }}
+{{- end -}}
+{{- define "status" -}}
+Cards per list: {{ range $i, $list := .Lists }}{{ if $i }}, {{ end }}{{ $list.Name }} {{ $list.Cards }}{{ end }}.
+{{- if .Unassigned }} {{ .Unassigned }} open {{ if eq .Unassigned 1 }}card has{{ else }}cards have{{ end }} no one assigned.{{ end }}
+
+Activity during the period: {{ .Created }} created, {{ .Moved }} moved between lists and {{ .Comments }} {{ if eq .Comments 1 }}comment{{ else }}comments{{ end }}.
+{{- end -}}
+`))
+
+// StatisticsReport renders a report from counts of the board's cards and
+// activity, for when no model could generate one. It is deterministic:
+// the same board data and time always give the same report. reason is why
+// the models failed and is noted in the report's data limitations. The
+// section titles are in lang; the text is always English.
+func StatisticsReport(boardData map[string]interface{}, reportType string, now time.Time, reason string, lang models.Language) (*ReportResult, error) {
+	stats, err := computeBoardStats(boardData, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary, status strings.Builder
+	if err := statisticsTemplates.ExecuteTemplate(&summary, "summary", stats); err != nil {
+		return nil, fmt.Errorf("error rendering summary: %v", err)
+	}
+	if err := statisticsTemplates.ExecuteTemplate(&status, "status", stats); err != nil {
+		return nil, fmt.Errorf("error rendering status: %v", err)
+	}
+
+	structured := &models.StructuredReport{
+		ExecutiveSummary: summary.String(),
+		CurrentStatus:    status.String(),
+		Progress:         []models.ProgressItem{},
+		Priorities:       []models.Priority{},
+		Risks:            []models.Risk{},
+		Contributions:    []models.MemberContribution{},
+		DataLimitations: []string{
+			"Generated without AI: no model was available, so this report only counts cards and activity and doesn't interpret them.",
+		},
+	}
+	if truncated, _ := boardData["activities_truncated"].(bool); truncated {
+		structured.DataLimitations = append(structured.DataLimitations, "The board had more activity in the period than could be fetched, so only the most recent actions are counted.")
+	}
+	if reason != "" {
+		structured.DataLimitations = append(structured.DataLimitations, "Model error: "+reason)
+	}
+
+	for _, name := range stats.Completed {
+		structured.Progress = append(structured.Progress, models.ProgressItem{
+			Title:  name,
+			Detail: "Moved to a done list",
+			Status: "completed",
+			Cards:  []string{name},
+		})
+	}
+
+	for _, card := range stats.DueSoon {
+		structured.Priorities = append(structured.Priorities, models.Priority{
+			Title: card.Name,
+			Due:   card.Due.Format("2006-01-02"),
+			Owner: strings.Join(card.Owners, ", "),
+			Cards: []string{card.Name},
+		})
+	}
+
+	for _, card := range stats.Overdue {
+		days := int(now.Sub(card.Due).Hours() / 24)
+		severity := "medium"
+		if days >= statisticsLateDays {
+			severity = "high"
+		}
+		mitigation := "Assign an owner and agree a new due date"
+		if len(card.Owners) > 0 {
+			mitigation = "Check in with " + strings.Join(card.Owners, ", ")
+		}
+		structured.Risks = append(structured.Risks, models.Risk{
+			Description: fmt.Sprintf("%s is overdue (due %s)", card.Name, card.Due.Format("2006-01-02")),
+			Severity:    severity,
+			Mitigation:  mitigation,
+			Cards:       []string{card.Name},
+		})
+	}
+	if stats.Unassigned > 0 {
+		structured.Risks = append(structured.Risks, models.Risk{
+			Description: plural(stats.Unassigned, "open card has", "open cards have") + " no one assigned",
+			Severity:    "low",
+			Mitigation:  "Assign owners during planning",
+		})
+	}
+
+	for _, member := range stats.Members {
+		if len(member.Open) == 0 && len(member.Done) == 0 && member.Moves == 0 {
+			continue
+		}
+		cards := "cards"
+		if len(member.Open)+len(member.Done) == 1 {
+			cards = "card"
+		}
+		structured.Contributions = append(structured.Contributions, models.MemberContribution{
+			Member:  member.Name,
+			Summary: fmt.Sprintf("%d open and %d finished %s assigned, %s during the period", len(member.Open), len(member.Done), cards, plural(member.Moves, "card moved", "cards moved")),
+			Cards:   append(append([]string{}, member.Open...), member.Done...),
+		})
+	}
+
+	return &ReportResult{
+		Content:    structured.Markdown(models.ReportType(reportType), lang),
+		Structured: structured,
+		Strategy:   StrategyStatistics,
+		WithoutAI:  true,
+	}, nil
+}
+
+// computeBoardStats counts the board's cards per list and member, the
+// cards that are overdue or due soon and the activity in the period
+func computeBoardStats(boardData map[string]interface{}, now time.Time) (*boardStats, error) {
+	board, ok := boardData["board"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid board data format")
+	}
+
+	stats := &boardStats{}
+	stats.BoardName, _ = board["name"].(string)
+
+	// Lists, in board order
+	listIndex := make(map[string]int)
+	doneLists := make(map[string]bool)
+	for _, list := range boardItems(boardData["lists"]) {
+		id, _ := list["id"].(string)
+		name, _ := list["name"].(string)
+		listIndex[id] = len(stats.Lists)
+		doneLists[id] = doneListPattern.MatchString(name)
+		stats.Lists = append(stats.Lists, listStats{Name: name, Done: doneLists[id]})
+	}
+
+	// Members, in board order
+	memberIndex := make(map[string]int)
+	for _, member := range boardItems(boardData["members"]) {
+		id, _ := member["id"].(string)
+		name, _ := member["fullName"].(string)
+		if name == "" {
+			name, _ = member["username"].(string)
+		}
+		memberIndex[id] = len(stats.Members)
+		stats.Members = append(stats.Members, memberStats{Name: name})
+	}
+
+	for _, card := range boardItems(boardData["cards"]) {
+		if closed, _ := card["closed"].(bool); closed {
+			continue
+		}
+		name, _ := card["name"].(string)
+		listID, _ := card["idList"].(string)
+		done := doneLists[listID]
+
+		if i, ok := listIndex[listID]; ok {
+			stats.Lists[i].Cards++
+		}
+		if done {
+			stats.DoneCards++
+		} else {
+			stats.OpenCards++
+		}
+
+		var owners []string
+		for _, memberID := range stringItems(card["idMembers"]) {
+			i, ok := memberIndex[memberID]
+			if !ok {
+				continue
+			}
+			owners = append(owners, stats.Members[i].Name)
+			if done {
+				stats.Members[i].Done = append(stats.Members[i].Done, name)
+			} else {
+				stats.Members[i].Open = append(stats.Members[i].Open, name)
+			}
+		}
+		if done {
+			continue
+		}
+		if len(owners) == 0 {
+			stats.Unassigned++
+		}
+
+		dueValue, _ := card["due"].(string)
+		due, err := time.Parse(time.RFC3339, dueValue)
+		if err != nil {
+			continue
+		}
+		switch {
+		case due.Before(now):
+			stats.Overdue = append(stats.Overdue, cardStats{Name: name, Due: due, Owners: owners})
+		case due.Before(now.AddDate(0, 0, statisticsDueSoonDays)):
+			stats.DueSoon = append(stats.DueSoon, cardStats{Name: name, Due: due, Owners: owners})
+		}
+	}
+
+	// Oldest deadlines first
+	sort.SliceStable(stats.Overdue, func(i, j int) bool { return stats.Overdue[i].Due.Before(stats.Overdue[j].Due) })
+	sort.SliceStable(stats.DueSoon, func(i, j int) bool { return stats.DueSoon[i].Due.Before(stats.DueSoon[j].Due) })
+
+	// Activity is returned newest first; count it oldest first
+	activity := boardItems(boardData["activities"])
+	completed := make(map[string]bool)
+	for i := len(activity) - 1; i >= 0; i-- {
+		action := activity[i]
+		data, _ := action["data"].(map[string]interface{})
+		card, _ := data["card"].(map[string]interface{})
+		cardName, _ := card["name"].(string)
+
+		switch actionType, _ := action["type"].(string); actionType {
+		case "createCard":
+			stats.Created++
+		case "commentCard":
+			stats.Comments++
+		case "updateCard":
+			listAfter, ok := data["listAfter"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			stats.Moved++
+			creatorID, _ := action["idMemberCreator"].(string)
+			if creator, ok := action["memberCreator"].(map[string]interface{}); ok && creatorID == "" {
+				creatorID, _ = creator["id"].(string)
+			}
+			if i, ok := memberIndex[creatorID]; ok {
+				stats.Members[i].Moves++
+			}
+			listID, _ := listAfter["id"].(string)
+			listName, _ := listAfter["name"].(string)
+			if (doneLists[listID] || doneListPattern.MatchString(listName)) && cardName != "" && !completed[cardName] {
+				completed[cardName] = true
+				stats.Completed = append(stats.Completed, cardName)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// plural formats a count with the singular or plural form of a phrase
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
+
+// boardItems returns the maps in a board data value, which may be a slice
+// or a map wrapping the slice in "items"
+func boardItems(data interface{}) []map[string]interface{} {
+	if dataMap, ok := data.(map[string]interface{}); ok {
+		data = dataMap["items"]
+	}
+
+	switch v := data.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		result := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// stringItems returns the strings in a JSON array value
+func stringItems(data interface{}) []string {
+	list, _ := data.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
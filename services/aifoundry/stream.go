@@ -0,0 +1,195 @@
+package aifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+)
+
+// previewInterval throttles report previews while the report is streamed
+const previewInterval = 300 * time.Millisecond
+
+// Progress receives output while a chat reply or report is generated, e.g.
+// to stream it to the browser. Any of the functions may be nil.
+type Progress struct {
+	// Status is called when generation moves to a new step
+	Status func(message string)
+	// Delta is called with each chunk of a chat reply as it is generated
+	Delta func(text string) error
+	// Preview is called with the report rendered from the output so far
+	Preview func(content string) error
+}
+
+// status reports a new step
+func (p *Progress) status(message string) {
+	if p != nil && p.Status != nil {
+		p.Status(message)
+	}
+}
+
+// streaming reports whether chat replies should be streamed
+func (p *Progress) streaming() bool {
+	return p != nil && p.Delta != nil
+}
+
+// previewing reports whether reports should be streamed
+func (p *Progress) previewing() bool {
+	return p != nil && p.Preview != nil
+}
+
+// send completes a request, streaming the content to onDelta if it is set
+func (c *AIFoundryClient) send(ctx context.Context, req llm.Request, onDelta func(delta string) error) (*llm.Response, error) {
+	if onDelta == nil {
+		return c.provider.Complete(ctx, req)
+	}
+	return c.provider.Stream(ctx, req, onDelta)
+}
+
+// reportPreviewer turns the streamed JSON of a structured report into
+// markdown previews, at most once per previewInterval
+type reportPreviewer struct {
+	reportType  models.ReportType
+	language    models.Language
+	audience    models.Audience
+	preview     func(content string) error
+	raw         strings.Builder
+	last        time.Time
+	lastContent string
+}
+
+// onDelta adds a chunk of the response and sends a preview if one is due
+func (p *reportPreviewer) onDelta(delta string) error {
+	p.raw.WriteString(delta)
+	if time.Since(p.last) < previewInterval {
+		return nil
+	}
+	return p.flush()
+}
+
+// flush sends a preview of the output so far, if it has changed
+func (p *reportPreviewer) flush() error {
+	structured := parsePartialReport(p.raw.String())
+	if structured == nil {
+		return nil
+	}
+	structured.Audience = p.audience
+
+	content := previewMarkdown(structured, p.reportType, p.language)
+	if content == "" || content == p.lastContent {
+		return nil
+	}
+	p.last = time.Now()
+	p.lastContent = content
+	return p.preview(content)
+}
+
+// reset discards the output, e.g. before a repair attempt
+func (p *reportPreviewer) reset() {
+	p.raw.Reset()
+	p.last = time.Time{}
+}
+
+// previewMarkdown renders the sections of a partial report that have content
+func previewMarkdown(structured *models.StructuredReport, reportType models.ReportType, lang models.Language) string {
+	var sb strings.Builder
+	for _, section := range structured.Sections(reportType, lang) {
+		if len(section.Paragraphs) == 0 && len(section.Items) == 0 {
+			continue
+		}
+		sb.WriteString("## " + section.Title + "\n\n")
+		for _, p := range section.Paragraphs {
+			sb.WriteString(p + "\n\n")
+		}
+		for _, item := range section.Items {
+			sb.WriteString("- " + item + "\n")
+		}
+		if len(section.Items) > 0 {
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// parsePartialReport decodes the beginning of a structured report's JSON by
+// closing any open strings, arrays and objects. When the text ends in the
+// middle of a key, the incomplete field is dropped. It returns nil if the
+// text can't be completed.
+func parsePartialReport(raw string) *models.StructuredReport {
+	start := strings.Index(raw, "{")
+	if start < 0 {
+		return nil
+	}
+	text := raw[start:]
+
+	var structured models.StructuredReport
+	closed, lastComma := closePartialJSON(text)
+	if err := json.Unmarshal([]byte(closed), &structured); err == nil {
+		return &structured
+	}
+	if lastComma < 0 {
+		return nil
+	}
+
+	// Retry without the field after the last comma
+	structured = models.StructuredReport{}
+	closed, _ = closePartialJSON(text[:lastComma])
+	if err := json.Unmarshal([]byte(closed), &structured); err != nil {
+		return nil
+	}
+	return &structured
+}
+
+// closePartialJSON appends the closing quotes and brackets a truncated JSON
+// document needs, dropping a trailing comma or incomplete escape. It also
+// returns the index of the last comma outside a string, or -1.
+func closePartialJSON(text string) (string, int) {
+	var stack []byte
+	inString, escaped := false, false
+	lastComma := -1
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case inString && escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == ',':
+			lastComma = i
+		case ch == '{':
+			stack = append(stack, '}')
+		case ch == '[':
+			stack = append(stack, ']')
+		case (ch == '}' || ch == ']') && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if inString {
+		if escaped {
+			text = text[:len(text)-1]
+		}
+		// A \u escape cut short would make the string invalid
+		if i := strings.LastIndex(text, `\u`); i >= 0 && len(text)-i < 6 {
+			text = text[:i]
+		}
+		text += `"`
+	} else {
+		text = strings.TrimRight(text, " \t\r\n")
+		text = strings.TrimSuffix(text, ",")
+		if strings.HasSuffix(text, ":") {
+			text += "null"
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		text += string(stack[i])
+	}
+	return text, lastComma
+}
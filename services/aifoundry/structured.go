@@ -0,0 +1,143 @@
+package aifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+)
+
+// structuredReportFormat names the response format of structured reports
+const structuredReportFormat = "trello_report"
+
+// maxRepairAttempts bounds how many times invalid JSON is sent back to the
+// model for correction
+const maxRepairAttempts = 2
+
+// structuredOutputPrompt is appended to report system prompts so the model
+// returns JSON matching models.StructuredReportSchema
+func structuredOutputPrompt() string {
+	return `Return the report as a single JSON object that conforms to this JSON schema, with no markdown fences and no text before or after it:
+
+` + models.StructuredReportSchema + `
+
+Guidelines for the fields:
+- executive_summary: plain text, two to four sentences
+- progress: work completed or moved forward in the period, with the card names involved
+- current_status: plain text describing where the project stands now
+- priorities: upcoming work and deadlines, with due dates as YYYY-MM-DD where known
+- risks: blockers, overdue cards and risks, each with a severity of low, medium, high or critical
+- contributions: one entry per active member
+- data_limitations: anything missing from the data that limits the report
+Use empty arrays when there is nothing to report. Only mention cards, members and dates that appear in the board data.`
+}
+
+// languagePrompt asks for the report's text in the given language. The JSON
+// field names and enum values stay English so the report still validates,
+// and the section titles are added when the report is rendered. It returns
+// an empty string for English.
+func languagePrompt(lang models.Language) string {
+	if lang == "" || lang == models.English {
+		return ""
+	}
+	return fmt.Sprintf(`Write every text value of the report in %s (%s): the summary, status, details, descriptions, mitigations and data limitations. Keep the JSON field names and the status and severity values in English, and keep card, list and member names exactly as they appear in the board data.`, lang.Name(), lang.NativeName())
+}
+
+// completeStructured asks for a structured report and validates the
+// response. Invalid output is sent back with the problems found, up to
+// maxRepairAttempts times. It returns the raw content of the last response
+// along with any validation error. If progress takes previews, the response
+// is streamed and previews of the partial report are sent as it arrives.
+func (c *AIFoundryClient) completeStructured(ctx context.Context, messages []llm.Message, temperature float32, maxTokens int, reportType string, progress *Progress) (*models.StructuredReport, string, error) {
+	format := &llm.ResponseFormat{
+		Name:   structuredReportFormat,
+		Schema: json.RawMessage(models.StructuredReportSchema),
+	}
+
+	var raw string
+	var problems []string
+
+	var previewer *reportPreviewer
+	var onDelta func(delta string) error
+	if progress.previewing() {
+		previewer = &reportPreviewer{reportType: models.ReportType(reportType), language: c.language, audience: c.audience, preview: progress.Preview}
+		onDelta = previewer.onDelta
+	}
+
+	for attempt := 0; attempt <= maxRepairAttempts; attempt++ {
+		if previewer != nil {
+			previewer.reset()
+		}
+		resp, err := c.send(ctx, llm.Request{
+			Model:          c.model,
+			Messages:       messages,
+			Temperature:    temperature,
+			MaxTokens:      maxTokens,
+			ResponseFormat: format,
+		}, onDelta)
+		if err != nil {
+			return nil, raw, err
+		}
+		raw = resp.Content
+
+		structured, parseProblems := parseStructuredReport(raw)
+		if len(parseProblems) == 0 {
+			return structured, raw, nil
+		}
+		problems = parseProblems
+
+		log.Printf("Structured report failed validation (attempt %d of %d): %s", attempt+1, maxRepairAttempts+1, strings.Join(problems, "; "))
+		progress.status("Fixing the report format")
+
+		// Ask the model to fix its own output
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: raw},
+			llm.Message{Role: llm.RoleUser, Content: repairPrompt(problems)},
+		)
+		temperature = 0
+	}
+
+	return nil, raw, fmt.Errorf("structured report still invalid after %d repair attempts: %s", maxRepairAttempts, strings.Join(problems, "; "))
+}
+
+// parseStructuredReport decodes and validates model output, returning the
+// problems found
+func parseStructuredReport(raw string) (*models.StructuredReport, []string) {
+	text := extractJSON(raw)
+	if text == "" {
+		return nil, []string{"the response does not contain a JSON object"}
+	}
+
+	var structured models.StructuredReport
+	if err := json.Unmarshal([]byte(text), &structured); err != nil {
+		return nil, []string{fmt.Sprintf("the response is not valid JSON for the schema: %v", err)}
+	}
+
+	if problems := structured.Validate(); len(problems) > 0 {
+		return nil, problems
+	}
+
+	return &structured, nil
+}
+
+// extractJSON returns the outermost JSON object in the text, tolerating
+// markdown code fences and surrounding prose
+func extractJSON(raw string) string {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return raw[start : end+1]
+}
+
+// repairPrompt asks the model to correct invalid output
+func repairPrompt(problems []string) string {
+	return "Your previous response did not match the required JSON schema:\n- " +
+		strings.Join(problems, "\n- ") +
+		"\n\nReturn the corrected JSON object only, with no other text."
+}
@@ -0,0 +1,157 @@
+package aifoundry
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"agents_go/config"
+	"agents_go/services/llm"
+)
+
+func TestCompleteStructured(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	const valid = `{"executive_summary": "Payments are unblocked.", "current_status": "On track.", "progress": [{"title": "Webhook retries", "status": "completed"}], "priorities": [], "risks": [], "contributions": []}`
+	const invalidSeverity = `{"executive_summary": "Payments are unblocked.", "risks": [{"description": "Launch slips", "severity": "urgent"}]}`
+
+	tests := []struct {
+		name string
+		// responses are the model's replies in order; an empty one fails
+		// the request
+		responses []string
+		wantErr   string
+		// wantSummary is the executive summary of the accepted report
+		wantSummary string
+		// wantProblems are reported to the model in the repair prompts
+		wantProblems []string
+	}{
+		{
+			name:        "valid JSON",
+			responses:   []string{valid},
+			wantSummary: "Payments are unblocked.",
+		},
+		{
+			name:        "valid JSON in a code fence",
+			responses:   []string{"Here is the report:\n```json\n" + valid + "\n```"},
+			wantSummary: "Payments are unblocked.",
+		},
+		{
+			name:         "not JSON, then repaired",
+			responses:    []string{"The board looks fine this week.", valid},
+			wantSummary:  "Payments are unblocked.",
+			wantProblems: []string{"does not contain a JSON object"},
+		},
+		{
+			name:         "invalid against the schema, then repaired",
+			responses:    []string{invalidSeverity, valid},
+			wantSummary:  "Payments are unblocked.",
+			wantProblems: []string{`risks[0].severity must be one of`},
+		},
+		{
+			name:         "repaired on the last attempt",
+			responses:    []string{`{"executive_summary": ""}`, `{"executive_summary": "Done", "progress": "none"}`, valid},
+			wantSummary:  "Payments are unblocked.",
+			wantProblems: []string{"executive_summary must not be empty", "not valid JSON"},
+		},
+		{
+			name:         "repairs exhausted",
+			responses:    []string{"no", "still no", "never"},
+			wantErr:      "still invalid after 2 repair attempts",
+			wantProblems: []string{"does not contain a JSON object", "does not contain a JSON object"},
+		},
+		{
+			name:      "request failed",
+			responses: []string{""},
+			wantErr:   "unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := llm.NewProvider(config.LLMProviderConfig{Name: "fake", Type: "fake", Model: "fake-model", ContextWindow: 32768})
+			if err != nil {
+				t.Fatalf("Error creating fake LLM: %v", err)
+			}
+			fake := provider.(*llm.FakeProvider)
+			calls := 0
+			fake.SetHandler(func(req llm.Request) (*llm.Response, error) {
+				if calls >= len(tt.responses) {
+					t.Fatalf("unexpected request %d", calls+1)
+				}
+				content := tt.responses[calls]
+				calls++
+				if content == "" {
+					return nil, &llm.APIError{StatusCode: http.StatusServiceUnavailable, Body: "unavailable"}
+				}
+				return &llm.Response{Content: content}, nil
+			})
+			registry := llm.NewRegistry("fake")
+			registry.Register(fake)
+			client, err := NewClientWithRegistry(registry)
+			if err != nil {
+				t.Fatalf("Error creating LLM client: %v", err)
+			}
+			client = client.WithRetryPolicy(llm.RetryPolicy{MaxAttempts: 1})
+
+			messages := []llm.Message{
+				{Role: llm.RoleSystem, Content: "Write the report."},
+				{Role: llm.RoleUser, Content: boardDataBlock("# Board: \"Launch\"")},
+			}
+			structured, raw, err := client.completeStructured(context.Background(), messages, 0.7, reportMaxTokens, "weekly", nil)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Error completing structured report: %v", err)
+				}
+				if structured.ExecutiveSummary != tt.wantSummary {
+					t.Errorf("executive summary is %q, want %q", structured.ExecutiveSummary, tt.wantSummary)
+				}
+				if raw != tt.responses[len(tt.responses)-1] {
+					t.Errorf("raw content is %q, want the last response", raw)
+				}
+			}
+
+			// Every request asks for the schema, and each repair sends the
+			// invalid output back with its problems, at temperature zero
+			requests := fake.Requests()
+			if len(requests) != len(tt.responses) {
+				t.Fatalf("sent %d requests, want %d", len(requests), len(tt.responses))
+			}
+			for i, req := range requests {
+				if req.ResponseFormat == nil || req.ResponseFormat.Name != structuredReportFormat {
+					t.Errorf("request %d doesn't ask for the report schema", i+1)
+				}
+				if i == 0 {
+					if len(req.Messages) != len(messages) || req.Temperature != 0.7 {
+						t.Errorf("first request has %d messages at temperature %v, want %d at 0.7", len(req.Messages), req.Temperature, len(messages))
+					}
+					continue
+				}
+				if req.Temperature != 0 {
+					t.Errorf("repair %d at temperature %v, want 0", i, req.Temperature)
+				}
+				if len(req.Messages) != len(messages)+2*i {
+					t.Fatalf("repair %d has %d messages, want %d", i, len(req.Messages), len(messages)+2*i)
+				}
+				previous := req.Messages[len(req.Messages)-2]
+				repair := req.Messages[len(req.Messages)-1]
+				if previous.Role != llm.RoleAssistant || previous.Content != tt.responses[i-1] {
+					t.Errorf("repair %d doesn't send back the invalid output", i)
+				}
+				if repair.Role != llm.RoleUser || !strings.Contains(repair.Content, tt.wantProblems[i-1]) {
+					t.Errorf("repair %d prompt is %q, want it to mention %q", i, repair.Content, tt.wantProblems[i-1])
+				}
+			}
+		})
+	}
+}
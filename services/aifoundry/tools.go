@@ -0,0 +1,199 @@
+package aifoundry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+	"agents_go/services/tools"
+)
+
+// ToolBudget bounds the tool-calling loop
+type ToolBudget struct {
+	// MaxSteps is the number of model turns that may call tools
+	MaxSteps int
+	// MaxTokens is the total prompt and completion tokens the loop may use
+	// before the model has to answer
+	MaxTokens int
+}
+
+// DefaultToolBudget is used when no budget is set
+var DefaultToolBudget = ToolBudget{MaxSteps: 6, MaxTokens: 40000}
+
+const (
+	// toolResultMaxTokens truncates each tool result given to the model
+	toolResultMaxTokens = 2000
+	// provenanceResultChars truncates the tool results kept in provenance
+	provenanceResultChars = 2000
+)
+
+// runTools lets the model call tools until it answers without calling any,
+// or until the step or token budget runs out, at which point it is asked to
+// answer with what it has. It returns the final answer and a record of
+// every tool call. If progress streams replies, every step is streamed and
+// text written alongside tool calls is kept in the answer, so the saved
+// answer matches what was shown.
+func (c *AIFoundryClient) runTools(ctx context.Context, messages []llm.Message, registry *tools.Registry, budget ToolBudget, temperature float32, maxTokens int, progress *Progress) (*llm.Response, []models.ToolCallRecord, error) {
+	if budget.MaxSteps <= 0 {
+		budget = DefaultToolBudget
+	}
+
+	messages = append([]llm.Message(nil), messages...)
+	var records []models.ToolCallRecord
+	var usage llm.Usage
+	var preamble strings.Builder
+
+	var onDelta func(delta string) error
+	if progress.streaming() {
+		onDelta = progress.Delta
+	}
+
+	for step := 1; ; step++ {
+		req := llm.Request{
+			Model:       c.model,
+			Messages:    messages,
+			Temperature: temperature,
+			MaxTokens:   maxTokens,
+		}
+
+		// Offer tools while there is budget left, otherwise ask for the answer
+		used := usage.PromptTokens + usage.CompletionTokens
+		exhausted := step > budget.MaxSteps || used >= budget.MaxTokens ||
+			c.provider.CountTokens(messages) > c.promptBudget(maxTokens)
+		if exhausted {
+			log.Printf("Tool budget used up after %d steps and %d tokens", step-1, used)
+			req.Messages = append(messages, llm.Message{
+				Role:    llm.RoleUser,
+				Content: "You can't call any more tools. Answer now using the information gathered so far, and say what you could not check.",
+			})
+		} else {
+			req.Tools = registry.Definitions()
+		}
+
+		resp, err := c.send(ctx, req, onDelta)
+		if err != nil {
+			return nil, records, err
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+
+		if len(resp.ToolCalls) == 0 || exhausted {
+			resp.Usage = usage
+			resp.ToolCalls = nil
+			resp.Content = preamble.String() + resp.Content
+			return resp, records, nil
+		}
+
+		// Keep text the model streamed before calling tools
+		if onDelta != nil && strings.TrimSpace(resp.Content) != "" {
+			preamble.WriteString(resp.Content + "\n\n")
+			if err := onDelta("\n\n"); err != nil {
+				return nil, records, err
+			}
+		}
+
+		// Run the requested tools and give the results back to the model
+		messages = append(messages, llm.Message{
+			Role:      llm.RoleAssistant,
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			progress.status(toolStatus(call.Name))
+			record := models.ToolCallRecord{
+				Step:      step,
+				Tool:      call.Name,
+				Arguments: call.Arguments,
+				CalledAt:  time.Now(),
+			}
+
+			result, err := registry.Call(ctx, call.Name, call.Arguments)
+			record.DurationMs = time.Since(record.CalledAt).Milliseconds()
+			if err != nil {
+				record.Error = err.Error()
+				result = fmt.Sprintf("Error: %v", err)
+			}
+			result = truncateTokens(result, toolResultMaxTokens)
+			record.Result = truncateChars(result, provenanceResultChars)
+			records = append(records, record)
+
+			messages = append(messages, llm.Message{
+				Role:       llm.RoleTool,
+				Content:    result,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
+
+// toolStatus describes a tool call for progress updates
+func toolStatus(name string) string {
+	switch name {
+	case "search_cards":
+		return "Searching cards"
+	case "get_card":
+		return "Reading a card"
+	case "list_board_members":
+		return "Checking board members"
+	case "get_actions":
+		return "Reading board activity"
+	case "propose_changes":
+		return "Preparing board changes"
+	default:
+		return "Calling " + name
+	}
+}
+
+// truncateTokens shortens text to about maxTokens
+func truncateTokens(text string, maxTokens int) string {
+	if llm.EstimateTokens(text) <= maxTokens {
+		return text
+	}
+	return truncateChars(text, maxTokens*4)
+}
+
+// truncateChars shortens text to at most maxChars characters, marking the cut
+func truncateChars(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	return string(runes[:maxChars]) + "\n... (truncated)"
+}
+
+// research runs the tool-calling loop over the board overview and returns
+// the model's notes for the report writer
+func (c *AIFoundryClient) research(ctx context.Context, sections *boardSections, reportType string, registry *tools.Registry, budget ToolBudget, progress *Progress) (string, []models.ToolCallRecord, error) {
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: getResearchPrompt(reportType)},
+		{Role: llm.RoleUser, Content: truncateBoard(sections, c.promptBudget(summaryMaxTokens)/2)},
+	}
+
+	// The notes aren't part of the report, so only status is passed on
+	var status *Progress
+	if progress != nil {
+		status = &Progress{Status: progress.Status}
+	}
+	resp, records, err := c.runTools(ctx, messages, registry, budget, 0.2, summaryMaxTokens, status)
+	if err != nil {
+		return "", records, err
+	}
+
+	log.Printf("Researched board %s with %d tool calls", sections.BoardName, len(records))
+	return strings.TrimSpace(resp.Content), records, nil
+}
+
+// getResearchPrompt returns the system prompt for the research step
+func getResearchPrompt(reportType string) string {
+	return fmt.Sprintf(`You are an AI assistant preparing to write a %s report for a Trello board. An overview of the board is below.
+
+Use the tools to look up what the overview doesn't show: comments on blocked, overdue or recently moved cards, activity during the period, and who is working on what.
+Only call tools that help the report. When you have enough, reply with concise research notes as markdown bullet points, naming the cards and members involved and citing card IDs.
+Do not write the report itself.
+
+%s`, reportType, untrustedDataPrompt())
+}
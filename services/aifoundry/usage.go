@@ -0,0 +1,96 @@
+package aifoundry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"agents_go/services/llm"
+)
+
+// Usage totals the completions requested while generating a report or chat
+// reply. Completions served from the cache aren't counted.
+type Usage struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	// Latency is the total time spent waiting for completions
+	Latency time.Duration
+	// Cost is the estimated price of the tokens, each completion priced for
+	// the model that answered it
+	Cost float64
+}
+
+// usageMeter counts the tokens and time used by a provider's completions
+type usageMeter struct {
+	llm.Provider
+	// used returns the model that answered the last request when the
+	// provider falls back between models
+	used func() *llm.Target
+
+	mutex sync.Mutex
+	usage Usage
+}
+
+// newUsageMeter wraps a provider with a meter. used may be nil if the
+// provider doesn't fall back to other models.
+func newUsageMeter(provider llm.Provider, used func() *llm.Target) *usageMeter {
+	return &usageMeter{Provider: provider, used: used}
+}
+
+// Complete requests a completion and counts its usage
+func (m *usageMeter) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	start := time.Now()
+	resp, err := m.Provider.Complete(ctx, req)
+	m.add(req, resp, time.Since(start))
+	return resp, err
+}
+
+// Stream streams a completion and counts its usage
+func (m *usageMeter) Stream(ctx context.Context, req llm.Request, onDelta func(delta string) error) (*llm.Response, error) {
+	start := time.Now()
+	resp, err := m.Provider.Stream(ctx, req, onDelta)
+	m.add(req, resp, time.Since(start))
+	return resp, err
+}
+
+// add counts a completion. Providers that don't report usage, which is
+// common when streaming, are charged the estimated token counts.
+func (m *usageMeter) add(req llm.Request, resp *llm.Response, latency time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.usage.Requests++
+	m.usage.Latency += latency
+	if resp == nil {
+		// Failed requests may still have used prompt tokens, but there is
+		// nothing to count
+		return
+	}
+
+	usage := resp.Usage
+	if usage == (llm.Usage{}) {
+		usage.PromptTokens = m.CountTokens(req.Messages)
+		usage.CompletionTokens = llm.EstimateTokens(resp.Content)
+	}
+	m.usage.PromptTokens += usage.PromptTokens
+	m.usage.CompletionTokens += usage.CompletionTokens
+
+	model := req.Model
+	if m.used != nil {
+		if target := m.used(); target != nil {
+			model = target.Model
+		}
+	}
+	if model == "" {
+		model = m.DefaultModel()
+	}
+	m.usage.Cost += llm.EstimateCost(model, usage)
+}
+
+// Usage returns the totals so far
+func (m *usageMeter) Usage() Usage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.usage
+}
@@ -0,0 +1,289 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agents_go/models"
+)
+
+// embedBatchSize is how many sections are embedded per request
+const embedBatchSize = 32
+
+// EmbedFunc returns one embedding for each text, in order
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// entry is a report's embedded sections, saved as one file per report
+type entry struct {
+	ReportID string `json:"report_id"`
+	BoardID  string `json:"board_id"`
+	// Model is the embedding model; sections embedded with another model
+	// can't be compared and are embedded again
+	Model     string    `json:"model"`
+	IndexedAt time.Time `json:"indexed_at"`
+	Sections  []Section `json:"sections"`
+}
+
+// Match is a section that matched a question
+type Match struct {
+	Section
+	// Score is the cosine similarity of the section and the question
+	Score float64 `json:"score"`
+}
+
+// Index is a vector index of report sections, kept in
+// {StoragePath}/{board}/{report}.json and loaded a board at a time. It is
+// safe for concurrent use.
+type Index struct {
+	StoragePath string
+
+	mutex sync.Mutex
+	// boards holds the loaded boards' entries by report ID
+	boards map[string]map[string]*entry
+	// syncMutex serializes Sync, so reports are embedded once
+	syncMutex sync.Mutex
+}
+
+// NewIndex opens an index in a directory, creating it if needed
+func NewIndex(storagePath string) (*Index, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &Index{
+		StoragePath: storagePath,
+		boards:      make(map[string]map[string]*entry),
+	}, nil
+}
+
+// Sync brings a board's index in line with its reports: reports that
+// aren't indexed with the model are split into sections and embedded, and
+// reports that aren't in the list any more are removed. It returns the
+// number of reports embedded.
+func (idx *Index) Sync(ctx context.Context, boardID string, reports []*models.Report, model string, embed EmbedFunc) (int, error) {
+	idx.syncMutex.Lock()
+	defer idx.syncMutex.Unlock()
+
+	entries, err := idx.board(boardID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Remove the reports that were deleted or replaced
+	keep := make(map[string]bool)
+	for _, report := range reports {
+		keep[report.ID] = true
+	}
+	for id := range entries {
+		if !keep[id] {
+			if err := idx.remove(boardID, id); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Embed the reports that are new or were embedded with another model
+	embedded := 0
+	for _, report := range reports {
+		if existing, ok := entries[report.ID]; ok && existing.Model == model {
+			continue
+		}
+		if err := models.ValidReportID(report.ID); err != nil {
+			log.Printf("Not embedding report: %v", err)
+			continue
+		}
+
+		sections := Sections(report)
+		for start := 0; start < len(sections); start += embedBatchSize {
+			end := start + embedBatchSize
+			if end > len(sections) {
+				end = len(sections)
+			}
+			texts := make([]string, 0, end-start)
+			for _, section := range sections[start:end] {
+				texts = append(texts, section.EmbeddingText())
+			}
+			vectors, err := embed(ctx, texts)
+			if err != nil {
+				return embedded, fmt.Errorf("error embedding report %s: %v", report.ID, err)
+			}
+			if len(vectors) != len(texts) {
+				return embedded, fmt.Errorf("error embedding report %s: got %d embeddings for %d sections", report.ID, len(vectors), len(texts))
+			}
+			for i, vector := range vectors {
+				sections[start+i].Vector = normalize(vector)
+			}
+		}
+
+		e := &entry{
+			ReportID:  report.ID,
+			BoardID:   boardID,
+			Model:     model,
+			IndexedAt: time.Now(),
+			Sections:  sections,
+		}
+		if err := idx.save(e); err != nil {
+			return embedded, err
+		}
+		embedded++
+	}
+	return embedded, nil
+}
+
+// Search returns a board's sections most similar to the vector, best
+// first. Only sections embedded with the model and of reports that pass
+// the filter are considered, and only those with a positive similarity
+// are returned.
+func (idx *Index) Search(boardID string, vector []float32, model string, filter models.ReportFilter, limit int) ([]Match, error) {
+	entries, err := idx.board(boardID)
+	if err != nil {
+		return nil, err
+	}
+	query := normalize(vector)
+
+	var matches []Match
+	idx.mutex.Lock()
+	for _, e := range entries {
+		if e.Model != model {
+			continue
+		}
+		for _, section := range e.Sections {
+			report := &models.Report{
+				ID:          section.ReportID,
+				BoardID:     section.BoardID,
+				Type:        section.Type,
+				Period:      section.Period,
+				GeneratedAt: section.GeneratedAt,
+			}
+			if !filter.Matches(report) || len(section.Vector) != len(query) {
+				continue
+			}
+			// Sections unrelated to the question only distract the model
+			if score := dot(query, section.Vector); score > 0 {
+				matches = append(matches, Match{Section: section, Score: score})
+			}
+		}
+	}
+	idx.mutex.Unlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].GeneratedAt.After(matches[j].GeneratedAt)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// board returns a board's entries, loading them from disk the first time
+func (idx *Index) board(boardID string) (map[string]*entry, error) {
+	if boardID == "" || strings.ContainsAny(boardID, `/\`) || strings.Contains(boardID, "..") {
+		return nil, fmt.Errorf("invalid board ID %q", boardID)
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if entries, ok := idx.boards[boardID]; ok {
+		return entries, nil
+	}
+
+	entries := make(map[string]*entry)
+	matches, err := filepath.Glob(filepath.Join(idx.StoragePath, boardID, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding embeddings: %v", err)
+	}
+	for _, match := range matches {
+		data, err := ioutil.ReadFile(match)
+		if err != nil {
+			return nil, fmt.Errorf("error reading embeddings file: %v", err)
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			// Embeddings can always be made again, so skip the file
+			log.Printf("Error parsing embeddings file %s, it will be embedded again: %v", match, err)
+			continue
+		}
+		entries[e.ReportID] = &e
+	}
+	idx.boards[boardID] = entries
+	return entries, nil
+}
+
+// save writes an entry to disk and to the loaded board
+func (idx *Index) save(e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling embeddings: %v", err)
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Join(idx.StoragePath, e.BoardID), 0755); err != nil {
+		return fmt.Errorf("error creating embeddings directory: %v", err)
+	}
+	if err := models.WriteFileAtomic(idx.path(e.BoardID, e.ReportID), data, 0644); err != nil {
+		return fmt.Errorf("error writing embeddings file: %v", err)
+	}
+	idx.boards[e.BoardID][e.ReportID] = e
+	return nil
+}
+
+// remove deletes a report's entry from disk and from the loaded board
+func (idx *Index) remove(boardID, reportID string) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if err := os.Remove(idx.path(boardID, reportID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting embeddings file: %v", err)
+	}
+	delete(idx.boards[boardID], reportID)
+	return nil
+}
+
+// path returns the file of a report's embeddings
+func (idx *Index) path(boardID, reportID string) string {
+	return filepath.Join(idx.StoragePath, boardID, reportID+".json")
+}
+
+// normalize returns a copy of the vector scaled to unit length, so the
+// cosine similarity of two vectors is their dot product
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	normalized := make([]float32, len(vector))
+	if norm == 0 {
+		return normalized
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) * scale)
+	}
+	return normalized
+}
+
+// dot returns the dot product of two vectors of the same length
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,137 @@
+// Package embeddings keeps a local vector index of report sections, so
+// questions about a board's report history can be answered from the most
+// relevant ones.
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+)
+
+// maxSectionTokens is the longest a section gets before it is split into
+// parts, so each part's embedding stays specific
+const maxSectionTokens = 400
+
+// Section is a section of a report and its embedding
+type Section struct {
+	ReportID    string            `json:"report_id"`
+	BoardID     string            `json:"board_id"`
+	BoardName   string            `json:"board_name"`
+	Type        models.ReportType `json:"type"`
+	Period      string            `json:"period"`
+	StartDate   time.Time         `json:"start_date"`
+	EndDate     time.Time         `json:"end_date"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	// Title is the section's heading, with the part number if the section
+	// was split
+	Title  string    `json:"title"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// Label describes where the section comes from, e.g. "Weekly report for
+// Website, Jan 1 to Jan 7, 2026: Progress This Week"
+func (s *Section) Label() string {
+	return fmt.Sprintf("%s report for %s, %s to %s: %s",
+		strings.Title(string(s.Type)), s.BoardName,
+		s.StartDate.Format("Jan 2, 2006"), s.EndDate.Format("Jan 2, 2006"), s.Title)
+}
+
+// EmbeddingText is the text embedded for the section: its label and its
+// text, so a question about a board or a period finds the right reports
+func (s *Section) EmbeddingText() string {
+	return s.Label() + "\n\n" + s.Text
+}
+
+// Sections splits a report into sections without embeddings: the
+// sections of its structured form, or else the parts of its content under
+// each markdown heading. Long sections are split into parts.
+func Sections(report *models.Report) []Section {
+	type part struct{ title, text string }
+	var parts []part
+
+	if report.Structured != nil {
+		for _, section := range report.Structured.Sections(report.Type, report.Language) {
+			lines := append([]string{}, section.Paragraphs...)
+			for _, item := range section.Items {
+				lines = append(lines, "- "+item)
+			}
+			parts = append(parts, part{section.Title, strings.Join(lines, "\n")})
+		}
+	} else {
+		// Split the markdown on headings; text before the first heading
+		// is the report's introduction
+		title := "Introduction"
+		var lines []string
+		flush := func() {
+			parts = append(parts, part{title, strings.Join(lines, "\n")})
+			lines = nil
+		}
+		for _, line := range strings.Split(report.Content, "\n") {
+			if heading := strings.TrimLeft(line, "#"); heading != line && strings.HasPrefix(heading, " ") {
+				flush()
+				title = strings.TrimSpace(heading)
+				continue
+			}
+			lines = append(lines, line)
+		}
+		flush()
+	}
+
+	var sections []Section
+	for _, p := range parts {
+		text := strings.TrimSpace(p.text)
+		if text == "" {
+			continue
+		}
+		chunks := splitText(text, maxSectionTokens)
+		for i, chunk := range chunks {
+			title := p.title
+			if len(chunks) > 1 {
+				title = fmt.Sprintf("%s (part %d of %d)", p.title, i+1, len(chunks))
+			}
+			sections = append(sections, Section{
+				ReportID:    report.ID,
+				BoardID:     report.BoardID,
+				BoardName:   report.BoardName,
+				Type:        report.Type,
+				Period:      report.PeriodKey(),
+				StartDate:   report.StartDate,
+				EndDate:     report.EndDate,
+				GeneratedAt: report.GeneratedAt,
+				Title:       title,
+				Text:        chunk,
+			})
+		}
+	}
+	return sections
+}
+
+// splitText splits text into chunks of at most maxTokens, on line breaks
+// where it can. A single line longer than that is kept whole.
+func splitText(text string, maxTokens int) []string {
+	if llm.EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current []string
+	tokens := 0
+	for _, line := range strings.Split(text, "\n") {
+		cost := llm.EstimateTokens(line) + 1
+		if tokens+cost > maxTokens && len(current) > 0 {
+			chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n")))
+			current, tokens = nil, 0
+		}
+		current = append(current, line)
+		tokens += cost
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+	return chunks
+}
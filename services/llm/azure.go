@@ -0,0 +1,315 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// AzureProvider talks to Azure AI Foundry through the azopenai SDK
+type AzureProvider struct {
+	base
+	client *azopenai.Client
+}
+
+// NewAzureProvider creates a provider for an Azure AI Foundry endpoint
+func NewAzureProvider(name, endpoint, apiKey, model string) (*AzureProvider, error) {
+	// Create the API key credential
+	cred := azcore.NewKeyCredential(apiKey)
+
+	// Create the client with API key
+	client, err := azopenai.NewClientWithKeyCredential(endpoint, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure OpenAI client: %v", err)
+	}
+
+	return &AzureProvider{
+		base:   base{name: name, model: model},
+		client: client,
+	}, nil
+}
+
+// Complete sends a chat completion request
+func (p *AzureProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	deploymentID := modelOrDefault(req, p.model)
+	options := azopenai.ChatCompletionsOptions{
+		DeploymentName: &deploymentID,
+		Messages:       azureMessages(req.Messages),
+		Temperature:    float32Ptr(req.Temperature),
+	}
+	if req.MaxTokens > 0 {
+		options.MaxTokens = int32Ptr(int32(req.MaxTokens))
+	}
+	if req.ResponseFormat != nil {
+		options.ResponseFormat = azureResponseFormat(req.ResponseFormat)
+	}
+	if len(req.Tools) > 0 {
+		options.Tools = azureTools(req.Tools)
+	}
+
+	// Send the request
+	resp, err := p.client.GetChatCompletions(ctx, options, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", azureError(err))
+	}
+
+	// Extract the response content
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned")
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message == nil || (choice.Message.Content == nil && len(choice.Message.ToolCalls) == 0) {
+		return nil, fmt.Errorf("empty response content")
+	}
+
+	result := &Response{
+		Model:     deploymentID,
+		ToolCalls: azureToolCalls(choice.Message.ToolCalls),
+	}
+	if choice.Message.Content != nil {
+		result.Content = *choice.Message.Content
+	}
+	if choice.FinishReason != nil {
+		result.FinishReason = string(*choice.FinishReason)
+	}
+	if resp.Usage != nil {
+		result.Usage = azureUsage(resp.Usage)
+	}
+
+	return result, nil
+}
+
+// Embed sends an embeddings request to the embedding model's deployment
+func (p *AzureProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	deploymentID := embeddingModelOrDefault(req, p.embeddingModel)
+	resp, err := p.client.GetEmbeddings(ctx, azopenai.EmbeddingsOptions{
+		DeploymentName: &deploymentID,
+		Input:          req.Input,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", azureError(err))
+	}
+
+	// Put the vectors in the order of the inputs
+	vectors := make([][]float32, len(req.Input))
+	for i, item := range resp.Data {
+		index := i
+		if item.Index != nil {
+			index = int(*item.Index)
+		}
+		if index < 0 || index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", index)
+		}
+		vectors[index] = item.Embedding
+	}
+	if err := checkEmbeddings(req, vectors); err != nil {
+		return nil, err
+	}
+
+	result := &EmbeddingResponse{Vectors: vectors, Model: deploymentID}
+	if resp.Usage != nil && resp.Usage.PromptTokens != nil {
+		result.Usage.PromptTokens = int(*resp.Usage.PromptTokens)
+	}
+	return result, nil
+}
+
+// Stream sends a chat completion request and streams the content, assembling
+// any tool calls from their pieces
+func (p *AzureProvider) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	deploymentID := modelOrDefault(req, p.model)
+	options := azopenai.ChatCompletionsStreamOptions{
+		DeploymentName: &deploymentID,
+		Messages:       azureMessages(req.Messages),
+		Temperature:    float32Ptr(req.Temperature),
+	}
+	if req.MaxTokens > 0 {
+		options.MaxTokens = int32Ptr(int32(req.MaxTokens))
+	}
+	if req.ResponseFormat != nil {
+		options.ResponseFormat = azureResponseFormat(req.ResponseFormat)
+	}
+	if len(req.Tools) > 0 {
+		options.Tools = azureTools(req.Tools)
+	}
+
+	resp, err := p.client.GetChatCompletionsStream(ctx, options, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", azureError(err))
+	}
+	defer resp.ChatCompletionsStream.Close()
+
+	result := &Response{Model: deploymentID}
+	var content strings.Builder
+	var toolCalls toolCallBuilder
+
+	for {
+		chunk, err := resp.ChatCompletionsStream.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream: %w", azureError(err))
+		}
+
+		if chunk.Usage != nil {
+			result.Usage = azureUsage(chunk.Usage)
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				result.FinishReason = string(*choice.FinishReason)
+			}
+			if choice.Delta == nil {
+				continue
+			}
+			// Stream deltas don't carry an index; a call starts with its ID
+			for _, call := range choice.Delta.ToolCalls {
+				if function, ok := call.(*azopenai.ChatCompletionsFunctionToolCall); ok && function.Function != nil {
+					toolCalls.add(-1, stringValue(function.ID), stringValue(function.Function.Name), stringValue(function.Function.Arguments))
+				}
+			}
+			if choice.Delta.Content == nil || *choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(*choice.Delta.Content)
+			if err := onDelta(*choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Content = content.String()
+	result.ToolCalls = toolCalls.calls
+	return result, nil
+}
+
+// azureError converts an SDK response error to an APIError so it can be
+// classified by status code
+func azureError(err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return &APIError{StatusCode: respErr.StatusCode, Body: respErr.ErrorCode}
+	}
+	return err
+}
+
+// azureMessages converts messages to the azopenai request types
+func azureMessages(messages []Message) []azopenai.ChatRequestMessageClassification {
+	result := make([]azopenai.ChatRequestMessageClassification, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		case RoleSystem:
+			result = append(result, &azopenai.ChatRequestSystemMessage{
+				Content: azopenai.NewChatRequestSystemMessageContent(m.Content),
+			})
+		case RoleAssistant:
+			message := &azopenai.ChatRequestAssistantMessage{
+				Content: azopenai.NewChatRequestAssistantMessageContent(m.Content),
+			}
+			for _, call := range m.ToolCalls {
+				id, name, arguments := call.ID, call.Name, call.Arguments
+				message.ToolCalls = append(message.ToolCalls, &azopenai.ChatCompletionsFunctionToolCall{
+					ID:       &id,
+					Function: &azopenai.FunctionCall{Name: &name, Arguments: &arguments},
+				})
+			}
+			result = append(result, message)
+		case RoleTool:
+			toolCallID := m.ToolCallID
+			result = append(result, &azopenai.ChatRequestToolMessage{
+				Content:    azopenai.NewChatRequestToolMessageContent(m.Content),
+				ToolCallID: &toolCallID,
+			})
+		default:
+			result = append(result, &azopenai.ChatRequestUserMessage{
+				Content: azopenai.NewChatRequestUserMessageContent(m.Content),
+			})
+		}
+	}
+	return result
+}
+
+// azureTools converts tool definitions to function tools
+func azureTools(tools []Tool) []azopenai.ChatCompletionsToolDefinitionClassification {
+	result := make([]azopenai.ChatCompletionsToolDefinitionClassification, 0, len(tools))
+	for _, tool := range tools {
+		name, description := tool.Name, tool.Description
+		result = append(result, &azopenai.ChatCompletionsFunctionToolDefinition{
+			Function: &azopenai.ChatCompletionsFunctionToolDefinitionFunction{
+				Name:        &name,
+				Description: &description,
+				Parameters:  []byte(tool.Parameters),
+			},
+		})
+	}
+	return result
+}
+
+// azureToolCalls converts the function tool calls in a response
+func azureToolCalls(calls []azopenai.ChatCompletionsToolCallClassification) []ToolCall {
+	var result []ToolCall
+	for _, call := range calls {
+		function, ok := call.(*azopenai.ChatCompletionsFunctionToolCall)
+		if !ok || function.Function == nil {
+			continue
+		}
+
+		var toolCall ToolCall
+		if function.ID != nil {
+			toolCall.ID = *function.ID
+		}
+		if function.Function.Name != nil {
+			toolCall.Name = *function.Function.Name
+		}
+		if function.Function.Arguments != nil {
+			toolCall.Arguments = *function.Function.Arguments
+		}
+		result = append(result, toolCall)
+	}
+	return result
+}
+
+// azureResponseFormat converts a JSON schema response format
+func azureResponseFormat(format *ResponseFormat) azopenai.ChatCompletionsResponseFormatClassification {
+	name := format.Name
+	return &azopenai.ChatCompletionsJSONSchemaResponseFormat{
+		JSONSchema: &azopenai.ChatCompletionsJSONSchemaResponseFormatJSONSchema{
+			Name:   &name,
+			Schema: format.Schema,
+		},
+	}
+}
+
+// azureUsage converts the SDK usage type
+func azureUsage(usage *azopenai.CompletionsUsage) Usage {
+	var result Usage
+	if usage.PromptTokens != nil {
+		result.PromptTokens = int(*usage.PromptTokens)
+	}
+	if usage.CompletionTokens != nil {
+		result.CompletionTokens = int(*usage.CompletionTokens)
+	}
+	return result
+}
+
+// Helper functions for pointer types
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func stringValue(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
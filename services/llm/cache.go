@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores completions on disk so identical requests aren't paid for
+// twice. Entries expire after the TTL, and the least recently used entries
+// are evicted when the cache grows past its size limit.
+type Cache struct {
+	StoragePath string
+	TTL         time.Duration
+	// MaxBytes limits the total size of the cached entries
+	MaxBytes int64
+	mutex    sync.Mutex
+}
+
+// cacheEntry is a cached completion and the provider and model that wrote
+// it, which may be a fallback rather than the model requested
+type cacheEntry struct {
+	Key       string    `json:"key"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Response  Response  `json:"response"`
+}
+
+// NewCache creates a completion cache in the given directory
+func NewCache(storagePath string, ttl time.Duration, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	return &Cache{
+		StoragePath: storagePath,
+		TTL:         ttl,
+		MaxBytes:    maxBytes,
+	}, nil
+}
+
+// CacheKey hashes everything that determines a completion: the provider,
+// model and parameters, the tools offered and the messages. Message text is
+// normalized so differences in line endings and trailing whitespace don't
+// cause misses.
+func CacheKey(provider string, req Request) string {
+	type keyMessage struct {
+		Role       Role       `json:"role"`
+		Content    string     `json:"content"`
+		ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+		ToolCallID string     `json:"tool_call_id,omitempty"`
+	}
+	type keyTool struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	}
+	key := struct {
+		Provider       string          `json:"provider"`
+		Model          string          `json:"model"`
+		Temperature    float32         `json:"temperature"`
+		MaxTokens      int             `json:"max_tokens"`
+		ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+		Tools          []keyTool       `json:"tools,omitempty"`
+		Messages       []keyMessage    `json:"messages"`
+	}{
+		Provider:       provider,
+		Model:          req.Model,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		ResponseFormat: req.ResponseFormat,
+	}
+	for _, tool := range req.Tools {
+		key.Tools = append(key.Tools, keyTool{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+	}
+	for _, m := range req.Messages {
+		key.Messages = append(key.Messages, keyMessage{
+			Role:       m.Role,
+			Content:    normalizeText(m.Content),
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+		})
+	}
+
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeText unifies line endings and strips trailing whitespace
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Get returns the cached response for a key, if present and not expired,
+// with the provider and model that wrote it
+func (c *Cache) Get(key string) (*Response, *CacheSource, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		os.Remove(path)
+		return nil, nil, false
+	}
+	if c.TTL > 0 && time.Since(entry.CreatedAt) > c.TTL {
+		os.Remove(path)
+		return nil, nil, false
+	}
+
+	// Mark the entry as recently used for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	source := &CacheSource{Provider: entry.Provider, Model: entry.Model}
+	if source.Model == "" {
+		source.Model = entry.Response.Model
+	}
+	return &entry.Response, source, true
+}
+
+// CacheSource is the provider and model that wrote a cached response
+type CacheSource struct {
+	Provider string
+	Model    string
+}
+
+// Put stores a response written by the source's provider and model, and
+// evicts old entries if the cache is too large
+func (c *Cache) Put(key string, source CacheSource, resp *Response) error {
+	entry := cacheEntry{
+		Key:       key,
+		Provider:  source.Provider,
+		Model:     source.Model,
+		CreatedAt: time.Now(),
+		Response:  *resp,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling cache entry: %v", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := ioutil.WriteFile(c.path(key), data, 0644); err != nil {
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+
+	return c.evict()
+}
+
+// evict removes expired entries, then the least recently used ones until
+// the cache fits in MaxBytes
+func (c *Cache) evict() error {
+	files, err := ioutil.ReadDir(c.StoragePath)
+	if err != nil {
+		return fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	var live []os.FileInfo
+	var total int64
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		// Entries are touched when used, so an old modification time means
+		// the entry is at least that old
+		if c.TTL > 0 && time.Since(file.ModTime()) > c.TTL {
+			os.Remove(filepath.Join(c.StoragePath, file.Name()))
+			continue
+		}
+		live = append(live, file)
+		total += file.Size()
+	}
+
+	if c.MaxBytes <= 0 || total <= c.MaxBytes {
+		return nil
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].ModTime().Before(live[j].ModTime())
+	})
+	for _, file := range live {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.StoragePath, file.Name())); err == nil {
+			total -= file.Size()
+		}
+	}
+
+	return nil
+}
+
+// path returns the file for a cache key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.StoragePath, filepath.Base(key)+".json")
+}
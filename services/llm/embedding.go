@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embedder is implemented by providers that can embed text as vectors for
+// semantic search
+type Embedder interface {
+	// Name identifies the provider, e.g. in usage records
+	Name() string
+	// Embed returns one vector for each input, in order
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+	// DefaultEmbeddingModel returns the model used when a request doesn't
+	// set one
+	DefaultEmbeddingModel() string
+}
+
+// EmbeddingRequest asks for the embeddings of some texts
+type EmbeddingRequest struct {
+	// Model is the embedding model or deployment name. Providers fall back
+	// to their default embedding model when it is empty.
+	Model string
+	Input []string
+}
+
+// EmbeddingResponse holds one vector for each input of the request
+type EmbeddingResponse struct {
+	Vectors [][]float32
+	Model   string
+	// Usage counts the input tokens; embeddings have no completion tokens
+	Usage Usage
+}
+
+// DefaultEmbeddingModel returns the model used when an embedding request
+// doesn't set one
+func (b *base) DefaultEmbeddingModel() string {
+	return b.embeddingModel
+}
+
+// SetEmbeddingModel sets the default embedding model
+func (b *base) SetEmbeddingModel(model string) {
+	b.embeddingModel = model
+}
+
+// embeddingModelOrDefault returns the request model, or the provider
+// default if unset
+func embeddingModelOrDefault(req EmbeddingRequest, defaultModel string) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return defaultModel
+}
+
+// checkEmbeddings checks that a response has one vector for each input
+func checkEmbeddings(req EmbeddingRequest, vectors [][]float32) error {
+	if len(vectors) != len(req.Input) {
+		return fmt.Errorf("got %d embeddings for %d inputs", len(vectors), len(req.Input))
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return fmt.Errorf("empty embedding for input %d", i)
+		}
+	}
+	return nil
+}
+
+// HashEmbeddingDimensions is the length of the vectors from HashEmbedding
+const HashEmbeddingDimensions = 256
+
+// HashEmbedding embeds text by hashing its lowercase words and adjacent
+// word pairs into a fixed number of dimensions. It needs no model, so the
+// fake provider and the local stand-in endpoint use it: texts that share
+// words get similar vectors, which is enough to exercise semantic search
+// offline, though it knows nothing of meaning.
+func HashEmbedding(text string, dimensions int) []float32 {
+	vector := make([]float32, dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The top bit picks the sign, so unrelated features cancel out
+		// rather than all adding up
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(dimensions)] += weight
+	}
+	for i, word := range words {
+		add(word, 1)
+		if i > 0 {
+			add(words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	// Normalize to unit length, so cosine similarity is a dot product
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind classifies a failed completion so callers can decide whether to
+// retry it, try another model or give up
+type ErrorKind string
+
+const (
+	// ErrorRateLimit means the provider is throttling requests
+	ErrorRateLimit ErrorKind = "rate_limit"
+	// ErrorTimeout means the request or the provider timed out
+	ErrorTimeout ErrorKind = "timeout"
+	// ErrorServer means the provider failed with a 5xx status
+	ErrorServer ErrorKind = "server"
+	// ErrorNetwork means the provider couldn't be reached
+	ErrorNetwork ErrorKind = "network"
+	// ErrorAuth means the credentials were rejected
+	ErrorAuth ErrorKind = "auth"
+	// ErrorNotFound means the model or deployment doesn't exist
+	ErrorNotFound ErrorKind = "not_found"
+	// ErrorInvalidRequest means the provider rejected the request itself
+	ErrorInvalidRequest ErrorKind = "invalid_request"
+	// ErrorCanceled means the caller gave up on the request
+	ErrorCanceled ErrorKind = "canceled"
+	// ErrorUnknown is anything else, such as an empty response
+	ErrorUnknown ErrorKind = "unknown"
+)
+
+// Retryable reports whether the same request may succeed if sent again
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case ErrorRateLimit, ErrorTimeout, ErrorServer, ErrorNetwork:
+		return true
+	}
+	return false
+}
+
+// Classify returns the kind of a completion error
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return classifyStatus(apiErr.StatusCode)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return ErrorNetwork
+	}
+
+	// The SDKs don't always wrap their errors, so fall back to the message
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "connection refused"), strings.Contains(message, "connection reset"),
+		strings.Contains(message, "no such host"):
+		return ErrorNetwork
+	case strings.Contains(message, "timeout"), strings.Contains(message, "deadline exceeded"):
+		return ErrorTimeout
+	}
+
+	return ErrorUnknown
+}
+
+// classifyStatus classifies an HTTP status code
+func classifyStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorTimeout
+	case status >= 500:
+		return ErrorServer
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusNotFound:
+		return ErrorNotFound
+	case status >= 400:
+		return ErrorInvalidRequest
+	}
+	return ErrorUnknown
+}
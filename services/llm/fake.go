@@ -0,0 +1,196 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is a scripted provider for tests and offline development.
+// Responses are returned in the order they were queued; once the script is
+// exhausted the handler, if any, is used.
+type FakeProvider struct {
+	base
+
+	mu       sync.Mutex
+	script   []FakeResponse
+	handler  func(req Request) (*Response, error)
+	requests []Request
+}
+
+// FakeResponse is one scripted reply
+type FakeResponse struct {
+	Content   string
+	ToolCalls []ToolCall
+	Err       error
+}
+
+// NewFakeProvider creates a fake that replies with the given contents in order
+func NewFakeProvider(name string, responses ...string) *FakeProvider {
+	p := &FakeProvider{base: base{name: name, model: "fake", embeddingModel: "fake-embedding"}}
+	for _, content := range responses {
+		p.script = append(p.script, FakeResponse{Content: content})
+	}
+	return p
+}
+
+// Push queues more scripted responses
+func (p *FakeProvider) Push(responses ...FakeResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.script = append(p.script, responses...)
+}
+
+// SetHandler sets a function that answers requests once the script is empty
+func (p *FakeProvider) SetHandler(handler func(req Request) (*Response, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+}
+
+// Requests returns every request the fake has received
+func (p *FakeProvider) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Request(nil), p.requests...)
+}
+
+// Complete returns the next scripted response
+func (p *FakeProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+
+	var next *FakeResponse
+	if len(p.script) > 0 {
+		next = &p.script[0]
+		p.script = p.script[1:]
+	}
+	handler := p.handler
+	p.mu.Unlock()
+
+	var resp *Response
+	switch {
+	case next != nil && next.Err != nil:
+		return nil, next.Err
+	case next != nil:
+		resp = &Response{Content: next.Content, ToolCalls: next.ToolCalls}
+	case handler != nil:
+		var err error
+		resp, err = handler(req)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("fake provider %q has no scripted response left", p.name)
+	}
+
+	if resp.Model == "" {
+		resp.Model = modelOrDefault(req, p.model)
+	}
+	if resp.FinishReason == "" {
+		resp.FinishReason = "stop"
+		if len(resp.ToolCalls) > 0 {
+			resp.FinishReason = "tool_calls"
+		}
+	}
+	if resp.Usage == (Usage{}) {
+		resp.Usage = Usage{
+			PromptTokens:     EstimateMessageTokens(req.Messages),
+			CompletionTokens: EstimateTokens(resp.Content),
+		}
+	}
+
+	return resp, nil
+}
+
+// Stream returns the next scripted response, delivered word by word
+func (p *FakeProvider) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(word); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Embed returns a HashEmbedding of each input, so semantic search works
+// offline without an embedding model
+func (p *FakeProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &EmbeddingResponse{Model: embeddingModelOrDefault(req, p.embeddingModel)}
+	for _, input := range req.Input {
+		result.Vectors = append(result.Vectors, HashEmbedding(input, HashEmbeddingDimensions))
+		result.Usage.PromptTokens += EstimateTokens(input)
+	}
+	return result, nil
+}
+
+// echoHandler answers with a short canned reply, used when the fake is
+// selected from configuration for offline development. Requests for JSON
+// get a placeholder object built from the schema.
+func echoHandler(req Request) (*Response, error) {
+	if req.ResponseFormat != nil {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(req.ResponseFormat.Schema, &schema); err != nil {
+			return nil, fmt.Errorf("invalid response schema: %v", err)
+		}
+		data, err := json.Marshal(placeholderFromSchema(schema))
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Content: string(data)}, nil
+	}
+
+	last := ""
+	if len(req.Messages) > 0 {
+		last = req.Messages[len(req.Messages)-1].Content
+	}
+	if len(last) > 200 {
+		last = last[:200] + "..."
+	}
+	return &Response{Content: "This is a response from the fake LLM provider.\n\nInput received:\n\n" + last}, nil
+}
+
+// placeholderFromSchema builds the smallest value that satisfies a JSON schema
+func placeholderFromSchema(schema map[string]interface{}) interface{} {
+	switch schema["type"] {
+	case "object":
+		result := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if propertySchema, ok := property.(map[string]interface{}); ok {
+				result[name] = placeholderFromSchema(propertySchema)
+			}
+		}
+		return result
+	case "array":
+		return []interface{}{}
+	case "number", "integer":
+		return 0
+	case "boolean":
+		return false
+	default:
+		if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+			return enum[0]
+		}
+		return "Generated by the fake LLM provider."
+	}
+}
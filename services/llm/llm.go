@@ -0,0 +1,219 @@
+// Package llm defines a provider-neutral interface for chat completion models
+// and implementations for Azure AI Foundry, OpenAI-compatible endpoints and a
+// scripted fake.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Role is the author of a chat message
+type Role string
+
+const (
+	// RoleSystem is used for system prompts
+	RoleSystem Role = "system"
+	// RoleUser is used for user messages
+	RoleUser Role = "user"
+	// RoleAssistant is used for model responses
+	RoleAssistant Role = "assistant"
+	// RoleTool is used for the results of tool calls
+	RoleTool Role = "tool"
+)
+
+// Message is a single chat message
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant message asked to call
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a tool message to the call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object
+	Parameters json.RawMessage
+}
+
+// ToolCall is a request from the model to call a tool
+type ToolCall struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Arguments is the JSON arguments object as sent by the model; it is
+	// not guaranteed to be valid JSON
+	Arguments string `json:"arguments"`
+}
+
+// Request is a chat completion request
+type Request struct {
+	// Model is the model or deployment name. Providers fall back to their
+	// default model when it is empty.
+	Model       string
+	Messages    []Message
+	Temperature float32
+	MaxTokens   int
+	// ResponseFormat asks for JSON output matching a schema. Not every
+	// backend enforces it, so callers must still validate the output.
+	ResponseFormat *ResponseFormat
+	// Tools are the functions the model may call
+	Tools []Tool
+}
+
+// ResponseFormat describes the JSON schema a response should conform to
+type ResponseFormat struct {
+	Name   string
+	Schema json.RawMessage
+}
+
+// Usage reports the tokens consumed by a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Response is a chat completion response
+type Response struct {
+	Content      string
+	Model        string
+	FinishReason string
+	Usage        Usage
+	// ToolCalls are the tools the model asked to call instead of, or as
+	// well as, answering
+	ToolCalls []ToolCall
+}
+
+// Provider is a chat completion backend
+type Provider interface {
+	// Name returns the configured name of the provider
+	Name() string
+	// DefaultModel returns the model used when a request doesn't set one
+	DefaultModel() string
+	// Complete sends a request and waits for the full response
+	Complete(ctx context.Context, req Request) (*Response, error)
+	// Stream sends a request and calls onDelta with each chunk of content as
+	// it arrives. The returned response holds the full content and any tool
+	// calls.
+	Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error)
+	// CountTokens estimates the number of prompt tokens for the messages
+	CountTokens(messages []Message) int
+	// ContextWindow returns the maximum prompt plus completion tokens
+	ContextWindow() int
+}
+
+// DefaultContextWindow is used for providers that don't configure one
+const DefaultContextWindow = 32768
+
+// base implements the parts of Provider shared by every backend
+type base struct {
+	name          string
+	model         string
+	contextWindow int
+	// embeddingModel is the default model for embeddings, for providers
+	// that implement Embedder
+	embeddingModel string
+}
+
+// Name returns the configured name of the provider
+func (b *base) Name() string {
+	return b.name
+}
+
+// DefaultModel returns the model used when a request doesn't set one
+func (b *base) DefaultModel() string {
+	return b.model
+}
+
+// CountTokens estimates the number of prompt tokens for the messages
+func (b *base) CountTokens(messages []Message) int {
+	return EstimateMessageTokens(messages)
+}
+
+// ContextWindow returns the maximum prompt plus completion tokens
+func (b *base) ContextWindow() int {
+	if b.contextWindow > 0 {
+		return b.contextWindow
+	}
+	return DefaultContextWindow
+}
+
+// SetContextWindow overrides the context window size
+func (b *base) SetContextWindow(tokens int) {
+	b.contextWindow = tokens
+}
+
+// tokensPerMessage approximates the per-message overhead of chat formatting
+const tokensPerMessage = 4
+
+// EstimateTokens approximates the number of tokens in a piece of text. It
+// assumes roughly four characters per token, which is close enough for
+// budgeting with the models we use.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// EstimateMessageTokens approximates the prompt tokens for a list of messages
+func EstimateMessageTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += tokensPerMessage + EstimateTokens(m.Content)
+		for _, call := range m.ToolCalls {
+			total += EstimateTokens(call.Name) + EstimateTokens(call.Arguments)
+		}
+	}
+	return total
+}
+
+// modelOrDefault returns the request model, or the provider default if unset
+func modelOrDefault(req Request, defaultModel string) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return defaultModel
+}
+
+// toolCallBuilder assembles tool calls from stream deltas. Each call's ID
+// and name arrive in its first delta and the arguments are split across the
+// following ones.
+type toolCallBuilder struct {
+	calls []ToolCall
+	// indexes maps stream indexes to positions in calls
+	indexes map[int]int
+}
+
+// add appends a delta. Deltas without an index (index < 0) start a new call
+// when they carry an ID and otherwise continue the last one.
+func (b *toolCallBuilder) add(index int, id, name, arguments string) {
+	position := -1
+	if index >= 0 {
+		if b.indexes == nil {
+			b.indexes = make(map[int]int)
+		}
+		if p, ok := b.indexes[index]; ok {
+			position = p
+		} else {
+			b.indexes[index] = len(b.calls)
+		}
+	} else if id == "" && len(b.calls) > 0 {
+		position = len(b.calls) - 1
+	}
+
+	if position < 0 {
+		b.calls = append(b.calls, ToolCall{})
+		position = len(b.calls) - 1
+	}
+
+	call := &b.calls[position]
+	if id != "" {
+		call.ID = id
+	}
+	if name != "" {
+		call.Name = name
+	}
+	call.Arguments += arguments
+}
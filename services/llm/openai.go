@@ -0,0 +1,362 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIProvider talks to any OpenAI-compatible /v1/chat/completions
+// endpoint, such as OpenAI itself, a llama.cpp server or Ollama
+type OpenAIProvider struct {
+	base
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates a provider for an OpenAI-compatible endpoint.
+// baseURL should include the version prefix, e.g. http://localhost:11434/v1.
+func NewOpenAIProvider(name, baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		base:       base{name: name, model: model},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+	}
+}
+
+// APIError is returned when the endpoint responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// openAIRequest is the wire format of a chat completion request
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    float32               `json:"temperature"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+}
+
+// openAIMessage is the wire format of a chat message
+type openAIMessage struct {
+	Role       Role             `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	// Index identifies the call a stream delta belongs to
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type openAITool struct {
+	Type     string            `json:"type"`
+	Function openAIFunctionDef `json:"function"`
+}
+
+type openAIFunctionDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIResponse is the wire format of a completion or a stream chunk
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      *openAIMessage `json:"message"`
+		Delta        *openAIMessage `json:"delta"`
+		FinishReason *string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// Complete sends a chat completion request
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := p.wireRequest(req)
+
+	resp, err := p.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	// Extract the response content
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned")
+	}
+
+	choice := parsed.Choices[0]
+	if choice.Message == nil {
+		return nil, fmt.Errorf("empty response content")
+	}
+
+	result := &Response{
+		Content: choice.Message.Content,
+		Model:   body.Model,
+	}
+	for _, call := range choice.Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	if parsed.Model != "" {
+		result.Model = parsed.Model
+	}
+	if choice.FinishReason != nil {
+		result.FinishReason = *choice.FinishReason
+	}
+	if parsed.Usage != nil {
+		result.Usage = *parsed.Usage
+	}
+
+	return result, nil
+}
+
+// Stream sends a chat completion request and streams the content as
+// server-sent events. Tool calls arrive in pieces and are assembled into the
+// returned response.
+func (p *OpenAIProvider) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	body := p.wireRequest(req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	resp, err := p.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Response{Model: body.Model}
+	var content strings.Builder
+	var toolCalls toolCallBuilder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("error parsing stream chunk: %v", err)
+		}
+
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				result.FinishReason = *choice.FinishReason
+			}
+			if choice.Delta == nil {
+				continue
+			}
+			for _, call := range choice.Delta.ToolCalls {
+				index := -1
+				if call.Index != nil {
+					index = *call.Index
+				}
+				toolCalls.add(index, call.ID, call.Function.Name, call.Function.Arguments)
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %w", err)
+	}
+
+	result.Content = content.String()
+	result.ToolCalls = toolCalls.calls
+	return result, nil
+}
+
+// openAIEmbeddingRequest is the wire format of an embeddings request
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbeddingResponse is the wire format of an embeddings response
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage *Usage `json:"usage"`
+}
+
+// Embed sends an embeddings request to the /embeddings endpoint
+func (p *OpenAIProvider) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	body := &openAIEmbeddingRequest{
+		Model: embeddingModelOrDefault(req, p.embeddingModel),
+		Input: req.Input,
+	}
+
+	resp, err := p.post(ctx, "/embeddings", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	// Put the vectors in the order of the inputs
+	vectors := make([][]float32, len(req.Input))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	if err := checkEmbeddings(req, vectors); err != nil {
+		return nil, err
+	}
+
+	result := &EmbeddingResponse{Vectors: vectors, Model: body.Model}
+	if parsed.Model != "" {
+		result.Model = parsed.Model
+	}
+	if parsed.Usage != nil {
+		result.Usage = *parsed.Usage
+	} else {
+		for _, input := range req.Input {
+			result.Usage.PromptTokens += EstimateTokens(input)
+		}
+	}
+	return result, nil
+}
+
+// wireRequest builds the JSON request body
+func (p *OpenAIProvider) wireRequest(req Request) *openAIRequest {
+	wire := &openAIRequest{
+		Model:       modelOrDefault(req, p.model),
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	for _, m := range req.Messages {
+		message := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, openAIToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+		wire.Messages = append(wire.Messages, message)
+	}
+	for _, tool := range req.Tools {
+		wire.Tools = append(wire.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunctionDef{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	if req.ResponseFormat != nil {
+		wire.ResponseFormat = &openAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &openAIJSONSchema{
+				Name:   req.ResponseFormat.Name,
+				Schema: req.ResponseFormat.Schema,
+			},
+		}
+	}
+	return wire
+}
+
+// post sends a request to an endpoint, e.g. /chat/completions
+func (p *OpenAIProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(errBody)}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,13 @@
+package llm
+
+import "agents_go/config"
+
+// EstimateCost returns the estimated cost in US dollars of the tokens used
+// with a model, from config.LLMPrices. Models without a price cost nothing.
+func EstimateCost(model string, usage Usage) float64 {
+	price, ok := config.LLMPrices[model]
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
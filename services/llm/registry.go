@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+
+	"agents_go/config"
+)
+
+// Registry holds the providers configured for this deployment
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry(defaultName string) *Registry {
+	return &Registry{
+		providers:   make(map[string]Provider),
+		defaultName: defaultName,
+	}
+}
+
+// NewRegistryFromConfig creates providers for every entry in config.LLMProviders
+func NewRegistryFromConfig() (*Registry, error) {
+	registry := NewRegistry(config.DefaultLLMProvider)
+
+	for _, pc := range config.LLMProviders {
+		provider, err := NewProvider(pc)
+		if err != nil {
+			return nil, fmt.Errorf("error creating LLM provider %q: %v", pc.Name, err)
+		}
+		registry.Register(provider)
+	}
+
+	if _, ok := registry.providers[registry.defaultName]; !ok {
+		return nil, fmt.Errorf("default LLM provider %q is not configured", registry.defaultName)
+	}
+
+	return registry, nil
+}
+
+// NewProvider creates a provider from its configuration
+func NewProvider(pc config.LLMProviderConfig) (Provider, error) {
+	switch pc.Type {
+	case "azure":
+		azure, err := NewAzureProvider(pc.Name, pc.BaseURL, pc.APIKey, pc.Model)
+		if err != nil {
+			return nil, err
+		}
+		azure.SetContextWindow(pc.ContextWindow)
+		azure.SetEmbeddingModel(pc.EmbeddingModel)
+		return azure, nil
+	case "openai":
+		openai := NewOpenAIProvider(pc.Name, pc.BaseURL, pc.APIKey, pc.Model)
+		openai.SetContextWindow(pc.ContextWindow)
+		openai.SetEmbeddingModel(pc.EmbeddingModel)
+		return openai, nil
+	case "fake":
+		fake := NewFakeProvider(pc.Name)
+		if pc.Model != "" {
+			fake.model = pc.Model
+		}
+		fake.SetContextWindow(pc.ContextWindow)
+		if pc.EmbeddingModel != "" {
+			fake.SetEmbeddingModel(pc.EmbeddingModel)
+		}
+		fake.SetHandler(echoHandler)
+		return fake, nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", pc.Type)
+	}
+}
+
+// Register adds a provider, replacing any provider with the same name
+func (r *Registry) Register(provider Provider) {
+	r.providers[provider.Name()] = provider
+}
+
+// Names returns the names of all registered providers
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultName returns the name of the default provider
+func (r *Registry) DefaultName() string {
+	return r.defaultName
+}
+
+// Resolve returns the named provider and the model to use with it. An empty
+// name selects the default provider and an empty model selects the
+// provider's default model.
+func (r *Registry) Resolve(name, model string) (Provider, string, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown LLM provider %q", name)
+	}
+
+	if model == "" {
+		model = provider.DefaultModel()
+	}
+
+	return provider, model, nil
+}
+
+// Embedder returns the named provider as an embedder and the embedding model
+// to use with it. An empty name selects the default provider.
+func (r *Registry) Embedder(name string) (Embedder, string, error) {
+	provider, _, err := r.Resolve(name, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, "", fmt.Errorf("LLM provider %q can't embed text", provider.Name())
+	}
+	model := embedder.DefaultEmbeddingModel()
+	if model == "" {
+		return nil, "", fmt.Errorf("LLM provider %q has no embedding model configured", provider.Name())
+	}
+	return embedder, model, nil
+}
@@ -0,0 +1,215 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"agents_go/config"
+)
+
+// ErrAllModelsFailed is returned when every model in a fallback chain failed
+var ErrAllModelsFailed = errors.New("all models failed")
+
+// errPartialStream marks a stream that failed after sending output
+var errPartialStream = errors.New("stream failed after output was sent")
+
+// RetryPolicy controls how failed completions are retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per model, including the first
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles for each
+	// further retry up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout cuts off each attempt; zero means no limit
+	Timeout time.Duration
+}
+
+// DefaultRetryPolicy returns the policy from the configuration
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: config.LLMRetryAttempts,
+		BaseDelay:   config.LLMRetryBaseDelay,
+		MaxDelay:    config.LLMRetryMaxDelay,
+		Timeout:     config.LLMRequestTimeout,
+	}
+}
+
+// backoff returns the wait before the given retry, with jitter so clients
+// that failed together don't retry together
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << uint(retry)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// do runs an attempt until it succeeds, fails with an error that isn't
+// transient or runs out of attempts. retryable can veto a retry, e.g. once
+// part of a stream has been delivered.
+func (p RetryPolicy) do(ctx context.Context, attempt func(ctx context.Context) (*Response, error), retryable func() bool) (*Response, error) {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(p.backoff(i - 1)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.Timeout)
+		}
+		var resp *Response
+		resp, err = attempt(attemptCtx)
+		cancel()
+		if err == nil {
+			return resp, nil
+		}
+
+		// Stop if the caller gave up or the error won't go away
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		kind := Classify(err)
+		if !kind.Retryable() || (retryable != nil && !retryable()) {
+			return nil, err
+		}
+		if i < attempts-1 {
+			log.Printf("LLM request failed (%s), retrying: %v", kind, err)
+		}
+	}
+
+	return nil, err
+}
+
+// Target is a provider and model to send requests to
+type Target struct {
+	Provider Provider
+	Model    string
+}
+
+// String returns the target as "provider/model"
+func (t Target) String() string {
+	return t.Provider.Name() + "/" + t.Model
+}
+
+// Fallback is a Provider that sends each request to a chain of targets in
+// order, retrying transient failures on each, until one succeeds. It
+// reports the name, token counting and context window of the first target.
+type Fallback struct {
+	targets []Target
+	policy  RetryPolicy
+
+	mutex sync.Mutex
+	used  *Target
+}
+
+// NewFallback creates a fallback chain; the first target is the primary
+func NewFallback(policy RetryPolicy, targets ...Target) *Fallback {
+	return &Fallback{targets: targets, policy: policy}
+}
+
+// Name returns the name of the primary provider
+func (f *Fallback) Name() string {
+	return f.targets[0].Provider.Name()
+}
+
+// DefaultModel returns the primary model
+func (f *Fallback) DefaultModel() string {
+	return f.targets[0].Model
+}
+
+// CountTokens estimates prompt tokens with the primary provider
+func (f *Fallback) CountTokens(messages []Message) int {
+	return f.targets[0].Provider.CountTokens(messages)
+}
+
+// ContextWindow returns the primary provider's context window
+func (f *Fallback) ContextWindow() int {
+	return f.targets[0].Provider.ContextWindow()
+}
+
+// Complete sends the request to each target until one succeeds
+func (f *Fallback) Complete(ctx context.Context, req Request) (*Response, error) {
+	return f.run(ctx, req, func(ctx context.Context, target Target, req Request) (*Response, error) {
+		return f.policy.do(ctx, func(ctx context.Context) (*Response, error) {
+			return target.Provider.Complete(ctx, req)
+		}, nil)
+	})
+}
+
+// Stream streams the request from each target until one succeeds. Once
+// part of a response has been delivered to onDelta, a failure is returned
+// rather than retried, since the output can't be taken back.
+func (f *Fallback) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	delivered := false
+	deliver := func(delta string) error {
+		delivered = true
+		return onDelta(delta)
+	}
+
+	return f.run(ctx, req, func(ctx context.Context, target Target, req Request) (*Response, error) {
+		resp, err := f.policy.do(ctx, func(ctx context.Context) (*Response, error) {
+			return target.Provider.Stream(ctx, req, deliver)
+		}, func() bool { return !delivered })
+		if err != nil && delivered {
+			return nil, fmt.Errorf("%w: %v", errPartialStream, err)
+		}
+		return resp, err
+	})
+}
+
+// run tries each target in order
+func (f *Fallback) run(ctx context.Context, req Request, send func(ctx context.Context, target Target, req Request) (*Response, error)) (*Response, error) {
+	var failures []string
+	for i, target := range f.targets {
+		req.Model = target.Model
+		resp, err := send(ctx, target, req)
+		if err == nil {
+			f.mutex.Lock()
+			f.used = &f.targets[i]
+			f.mutex.Unlock()
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", target, err))
+		// Once a stream has sent output, the remaining targets can't help
+		if errors.Is(err, errPartialStream) {
+			break
+		}
+		if i < len(f.targets)-1 {
+			log.Printf("LLM %s failed (%s), falling back to %s: %v", target, Classify(err), f.targets[i+1], err)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrAllModelsFailed, strings.Join(failures, "; "))
+}
+
+// Used returns the target that answered the last successful request, or
+// nil if none has succeeded
+func (f *Fallback) Used() *Target {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.used
+}
@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// apiError returns a provider error with an HTTP status
+func apiError(status int) FakeResponse {
+	return FakeResponse{Err: &APIError{StatusCode: status, Body: http.StatusText(status)}}
+}
+
+// partialStream is a provider whose streams fail after sending output
+type partialStream struct {
+	*FakeProvider
+}
+
+func (p partialStream) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	p.FakeProvider.Complete(ctx, req)
+	if err := onDelta("Half a "); err != nil {
+		return nil, err
+	}
+	return nil, &APIError{StatusCode: http.StatusBadGateway, Body: "connection lost"}
+}
+
+func TestFallback(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		// primary and secondary script the two targets' responses
+		primary, secondary []FakeResponse
+		// partial makes the primary's streams fail after sending output
+		partial bool
+		stream  bool
+		// wantUsed is the model that answered, empty if all failed
+		wantUsed string
+		// wantRequests is the number of requests to each target
+		wantRequests [2]int
+	}{
+		{
+			name:         "first attempt succeeds",
+			primary:      []FakeResponse{{Content: "ok"}},
+			wantUsed:     "model-a",
+			wantRequests: [2]int{1, 0},
+		},
+		{
+			name:         "retries rate limits and server errors",
+			primary:      []FakeResponse{apiError(http.StatusTooManyRequests), apiError(http.StatusServiceUnavailable), {Content: "ok"}},
+			wantUsed:     "model-a",
+			wantRequests: [2]int{3, 0},
+		},
+		{
+			name:         "falls back once the attempts run out",
+			primary:      []FakeResponse{apiError(http.StatusBadGateway), apiError(http.StatusBadGateway), apiError(http.StatusBadGateway)},
+			secondary:    []FakeResponse{{Content: "ok"}},
+			wantUsed:     "model-b",
+			wantRequests: [2]int{3, 1},
+		},
+		{
+			name:         "falls back without retrying errors that won't go away",
+			primary:      []FakeResponse{apiError(http.StatusUnauthorized)},
+			secondary:    []FakeResponse{{Content: "ok"}},
+			wantUsed:     "model-b",
+			wantRequests: [2]int{1, 1},
+		},
+		{
+			name:         "all models fail",
+			primary:      []FakeResponse{apiError(http.StatusBadRequest)},
+			secondary:    []FakeResponse{apiError(http.StatusNotFound)},
+			wantRequests: [2]int{1, 1},
+		},
+		{
+			name:         "stream retried before output",
+			primary:      []FakeResponse{apiError(http.StatusServiceUnavailable), {Content: "ok"}},
+			stream:       true,
+			wantUsed:     "model-a",
+			wantRequests: [2]int{2, 0},
+		},
+		{
+			name:         "stream not retried or fallen back after output",
+			primary:      []FakeResponse{{Content: "ok"}, {Content: "ok"}},
+			secondary:    []FakeResponse{{Content: "ok"}},
+			partial:      true,
+			stream:       true,
+			wantRequests: [2]int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := NewFakeProvider("primary")
+			primary.Push(tt.primary...)
+			secondary := NewFakeProvider("secondary")
+			secondary.Push(tt.secondary...)
+			var first Provider = primary
+			if tt.partial {
+				first = partialStream{primary}
+			}
+			chain := NewFallback(policy, Target{Provider: first, Model: "model-a"}, Target{Provider: secondary, Model: "model-b"})
+
+			req := Request{Messages: []Message{{Role: RoleUser, Content: "Hello"}}}
+			var resp *Response
+			var err error
+			if tt.stream {
+				resp, err = chain.Stream(context.Background(), req, func(string) error { return nil })
+			} else {
+				resp, err = chain.Complete(context.Background(), req)
+			}
+
+			if tt.wantUsed == "" {
+				if !errors.Is(err, ErrAllModelsFailed) {
+					t.Errorf("got %v, want ErrAllModelsFailed", err)
+				}
+				if chain.Used() != nil {
+					t.Errorf("Used() = %v after every model failed", chain.Used())
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if resp.Content != "ok" {
+					t.Errorf("content is %q, want ok", resp.Content)
+				}
+				if used := chain.Used(); used == nil || used.Model != tt.wantUsed {
+					t.Errorf("Used() = %v, want %s", used, tt.wantUsed)
+				}
+			}
+
+			// Each target gets its own model
+			for i, target := range []*FakeProvider{primary, secondary} {
+				requests := target.Requests()
+				if len(requests) != tt.wantRequests[i] {
+					t.Errorf("%s got %d requests, want %d", target.Name(), len(requests), tt.wantRequests[i])
+				}
+				for _, r := range requests {
+					if want := []string{"model-a", "model-b"}[i]; r.Model != want {
+						t.Errorf("%s was asked for model %q, want %q", target.Name(), r.Model, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err       error
+		want      ErrorKind
+		retryable bool
+	}{
+		{err: &APIError{StatusCode: http.StatusTooManyRequests}, want: ErrorRateLimit, retryable: true},
+		{err: &APIError{StatusCode: http.StatusGatewayTimeout}, want: ErrorTimeout, retryable: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError}, want: ErrorServer, retryable: true},
+		{err: &APIError{StatusCode: http.StatusForbidden}, want: ErrorAuth},
+		{err: &APIError{StatusCode: http.StatusNotFound}, want: ErrorNotFound},
+		{err: &APIError{StatusCode: http.StatusBadRequest}, want: ErrorInvalidRequest},
+		{err: fmt.Errorf("error sending request: %w", context.DeadlineExceeded), want: ErrorTimeout, retryable: true},
+		{err: context.Canceled, want: ErrorCanceled},
+		{err: errors.New("dial tcp: connection refused"), want: ErrorNetwork, retryable: true},
+		{err: errors.New("empty response"), want: ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			kind := Classify(tt.err)
+			if kind != tt.want {
+				t.Errorf("Classify = %s, want %s", kind, tt.want)
+			}
+			if kind.Retryable() != tt.retryable {
+				t.Errorf("%s retryable = %v, want %v", kind, kind.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 400 * time.Millisecond}
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{retry: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{retry: 1, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{retry: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{retry: 5, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{retry: 70, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if delay := policy.backoff(tt.retry); delay < tt.min || delay > tt.max {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.retry, delay, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
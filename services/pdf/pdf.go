@@ -7,12 +7,26 @@ import (
 	"strings"
 	"time"
 
+	"agents_go/models"
+
 	"github.com/jung-kurt/gofpdf"
 )
 
 // Generator is a PDF report generator
 type Generator struct {
 	// PDF configuration options can be added here
+
+	// Notice is printed under the title, e.g. to mark reports generated
+	// without AI
+	Notice string
+
+	// Language sets the language of the section titles, labels and dates;
+	// empty is English
+	Language models.Language
+
+	// tr converts UTF-8 text to the encoding of the core fonts, so accented
+	// letters print correctly
+	tr func(string) string
 }
 
 // NewGenerator creates a new PDF generator
@@ -22,12 +36,47 @@ func NewGenerator() *Generator {
 
 // GenerateReport generates a PDF report from the given content
 func (g *Generator) GenerateReport(content, boardName, reportType string, startDate, endDate time.Time) (*bytes.Buffer, error) {
+	pdf := g.newDocument(boardName, reportType, startDate, endDate)
+
+	// Process the content for PDF
+	processedContent := g.processContentForPDF(content)
+
+	// Add the content to the PDF
+	g.addFormattedContent(pdf, processedContent)
+
+	return g.output(pdf)
+}
+
+// GenerateStructuredReport generates a PDF report from a structured report,
+// using its sections directly instead of parsing the markdown content
+func (g *Generator) GenerateStructuredReport(structured *models.StructuredReport, boardName, reportType string, startDate, endDate time.Time) (*bytes.Buffer, error) {
+	pdf := g.newDocument(boardName, reportType, startDate, endDate)
+
+	// Convert the report sections to PDF sections
+	var sections []ContentSection
+	for _, section := range structured.Sections(models.ReportType(reportType), g.Language) {
+		sections = append(sections, ContentSection{
+			Title:        section.Title,
+			Paragraphs:   section.Paragraphs,
+			BulletPoints: section.Items,
+		})
+	}
+
+	g.addSections(pdf, sections)
+
+	return g.output(pdf)
+}
+
+// newDocument creates a PDF document with the report title and period
+func (g *Generator) newDocument(boardName, reportType string, startDate, endDate time.Time) *gofpdf.Fpdf {
 	// Create a new PDF document with margins
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(15, 15, 15) // Left, Top, Right margins for a clean layout
+	g.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	title := g.Language.ReportTitle(boardName, models.ReportType(reportType))
 
 	// Set document properties
-	pdf.SetTitle(fmt.Sprintf("%s %s Report", boardName, reportType), true)
+	pdf.SetTitle(title, true)
 	pdf.SetAuthor("Trello Reporting Agent", true)
 	pdf.SetCreationDate(time.Now())
 
@@ -36,22 +85,26 @@ func (g *Generator) GenerateReport(content, boardName, reportType string, startD
 
 	// Add title
 	pdf.SetFont("Arial", "B", 18)
-	pdf.CellFormat(180, 12, fmt.Sprintf("%s %s Report", boardName, strings.Title(reportType)), "", 0, "C", false, 0, "")
+	pdf.CellFormat(180, 12, g.tr(title), "", 0, "C", false, 0, "")
 	pdf.Ln(15)
 
 	// Add report period
 	pdf.SetFont("Arial", "I", 10)
-	pdf.CellFormat(180, 6, fmt.Sprintf("Period: %s to %s", startDate.Format("Jan 2, 2006"), endDate.Format("Jan 2, 2006")), "", 0, "L", false, 0, "")
+	pdf.CellFormat(180, 6, g.tr(fmt.Sprintf("%s: %s", g.Language.T("Period"), g.Language.FormatPeriod(startDate, endDate))), "", 0, "L", false, 0, "")
+	pdf.Ln(6)
+	pdf.CellFormat(180, 6, g.tr(fmt.Sprintf("%s: %s", g.Language.T("Generated"), g.Language.FormatDateTime(time.Now()))), "", 0, "L", false, 0, "")
+	pdf.Ln(6)
+	if g.Notice != "" {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.MultiCell(180, 6, g.tr(g.Notice), "", "L", false)
+	}
 	pdf.Ln(6)
-	pdf.CellFormat(180, 6, fmt.Sprintf("Generated: %s", time.Now().Format("January 2, 2006 at 3:04 PM")), "", 0, "L", false, 0, "")
-	pdf.Ln(12)
-
-	// Process the content for PDF
-	processedContent := g.processContentForPDF(content)
 
-	// Add the content to the PDF
-	g.addFormattedContent(pdf, processedContent)
+	return pdf
+}
 
+// output writes the document to a buffer
+func (g *Generator) output(pdf *gofpdf.Fpdf) (*bytes.Buffer, error) {
 	// Save to buffer
 	var buf bytes.Buffer
 	err := pdf.Output(&buf)
@@ -82,18 +135,30 @@ func (g *Generator) processContentForPDF(content string) string {
 	// Clean up bold markers for subsections (e.g., **Tasks Completed:**)
 	content = regexp.MustCompile(`\*\*(.*?):\*\*`).ReplaceAllString(content, "**$1**")
 
-	// Normalize whitespace and newlines
-	content = regexp.MustCompile(`\s+`).ReplaceAllString(content, " ")
+	// Normalize whitespace and newlines, keeping the line breaks the
+	// section parser splits on
+	content = regexp.MustCompile(`[ \t]+`).ReplaceAllString(content, " ")
 	content = regexp.MustCompile(`\n\s*\n+`).ReplaceAllString(content, "\n\n")
 
 	return strings.TrimSpace(content)
 }
 
+// ParseSections returns the sections the PDF of a report's markdown content
+// would have, e.g. to check that every heading is recognized
+func (g *Generator) ParseSections(content string) []ContentSection {
+	return g.parseContentSections(g.processContentForPDF(content))
+}
+
 // addFormattedContent adds formatted content to the PDF
 func (g *Generator) addFormattedContent(pdf *gofpdf.Fpdf, content string) {
 	// Parse content into sections
 	sections := g.parseContentSections(content)
 
+	g.addSections(pdf, sections)
+}
+
+// addSections adds report sections to the PDF
+func (g *Generator) addSections(pdf *gofpdf.Fpdf, sections []ContentSection) {
 	// Set initial font for body text
 	pdf.SetFont("Arial", "", 10)
 
@@ -102,7 +167,7 @@ func (g *Generator) addFormattedContent(pdf *gofpdf.Fpdf, content string) {
 		// Add section heading
 		pdf.Ln(10)
 		pdf.SetFont("Arial", "B", 16)
-		pdf.CellFormat(180, 8, section.Title, "", 0, "L", false, 0, "")
+		pdf.CellFormat(180, 8, g.tr(section.Title), "", 0, "L", false, 0, "")
 		pdf.Ln(8)
 
 		// Add paragraphs and subsections
@@ -113,12 +178,12 @@ func (g *Generator) addFormattedContent(pdf *gofpdf.Fpdf, content string) {
 				subTitle := strings.TrimPrefix(strings.TrimSuffix(para, "**"), "**")
 				pdf.Ln(4)
 				pdf.SetFont("Arial", "B", 12)
-				pdf.CellFormat(180, 6, subTitle, "", 0, "L", false, 0, "")
+				pdf.CellFormat(180, 6, g.tr(subTitle), "", 0, "L", false, 0, "")
 				pdf.Ln(5)
 			} else {
 				// Regular paragraph
 				pdf.SetFont("Arial", "", 10)
-				pdf.MultiCell(180, 5, para, "", "", false)
+				pdf.MultiCell(180, 5, g.tr(para), "", "", false)
 				pdf.Ln(4)
 			}
 		}
@@ -135,9 +200,9 @@ func (g *Generator) addFormattedContent(pdf *gofpdf.Fpdf, content string) {
 				}
 				pdf.SetX(float64(indent))
 				pdf.SetFont("Arial", "", 10)
-				pdf.Cell(5, 5, "•")
+				pdf.Cell(5, 5, g.tr("•"))
 				pdf.SetX(float64(indent + 5))
-				pdf.MultiCell(170, 5, strings.TrimSpace(bullet), "", "", false)
+				pdf.MultiCell(170, 5, g.tr(strings.TrimSpace(bullet)), "", "", false)
 				pdf.Ln(2)
 			}
 		}
@@ -153,15 +218,11 @@ type ContentSection struct {
 
 // parseContentSections parses the content into structured sections
 func (g *Generator) parseContentSections(content string) []ContentSection {
-	// Define main section titles
-	sectionTitles := []string{
-		"Executive Summary",
-		"Progress This Week",
-		"Current Project Status",
-		"Priorities & Deadlines for Next Week",
-		"Risks, Blockers & Issues",
-		"Team Focus & Contributions",
-		"Data Limitations",
+	// Match the main section titles in every report language, since the
+	// content may be in any of them
+	var sectionTitles []string
+	for _, title := range models.SectionTitles() {
+		sectionTitles = append(sectionTitles, regexp.QuoteMeta(title))
 	}
 
 	// Create regex pattern for section titles
@@ -0,0 +1,64 @@
+package prompts
+
+// DefaultTemplateName returns the name of the default template for a report
+// type. Saving a version under this name changes the default for every board
+// that hasn't selected a different template.
+func DefaultTemplateName(reportType string) string {
+	switch reportType {
+	case "weekly", "monthly":
+		return "default-" + reportType
+	default:
+		return "default"
+	}
+}
+
+// builtinTemplates are version 0 of the default templates
+var builtinTemplates = map[string]string{
+	"default-weekly": `You are an AI assistant that generates weekly reports for Trello boards. 
+Your task is to analyze the board data provided and create a comprehensive weekly report{{ if .BoardName }} for the board "{{ .BoardName }}"{{ end }}{{ if .Period }} covering {{ .Period }}{{ end }}.
+
+The report should include:
+1. A summary of the board's current state
+2. Progress made during the week (completed tasks, moved cards)
+3. Pending tasks and their status
+4. Any blockers or issues identified
+5. Recommendations for the upcoming week
+
+Be concise but thorough.
+Start with an executive summary, then break down the details by list/category.
+Highlight important metrics and trends.
+
+Your report should be professional and actionable, providing clear insights into the project's progress.`,
+
+	"default-monthly": `You are an AI assistant that generates monthly reports for Trello boards. 
+Your task is to analyze the board data provided and create a comprehensive monthly report{{ if .BoardName }} for the board "{{ .BoardName }}"{{ end }}{{ if .Period }} covering {{ .Period }}{{ end }}.
+
+The report should include:
+1. An executive summary of the month's progress
+2. Key achievements and milestones reached
+3. Detailed analysis of completed work
+4. Current status of ongoing tasks
+5. Blockers and challenges encountered
+6. Trends and patterns observed
+7. Strategic recommendations for the next month
+
+Include metrics where possible, such as completion rates, task distribution, etc.
+Compare the current state with previous periods if the data allows.
+
+Your report should be thorough, insightful, and provide strategic value to the project stakeholders.`,
+
+	"default": `You are an AI assistant that generates reports for Trello boards.
+Analyze the board data provided and create a comprehensive report.
+Focus on providing actionable insights and clear status updates.`,
+}
+
+// Builtin returns the built-in template for a report type
+func Builtin(reportType string) *Template {
+	name := DefaultTemplateName(reportType)
+	return &Template{
+		Name:    name,
+		Version: 0,
+		Text:    builtinTemplates[name],
+		Note:    "Built-in default",
+	}
+}
@@ -0,0 +1,153 @@
+// Package prompts manages the system prompt templates used for report
+// generation. Templates are Go text/template files rendered with the board
+// metadata and report period. Built-in defaults can be overridden by saving
+// new versions, and boards select a template per report type.
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data is what prompt templates can reference
+type Data struct {
+	BoardID          string
+	BoardName        string
+	BoardDescription string
+	Lists            []string
+	Members          []string
+	CardCount        int
+	ReportType       string
+	StartDate        time.Time
+	EndDate          time.Time
+	// Period is the formatted date range, e.g. "Oct 11, 2026 to Oct 18, 2026",
+	// or empty if the dates are unknown
+	Period string
+}
+
+// Board values are rendered into the system prompt, so they are flattened
+// to a single line and shortened; anyone who can edit the board can set them
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 300
+)
+
+// NewData builds template data from the board data returned by
+// trello.Client.GetBoardData
+func NewData(boardData map[string]interface{}, reportType string, startDate, endDate time.Time) *Data {
+	data := &Data{
+		ReportType: reportType,
+		StartDate:  startDate,
+		EndDate:    endDate,
+	}
+
+	if !startDate.IsZero() && !endDate.IsZero() {
+		data.Period = fmt.Sprintf("%s to %s", startDate.Format("Jan 2, 2006"), endDate.Format("Jan 2, 2006"))
+	}
+
+	if board, ok := boardData["board"].(map[string]interface{}); ok {
+		data.BoardID, _ = board["id"].(string)
+		name, _ := board["name"].(string)
+		desc, _ := board["desc"].(string)
+		data.BoardName = promptValue(name, maxNameLength)
+		data.BoardDescription = promptValue(desc, maxDescriptionLength)
+	}
+
+	for _, list := range items(boardData["lists"]) {
+		if name, _ := list["name"].(string); name != "" {
+			data.Lists = append(data.Lists, promptValue(name, maxNameLength))
+		}
+	}
+	for _, member := range items(boardData["members"]) {
+		if name, _ := member["fullName"].(string); name != "" {
+			data.Members = append(data.Members, promptValue(name, maxNameLength))
+		}
+	}
+	data.CardCount = len(items(boardData["cards"]))
+
+	return data
+}
+
+// promptValue flattens untrusted text to one line of at most n runes, with
+// double quotes and angle brackets replaced so it can't close the quotes
+// templates put it in or imitate tags
+func promptValue(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.NewReplacer(`"`, "'", "<", "(", ">", ")").Replace(text)
+	if runes := []rune(text); len(runes) > n {
+		text = string(runes[:n]) + "..."
+	}
+	return text
+}
+
+// SampleData returns placeholder data for validating templates
+func SampleData(reportType string) *Data {
+	end := time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -7)
+	if reportType == "monthly" {
+		start = end.AddDate(0, -1, 0)
+	}
+	return &Data{
+		BoardID:          "sample",
+		BoardName:        "Sample Board",
+		BoardDescription: "A board used to preview templates",
+		Lists:            []string{"To Do", "In Progress", "Done"},
+		Members:          []string{"Alex Example"},
+		CardCount:        12,
+		ReportType:       reportType,
+		StartDate:        start,
+		EndDate:          end,
+		Period:           fmt.Sprintf("%s to %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006")),
+	}
+}
+
+// funcs are the helper functions available to templates
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"date":  func(t time.Time) string { return t.Format("Jan 2, 2006") },
+	"title": strings.Title,
+}
+
+// Parse parses template text, returning an error if it is invalid
+func Parse(text string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing prompt template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// Render parses and executes template text with the given data
+func Render(text string, data *Data) (string, error) {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt template: %v", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// items extracts a slice of maps from board data, which wraps slices in a
+// map with an "items" key
+func items(data interface{}) []map[string]interface{} {
+	if dataMap, ok := data.(map[string]interface{}); ok {
+		data = dataMap["items"]
+	}
+
+	list, _ := data.([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,221 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Template is one version of a prompt template. Versions are immutable;
+// editing a template saves a new version.
+type Template struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Text      string    `json:"text"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Ref identifies the template version used for a report
+func (t *Template) Ref() string {
+	return fmt.Sprintf("%s@v%d", t.Name, t.Version)
+}
+
+// namePattern restricts template names to safe directory names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Store handles storage of prompt templates on disk, one directory per
+// template and one JSON file per version
+type Store struct {
+	StoragePath string
+	mutex       sync.Mutex
+}
+
+// NewStore creates a new prompt template store
+func NewStore(storagePath string) (*Store, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &Store{
+		StoragePath: storagePath,
+	}, nil
+}
+
+// Save validates the template text and stores it as the next version of the
+// named template
+func (s *Store) Save(name, text, note string) (*Template, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q: use lowercase letters, digits, dashes and underscores", name)
+	}
+
+	// Make sure the template parses and renders before saving it
+	if _, err := Render(text, SampleData("weekly")); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	versions, err := s.versions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	tmpl := &Template{
+		Name:      name,
+		Version:   next,
+		Text:      text,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.StoragePath, name), 0755); err != nil {
+		return nil, fmt.Errorf("error creating template directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling template: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.path(name, next), data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing template file: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+// Get returns a version of a template. Version 0 returns the latest saved
+// version, or the built-in default if nothing has been saved under a
+// default template name.
+func (s *Store) Get(name string, version int) (*Template, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+
+	if version == 0 {
+		versions, err := s.versions(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			if text, ok := builtinTemplates[name]; ok {
+				return &Template{Name: name, Text: text, Note: "Built-in default"}, nil
+			}
+			return nil, fmt.Errorf("template %q not found", name)
+		}
+		version = versions[len(versions)-1]
+	}
+
+	data, err := ioutil.ReadFile(s.path(name, version))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("template %s@v%d not found", name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading template file: %v", err)
+	}
+
+	var tmpl Template
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("error unmarshaling template: %v", err)
+	}
+
+	return &tmpl, nil
+}
+
+// Names returns the names of all saved and built-in templates
+func (s *Store) Names() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	for name := range builtinTemplates {
+		seen[name] = true
+	}
+
+	entries, err := ioutil.ReadDir(s.StoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading storage directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && namePattern.MatchString(entry.Name()) {
+			seen[entry.Name()] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// History returns every saved version of a template, newest first, followed
+// by the built-in version if there is one
+func (s *Store) History(name string) ([]*Template, error) {
+	s.mutex.Lock()
+	versions, err := s.versions(name)
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*Template, 0, len(versions)+1)
+	for i := len(versions) - 1; i >= 0; i-- {
+		tmpl, err := s.Get(name, versions[i])
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, tmpl)
+	}
+
+	if text, ok := builtinTemplates[name]; ok {
+		history = append(history, &Template{Name: name, Text: text, Note: "Built-in default"})
+	}
+
+	return history, nil
+}
+
+// versions returns the saved version numbers of a template in ascending order
+func (s *Store) versions(name string) ([]int, error) {
+	matches, err := filepath.Glob(filepath.Join(s.StoragePath, name, "v*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding template versions: %v", err)
+	}
+
+	versions := make([]int, 0, len(matches))
+	for _, match := range matches {
+		base := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "v"), ".json")
+		if v, err := strconv.Atoi(base); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+
+	return versions, nil
+}
+
+// path returns the file for a template version
+func (s *Store) path(name string, version int) string {
+	return filepath.Join(s.StoragePath, name, fmt.Sprintf("v%d.json", version))
+}
@@ -0,0 +1,513 @@
+// Package redact removes personal data from text before it is sent to an LLM
+// and replaces board members with stable pseudonyms that can be mapped back
+// to the real names in the model's reply.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"agents_go/models"
+)
+
+// Kinds of redacted data, used as keys of Counts
+const (
+	KindMember  = "member"
+	KindEmail   = "email"
+	KindPhone   = "phone"
+	KindURL     = "url"
+	KindPattern = "pattern"
+)
+
+// Placeholders replace the redacted values
+const (
+	emailPlaceholder   = "[email]"
+	phonePlaceholder   = "[phone]"
+	queryPlaceholder   = "[redacted]"
+	patternPlaceholder = "[redacted]"
+)
+
+// Pseudonyms are "Member N" for full names and "memberN" for usernames
+const (
+	namePseudonym     = "Member "
+	usernamePseudonym = "member"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d ().\-]{5,}\d`)
+	datePattern  = regexp.MustCompile(`^(?:\d{4}[./\-]\d{1,2}[./\-]\d{1,2}|\d{1,2}[./\-]\d{1,2}[./\-]\d{2,4})\b`)
+	urlPattern   = regexp.MustCompile(`https?://[^\s"'<>()\[\]]+`)
+
+	// pseudonymPattern finds pseudonyms in the model's reply
+	pseudonymPattern = regexp.MustCompile(`\b(?:Member (\d+)|member(\d+))\b`)
+	// partialPseudonym matches the end of a streamed chunk that may be
+	// completed into a pseudonym by the next chunk
+	partialPseudonym = regexp.MustCompile(`^(?:Member \d*|member\d*)$`)
+)
+
+// secretParams are query parameters whose presence marks a URL as carrying
+// credentials
+var secretParams = []string{"token", "key", "secret", "sig", "signature", "auth", "password", "code", "credential"}
+
+// Member is a board member to pseudonymize
+type Member struct {
+	ID       string
+	FullName string
+	Username string
+}
+
+// Redactor redacts text for one board. It remembers what it redacted so the
+// caller can log it, and is safe for concurrent use.
+type Redactor struct {
+	rules    models.RedactionRules
+	patterns []*regexp.Regexp
+
+	// names finds member names and usernames; terms maps their lower case
+	// form to the pseudonym
+	names *regexp.Regexp
+	terms map[string]string
+	// members holds the real names for pseudonym N at index N-1
+	members []Member
+
+	mutex sync.Mutex
+	seen  map[string]map[string]bool
+}
+
+// New creates a redactor from a board's rules and members. Members are
+// numbered by ID so that their pseudonyms stay the same between requests.
+func New(rules models.RedactionRules, members []Member) (*Redactor, error) {
+	r := &Redactor{
+		rules: rules,
+		terms: make(map[string]string),
+		seen:  make(map[string]map[string]bool),
+	}
+
+	// Compile the board's own patterns
+	for _, pattern := range rules.Patterns {
+		re, err := Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	if rules.Members {
+		r.addMembers(members)
+	}
+
+	return r, nil
+}
+
+// Compile checks a custom redaction pattern
+func Compile(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("invalid redaction pattern %q: matches empty text", pattern)
+	}
+	return re, nil
+}
+
+// addMembers assigns pseudonyms to the members and builds the pattern that
+// finds their names
+func (r *Redactor) addMembers(members []Member) {
+	// Number the members by ID, skipping duplicates
+	sorted := make([]Member, 0, len(members))
+	ids := make(map[string]bool)
+	for _, member := range members {
+		if member.FullName == "" && member.Username == "" {
+			continue
+		}
+		key := member.ID
+		if key == "" {
+			key = member.Username + "\x00" + member.FullName
+		}
+		if ids[key] {
+			continue
+		}
+		ids[key] = true
+		sorted = append(sorted, member)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	r.members = sorted
+
+	// First names are replaced too when no other member shares them
+	firstNames := make(map[string]int)
+	for _, member := range sorted {
+		if first := firstName(member.FullName); first != "" {
+			firstNames[strings.ToLower(first)]++
+		}
+	}
+
+	for i, member := range sorted {
+		n := strconv.Itoa(i + 1)
+		if member.FullName != "" {
+			r.terms[strings.ToLower(member.FullName)] = namePseudonym + n
+		}
+		if first := firstName(member.FullName); first != "" && firstNames[strings.ToLower(first)] == 1 {
+			if _, ok := r.terms[strings.ToLower(first)]; !ok {
+				r.terms[strings.ToLower(first)] = namePseudonym + n
+			}
+		}
+		if member.Username != "" {
+			r.terms[strings.ToLower(member.Username)] = usernamePseudonym + n
+		}
+	}
+	if len(r.terms) == 0 {
+		return
+	}
+
+	// Match the longest names first so full names win over first names
+	terms := make([]string, 0, len(r.terms))
+	for term := range r.terms {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	for i, term := range terms {
+		terms[i] = regexp.QuoteMeta(term)
+	}
+	r.names = regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// firstName returns the first word of a full name if it is long enough to
+// be replaced on its own
+func firstName(fullName string) string {
+	fields := strings.Fields(fullName)
+	if len(fields) < 2 || utf8.RuneCountInString(fields[0]) < 3 {
+		return ""
+	}
+	return fields[0]
+}
+
+// Redact removes personal data from text and replaces member names with
+// their pseudonyms
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	// URLs go first so that emails and numbers in their query strings are
+	// removed with them
+	if r.rules.TokenURLs {
+		text = urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+			redacted, ok := redactURL(match)
+			if !ok {
+				return match
+			}
+			r.record(KindURL, match)
+			return redacted
+		})
+	}
+	if r.rules.Emails {
+		text = emailPattern.ReplaceAllStringFunc(text, func(match string) string {
+			r.record(KindEmail, strings.ToLower(match))
+			return emailPlaceholder
+		})
+	}
+	for _, re := range r.patterns {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			r.record(KindPattern, match)
+			return patternPlaceholder
+		})
+	}
+	if r.rules.Phones {
+		text = replaceWords(text, phonePattern, func(match string) (string, bool) {
+			if !isPhone(match) {
+				return match, false
+			}
+			r.record(KindPhone, digits(match))
+			return phonePlaceholder, true
+		})
+	}
+	if r.names != nil {
+		text = replaceWords(text, r.names, func(match string) (string, bool) {
+			pseudonym, ok := r.terms[strings.ToLower(match)]
+			if !ok {
+				return match, false
+			}
+			// A member's name and username count once
+			r.record(KindMember, digits(pseudonym))
+			return pseudonym, true
+		})
+	}
+
+	return text
+}
+
+// Restore maps the pseudonyms in the model's reply back to the real names
+func (r *Redactor) Restore(text string) string {
+	return r.restore(text, false)
+}
+
+// RestoreJSON maps the pseudonyms in a JSON reply back to the real names,
+// escaping them for use inside JSON strings
+func (r *Redactor) RestoreJSON(text string) string {
+	return r.restore(text, true)
+}
+
+func (r *Redactor) restore(text string, escape bool) string {
+	if r == nil || len(r.members) == 0 {
+		return text
+	}
+
+	return pseudonymPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := pseudonymPattern.FindStringSubmatch(match)
+		real := ""
+		if n, err := strconv.Atoi(groups[1]); err == nil && n >= 1 && n <= len(r.members) {
+			real = r.members[n-1].FullName
+		} else if n, err := strconv.Atoi(groups[2]); err == nil && n >= 1 && n <= len(r.members) {
+			real = r.members[n-1].Username
+		}
+		if real == "" {
+			return match
+		}
+		if escape {
+			quoted, _ := json.Marshal(real)
+			real = string(quoted[1 : len(quoted)-1])
+		}
+		return real
+	})
+}
+
+// Counts returns the number of distinct values redacted so far by kind
+func (r *Redactor) Counts() map[string]int {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.seen) == 0 {
+		return nil
+	}
+	counts := make(map[string]int, len(r.seen))
+	for kind, values := range r.seen {
+		counts[kind] = len(values)
+	}
+	return counts
+}
+
+// Summary describes counts for logging, e.g. "2 emails, 3 members"
+func Summary(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "nothing"
+	}
+
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		noun := kind
+		if counts[kind] != 1 {
+			noun += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[kind], noun))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// record remembers a redacted value; only the number of distinct values is
+// ever reported
+func (r *Redactor) record(kind, value string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.seen[kind] == nil {
+		r.seen[kind] = make(map[string]bool)
+	}
+	r.seen[kind][value] = true
+}
+
+// replaceWords replaces matches of re that aren't part of a longer word.
+// replace returns false to keep a match, e.g. a number that isn't a phone
+// number.
+func replaceWords(text string, re *regexp.Regexp, replace func(match string) (string, bool)) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if start > 0 && isWordRune(before) || end < len(text) && isWordRune(after) {
+			continue
+		}
+		replacement, ok := replace(text[start:end])
+		if !ok {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(replacement)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isPhone tells phone numbers apart from dates, amounts and other numbers
+// the phone pattern matches
+func isPhone(match string) bool {
+	n := len(digits(match))
+	if n < 8 || n > 15 {
+		return false
+	}
+	if datePattern.MatchString(match) {
+		return false
+	}
+	// Plain runs of digits are more likely IDs than phone numbers
+	return strings.HasPrefix(match, "+") || strings.ContainsAny(match, " ().-")
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// redactURL removes the query string of a URL that carries a secret
+func redactURL(raw string) (string, bool) {
+	// Trailing punctuation belongs to the sentence, not the URL
+	trimmed := strings.TrimRight(raw, ".,;:!?")
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		return raw, false
+	}
+
+	// Credentials in the user info are always secret
+	secret := u.User != nil
+	for name := range u.Query() {
+		lower := strings.ToLower(name)
+		for _, param := range secretParams {
+			if strings.Contains(lower, param) {
+				secret = true
+			}
+		}
+	}
+	if !secret {
+		return raw, false
+	}
+
+	u.User = nil
+	redacted := u.Scheme + "://" + u.Host + u.EscapedPath()
+	if u.RawQuery != "" || u.Fragment != "" {
+		redacted += "?" + queryPlaceholder
+	}
+	return redacted + raw[len(trimmed):], true
+}
+
+// StreamRestorer maps pseudonyms back to real names in streamed text. A
+// pseudonym may be split between chunks, so the end of a chunk that could
+// start one is held back until the next chunk or Flush.
+type StreamRestorer struct {
+	redactor *Redactor
+	pending  string
+}
+
+// NewStreamRestorer creates a restorer for a streamed reply
+func (r *Redactor) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{redactor: r}
+}
+
+// Write adds a chunk and returns the text that can be shown so far
+func (s *StreamRestorer) Write(chunk string) string {
+	s.pending += chunk
+
+	// Hold back the earliest suffix that may still become a pseudonym
+	hold := len(s.pending)
+	const longest = len(namePseudonym) + 4
+	for i := max(0, len(s.pending)-longest); i < len(s.pending); i++ {
+		if partialPseudonym.MatchString(s.pending[i:]) || strings.HasPrefix(namePseudonym, s.pending[i:]) || strings.HasPrefix(usernamePseudonym, s.pending[i:]) {
+			hold = i
+			break
+		}
+	}
+
+	out := s.redactor.Restore(s.pending[:hold])
+	s.pending = s.pending[hold:]
+	return out
+}
+
+// Flush returns the text held back at the end of the stream
+func (s *StreamRestorer) Flush() string {
+	out := s.redactor.Restore(s.pending)
+	s.pending = ""
+	return out
+}
+
+// MembersFromBoard collects the board's members and the authors of its
+// activity, who may no longer be members, from trello.Client.GetBoardData
+func MembersFromBoard(boardData map[string]interface{}) []Member {
+	var members []Member
+	add := func(data map[string]interface{}) {
+		if data == nil {
+			return
+		}
+		id, _ := data["id"].(string)
+		fullName, _ := data["fullName"].(string)
+		username, _ := data["username"].(string)
+		members = append(members, Member{ID: id, FullName: fullName, Username: username})
+	}
+
+	for _, member := range maps(boardData["members"]) {
+		add(member)
+	}
+	for _, action := range maps(boardData["activities"]) {
+		creator, _ := action["memberCreator"].(map[string]interface{})
+		add(creator)
+	}
+	return members
+}
+
+// maps converts board data to a slice of maps, which may be wrapped in a map
+// with an "items" key
+func maps(data interface{}) []map[string]interface{} {
+	if dataMap, ok := data.(map[string]interface{}); ok {
+		if items, ok := dataMap["items"]; ok {
+			data = items
+		}
+	}
+
+	switch v := data.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		result := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package redact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"agents_go/models"
+)
+
+var testMembers = []Member{
+	{ID: "member-2", FullName: "Jonas Weber", Username: "jweber"},
+	{ID: "member-1", FullName: "Priya Sharma", Username: "priya"},
+	{ID: "member-3", FullName: "Lucia Gomez", Username: "lucia"},
+	{ID: "member-4", FullName: `Ann "Annie" Lee`, Username: "ann"},
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		// rules are the board's rules; nil uses the default ones
+		rules      *models.RedactionRules
+		text       string
+		want       string
+		wantCounts map[string]int
+	}{
+		{
+			name:       "full name",
+			text:       "Priya Sharma moved the card",
+			want:       "Member 1 moved the card",
+			wantCounts: map[string]int{KindMember: 1},
+		},
+		{
+			name:       "first name and username count once",
+			text:       "Ask Jonas, or @jweber on chat",
+			want:       "Ask Member 2, or @member2 on chat",
+			wantCounts: map[string]int{KindMember: 1},
+		},
+		{
+			name: "names inside words are kept",
+			text: "Priyanka and lucias",
+			want: "Priyanka and lucias",
+		},
+		{
+			name:       "case insensitive",
+			text:       "LUCIA GOMEZ signed off",
+			want:       "Member 3 signed off",
+			wantCounts: map[string]int{KindMember: 1},
+		},
+		{
+			name:       "email",
+			text:       "Mail priya.sharma@example.com for access",
+			want:       "Mail [email] for access",
+			wantCounts: map[string]int{KindEmail: 1},
+		},
+		{
+			name:       "phone",
+			text:       "Call +49 30 1234567 or (030) 765-4321.",
+			want:       "Call [phone] or [phone].",
+			wantCounts: map[string]int{KindPhone: 2},
+		},
+		{
+			name: "dates, amounts and IDs aren't phones",
+			text: "Due 2026-10-18, budget 12000000, ticket 4815162342",
+			want: "Due 2026-10-18, budget 12000000, ticket 4815162342",
+		},
+		{
+			name:       "URL with a token",
+			text:       "Webhook: https://example.com/hook?token=abc123&x=1.",
+			want:       "Webhook: https://example.com/hook?[redacted].",
+			wantCounts: map[string]int{KindURL: 1},
+		},
+		{
+			name: "URL without secrets",
+			text: "See https://example.com/docs?page=2",
+			want: "See https://example.com/docs?page=2",
+		},
+		{
+			name:       "custom pattern",
+			rules:      &models.RedactionRules{Patterns: []string{`INV-\d+`}},
+			text:       "Invoice INV-2041 is overdue",
+			want:       "Invoice [redacted] is overdue",
+			wantCounts: map[string]int{KindPattern: 1},
+		},
+		{
+			name:  "rules turned off",
+			rules: &models.RedactionRules{},
+			text:  "Priya Sharma, priya@example.com, +49 30 1234567",
+			want:  "Priya Sharma, priya@example.com, +49 30 1234567",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := models.DefaultRedactionRules()
+			if tt.rules != nil {
+				rules = *tt.rules
+			}
+			r, err := New(rules, testMembers)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if got := r.Counts(); !reflect.DeepEqual(got, tt.wantCounts) {
+				t.Errorf("Counts() = %v, want %v", got, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	r, err := New(models.DefaultRedactionRules(), testMembers)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		json bool
+		text string
+		want string
+	}{
+		{name: "names", text: "Member 1 and Member 3 finished", want: "Priya Sharma and Lucia Gomez finished"},
+		{name: "usernames", text: "assigned to member2", want: "assigned to jweber"},
+		{name: "unknown pseudonyms are kept", text: "Member 9 and member0", want: "Member 9 and member0"},
+		{name: "pseudonyms inside words are kept", text: "Member 12 and member23x", want: "Member 12 and member23x"},
+		{name: "plain text", text: "Member 4 said hi", want: `Ann "Annie" Lee said hi`},
+		{name: "JSON", json: true, text: `{"owner": "Member 4"}`, want: `{"owner": "Ann \"Annie\" Lee"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Restore(tt.text)
+			if tt.json {
+				got = r.RestoreJSON(tt.text)
+			}
+			if got != tt.want {
+				t.Errorf("restored %q to %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	// Redacting and restoring gives back the names
+	text := "Priya Sharma asked Jonas Weber to review"
+	if got := r.Restore(r.Redact(text)); got != text {
+		t.Errorf("round trip of %q gave %q", text, got)
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	r, err := New(models.DefaultRedactionRules(), testMembers)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{name: "whole pseudonym", chunks: []string{"Member 1 did it"}, want: "Priya Sharma did it"},
+		{name: "split in the word", chunks: []string{"Thanks Mem", "ber 2!"}, want: "Thanks Jonas Weber!"},
+		{name: "split before the number", chunks: []string{"Thanks Member", " 3."}, want: "Thanks Lucia Gomez."},
+		{name: "split username", chunks: []string{"cc member", "1 and mem", "ber3"}, want: "cc priya and lucia"},
+		{name: "number continues in the next chunk", chunks: []string{"Member 1", "0 tasks"}, want: "Member 10 tasks"},
+		{name: "pseudonym at the end", chunks: []string{"Owner: Member", " 2"}, want: "Owner: Jonas Weber"},
+		{name: "lookalike held back then released", chunks: []string{"Members", " agreed"}, want: "Members agreed"},
+		{name: "one character at a time", chunks: strings.Split("Member 1 and member2", ""), want: "Priya Sharma and jweber"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restorer := r.NewStreamRestorer()
+			var sb strings.Builder
+			for _, chunk := range tt.chunks {
+				out := restorer.Write(chunk)
+				// Pseudonyms must never be shown unrestored
+				if r.Restore(out) != out {
+					t.Errorf("Write(%q) showed a pseudonym: %q", chunk, out)
+				}
+				sb.WriteString(out)
+			}
+			sb.WriteString(restorer.Flush())
+			if got := sb.String(); got != tt.want {
+				t.Errorf("restored %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
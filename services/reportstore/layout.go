@@ -0,0 +1,139 @@
+package reportstore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"agents_go/models"
+)
+
+// DefaultLayout keeps each kind of object in its own tree, one folder per
+// board, so a board's reports can be listed by prefix
+const DefaultLayout = "{kind}/{board}/{id}.{ext}"
+
+// Kinds of objects kept for a report
+const (
+	kindReports   = "reports"
+	kindSnapshots = "snapshots"
+	kindPDFs      = "pdfs"
+)
+
+// placeholderPattern finds the placeholders of a layout
+var placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// placeholderValues are the patterns matching each placeholder's value when
+// parsing keys
+var placeholderValues = map[string]string{
+	"{kind}":  `(?P<kind>[^/]+)`,
+	"{board}": `[^/]+`,
+	"{type}":  `[^/]+`,
+	"{year}":  `[0-9]{4}`,
+	"{month}": `[0-9]{2}`,
+	"{id}":    `(?P<id>[^/]+?)`,
+	"{ext}":   `[^/.]+`,
+}
+
+// Layout maps reports to object keys. It is a template of placeholders:
+// {kind} (reports, snapshots or pdfs), {board}, {type}, {year} and {month}
+// of the time the report was generated, {id} and {ext} (json or pdf).
+// {kind} and {id} are required so every object has its own key.
+type Layout struct {
+	template string
+	pattern  *regexp.Regexp
+}
+
+// ParseLayout checks a layout template and compiles the pattern its keys
+// are parsed with
+func ParseLayout(template string) (*Layout, error) {
+	template = strings.Trim(template, "/")
+	for _, required := range []string{"{kind}", "{id}"} {
+		if strings.Count(template, required) != 1 {
+			return nil, fmt.Errorf("layout %q must contain %s once", template, required)
+		}
+	}
+
+	// Quote the text between placeholders and replace each placeholder
+	// with the pattern of its value
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(template, -1) {
+		value, ok := placeholderValues[template[loc[0]:loc[1]]]
+		if !ok {
+			return nil, fmt.Errorf("layout %q has unknown placeholder %s", template, template[loc[0]:loc[1]])
+		}
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		pattern.WriteString(value)
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("error compiling layout %q: %v", template, err)
+	}
+	return &Layout{template: template, pattern: compiled}, nil
+}
+
+// String returns the layout's template
+func (l *Layout) String() string {
+	return l.template
+}
+
+// Key returns the key of a report's object of the given kind
+func (l *Layout) Key(kind string, report *models.Report) string {
+	generated := report.GeneratedAt.UTC()
+	return strings.NewReplacer(
+		"{kind}", kind,
+		"{board}", report.BoardID,
+		"{type}", string(report.Type),
+		"{year}", generated.Format("2006"),
+		"{month}", generated.Format("01"),
+		"{id}", report.ID,
+		"{ext}", extension(kind),
+	).Replace(l.template)
+}
+
+// Prefix returns the longest key prefix shared by the objects of a kind,
+// and of a board if boardID is set
+func (l *Layout) Prefix(kind, boardID string) string {
+	prefix := l.template
+	if loc := l.firstUnknown(boardID != ""); loc >= 0 {
+		prefix = prefix[:loc]
+	}
+	return strings.NewReplacer("{kind}", kind, "{board}", boardID).Replace(prefix)
+}
+
+// firstUnknown returns the position of the first placeholder whose value
+// isn't known when listing: anything but {kind}, and {board} if the board
+// is known
+func (l *Layout) firstUnknown(boardKnown bool) int {
+	for _, loc := range placeholderPattern.FindAllStringIndex(l.template, -1) {
+		name := l.template[loc[0]:loc[1]]
+		if name == "{kind}" || (name == "{board}" && boardKnown) {
+			continue
+		}
+		return loc[0]
+	}
+	return -1
+}
+
+// Parse returns the kind and report ID of a key, or false if the key
+// doesn't follow the layout
+func (l *Layout) Parse(key string) (kind, id string, ok bool) {
+	match := l.pattern.FindStringSubmatch(key)
+	if match == nil {
+		return "", "", false
+	}
+	return match[l.pattern.SubexpIndex("kind")], match[l.pattern.SubexpIndex("id")], true
+}
+
+// extension returns the file extension of a kind of object
+func extension(kind string) string {
+	if kind == kindPDFs {
+		return "pdf"
+	}
+	return "json"
+}
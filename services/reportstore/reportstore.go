@@ -0,0 +1,31 @@
+// Package reportstore opens the configured report store backend: JSON
+// files, SQLite or an S3-compatible bucket.
+package reportstore
+
+import (
+	"fmt"
+
+	"agents_go/config"
+	"agents_go/models"
+)
+
+// Open opens a report store backend at location: a directory for the file
+// backend, a database file for SQLite or "bucket/prefix" for S3. The S3
+// backend is configured from the config package's S3 settings.
+func Open(backend, location string) (models.ReportStore, error) {
+	switch backend {
+	case "", models.ReportStoreFile:
+		return models.NewFileReportStore(location)
+	case models.ReportStoreSQLite:
+		return models.NewSQLiteReportStore(location)
+	case models.ReportStoreS3:
+		return OpenS3(location)
+	default:
+		return nil, fmt.Errorf("unknown report store backend %q", backend)
+	}
+}
+
+// OpenConfigured opens the report store set in the config
+func OpenConfigured() (models.ReportStore, error) {
+	return Open(config.ReportStoreBackend, config.ReportStoreLocation())
+}
@@ -0,0 +1,488 @@
+package reportstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"agents_go/config"
+	"agents_go/models"
+	"agents_go/services/s3"
+)
+
+// S3Store keeps reports, the board snapshots they were written from and
+// their rendered PDFs in an S3-compatible bucket, under a prefix and in the
+// store's layout.
+//
+// Writes are conditional on the ETag the store last read, so a report
+// changed by another instance isn't overwritten: the write fails with
+// models.ErrReportConflict and the store picks up the other version. An
+// in-memory index of the reports' keys and ETags is refreshed by listing
+// the bucket, so only reports that changed are read again.
+type S3Store struct {
+	client *s3.Client
+	prefix string
+	layout *Layout
+
+	mutex sync.Mutex
+	index map[string]*s3Entry
+}
+
+// s3Entry is what the index knows about a stored report
+type s3Entry struct {
+	key    string
+	etag   string
+	data   []byte
+	report *models.Report
+}
+
+// OpenS3 opens the S3 store at "bucket/prefix" (or "s3://bucket/prefix")
+// with the endpoint, credentials and layout from the config
+func OpenS3(location string) (*S3Store, error) {
+	location = strings.Trim(strings.TrimPrefix(location, "s3://"), "/")
+	bucket, prefix := location, ""
+	if i := strings.Index(location, "/"); i >= 0 {
+		bucket, prefix = location[:i], location[i+1:]
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("no S3 bucket given; set REPORT_STORE_PATH to bucket/prefix")
+	}
+
+	client, err := s3.NewClient(config.S3Endpoint, config.S3Region, bucket,
+		config.S3AccessKeyID, config.S3SecretAccessKey, config.S3PathStyle)
+	if err != nil {
+		return nil, err
+	}
+
+	template := config.S3Layout
+	if template == "" {
+		template = DefaultLayout
+	}
+	layout, err := ParseLayout(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3Store(client, prefix, layout)
+}
+
+// NewS3Store creates an S3 store and indexes the reports already in the
+// bucket
+func NewS3Store(client *s3.Client, prefix string, layout *Layout) (*S3Store, error) {
+	s := &S3Store{
+		client: client,
+		prefix: strings.Trim(prefix, "/"),
+		layout: layout,
+		index:  make(map[string]*s3Entry),
+	}
+	if err := s.refresh(""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// key returns the full key of a report's object of the given kind
+func (s *S3Store) key(kind string, report *models.Report) string {
+	return s.withPrefix(s.layout.Key(kind, report))
+}
+
+// withPrefix puts a key under the store's prefix
+func (s *S3Store) withPrefix(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// refresh brings the index up to date with the bucket, for one board if
+// boardID is set. Reports whose ETag is unchanged aren't read again. The
+// bucket is listed and read without holding the mutex, so a refresh doesn't
+// hold up other requests; entries a writer changed meanwhile are left as
+// the writer left them. The caller must not hold the mutex.
+func (s *S3Store) refresh(boardID string) error {
+	ctx := context.Background()
+
+	// Note the entries before listing, to tell which ones change meanwhile
+	s.mutex.Lock()
+	known := make(map[string]*s3Entry, len(s.index))
+	for id, entry := range s.index {
+		known[id] = entry
+	}
+	s.mutex.Unlock()
+
+	objects, err := s.client.ListObjects(ctx, s.withPrefix(s.layout.Prefix(kindReports, boardID)))
+	if err != nil {
+		return fmt.Errorf("error listing reports: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	loaded := make(map[string]*s3Entry)
+	for _, object := range objects {
+		key := strings.TrimPrefix(object.Key, s.withPrefix(""))
+		kind, id, ok := s.layout.Parse(key)
+		if !ok || kind != kindReports {
+			continue
+		}
+		seen[id] = true
+
+		if entry, ok := known[id]; ok && entry.key == object.Key && entry.etag == object.ETag {
+			continue
+		}
+		entry, err := s.read(ctx, object.Key)
+		if err != nil {
+			log.Printf("Skipping report object %s: %v", object.Key, err)
+			delete(seen, id)
+			continue
+		}
+		loaded[entry.report.ID] = entry
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, entry := range loaded {
+		if s.index[id] == known[id] {
+			s.index[id] = entry
+		}
+	}
+
+	// Forget the reports that were deleted by another writer
+	for id, entry := range s.index {
+		if !seen[id] && entry == known[id] && (boardID == "" || entry.report.BoardID == boardID) {
+			delete(s.index, id)
+		}
+	}
+
+	return nil
+}
+
+// read reads a report object. It doesn't touch the index, so the caller
+// needn't hold the mutex.
+func (s *S3Store) read(ctx context.Context, key string) (*s3Entry, error) {
+	data, etag, err := s.client.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error unmarshaling report: %v", err)
+	}
+	if report.ID == "" {
+		return nil, fmt.Errorf("no report ID")
+	}
+
+	return &s3Entry{key: key, etag: etag, data: data, report: &report}, nil
+}
+
+// load reads a report object into the index. The caller must hold the
+// mutex.
+func (s *S3Store) load(ctx context.Context, key string) error {
+	entry, err := s.read(ctx, key)
+	if err != nil {
+		return err
+	}
+	s.index[entry.report.ID] = entry
+	return nil
+}
+
+// entry returns the index entry of a report, listing the bucket if the
+// report isn't indexed yet. The caller must not hold the mutex.
+func (s *S3Store) entry(id string) (*s3Entry, error) {
+	s.mutex.Lock()
+	entry, ok := s.index[id]
+	s.mutex.Unlock()
+	if ok {
+		return entry, nil
+	}
+
+	if err := s.refresh(""); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if entry, ok := s.index[id]; ok {
+		return entry, nil
+	}
+	return nil, models.ErrReportNotFound
+}
+
+// SaveReport saves a report. A report the store hasn't seen is only
+// created if no other writer created it first, and a known report is only
+// replaced if it hasn't changed since the store read it.
+func (s *S3Store) SaveReport(report *models.Report) error {
+	if err := models.ValidReportID(report.ID); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling report: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ctx := context.Background()
+	key := s.key(kindReports, report)
+	old, exists := s.index[report.ID]
+	opts := s3.PutOptions{ContentType: "application/json", IfNoneMatch: "*"}
+	if exists && old.key == key {
+		opts = s3.PutOptions{ContentType: "application/json", IfMatch: old.etag}
+	}
+
+	etag, err := s.client.PutObject(ctx, key, data, opts)
+	if errors.Is(err, s3.ErrPreconditionFailed) {
+		// Pick up the other writer's version, so the caller can read it,
+		// apply its change again and retry
+		if err := s.load(ctx, key); err != nil {
+			log.Printf("Error reading changed report %s: %v", report.ID, err)
+		}
+		return fmt.Errorf("error saving report %s: %w", report.ID, models.ErrReportConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("error saving report: %v", err)
+	}
+
+	if exists {
+		// Remove the report's old object if the layout put it elsewhere,
+		// and its PDF, which no longer matches the report
+		if old.key != key {
+			s.deleteObject(ctx, old.key)
+		}
+		s.deleteObject(ctx, s.key(kindPDFs, old.report))
+	}
+
+	saved := *report
+	s.index[report.ID] = &s3Entry{key: key, etag: etag, data: data, report: &saved}
+	return nil
+}
+
+// GetReport retrieves a specific report by ID
+func (s *S3Store) GetReport(id string) (*models.Report, error) {
+	entry, err := s.entry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the report again in case another writer changed it, keeping
+	// the index as it is if a writer of this store changed it meanwhile
+	fresh, err := s.read(context.Background(), entry.key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if errors.Is(err, s3.ErrNotFound) {
+		if s.index[id] == entry {
+			delete(s.index, id)
+		}
+		return nil, models.ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading report: %v", err)
+	}
+	if s.index[id] == entry {
+		s.index[id] = fresh
+	}
+
+	return decodeReport(fresh.data)
+}
+
+// ListReports retrieves the reports matching the filter, newest first.
+// Filtering by board only lists the board's objects if the layout groups
+// them by board.
+func (s *S3Store) ListReports(filter models.ReportFilter) ([]*models.Report, error) {
+	if err := s.refresh(filter.BoardID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var candidates []*models.Report
+	entries := make(map[*models.Report]*s3Entry)
+	for _, entry := range s.index {
+		if filter.Matches(entry.report) {
+			candidates = append(candidates, entry.report)
+			entries[entry.report] = entry
+		}
+	}
+
+	page := filter.Page(candidates)
+	reports := make([]*models.Report, 0, len(page))
+	for _, summary := range page {
+		report, err := decodeReport(entries[summary].data)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// SearchReports retrieves the reports matching the filter whose board name
+// or content contains every word of the query, newest first
+func (s *S3Store) SearchReports(query string, filter models.ReportFilter) ([]*models.Report, error) {
+	// Search every matching report, then page the results
+	all := filter
+	all.Offset, all.Limit = 0, 0
+	reports, err := s.ListReports(all)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*models.Report
+	for _, report := range reports {
+		if models.MatchesQuery(report, query) {
+			found = append(found, report)
+		}
+	}
+	return filter.Page(found), nil
+}
+
+// DeleteReport deletes a report with its snapshot and PDF, unless another
+// writer changed it since the store read it
+func (s *S3Store) DeleteReport(id string) error {
+	if _, err := s.entry(id); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Delete the version last read, which a writer may have changed since
+	// the lookup
+	entry, ok := s.index[id]
+	if !ok {
+		return models.ErrReportNotFound
+	}
+
+	ctx := context.Background()
+	err := s.client.DeleteObject(ctx, entry.key, entry.etag)
+	if errors.Is(err, s3.ErrPreconditionFailed) {
+		if err := s.load(ctx, entry.key); err != nil {
+			log.Printf("Error reading changed report %s: %v", id, err)
+		}
+		return fmt.Errorf("error deleting report %s: %w", id, models.ErrReportConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting report: %v", err)
+	}
+
+	s.deleteObject(ctx, s.key(kindSnapshots, entry.report))
+	s.deleteObject(ctx, s.key(kindPDFs, entry.report))
+	delete(s.index, id)
+
+	return nil
+}
+
+// SaveSnapshot saves the board data a report was written from
+func (s *S3Store) SaveSnapshot(report *models.Report, data []byte) error {
+	if err := models.ValidReportID(report.ID); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(context.Background(), s.key(kindSnapshots, report), data, s3.PutOptions{ContentType: "application/json"})
+	if err != nil {
+		return fmt.Errorf("error saving snapshot: %v", err)
+	}
+	return nil
+}
+
+// GetSnapshot returns the board data a report was written from
+func (s *S3Store) GetSnapshot(id string) ([]byte, error) {
+	return s.getArtifact(kindSnapshots, id)
+}
+
+// HasSnapshot reports whether the board data a report was written from is
+// stored
+func (s *S3Store) HasSnapshot(id string) (bool, error) {
+	entry, err := s.entry(id)
+	if err != nil {
+		return false, err
+	}
+
+	key := s.key(kindSnapshots, entry.report)
+	objects, err := s.client.ListObjects(context.Background(), key)
+	if err != nil {
+		return false, fmt.Errorf("error finding snapshot: %v", err)
+	}
+	for _, object := range objects {
+		if object.Key == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteSnapshot deletes the board data a report was written from
+func (s *S3Store) DeleteSnapshot(id string) error {
+	entry, err := s.entry(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.DeleteObject(context.Background(), s.key(kindSnapshots, entry.report), ""); err != nil {
+		return fmt.Errorf("error deleting snapshot: %v", err)
+	}
+	return nil
+}
+
+// SavePDF saves a report's rendered PDF. It is deleted when the report is
+// saved again.
+func (s *S3Store) SavePDF(report *models.Report, pdf []byte) error {
+	if err := models.ValidReportID(report.ID); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(context.Background(), s.key(kindPDFs, report), pdf, s3.PutOptions{ContentType: "application/pdf"})
+	if err != nil {
+		return fmt.Errorf("error saving PDF: %v", err)
+	}
+	return nil
+}
+
+// GetPDF returns a report's rendered PDF
+func (s *S3Store) GetPDF(id string) ([]byte, error) {
+	return s.getArtifact(kindPDFs, id)
+}
+
+// getArtifact reads a report's object of the given kind
+func (s *S3Store) getArtifact(kind, id string) ([]byte, error) {
+	entry, err := s.entry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := s.client.GetObject(context.Background(), s.key(kind, entry.report))
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, models.ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", strings.TrimSuffix(kind, "s"), err)
+	}
+	return data, nil
+}
+
+// deleteObject deletes an object, logging failures
+func (s *S3Store) deleteObject(ctx context.Context, key string) {
+	if err := s.client.DeleteObject(ctx, key, ""); err != nil {
+		log.Printf("Error deleting %s: %v", key, err)
+	}
+}
+
+// Close does nothing; the S3 store holds no open resources
+func (s *S3Store) Close() error {
+	return nil
+}
+
+// decodeReport decodes a report stored as JSON
+func decodeReport(data []byte) (*models.Report, error) {
+	var report models.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error unmarshaling report: %v", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,153 @@
+package reportstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/s3"
+	"agents_go/services/s3/s3test"
+)
+
+// newTestS3Store opens a store on the fake S3 server
+func newTestS3Store(t *testing.T, server *s3test.Server) *S3Store {
+	t.Helper()
+	client, err := s3.NewClient(server.URL, "us-east-1", server.Bucket, server.AccessKey, server.SecretKey, true)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	layout, err := ParseLayout(DefaultLayout)
+	if err != nil {
+		t.Fatalf("ParseLayout: %v", err)
+	}
+	store, err := NewS3Store(client, "test", layout)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store
+}
+
+func TestS3StoreConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		// saved saves the report with the first store before the second
+		// store opens
+		saved bool
+		// other changes the stored report with the second store
+		other func(t *testing.T, other *S3Store, report *models.Report)
+		// wantConflict is whether the first store's next save conflicts
+		wantConflict bool
+		// wantContent is the stored content after the first store's save,
+		// or after it reads the report again and retries on a conflict
+		wantContent string
+	}{
+		{
+			name:        "unchanged",
+			saved:       true,
+			other:       func(t *testing.T, other *S3Store, report *models.Report) {},
+			wantContent: "first",
+		},
+		{
+			name:  "read but not changed by another store",
+			saved: true,
+			other: func(t *testing.T, other *S3Store, report *models.Report) {
+				if _, err := other.GetReport(report.ID); err != nil {
+					t.Fatalf("GetReport: %v", err)
+				}
+			},
+			wantContent: "first",
+		},
+		{
+			name:  "changed by another store",
+			saved: true,
+			other: func(t *testing.T, other *S3Store, report *models.Report) {
+				changed, err := other.GetReport(report.ID)
+				if err != nil {
+					t.Fatalf("GetReport: %v", err)
+				}
+				changed.Content = "other"
+				if err := other.SaveReport(changed); err != nil {
+					t.Fatalf("SaveReport: %v", err)
+				}
+			},
+			wantConflict: true,
+			wantContent:  "other first",
+		},
+		{
+			name: "created by another store",
+			other: func(t *testing.T, other *S3Store, report *models.Report) {
+				created := *report
+				created.Content = "other"
+				if err := other.SaveReport(&created); err != nil {
+					t.Fatalf("SaveReport: %v", err)
+				}
+			},
+			wantConflict: true,
+			wantContent:  "other first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := s3test.NewServer()
+			defer server.Close()
+
+			report := &models.Report{
+				ID:          models.NewReportID(time.Now()),
+				BoardID:     "board-1",
+				Type:        models.Weekly,
+				GeneratedAt: time.Now().UTC(),
+			}
+			first := newTestS3Store(t, server)
+			if tt.saved {
+				if err := first.SaveReport(report); err != nil {
+					t.Fatalf("SaveReport: %v", err)
+				}
+			}
+			other := newTestS3Store(t, server)
+			tt.other(t, other, report)
+
+			// On a conflict, read the other store's version and apply the
+			// change to it, as the agent's updates do
+			retry := func() error {
+				current, err := first.GetReport(report.ID)
+				if err != nil {
+					return err
+				}
+				current.Content += " first"
+				return first.SaveReport(current)
+			}
+
+			// Saving the first store's version without reading the report
+			// again must not overwrite the other store's change
+			stale := *report
+			stale.Content = "first"
+			err := first.SaveReport(&stale)
+			if tt.wantConflict {
+				if !errors.Is(err, models.ErrReportConflict) {
+					t.Fatalf("SaveReport returned %v, want a conflict", err)
+				}
+				if err := retry(); err != nil {
+					t.Fatalf("retrying after the conflict: %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("SaveReport: %v", err)
+			}
+
+			// Both stores must see the result
+			for _, store := range []*S3Store{first, other} {
+				got, err := store.GetReport(report.ID)
+				if err != nil {
+					t.Fatalf("GetReport: %v", err)
+				}
+				if got.Content != tt.wantContent {
+					t.Errorf("content is %q, want %q", got.Content, tt.wantContent)
+				}
+			}
+			if keys := server.Keys(); len(keys) != 1 {
+				t.Errorf("stored objects %v, want one report", keys)
+			}
+		})
+	}
+}
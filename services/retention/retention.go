@@ -0,0 +1,233 @@
+// Package retention prunes old reports according to each board's
+// retention policy and keeps an audit record of what was deleted.
+package retention
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"agents_go/config"
+	"agents_go/models"
+)
+
+// DefaultPolicy returns the policy of boards without their own, from the
+// config
+func DefaultPolicy() models.RetentionPolicy {
+	policy := models.RetentionPolicy{
+		CanonicalOnlyAfterDays: config.RetentionCanonicalOnlyDays,
+		SnapshotDays:           config.RetentionSnapshotDays,
+	}
+	if config.RetentionKeepWeekly > 0 || config.RetentionKeepMonthly > 0 {
+		policy.Keep = map[models.ReportType]int{
+			models.Weekly:  config.RetentionKeepWeekly,
+			models.Monthly: config.RetentionKeepMonthly,
+		}
+	}
+	return policy
+}
+
+// Pruner deletes the reports and snapshots a board's retention policy no
+// longer keeps. Pinned reports and their snapshots are never deleted.
+type Pruner struct {
+	Store    models.ReportStore
+	Settings *models.BoardSettingsStore
+	Audit    *models.PruneAuditStore
+	// Default is the policy of boards without their own
+	Default models.RetentionPolicy
+}
+
+// NewPruner creates a pruner with the default policy from the config
+func NewPruner(store models.ReportStore, settings *models.BoardSettingsStore, audit *models.PruneAuditStore) *Pruner {
+	return &Pruner{
+		Store:    store,
+		Settings: settings,
+		Audit:    audit,
+		Default:  DefaultPolicy(),
+	}
+}
+
+// Policy returns a board's retention policy
+func (p *Pruner) Policy(boardID string) models.RetentionPolicy {
+	settings, err := p.Settings.GetSettings(boardID)
+	if err != nil {
+		log.Printf("Error getting board settings, using the default retention policy: %v", err)
+		return p.Default
+	}
+	if settings.Retention != nil {
+		return *settings.Retention
+	}
+	return p.Default
+}
+
+// Prune applies every board's policy as of now. In a dry run nothing is
+// deleted and the record lists what would have been. Runs that select
+// anything are saved to the audit store.
+func (p *Pruner) Prune(now time.Time, dryRun bool) (*models.PruneRecord, error) {
+	record := &models.PruneRecord{StartedAt: now, DryRun: dryRun, Actions: []models.PruneAction{}}
+
+	reports, err := p.Store.ListReports(models.ReportFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing reports: %v", err)
+	}
+
+	// Plan each board's deletions with its own policy
+	boards := make(map[string][]*models.Report)
+	var boardIDs []string
+	for _, report := range reports {
+		if _, ok := boards[report.BoardID]; !ok {
+			boardIDs = append(boardIDs, report.BoardID)
+		}
+		boards[report.BoardID] = append(boards[report.BoardID], report)
+	}
+	sort.Strings(boardIDs)
+
+	artifacts, _ := p.Store.(models.ReportArtifacts)
+	for _, boardID := range boardIDs {
+		policy := p.Policy(boardID)
+		if !policy.Enabled() {
+			continue
+		}
+		for _, action := range Plan(boards[boardID], policy, now, artifacts != nil) {
+			// Skip the snapshots that are already gone
+			if action.Kind == models.PruneSnapshot {
+				if ok, err := artifacts.HasSnapshot(action.ReportID); err != nil || !ok {
+					if err != nil {
+						log.Printf("Error finding snapshot of report %s: %v", action.ReportID, err)
+					}
+					continue
+				}
+			}
+			if !dryRun {
+				p.apply(&action, artifacts)
+			}
+			record.Actions = append(record.Actions, action)
+		}
+	}
+
+	record.FinishedAt = time.Now()
+	if len(record.Actions) > 0 && p.Audit != nil {
+		if err := p.Audit.Save(record); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// apply carries out an action, recording any error in it
+func (p *Pruner) apply(action *models.PruneAction, artifacts models.ReportArtifacts) {
+	var err error
+	switch action.Kind {
+	case models.PruneReport:
+		err = p.Store.DeleteReport(action.ReportID)
+	case models.PruneSnapshot:
+		err = artifacts.DeleteSnapshot(action.ReportID)
+	}
+	if err != nil {
+		action.Error = err.Error()
+		log.Printf("Error pruning %s of report %s: %v", action.Kind, action.ReportID, err)
+	}
+}
+
+// Plan returns what a policy deletes from one board's reports as of now.
+// Snapshots are only considered if the store keeps them.
+func Plan(reports []*models.Report, policy models.RetentionPolicy, now time.Time, snapshots bool) []models.PruneAction {
+	var actions []models.PruneAction
+	deleted := make(map[string]bool)
+	remove := func(report *models.Report, reason string) {
+		if report.Pinned || deleted[report.ID] {
+			return
+		}
+		deleted[report.ID] = true
+		actions = append(actions, newAction(models.PruneReport, report, reason))
+	}
+
+	groups := models.GroupRevisions(reports)
+
+	// Keep the newest periods of each type; groups are newest first, and a
+	// period's audiences count once
+	periods := make(map[models.ReportType][]string)
+	rank := make(map[string]int)
+	for _, group := range groups {
+		if _, ok := rank[group.Period]; ok {
+			continue
+		}
+		reportType := group.Revisions[0].Type
+		rank[group.Period] = len(periods[reportType])
+		periods[reportType] = append(periods[reportType], group.Period)
+	}
+	for _, group := range groups {
+		reportType := group.Revisions[0].Type
+		keep := policy.Keep[reportType]
+		if keep > 0 && rank[group.Period] >= keep {
+			for _, report := range group.Revisions {
+				remove(report, fmt.Sprintf("older than the %d newest %s periods", keep, reportType))
+			}
+		}
+	}
+
+	// Keep only the canonical revision of older periods
+	if policy.CanonicalOnlyAfterDays > 0 {
+		cutoff := now.AddDate(0, 0, -policy.CanonicalOnlyAfterDays)
+		for _, group := range groups {
+			for _, report := range group.Revisions {
+				if report != group.Canonical && report.GeneratedAt.Before(cutoff) {
+					remove(report, fmt.Sprintf("not the canonical revision and older than %d days", policy.CanonicalOnlyAfterDays))
+				}
+			}
+		}
+	}
+
+	// Delete the snapshots of the reports that are left
+	if policy.SnapshotDays > 0 && snapshots {
+		cutoff := now.AddDate(0, 0, -policy.SnapshotDays)
+		for _, report := range reports {
+			if !deleted[report.ID] && !report.Pinned && report.GeneratedAt.Before(cutoff) {
+				actions = append(actions, newAction(models.PruneSnapshot, report, fmt.Sprintf("snapshot older than %d days", policy.SnapshotDays)))
+			}
+		}
+	}
+
+	return actions
+}
+
+// newAction describes deleting a report or its snapshot
+func newAction(kind string, report *models.Report, reason string) models.PruneAction {
+	return models.PruneAction{
+		Kind:        kind,
+		ReportID:    report.ID,
+		BoardID:     report.BoardID,
+		BoardName:   report.BoardName,
+		Type:        report.Type,
+		Period:      report.PeriodKey(),
+		Revision:    report.RevisionNumber(),
+		GeneratedAt: report.GeneratedAt,
+		Reason:      reason,
+	}
+}
+
+// Run prunes every interval until stop is closed, starting immediately
+func (p *Pruner) Run(interval time.Duration, dryRun bool, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		record, err := p.Prune(time.Now(), dryRun)
+		if err != nil {
+			log.Printf("Error pruning reports: %v", err)
+		} else if len(record.Actions) > 0 {
+			verb := "Pruned"
+			if dryRun {
+				verb = "Dry run: would prune"
+			}
+			log.Printf("%s %d report(s) and snapshot(s), audit record %s", verb, len(record.Actions), record.ID)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package retention
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"agents_go/models"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	// report is a revision of the weekly or monthly report of the period
+	// ending weeks ago, generated days ago
+	report := func(id string, reportType models.ReportType, weeks, revision, days int) *models.Report {
+		end := now.AddDate(0, 0, -7*weeks)
+		return &models.Report{
+			ID: id, BoardID: "board-1", Type: reportType, EndDate: end,
+			Period:   models.ReportPeriod("board-1", reportType, end),
+			Revision: revision, GeneratedAt: now.AddDate(0, 0, -days),
+		}
+	}
+	pinned := func(r *models.Report) *models.Report {
+		r.Pinned = true
+		return r
+	}
+	canonical := func(r *models.Report) *models.Report {
+		r.Canonical = true
+		return r
+	}
+	executive := func(r *models.Report) *models.Report {
+		r.Audience = "executive"
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		reports   []*models.Report
+		policy    models.RetentionPolicy
+		snapshots bool
+		// want lists the actions as "kind id"
+		want []string
+	}{
+		{
+			name:    "empty policy keeps everything",
+			reports: []*models.Report{report("w1", models.Weekly, 0, 1, 0), report("w2", models.Weekly, 10, 1, 70)},
+		},
+		{
+			name: "keeps the newest periods of each type",
+			reports: []*models.Report{
+				report("w0", models.Weekly, 0, 1, 0),
+				report("w1", models.Weekly, 1, 1, 7),
+				report("w2", models.Weekly, 2, 1, 14),
+				report("w3", models.Weekly, 3, 1, 21),
+				report("m0", models.Monthly, 0, 1, 0),
+				report("m1", models.Monthly, 4, 1, 28),
+			},
+			policy: models.RetentionPolicy{Keep: map[models.ReportType]int{models.Weekly: 2, models.Monthly: 2}},
+			want:   []string{"report w2", "report w3"},
+		},
+		{
+			name: "deletes every revision of an old period",
+			reports: []*models.Report{
+				report("w0", models.Weekly, 0, 1, 0),
+				report("w1r2", models.Weekly, 1, 2, 6),
+				report("w1r1", models.Weekly, 1, 1, 7),
+			},
+			policy: models.RetentionPolicy{Keep: map[models.ReportType]int{models.Weekly: 1}},
+			want:   []string{"report w1r2", "report w1r1"},
+		},
+		{
+			name: "audiences of a period count once",
+			reports: []*models.Report{
+				report("w0", models.Weekly, 0, 1, 0),
+				executive(report("w0e", models.Weekly, 0, 1, 0)),
+				report("w1", models.Weekly, 1, 1, 7),
+				executive(report("w1e", models.Weekly, 1, 1, 7)),
+				report("w2", models.Weekly, 2, 1, 14),
+			},
+			policy: models.RetentionPolicy{Keep: map[models.ReportType]int{models.Weekly: 2}},
+			want:   []string{"report w2"},
+		},
+		{
+			name: "pinned reports are kept",
+			reports: []*models.Report{
+				report("w0", models.Weekly, 0, 1, 0),
+				pinned(report("w1", models.Weekly, 1, 1, 7)),
+				report("w2", models.Weekly, 2, 1, 14),
+			},
+			policy: models.RetentionPolicy{Keep: map[models.ReportType]int{models.Weekly: 1}},
+			want:   []string{"report w2"},
+		},
+		{
+			name: "older revisions that aren't canonical",
+			reports: []*models.Report{
+				report("w0r2", models.Weekly, 0, 2, 1),
+				report("w0r1", models.Weekly, 0, 1, 2),
+				report("w1r3", models.Weekly, 1, 3, 40),
+				canonical(report("w1r2", models.Weekly, 1, 2, 41)),
+				report("w1r1", models.Weekly, 1, 1, 42),
+			},
+			policy: models.RetentionPolicy{CanonicalOnlyAfterDays: 30},
+			want:   []string{"report w1r3", "report w1r1"},
+		},
+		{
+			name: "snapshots of the reports that are left",
+			reports: []*models.Report{
+				report("w0", models.Weekly, 0, 1, 0),
+				report("w1", models.Weekly, 1, 1, 7),
+				pinned(report("w2", models.Weekly, 2, 1, 14)),
+				report("w3", models.Weekly, 3, 1, 21),
+			},
+			policy:    models.RetentionPolicy{Keep: map[models.ReportType]int{models.Weekly: 3}, SnapshotDays: 5},
+			snapshots: true,
+			want:      []string{"report w3", "snapshot w1"},
+		},
+		{
+			name:    "snapshots of stores without them",
+			reports: []*models.Report{report("w1", models.Weekly, 1, 1, 7)},
+			policy:  models.RetentionPolicy{SnapshotDays: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, action := range Plan(tt.reports, tt.policy, now, tt.snapshots) {
+				got = append(got, fmt.Sprintf("%s %s", action.Kind, action.ReportID))
+				if action.Reason == "" {
+					t.Errorf("%s of %s has no reason", action.Kind, action.ReportID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got actions %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
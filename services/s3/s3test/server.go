@@ -0,0 +1,333 @@
+// Package s3test provides an in-process fake of an S3-compatible object
+// store for local development and tests. It serves one bucket in path
+// style and implements what the s3 client uses: putting, getting, deleting
+// and listing objects, conditional writes on ETags and request signing
+// checks.
+package s3test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agents_go/config"
+)
+
+// Default credentials accepted by the fake
+const (
+	DefaultAccessKey = "fake-access-key"
+	DefaultSecretKey = "fake-secret-key"
+	DefaultBucket    = "reports"
+)
+
+// maxKeys is the page size of object listings
+const maxKeys = 1000
+
+// Server is a fake S3 server
+type Server struct {
+	// URL is the endpoint of the running server, e.g. http://127.0.0.1:1234
+	URL       string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	httpServer *httptest.Server
+
+	mu       sync.Mutex
+	objects  map[string]*object
+	maxKeys  int
+	failures []int
+	requests []string
+}
+
+// object is a stored object
+type object struct {
+	data         []byte
+	etag         string
+	contentType  string
+	lastModified time.Time
+}
+
+// NewServer starts a fake S3 server with an empty bucket
+func NewServer() *Server {
+	s := NewHandler()
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// NewHandler creates a fake S3 server without starting a listener, so it
+// can be mounted on any http.Server
+func NewHandler() *Server {
+	return &Server{
+		Bucket:    DefaultBucket,
+		AccessKey: DefaultAccessKey,
+		SecretKey: DefaultSecretKey,
+		objects:   make(map[string]*object),
+		maxKeys:   maxKeys,
+	}
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// Configure points the report store at the fake
+func (s *Server) Configure(prefix string) {
+	config.ReportStoreBackend = "s3"
+	config.ReportStorePath = strings.TrimSuffix(s.Bucket+"/"+prefix, "/")
+	config.S3Endpoint = s.URL
+	config.S3Region = "us-east-1"
+	config.S3AccessKeyID = s.AccessKey
+	config.S3SecretAccessKey = s.SecretKey
+	config.S3PathStyle = true
+}
+
+// SetPageSize sets how many objects a listing returns per page, to
+// exercise pagination
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxKeys = n
+}
+
+// FailNext makes the next count requests fail with the given HTTP status
+func (s *Server) FailNext(status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// Keys returns the keys of the stored objects, sorted
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Requests returns the method and path of every request received
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, status, "InternalError", "simulated failure")
+		return
+	}
+
+	if problem := s.checkSignature(r, body); problem != "" {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", problem)
+		return
+	}
+
+	// Paths are /<bucket> or /<bucket>/<key>
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		bucket, key = path[:i], path[i+1:]
+	}
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, r)
+	case key != "" && r.Method == http.MethodPut:
+		s.put(w, r, key, body)
+	case key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		s.get(w, r, key)
+	case key != "" && r.Method == http.MethodDelete:
+		s.delete(w, r, key)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed")
+	}
+}
+
+// checkSignature checks that the request is signed with the server's
+// access key and that the signed payload hash matches the body
+func (s *Server) checkSignature(r *http.Request, body []byte) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/") {
+		return "the request isn't signed with the expected access key"
+	}
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		return "the payload hash doesn't match the body"
+	}
+	if r.Header.Get("X-Amz-Date") == "" {
+		return "the request has no date"
+	}
+	return ""
+}
+
+// put stores an object, honoring If-Match and If-None-Match
+func (s *Server) put(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	existing := s.objects[key]
+	if match := r.Header.Get("If-Match"); match != "" && (existing == nil || existing.etag != match) {
+		writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return
+	}
+	if r.Header.Get("If-None-Match") == "*" && existing != nil {
+		writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return
+	}
+
+	sum := md5.Sum(body)
+	obj := &object{
+		data:         body,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		contentType:  r.Header.Get("Content-Type"),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	s.objects[key] = obj
+
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// get serves an object
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	obj, ok := s.objects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	if obj.contentType != "" {
+		w.Header().Set("Content-Type", obj.contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(obj.data)
+	}
+}
+
+// delete removes an object, honoring If-Match
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, key string) {
+	existing := s.objects[key]
+	if match := r.Header.Get("If-Match"); match != "" && existing != nil && existing.etag != match {
+		writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return
+	}
+	delete(s.objects, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listResult is the ListObjectsV2 response
+type listResult struct {
+	XMLName               xml.Name      `xml:"ListBucketResult"`
+	Name                  string        `xml:"Name"`
+	Prefix                string        `xml:"Prefix"`
+	KeyCount              int           `xml:"KeyCount"`
+	MaxKeys               int           `xml:"MaxKeys"`
+	IsTruncated           bool          `xml:"IsTruncated"`
+	ContinuationToken     string        `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
+	Contents              []listContent `xml:"Contents"`
+}
+
+// listContent is an object in a listing
+type listContent struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+}
+
+// list serves ListObjectsV2, paginated with continuation tokens that are
+// the last key of the previous page
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("list-type") != "2" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 is supported")
+		return
+	}
+	prefix := query.Get("prefix")
+	after := query.Get("continuation-token")
+
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listResult{Name: s.Bucket, Prefix: prefix, MaxKeys: s.maxKeys, ContinuationToken: after}
+	if len(keys) > s.maxKeys {
+		keys = keys[:s.maxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, listContent{
+			Key:          key,
+			LastModified: obj.lastModified.Format(time.RFC3339),
+			ETag:         obj.etag,
+			Size:         len(obj.data),
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+// errorBody is an S3 error response
+type errorBody struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// writeError writes an S3 error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(errorBody{Code: code, Message: message})
+}
@@ -0,0 +1,503 @@
+// Package search keeps a full-text index of the reports in a report store,
+// ranking matches with BM25 and picking highlighted snippets from their
+// content.
+package search
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agents_go/models"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Field weights: a term in a board name or a structured heading counts for
+// more than one in the body of a report
+const (
+	boardNameWeight = 2.0
+	headingWeight   = 1.5
+	contentWeight   = 1.0
+	periodWeight    = 1.0
+)
+
+// Sort orders for results
+const (
+	SortRelevance = "relevance"
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+)
+
+// Query is a search. Text is a list of words, with "quoted phrases" that a
+// report must contain; reports matching more of the words rank higher.
+type Query struct {
+	Text string
+	// Filter selects the reports to search and the page of results;
+	// Period, Offset and Limit apply as in ReportStore.ListReports
+	Filter models.ReportFilter
+	// AllRevisions returns every matching revision of a period's report
+	// instead of only the best one
+	AllRevisions bool
+	// Sort is SortRelevance (the default), SortNewest or SortOldest
+	Sort string
+	// Boards restricts the search to the reports of these boards, before
+	// paging; nil searches every board
+	Boards map[string]bool
+}
+
+// Result is a report that matched a query
+type Result struct {
+	ReportID    string            `json:"report_id"`
+	URL         string            `json:"url"`
+	BoardID     string            `json:"board_id"`
+	BoardName   string            `json:"board_name"`
+	Type        models.ReportType `json:"type"`
+	Audience    models.Audience   `json:"audience,omitempty"`
+	Period      string            `json:"period"`
+	Revision    int               `json:"revision"`
+	Canonical   bool              `json:"canonical"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	StartDate   time.Time         `json:"start_date"`
+	EndDate     time.Time         `json:"end_date"`
+	Score       float64           `json:"score"`
+	// Snippet is the passage of the report that best matches the query,
+	// split into the matched words and the text around them
+	Snippet []Fragment `json:"snippet"`
+}
+
+// Results are a page of a query's results
+type Results struct {
+	Query string `json:"query"`
+	// Total counts the results on every page
+	Total   int      `json:"total"`
+	Results []Result `json:"results"`
+}
+
+// Board is a board with indexed reports
+type Board struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Index is an inverted index of reports. It is safe for concurrent use.
+type Index struct {
+	mutex sync.RWMutex
+	docs  map[string]*document
+	// postings maps each term to the weighted frequency of the term in
+	// each report that contains it
+	postings map[string]map[string]float64
+	// groups maps each period and audience to the IDs of its revisions
+	groups      map[string][]string
+	totalLength float64
+}
+
+// document is an indexed report
+type document struct {
+	report *models.Report
+	terms  map[string]float64
+	length float64
+	// text is the report's indexed fields, tokenized, for matching phrases
+	text [][]string
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*document),
+		postings: make(map[string]map[string]float64),
+		groups:   make(map[string][]string),
+	}
+}
+
+// Build replaces the index's contents with every report in the store
+func (idx *Index) Build(store models.ReportStore) error {
+	reports, err := store.ListReports(models.ReportFilter{})
+	if err != nil {
+		return fmt.Errorf("error listing reports: %v", err)
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.docs = make(map[string]*document)
+	idx.postings = make(map[string]map[string]float64)
+	idx.groups = make(map[string][]string)
+	idx.totalLength = 0
+	for _, report := range reports {
+		idx.add(report)
+	}
+	return nil
+}
+
+// Len returns the number of indexed reports
+func (idx *Index) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.docs)
+}
+
+// Add indexes a report, replacing any report with the same ID
+func (idx *Index) Add(report *models.Report) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(report.ID)
+	idx.add(report)
+}
+
+// Remove removes a report from the index
+func (idx *Index) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+// add indexes a report; the caller holds the lock
+func (idx *Index) add(report *models.Report) {
+	// Keep a copy, so later changes to the report don't change the index
+	copied := *report
+	doc := &document{report: &copied, terms: make(map[string]float64)}
+	for _, field := range fields(&copied) {
+		var words []string
+		for _, token := range tokenize(field.text) {
+			doc.terms[token.term] += field.weight
+			doc.length += field.weight
+			words = append(words, token.term)
+		}
+		doc.text = append(doc.text, words)
+	}
+
+	idx.docs[report.ID] = doc
+	idx.totalLength += doc.length
+	for term, frequency := range doc.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]float64)
+		}
+		idx.postings[term][report.ID] = frequency
+	}
+	key := groupKey(&copied)
+	idx.groups[key] = append(idx.groups[key], report.ID)
+}
+
+// remove removes a report from the index; the caller holds the lock
+func (idx *Index) remove(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	delete(idx.docs, id)
+	idx.totalLength -= doc.length
+	for term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+
+	key := groupKey(doc.report)
+	ids := idx.groups[key]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(idx.groups, key)
+	} else {
+		idx.groups[key] = ids
+	}
+}
+
+// Boards lists the boards with indexed reports, by name
+func (idx *Index) Boards() []Board {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	names := make(map[string]string)
+	for _, doc := range idx.docs {
+		names[doc.report.BoardID] = doc.report.BoardName
+	}
+	boards := make([]Board, 0, len(names))
+	for id, name := range names {
+		boards = append(boards, Board{ID: id, Name: name})
+	}
+	sort.Slice(boards, func(i, j int) bool {
+		if boards[i].Name != boards[j].Name {
+			return boards[i].Name < boards[j].Name
+		}
+		return boards[i].ID < boards[j].ID
+	})
+	return boards
+}
+
+// match is a report that matched a query, before paging
+type match struct {
+	doc       *document
+	score     float64
+	canonical bool
+}
+
+// Search returns the page of reports matching the query. Unless the query
+// asks for all revisions, each period's report appears once per audience:
+// its canonical revision if that matches, or else its best matching one.
+func (idx *Index) Search(query Query) *Results {
+	results := &Results{Query: query.Text, Results: []Result{}}
+	terms, phrases := parseQuery(query.Text)
+	if len(terms) == 0 {
+		return results
+	}
+
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	// Score every report containing at least one term with BM25, scaled
+	// by the share of the terms it contains
+	count := float64(len(idx.docs))
+	averageLength := idx.totalLength / math.Max(count, 1)
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, term := range terms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (count-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			length := idx.docs[id].length
+			scores[id] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/math.Max(averageLength, 1)))
+			matched[id]++
+		}
+	}
+
+	var matches []*match
+	for id, score := range scores {
+		doc := idx.docs[id]
+		if !query.Filter.Matches(doc.report) || !doc.containsPhrases(phrases) {
+			continue
+		}
+		if query.Boards != nil && !query.Boards[doc.report.BoardID] {
+			continue
+		}
+		coverage := float64(matched[id]) / float64(len(terms))
+		matches = append(matches, &match{
+			doc:       doc,
+			score:     score * coverage * coverage,
+			canonical: idx.isCanonical(doc.report),
+		})
+	}
+	if !query.AllRevisions {
+		matches = bestRevisions(matches)
+	}
+	sortMatches(matches, query.Sort)
+
+	// Page the matches
+	results.Total = len(matches)
+	if query.Filter.Offset > 0 {
+		if query.Filter.Offset >= len(matches) {
+			matches = nil
+		} else {
+			matches = matches[query.Filter.Offset:]
+		}
+	}
+	if query.Filter.Limit > 0 && len(matches) > query.Filter.Limit {
+		matches = matches[:query.Filter.Limit]
+	}
+
+	for _, m := range matches {
+		report := m.doc.report
+		results.Results = append(results.Results, Result{
+			ReportID:    report.ID,
+			URL:         "/view-report?id=" + report.ID,
+			BoardID:     report.BoardID,
+			BoardName:   report.BoardName,
+			Type:        report.Type,
+			Audience:    report.Audience,
+			Period:      report.PeriodKey(),
+			Revision:    report.RevisionNumber(),
+			Canonical:   m.canonical,
+			GeneratedAt: report.GeneratedAt,
+			StartDate:   report.StartDate,
+			EndDate:     report.EndDate,
+			Score:       math.Round(m.score*1000) / 1000,
+			Snippet:     snippet(report.Content, terms),
+		})
+	}
+	return results
+}
+
+// isCanonical reports whether a report is the canonical revision of its
+// period among the indexed ones; the caller holds the lock
+func (idx *Index) isCanonical(report *models.Report) bool {
+	var revisions []*models.Report
+	for _, id := range idx.groups[groupKey(report)] {
+		revisions = append(revisions, idx.docs[id].report)
+	}
+	canonical := models.CanonicalRevision(revisions)
+	return canonical != nil && canonical.ID == report.ID
+}
+
+// bestRevisions keeps one match per period and audience: the canonical
+// revision if it matched, or else the best scoring one
+func bestRevisions(matches []*match) []*match {
+	best := make(map[string]*match)
+	var keys []string
+	for _, m := range matches {
+		key := groupKey(m.doc.report)
+		current, ok := best[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		if !ok || (m.canonical && !current.canonical) ||
+			(m.canonical == current.canonical && m.score > current.score) {
+			best[key] = m
+		}
+	}
+	kept := make([]*match, 0, len(keys))
+	for _, key := range keys {
+		kept = append(kept, best[key])
+	}
+	return kept
+}
+
+// sortMatches sorts matches by score or by the time the reports were
+// generated; ties go to the newer report, then to its ID
+func sortMatches(matches []*match, order string) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, rj := matches[i].doc.report, matches[j].doc.report
+		switch order {
+		case SortNewest:
+			if !ri.GeneratedAt.Equal(rj.GeneratedAt) {
+				return ri.GeneratedAt.After(rj.GeneratedAt)
+			}
+		case SortOldest:
+			if !ri.GeneratedAt.Equal(rj.GeneratedAt) {
+				return ri.GeneratedAt.Before(rj.GeneratedAt)
+			}
+		default:
+			if matches[i].score != matches[j].score {
+				return matches[i].score > matches[j].score
+			}
+			if !ri.GeneratedAt.Equal(rj.GeneratedAt) {
+				return ri.GeneratedAt.After(rj.GeneratedAt)
+			}
+		}
+		return ri.ID < rj.ID
+	})
+}
+
+// containsPhrases reports whether one of the document's fields contains
+// each phrase
+func (d *document) containsPhrases(phrases [][]string) bool {
+	for _, phrase := range phrases {
+		found := false
+		for _, words := range d.text {
+			if containsSequence(words, phrase) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// containsSequence reports whether words contains phrase as consecutive
+// words
+func containsSequence(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		found := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey identifies the revisions of a period's report for one audience
+func groupKey(report *models.Report) string {
+	return report.PeriodKey() + "/" + string(report.Audience)
+}
+
+// field is a piece of a report's text and the weight of its terms
+type field struct {
+	text   string
+	weight float64
+}
+
+// fields returns the text of a report to index: its board name, period and
+// type, its structured headings and its content
+func fields(report *models.Report) []field {
+	indexed := []field{
+		{report.BoardName, boardNameWeight},
+		{strings.Join([]string{report.PeriodKey(), string(report.Type), string(report.Audience)}, " "), periodWeight},
+	}
+
+	if s := report.Structured; s != nil {
+		var headings []string
+		headings = append(headings, s.ExecutiveSummary, s.CurrentStatus)
+		for _, item := range s.Progress {
+			headings = append(headings, item.Title)
+		}
+		for _, priority := range s.Priorities {
+			headings = append(headings, priority.Title, priority.Owner)
+		}
+		for _, risk := range s.Risks {
+			headings = append(headings, risk.Description)
+		}
+		for _, contribution := range s.Contributions {
+			headings = append(headings, contribution.Member)
+		}
+		indexed = append(indexed, field{strings.Join(headings, "\n"), headingWeight})
+	}
+
+	return append(indexed, field{report.Content, contentWeight})
+}
+
+// quotedPhrase matches a "quoted phrase" in a query
+var quotedPhrase = regexp.MustCompile(`"([^"]*)"`)
+
+// parseQuery returns a query's distinct terms and its quoted phrases
+func parseQuery(text string) ([]string, [][]string) {
+	var terms []string
+	seen := make(map[string]bool)
+	addTerms := func(tokens []token) {
+		for _, token := range tokens {
+			if !seen[token.term] {
+				seen[token.term] = true
+				terms = append(terms, token.term)
+			}
+		}
+	}
+
+	var phrases [][]string
+	for _, quoted := range quotedPhrase.FindAllStringSubmatch(text, -1) {
+		tokens := tokenize(quoted[1])
+		addTerms(tokens)
+		if len(tokens) > 0 {
+			phrase := make([]string, len(tokens))
+			for i, token := range tokens {
+				phrase[i] = token.term
+			}
+			phrases = append(phrases, phrase)
+		}
+	}
+	addTerms(tokenize(quotedPhrase.ReplaceAllString(text, " ")))
+	return terms, phrases
+}
@@ -0,0 +1,148 @@
+package search_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/search"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Now().UTC()
+
+	// Reports are generated an hour apart, report-0 the oldest
+	reports := []struct {
+		boardID, boardName, content string
+	}{
+		{"board-1", "Payments", "The webhook retries were fixed."},
+		{"board-1", "Website", "Webhook failures and retries, webhook timeouts and more webhook alerts."},
+		{"board-2", "Mobile", "Login screen redesign finished."},
+		{"board-2", "Billing", "Webhook retries and payment reminders."},
+		{"board-3", "Website", "A webhook test ran."},
+	}
+	index := search.NewIndex()
+	for i, r := range reports {
+		index.Add(&models.Report{
+			ID:          fmt.Sprintf("report-%d", i),
+			Period:      fmt.Sprintf("period-%d", i),
+			BoardID:     r.boardID,
+			BoardName:   r.boardName,
+			Type:        models.Weekly,
+			Content:     r.content,
+			GeneratedAt: now.Add(time.Duration(i-len(reports)) * time.Hour),
+		})
+	}
+
+	tests := []struct {
+		name  string
+		query search.Query
+		// want are the indexes of the reports on the page, in order; nil
+		// only checks wantTotal and, for relevance, wantLast
+		want      []int
+		wantTotal int
+		// wantLast is the report ranked last
+		wantLast int
+	}{
+		{
+			name:      "partial matches rank last",
+			query:     search.Query{Text: "webhook retries"},
+			wantTotal: 4,
+			wantLast:  4,
+		},
+		{
+			name:      "newest first",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest},
+			want:      []int{4, 3, 1, 0},
+			wantTotal: 4,
+		},
+		{
+			name:      "oldest first",
+			query:     search.Query{Text: "webhook", Sort: search.SortOldest},
+			want:      []int{0, 1, 3, 4},
+			wantTotal: 4,
+		},
+		{
+			name:      "phrase",
+			query:     search.Query{Text: `"webhook retries"`, Sort: search.SortNewest},
+			want:      []int{3, 0},
+			wantTotal: 2,
+		},
+		{
+			name:      "first page",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest, Filter: models.ReportFilter{Limit: 3}},
+			want:      []int{4, 3, 1},
+			wantTotal: 4,
+		},
+		{
+			name:      "last page",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest, Filter: models.ReportFilter{Offset: 3, Limit: 3}},
+			want:      []int{0},
+			wantTotal: 4,
+		},
+		{
+			name:      "past the last page",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest, Filter: models.ReportFilter{Offset: 4, Limit: 3}},
+			want:      []int{},
+			wantTotal: 4,
+		},
+		{
+			name:      "readable boards only, before paging",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest, Filter: models.ReportFilter{Limit: 1}, Boards: map[string]bool{"board-1": true, "board-9": true}},
+			want:      []int{1},
+			wantTotal: 2,
+		},
+		{
+			name:      "no readable boards",
+			query:     search.Query{Text: "webhook", Boards: map[string]bool{}},
+			want:      []int{},
+			wantTotal: 0,
+		},
+		{
+			name:      "board filter",
+			query:     search.Query{Text: "webhook", Sort: search.SortNewest, Filter: models.ReportFilter{BoardID: "board-2"}},
+			want:      []int{3},
+			wantTotal: 1,
+		},
+		{
+			name:      "no terms",
+			query:     search.Query{Text: "  "},
+			want:      []int{},
+			wantTotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := index.Search(tt.query)
+			if results.Total != tt.wantTotal {
+				t.Errorf("total is %d, want %d", results.Total, tt.wantTotal)
+			}
+
+			got := []int{}
+			for _, result := range results.Results {
+				var i int
+				fmt.Sscanf(result.ReportID, "report-%d", &i)
+				got = append(got, i)
+			}
+			if tt.want != nil {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got reports %v, want %v", got, tt.want)
+				}
+				return
+			}
+
+			// Scores never increase down the page
+			for i := 1; i < len(results.Results); i++ {
+				if results.Results[i].Score > results.Results[i-1].Score {
+					t.Errorf("report-%d scores %v, more than report-%d above it", got[i], results.Results[i].Score, got[i-1])
+				}
+			}
+			if len(got) == 0 || got[len(got)-1] != tt.wantLast {
+				t.Errorf("got reports %v, want report-%d last", got, tt.wantLast)
+			}
+		})
+	}
+}
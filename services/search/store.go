@@ -0,0 +1,50 @@
+package search
+
+import (
+	"errors"
+
+	"agents_go/models"
+)
+
+// indexedStore is a report store that keeps an index of its reports up to
+// date as they are saved and deleted
+type indexedStore struct {
+	models.ReportStore
+	index *Index
+}
+
+// indexedArtifactStore is an indexed store that also keeps report
+// artifacts
+type indexedArtifactStore struct {
+	*indexedStore
+	models.ReportArtifacts
+}
+
+// Indexed returns the store wrapped to add every report it saves to the
+// index and remove every report it deletes. The wrapped store keeps the
+// store's artifacts if it has any.
+func Indexed(store models.ReportStore, index *Index) models.ReportStore {
+	indexed := &indexedStore{ReportStore: store, index: index}
+	if artifacts, ok := store.(models.ReportArtifacts); ok {
+		return &indexedArtifactStore{indexedStore: indexed, ReportArtifacts: artifacts}
+	}
+	return indexed
+}
+
+// SaveReport saves a report and indexes it
+func (s *indexedStore) SaveReport(report *models.Report) error {
+	if err := s.ReportStore.SaveReport(report); err != nil {
+		return err
+	}
+	s.index.Add(report)
+	return nil
+}
+
+// DeleteReport deletes a report and removes it from the index
+func (s *indexedStore) DeleteReport(id string) error {
+	err := s.ReportStore.DeleteReport(id)
+	if err == nil || errors.Is(err, models.ErrReportNotFound) {
+		s.index.Remove(id)
+	}
+	return err
+}
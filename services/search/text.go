@@ -0,0 +1,168 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is an indexed word and where it is in the text
+type token struct {
+	term string
+	// start and end are the word's byte offsets in the text
+	start, end int
+}
+
+// stopWords aren't indexed or searched for
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "did": true, "do": true, "does": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true,
+	"how": true, "i": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "our": true,
+	"so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "this": true, "to": true, "was": true,
+	"we": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "why": true, "will": true, "with": true,
+}
+
+// tokenize splits text into lowercase words of letters and digits, without
+// stop words, with plural endings removed
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			word := strings.ToLower(text[start:i])
+			if !stopWords[word] {
+				tokens = append(tokens, token{term: stem(word), start: start, end: i})
+			}
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// stem removes plural endings, so "outages" matches "outage" and
+// "priorities" matches "priority"
+func stem(word string) string {
+	if utf8.RuneCountInString(word) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// snippetWords is the length of a snippet, in indexed words
+const snippetWords = 30
+
+// snippetLead is how many words a snippet starts before its first match
+const snippetLead = 6
+
+// Fragment is a piece of a snippet; Match marks a word of the query
+type Fragment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// snippet returns the passage of content with the most distinct query
+// terms, or its beginning if none match, split into fragments
+func snippet(content string, terms []string) []Fragment {
+	tokens := tokenize(content)
+	if len(tokens) == 0 {
+		return []Fragment{}
+	}
+	wanted := make(map[string]bool)
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	// Find the window of words holding the most distinct terms, then the
+	// most matches
+	bestStart, bestDistinct, bestMatches := 0, 0, 0
+	for i, t := range tokens {
+		if !wanted[t.term] {
+			continue
+		}
+		distinct := make(map[string]bool)
+		matches := 0
+		for j := i; j < len(tokens) && j < i+snippetWords-snippetLead; j++ {
+			if wanted[tokens[j].term] {
+				distinct[tokens[j].term] = true
+				matches++
+			}
+		}
+		if len(distinct) > bestDistinct || (len(distinct) == bestDistinct && matches > bestMatches) {
+			bestStart, bestDistinct, bestMatches = i, len(distinct), matches
+		}
+	}
+	first := bestStart - snippetLead
+	if first < 0 || bestDistinct == 0 {
+		first = 0
+	}
+	last := first + snippetWords
+	if last > len(tokens) {
+		last = len(tokens)
+	}
+
+	// Split the window into the matched words and the text between them
+	var fragments []Fragment
+	text := func(s string, match bool) {
+		s = cleanMarkdown(s)
+		if s == "" {
+			return
+		}
+		if n := len(fragments); n > 0 && fragments[n-1].Match == match {
+			fragments[n-1].Text += s
+			return
+		}
+		fragments = append(fragments, Fragment{Text: s, Match: match})
+	}
+	if first > 0 {
+		text("…", false)
+	}
+	offset := tokens[first].start
+	for _, t := range tokens[first:last] {
+		if wanted[t.term] {
+			text(content[offset:t.start], false)
+			text(content[t.start:t.end], true)
+			offset = t.end
+		}
+	}
+	if last < len(tokens) {
+		text(content[offset:tokens[last-1].end], false)
+		text("…", false)
+	} else {
+		text(strings.TrimRight(content[offset:], " \t\r\n"), false)
+	}
+	return fragments
+}
+
+// markdownMarks are removed from snippets
+var markdownMarks = strings.NewReplacer("**", "", "__", "", "`", "", "#", "", "\r", "", "\n", " ", "\t", " ")
+
+// cleanMarkdown removes markdown emphasis and headings and puts a snippet
+// on one line
+func cleanMarkdown(s string) string {
+	s = markdownMarks.Replace(s)
+	for strings.Contains(s, "  ") {
+		s = strings.ReplaceAll(s, "  ", " ")
+	}
+	return s
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"agents_go/models"
+	"agents_go/services/llm"
+	"agents_go/services/trello"
+)
+
+// maxProposedChanges caps the number of changes in one proposal
+const maxProposedChanges = 25
+
+// ProposeFunc receives a validated change proposal
+type ProposeFunc func(summary string, changes []models.Change)
+
+// NewProposeChangesTool creates a tool the model calls to propose changes to
+// a board. The tool doesn't modify the board: it checks the cards, lists and
+// members exist on the board and passes the proposal to propose, so the user
+// can review and apply it.
+func NewProposeChangesTool(client *trello.Client, boardID string, propose ProposeFunc) Tool {
+	t := &proposeChanges{client: client, boardID: boardID, propose: propose}
+	return &toolFunc{
+		definition: llm.Tool{
+			Name: "propose_changes",
+			Description: "Propose changes to the board: move cards between lists, assign or unassign members, and set or clear due dates. " +
+				"Nothing is changed until the user approves the proposal, so never say the changes were made. " +
+				"Cards, lists and members may be given by ID or by exact name. Call this at most once per reply with all the changes.",
+			Parameters: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "summary": {"type": "string", "description": "One sentence describing the changes for the user"},
+    "changes": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["move_card", "assign_member", "unassign_member", "set_due"]},
+          "card": {"type": "string", "description": "Card ID or name"},
+          "list": {"type": "string", "description": "Target list ID or name, for move_card"},
+          "member": {"type": "string", "description": "Member ID, username or full name, for assign_member and unassign_member"},
+          "due": {"type": "string", "description": "Due date as YYYY-MM-DD or RFC 3339 for set_due; empty clears the due date"}
+        },
+        "required": ["type", "card"]
+      }
+    }
+  },
+  "required": ["summary", "changes"]
+}`),
+		},
+		call: t.call,
+	}
+}
+
+// proposeChanges implements the propose_changes tool for one board
+type proposeChanges struct {
+	client  *trello.Client
+	boardID string
+	propose ProposeFunc
+}
+
+// call validates the proposed changes and resolves names to IDs
+func (t *proposeChanges) call(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Summary string `json:"summary"`
+		Changes []struct {
+			Type   string `json:"type"`
+			Card   string `json:"card"`
+			List   string `json:"list"`
+			Member string `json:"member"`
+			Due    string `json:"due"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if len(args.Changes) == 0 {
+		return "", fmt.Errorf("no changes proposed")
+	}
+	if len(args.Changes) > maxProposedChanges {
+		return "", fmt.Errorf("too many changes: at most %d per proposal", maxProposedChanges)
+	}
+
+	// Get the board's cards, lists and members to resolve names
+	cards, err := t.client.GetCards(t.boardID)
+	if err != nil {
+		return "", err
+	}
+	lists, err := t.client.GetLists(t.boardID)
+	if err != nil {
+		return "", err
+	}
+	members, err := t.client.GetBoardMembers(t.boardID)
+	if err != nil {
+		return "", err
+	}
+
+	changes := make([]models.Change, 0, len(args.Changes))
+	for i, proposed := range args.Changes {
+		card, err := findCard(cards, proposed.Card)
+		if err != nil {
+			return "", fmt.Errorf("change %d: %v", i+1, err)
+		}
+		change := models.Change{
+			Type:     models.ChangeType(proposed.Type),
+			CardID:   card.ID,
+			CardName: card.Name,
+		}
+
+		switch change.Type {
+		case models.ChangeMoveCard:
+			list, err := findList(lists, proposed.List)
+			if err != nil {
+				return "", fmt.Errorf("change %d: %v", i+1, err)
+			}
+			if list.ID == card.ListID {
+				return "", fmt.Errorf("change %d: card '%s' is already in list '%s'", i+1, card.Name, list.Name)
+			}
+			change.ListID = list.ID
+			change.ListName = list.Name
+		case models.ChangeAssignMember, models.ChangeUnassignMember:
+			member, err := findMember(members, proposed.Member)
+			if err != nil {
+				return "", fmt.Errorf("change %d: %v", i+1, err)
+			}
+			change.MemberID = member.ID
+			change.MemberName = member.FullName
+		case models.ChangeSetDue:
+			if strings.TrimSpace(proposed.Due) != "" {
+				due, err := parseDate(strings.TrimSpace(proposed.Due))
+				if err != nil {
+					return "", fmt.Errorf("change %d: invalid due date %q", i+1, proposed.Due)
+				}
+				change.Due = due.UTC().Format(time.RFC3339)
+			}
+		default:
+			return "", fmt.Errorf("change %d: unknown change type %q", i+1, proposed.Type)
+		}
+
+		changes = append(changes, change)
+	}
+
+	t.propose(strings.TrimSpace(args.Summary), changes)
+
+	descriptions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		descriptions = append(descriptions, change.Describe())
+	}
+	return toJSON(map[string]interface{}{
+		"status":  "proposed",
+		"note":    "The changes are shown to the user for approval. They have not been applied.",
+		"changes": descriptions,
+	})
+}
+
+// findCard finds an open card by ID or case-insensitive name
+func findCard(cards []trello.Card, ref string) (*trello.Card, error) {
+	var found *trello.Card
+	for i := range cards {
+		if cards[i].ID == ref {
+			return &cards[i], nil
+		}
+		if strings.EqualFold(cards[i].Name, strings.TrimSpace(ref)) {
+			if found != nil {
+				return nil, fmt.Errorf("more than one card is named '%s'; use the card ID", ref)
+			}
+			found = &cards[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no card '%s' on this board", ref)
+	}
+	return found, nil
+}
+
+// findList finds a list by ID or case-insensitive name
+func findList(lists []trello.List, ref string) (*trello.List, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, fmt.Errorf("list is required for move_card")
+	}
+	for i := range lists {
+		if lists[i].ID == ref || strings.EqualFold(lists[i].Name, strings.TrimSpace(ref)) {
+			return &lists[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no list '%s' on this board", ref)
+}
+
+// findMember finds a board member by ID, username or full name
+func findMember(members []trello.Member, ref string) (*trello.Member, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("member is required")
+	}
+	username := strings.TrimPrefix(ref, "@")
+	for i := range members {
+		if members[i].ID == ref || strings.EqualFold(members[i].Username, username) || strings.EqualFold(members[i].FullName, ref) {
+			return &members[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no member '%s' on this board", ref)
+}
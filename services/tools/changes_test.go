@@ -0,0 +1,111 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"agents_go/models"
+	"agents_go/services/tools"
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+func TestProposeChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		// want are the proposed changes; nil expects the proposal to be
+		// refused with an error containing wantErr
+		want    []models.Change
+		wantErr string
+	}{
+		{
+			name: "names resolved",
+			arguments: `{"summary": "Finish the launch", "changes": [
+				{"type": "move_card", "card": "fix payment webhook retries", "list": "Done"},
+				{"type": "assign_member", "card": "card-4", "member": "lucia"},
+				{"type": "unassign_member", "card": "Redesign landing page", "member": "Priya Sharma"},
+				{"type": "set_due", "card": "card-4", "due": "2030-05-01"},
+				{"type": "set_due", "card": "card-1", "due": ""}
+			]}`,
+			want: []models.Change{
+				{Type: models.ChangeMoveCard, CardID: "card-2", CardName: "Fix payment webhook retries", ListID: "list-done", ListName: "Done"},
+				{Type: models.ChangeAssignMember, CardID: "card-4", CardName: "Set up analytics dashboard", MemberID: "member-3", MemberName: "Lucia Gomez"},
+				{Type: models.ChangeUnassignMember, CardID: "card-1", CardName: "Redesign landing page", MemberID: "member-1", MemberName: "Priya Sharma"},
+				{Type: models.ChangeSetDue, CardID: "card-4", CardName: "Set up analytics dashboard", Due: "2030-05-01T00:00:00Z"},
+				{Type: models.ChangeSetDue, CardID: "card-1", CardName: "Redesign landing page"},
+			},
+		},
+		{
+			name:      "card already in the list",
+			arguments: `{"summary": "Move it", "changes": [{"type": "move_card", "card": "card-3", "list": "Done"}]}`,
+			wantErr:   "already in list",
+		},
+		{
+			name:      "unknown card",
+			arguments: `{"summary": "Move it", "changes": [{"type": "move_card", "card": "No such card", "list": "Done"}]}`,
+			wantErr:   "change 1",
+		},
+		{
+			name:      "unknown member",
+			arguments: `{"summary": "Assign", "changes": [{"type": "assign_member", "card": "card-1", "member": "nobody"}]}`,
+			wantErr:   "change 1",
+		},
+		{
+			name:      "invalid due date",
+			arguments: `{"summary": "Due", "changes": [{"type": "set_due", "card": "card-1", "due": "next week"}]}`,
+			wantErr:   "invalid due date",
+		},
+		{
+			name:      "no changes",
+			arguments: `{"summary": "Nothing", "changes": []}`,
+			wantErr:   "no changes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := trellotest.DefaultFixture()
+			server := trellotest.NewServer(fixture)
+			defer server.Close()
+			server.Configure()
+			client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+			var proposed []models.Change
+			tool := tools.NewProposeChangesTool(client, "board-1", func(summary string, changes []models.Change) {
+				proposed = changes
+			})
+			_, err := tool.Call(context.Background(), json.RawMessage(tt.arguments))
+
+			if tt.want == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				if proposed != nil {
+					t.Errorf("refused proposal was passed on: %+v", proposed)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Error proposing changes: %v", err)
+				}
+				if len(proposed) != len(tt.want) {
+					t.Fatalf("got %d changes, want %d", len(proposed), len(tt.want))
+				}
+				for i := range tt.want {
+					if proposed[i] != tt.want[i] {
+						t.Errorf("change %d is %+v, want %+v", i, proposed[i], tt.want[i])
+					}
+				}
+			}
+
+			// Proposing never modifies the board
+			for _, request := range server.Requests() {
+				if !strings.HasPrefix(request, "GET ") {
+					t.Errorf("board modified: %s", request)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package tools defines the functions a model can call while writing a
+// report or answering a question, and a registry to look them up by name.
+// Tools never modify a board: changes are only proposed, for the user to
+// review and apply.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"agents_go/services/llm"
+)
+
+// Tool is a function the model can call
+type Tool interface {
+	// Definition describes the tool and its JSON schema to the model
+	Definition() llm.Tool
+	// Call runs the tool with the model's JSON arguments and returns the
+	// result as text for the model
+	Call(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// Registry holds the tools available to a model
+type Registry struct {
+	tools map[string]Tool
+}
+
+// NewRegistry creates a registry with the given tools
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool)}
+	for _, tool := range tools {
+		r.Register(tool)
+	}
+	return r
+}
+
+// Register adds a tool, replacing any tool with the same name
+func (r *Registry) Register(tool Tool) {
+	r.tools[tool.Definition().Name] = tool
+}
+
+// Definitions returns the definitions of all tools, sorted by name
+func (r *Registry) Definitions() []llm.Tool {
+	definitions := make([]llm.Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		definitions = append(definitions, tool.Definition())
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// Call runs the named tool
+func (r *Registry) Call(ctx context.Context, name string, arguments string) (string, error) {
+	tool, ok := r.tools[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("arguments are not valid JSON")
+	}
+
+	return tool.Call(ctx, json.RawMessage(arguments))
+}
+
+// Len returns the number of registered tools
+func (r *Registry) Len() int {
+	return len(r.tools)
+}
+
+// toolFunc adapts a function to the Tool interface
+type toolFunc struct {
+	definition llm.Tool
+	call       func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// Definition describes the tool
+func (t *toolFunc) Definition() llm.Tool {
+	return t.definition
+}
+
+// Call runs the tool
+func (t *toolFunc) Call(ctx context.Context, arguments json.RawMessage) (string, error) {
+	return t.call(ctx, arguments)
+}
+
+// toJSON renders a tool result as indented JSON
+func toJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshaling result: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"agents_go/services/llm"
+	"agents_go/services/trello"
+)
+
+const (
+	// maxSearchResults caps the number of cards returned by search_cards
+	maxSearchResults = 20
+	// defaultActionsLimit and maxActionsLimit bound get_actions
+	defaultActionsLimit = 50
+	maxActionsLimit     = 200
+)
+
+// NewTrelloTools creates the read-only Trello tools for one board. Tools
+// refuse to return data from other boards.
+func NewTrelloTools(client *trello.Client, boardID string) *Registry {
+	t := &trelloTools{client: client, boardID: boardID}
+	return NewRegistry(
+		&toolFunc{
+			definition: llm.Tool{
+				Name:        "search_cards",
+				Description: "Search the board's open cards by text in their name or description. Optionally limit the search to one list. Returns up to 20 cards with their ID, list, due date and labels.",
+				Parameters: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "query": {"type": "string", "description": "Text to look for; empty matches every card"},
+    "list": {"type": "string", "description": "Only return cards in the list with this name"}
+  }
+}`),
+			},
+			call: t.searchCards,
+		},
+		&toolFunc{
+			definition: llm.Tool{
+				Name:        "get_card",
+				Description: "Get the details of a card by ID, including its description, list, due date, labels, assigned members and comments.",
+				Parameters: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "card_id": {"type": "string", "description": "The card ID, as returned by search_cards"}
+  },
+  "required": ["card_id"]
+}`),
+			},
+			call: t.getCard,
+		},
+		&toolFunc{
+			definition: llm.Tool{
+				Name:        "list_board_members",
+				Description: "List the board's members with the number of open cards assigned to each.",
+				Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+			},
+			call: t.listBoardMembers,
+		},
+		&toolFunc{
+			definition: llm.Tool{
+				Name:        "get_actions",
+				Description: "Get the board's activity (card moves, creations, comments and updates) between two dates, newest first.",
+				Parameters: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "since": {"type": "string", "description": "Start date, YYYY-MM-DD or RFC 3339"},
+    "until": {"type": "string", "description": "End date, YYYY-MM-DD or RFC 3339; defaults to now"},
+    "type": {"type": "string", "description": "Only return actions of this type, e.g. commentCard or updateCard"},
+    "limit": {"type": "integer", "description": "Maximum number of actions, default 50, at most 200"}
+  },
+  "required": ["since"]
+}`),
+			},
+			call: t.getActions,
+		},
+	)
+}
+
+// trelloTools implements the Trello tools for one board
+type trelloTools struct {
+	client  *trello.Client
+	boardID string
+}
+
+// searchCards finds cards by text and list
+func (t *trelloTools) searchCards(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+		List  string `json:"list"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	cards, err := t.client.GetCards(t.boardID)
+	if err != nil {
+		return "", err
+	}
+	listNames, err := t.listNames()
+	if err != nil {
+		return "", err
+	}
+
+	query := strings.ToLower(strings.TrimSpace(args.Query))
+	type result struct {
+		ID     string   `json:"id"`
+		Name   string   `json:"name"`
+		List   string   `json:"list"`
+		Due    string   `json:"due,omitempty"`
+		Labels []string `json:"labels,omitempty"`
+	}
+	results := []result{}
+	total := 0
+	for _, card := range cards {
+		list := listNames[card.ListID]
+		if args.List != "" && !strings.EqualFold(list, args.List) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(card.Name+"\n"+card.Description), query) {
+			continue
+		}
+
+		total++
+		if len(results) >= maxSearchResults {
+			continue
+		}
+		results = append(results, result{
+			ID:     card.ID,
+			Name:   card.Name,
+			List:   list,
+			Due:    formatDue(card.Due),
+			Labels: labelNames(card.Labels),
+		})
+	}
+
+	return toJSON(map[string]interface{}{
+		"total": total,
+		"cards": results,
+	})
+}
+
+// getCard returns a card's details and comments
+func (t *trelloTools) getCard(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		CardID string `json:"card_id"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if args.CardID == "" {
+		return "", fmt.Errorf("card_id is required")
+	}
+
+	card, err := t.client.GetCard(args.CardID)
+	if err != nil {
+		return "", err
+	}
+	if card.BoardID != t.boardID {
+		return "", fmt.Errorf("card %s is not on this board", args.CardID)
+	}
+
+	listNames, err := t.listNames()
+	if err != nil {
+		return "", err
+	}
+	memberNames, err := t.memberNames()
+	if err != nil {
+		return "", err
+	}
+
+	comments, err := t.client.GetCardComments(card.ID)
+	if err != nil {
+		return "", err
+	}
+
+	members := []string{}
+	for _, id := range card.Members {
+		members = append(members, memberNames[id])
+	}
+
+	type comment struct {
+		Member string `json:"member"`
+		Date   string `json:"date"`
+		Text   string `json:"text"`
+	}
+	formatted := []comment{}
+	for _, action := range comments {
+		data, _ := action["data"].(map[string]interface{})
+		text, _ := data["text"].(string)
+		date, _ := action["date"].(string)
+		formatted = append(formatted, comment{Member: actionMember(action), Date: date, Text: text})
+	}
+
+	return toJSON(map[string]interface{}{
+		"id":          card.ID,
+		"name":        card.Name,
+		"description": card.Description,
+		"list":        listNames[card.ListID],
+		"closed":      card.Closed,
+		"due":         formatDue(card.Due),
+		"labels":      labelNames(card.Labels),
+		"members":     members,
+		"comments":    formatted,
+	})
+}
+
+// listBoardMembers returns the members and their open card counts
+func (t *trelloTools) listBoardMembers(ctx context.Context, arguments json.RawMessage) (string, error) {
+	members, err := t.client.GetBoardMembers(t.boardID)
+	if err != nil {
+		return "", err
+	}
+	cards, err := t.client.GetCards(t.boardID)
+	if err != nil {
+		return "", err
+	}
+
+	assigned := make(map[string]int)
+	for _, card := range cards {
+		for _, id := range card.Members {
+			assigned[id]++
+		}
+	}
+
+	type result struct {
+		Name      string `json:"name"`
+		Username  string `json:"username"`
+		OpenCards int    `json:"open_cards"`
+	}
+	results := []result{}
+	for _, member := range members {
+		results = append(results, result{Name: member.FullName, Username: member.Username, OpenCards: assigned[member.ID]})
+	}
+
+	return toJSON(results)
+}
+
+// getActions returns the board's actions in a date range
+func (t *trelloTools) getActions(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Since string `json:"since"`
+		Until string `json:"until"`
+		Type  string `json:"type"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	since, err := parseDate(args.Since)
+	if err != nil {
+		return "", fmt.Errorf("invalid since: %v", err)
+	}
+	var until time.Time
+	if args.Until != "" {
+		if until, err = parseDate(args.Until); err != nil {
+			return "", fmt.Errorf("invalid until: %v", err)
+		}
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultActionsLimit
+	}
+	if limit > maxActionsLimit {
+		limit = maxActionsLimit
+	}
+
+	actions, err := t.client.GetBoardActions(t.boardID, since, until)
+	if err != nil {
+		return "", err
+	}
+
+	type result struct {
+		Date    string `json:"date"`
+		Type    string `json:"type"`
+		Member  string `json:"member"`
+		Card    string `json:"card,omitempty"`
+		Details string `json:"details,omitempty"`
+	}
+	results := []result{}
+	for _, action := range actions {
+		actionType, _ := action["type"].(string)
+		if args.Type != "" && actionType != args.Type {
+			continue
+		}
+		if len(results) >= limit {
+			break
+		}
+
+		date, _ := action["date"].(string)
+		data, _ := action["data"].(map[string]interface{})
+		card, _ := data["card"].(map[string]interface{})
+		cardName, _ := card["name"].(string)
+
+		var details string
+		if text, ok := data["text"].(string); ok {
+			details = text
+		} else if listAfter, ok := data["listAfter"].(map[string]interface{}); ok {
+			listBefore, _ := data["listBefore"].(map[string]interface{})
+			details = fmt.Sprintf("moved from '%v' to '%v'", listBefore["name"], listAfter["name"])
+		} else if list, ok := data["list"].(map[string]interface{}); ok {
+			details = fmt.Sprintf("in list '%v'", list["name"])
+		}
+
+		results = append(results, result{
+			Date:    date,
+			Type:    actionType,
+			Member:  actionMember(action),
+			Card:    cardName,
+			Details: details,
+		})
+	}
+
+	return toJSON(results)
+}
+
+// listNames maps the board's list IDs to names
+func (t *trelloTools) listNames() (map[string]string, error) {
+	lists, err := t.client.GetLists(t.boardID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(lists))
+	for _, list := range lists {
+		names[list.ID] = list.Name
+	}
+	return names, nil
+}
+
+// memberNames maps the board's member IDs to full names
+func (t *trelloTools) memberNames() (map[string]string, error) {
+	members, err := t.client.GetBoardMembers(t.boardID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(members))
+	for _, member := range members {
+		names[member.ID] = member.FullName
+	}
+	return names, nil
+}
+
+// actionMember returns the name of the member who performed an action
+func actionMember(action map[string]interface{}) string {
+	memberCreator, _ := action["memberCreator"].(map[string]interface{})
+	name, _ := memberCreator["fullName"].(string)
+	return name
+}
+
+// labelNames returns the names of labels, falling back to their colors
+func labelNames(labels []trello.Label) []string {
+	var names []string
+	for _, label := range labels {
+		if label.Name != "" {
+			names = append(names, label.Name)
+		} else {
+			names = append(names, label.Color)
+		}
+	}
+	return names
+}
+
+// formatDue formats an optional due date
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	return due.Format(time.RFC3339)
+}
+
+// parseDate parses a YYYY-MM-DD or RFC 3339 date
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
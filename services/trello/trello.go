@@ -2,10 +2,12 @@ package trello
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"reflect"
+	"strings"
 	"time"
 
 	"agents_go/config"
@@ -65,14 +67,67 @@ type Member struct {
 type Client struct {
 	AccessToken  string
 	AccessSecret string
+	// BaseURL is the Trello REST API base URL, e.g. https://api.trello.com/1
+	BaseURL string
 }
 
+// activityPageSize is the number of actions requested per page
+const activityPageSize = 50
+
+// maxActivityPages caps how many pages of actions are fetched for a board
+const maxActivityPages = 10
+
+// ActivityLimit is the most actions fetched for a board at once
+const ActivityLimit = maxActivityPages * activityPageSize
+
 // NewClient creates a new Trello client
 func NewClient(accessToken, accessSecret string) *Client {
 	return &Client{
 		AccessToken:  accessToken,
 		AccessSecret: accessSecret,
+		BaseURL:      config.TrelloAPIURL,
+	}
+}
+
+// url builds a full API URL for the given path
+func (c *Client) url(format string, args ...interface{}) string {
+	return c.BaseURL + fmt.Sprintf(format, args...)
+}
+
+// apiError shortens Trello error responses to their status and body. The
+// OAuth library's error spreads the status, code, body and request headers
+// over several lines, which is too verbose for logs and error messages.
+func apiError(err error) error {
+	var httpErr oauth.HTTPExecuteError
+	if errors.As(err, &httpErr) {
+		return fmt.Errorf("Trello returned %s: %s", httpErr.Status, strings.TrimSpace(string(httpErr.ResponseBodyBytes)))
+	}
+	return err
+}
+
+// GetMember returns the authenticated member
+func (c *Client) GetMember() (*Member, error) {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
 	}
+
+	resp, err := config.Consumer.Get(
+		c.url("/members/me"),
+		map[string]string{"fields": "fullName,username,avatarUrl"},
+		token,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting member: %v", apiError(err))
+	}
+	defer resp.Body.Close()
+
+	var member Member
+	if err := json.NewDecoder(resp.Body).Decode(&member); err != nil {
+		return nil, fmt.Errorf("error parsing member data: %v", err)
+	}
+
+	return &member, nil
 }
 
 // GetBoards returns all boards for the authenticated user
@@ -83,12 +138,12 @@ func (c *Client) GetBoards() ([]Board, error) {
 	}
 
 	resp, err := config.Consumer.Get(
-		"https://api.trello.com/1/members/me/boards",
+		c.url("/members/me/boards"),
 		map[string]string{"fields": "name,desc,url,shortUrl"},
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting boards: %v", err)
+		return nil, fmt.Errorf("error getting boards: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
@@ -108,12 +163,12 @@ func (c *Client) GetBoardDetails(boardID string) (*Board, error) {
 	}
 
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s", boardID),
+		c.url("/boards/%s", boardID),
 		map[string]string{"fields": "name,desc,url,shortUrl"},
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting board details: %v", err)
+		return nil, fmt.Errorf("error getting board details: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
@@ -133,12 +188,12 @@ func (c *Client) GetLists(boardID string) ([]List, error) {
 	}
 
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s/lists", boardID),
+		c.url("/boards/%s/lists", boardID),
 		map[string]string{"fields": "name,closed,idBoard,pos"},
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting lists: %v", err)
+		return nil, fmt.Errorf("error getting lists: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
@@ -158,7 +213,7 @@ func (c *Client) GetCards(boardID string) ([]Card, error) {
 	}
 
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s/cards", boardID),
+		c.url("/boards/%s/cards", boardID),
 		map[string]string{
 			"fields": "name,desc,closed,idBoard,idList,due,labels,idMembers,dateLastActivity",
 			"members": "true",
@@ -167,7 +222,7 @@ func (c *Client) GetCards(boardID string) ([]Card, error) {
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting cards: %v", err)
+		return nil, fmt.Errorf("error getting cards: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
@@ -187,12 +242,12 @@ func (c *Client) GetBoardMembers(boardID string) ([]Member, error) {
 	}
 
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s/members", boardID),
+		c.url("/boards/%s/members", boardID),
 		map[string]string{"fields": "fullName,username,avatarUrl"},
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting board members: %v", err)
+		return nil, fmt.Errorf("error getting board members: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
@@ -204,42 +259,136 @@ func (c *Client) GetBoardMembers(boardID string) ([]Member, error) {
 	return members, nil
 }
 
-// GetBoardActivity returns recent activity for a specific board
-func (c *Client) GetBoardActivity(boardID string, since time.Time) ([]map[string]interface{}, error) {
+// GetCard returns a single card
+func (c *Client) GetCard(cardID string) (*Card, error) {
 	token := &oauth.AccessToken{
 		Token:  c.AccessToken,
 		Secret: c.AccessSecret,
 	}
 
-	params := map[string]string{
-		"limit": "50",
+	resp, err := config.Consumer.Get(
+		c.url("/cards/%s", cardID),
+		map[string]string{"fields": "name,desc,closed,idBoard,idList,due,labels,idMembers,dateLastActivity"},
+		token,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting card: %v", apiError(err))
+	}
+	defer resp.Body.Close()
+
+	var card Card
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("error parsing card data: %v", err)
 	}
-	
-	if !since.IsZero() {
-		params["since"] = since.Format(time.RFC3339)
+
+	return &card, nil
+}
+
+// GetCardComments returns the comments on a card, newest first
+func (c *Client) GetCardComments(cardID string) ([]map[string]interface{}, error) {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
 	}
 
 	resp, err := config.Consumer.Get(
-		fmt.Sprintf("https://api.trello.com/1/boards/%s/actions", boardID),
-		params,
+		c.url("/cards/%s/actions", cardID),
+		map[string]string{"filter": "commentCard"},
 		token,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting board activity: %v", err)
+		return nil, fmt.Errorf("error getting card comments: %v", apiError(err))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+	var comments []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		return nil, fmt.Errorf("error parsing comments data: %v", err)
+	}
+
+	return comments, nil
+}
+
+// GetBoardActivity returns recent activity for a specific board. Actions are
+// paged through with the "before" parameter until a short page is returned.
+func (c *Client) GetBoardActivity(boardID string, since time.Time) ([]map[string]interface{}, error) {
+	return c.GetBoardActions(boardID, since, time.Time{})
+}
+
+// GetBoardActions returns the board's actions between since and until,
+// newest first. Zero times leave that end of the range open. At most
+// ActivityLimit actions are fetched; a warning is logged if there were more.
+func (c *Client) GetBoardActions(boardID string, since, until time.Time) ([]map[string]interface{}, error) {
+	activities, truncated, err := c.boardActions(boardID, since, until)
+	if truncated {
+		log.Printf("Warning: board %s has more than %d actions in the range; only the newest %d were fetched", boardID, ActivityLimit, ActivityLimit)
+	}
+	return activities, err
+}
+
+// boardActions pages through the board's actions, newest first, and
+// reports whether it stopped at ActivityLimit with actions left to fetch
+func (c *Client) boardActions(boardID string, since, until time.Time) ([]map[string]interface{}, bool, error) {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
 	}
 
-	var activities []map[string]interface{}
-	if err := json.Unmarshal(body, &activities); err != nil {
-		return nil, fmt.Errorf("error parsing activity data: %v", err)
+	activities := []map[string]interface{}{}
+	before := ""
+	if !until.IsZero() {
+		before = until.Format(time.RFC3339)
 	}
 
-	return activities, nil
+	for page := 0; page < maxActivityPages; page++ {
+		params := map[string]string{
+			"limit": fmt.Sprintf("%d", activityPageSize),
+		}
+
+		if !since.IsZero() {
+			params["since"] = since.Format(time.RFC3339)
+		}
+		if before != "" {
+			params["before"] = before
+		}
+
+		resp, err := config.Consumer.Get(
+			c.url("/boards/%s/actions", boardID),
+			params,
+			token,
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting board activity: %v", apiError(err))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading response body: %v", err)
+		}
+
+		var pageActivities []map[string]interface{}
+		if err := json.Unmarshal(body, &pageActivities); err != nil {
+			return nil, false, fmt.Errorf("error parsing activity data: %v", err)
+		}
+
+		activities = append(activities, pageActivities...)
+
+		// A short page means there is nothing left to fetch
+		if len(pageActivities) < activityPageSize {
+			return activities, false, nil
+		}
+
+		// Continue from the oldest action on this page
+		lastID, _ := pageActivities[len(pageActivities)-1]["id"].(string)
+		if lastID == "" {
+			return activities, false, nil
+		}
+		before = lastID
+	}
+
+	// The last page was full, so there may be older actions left
+	return activities, true, nil
 }
 
 // GetBoardData fetches all relevant data for a board report
@@ -264,11 +413,14 @@ func (c *Client) GetBoardData(boardID string, since time.Time) (map[string]inter
 		return nil, err
 	}
 
-	activities, err := c.GetBoardActivity(boardID, since)
+	activities, truncated, err := c.boardActions(boardID, since, time.Time{})
 	if err != nil {
 		log.Printf("Warning: Could not fetch board activities: %v", err)
 		activities = []map[string]interface{}{}
 	}
+	if truncated {
+		log.Printf("Warning: board %s has more than %d actions since %s; only the newest %d are included", boardID, ActivityLimit, since.Format("2006-01-02"), ActivityLimit)
+	}
 
 	// Convert board to map
 	boardData, err := convertToMap(board)
@@ -300,6 +452,9 @@ func (c *Client) GetBoardData(boardID string, since time.Time) (map[string]inter
 		"cards":      cardsData,
 		"members":    membersData,
 		"activities": activities,
+		// activities_truncated is set if older activity in the range
+		// wasn't fetched
+		"activities_truncated": truncated,
 	}, nil
 }
 
@@ -334,3 +489,70 @@ func convertToMap(data interface{}) (map[string]interface{}, error) {
 	
 	return result, nil
 }
+
+// UpdateCard updates fields of a card, e.g. idList to move it or due to set
+// its due date, and returns the updated card
+func (c *Client) UpdateCard(cardID string, fields map[string]string) (*Card, error) {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
+	}
+
+	resp, err := config.Consumer.Put(
+		c.url("/cards/%s", cardID),
+		"",
+		fields,
+		token,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error updating card: %v", apiError(err))
+	}
+	defer resp.Body.Close()
+
+	var card Card
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("error parsing card data: %v", err)
+	}
+
+	return &card, nil
+}
+
+// AddCardMember assigns a member to a card
+func (c *Client) AddCardMember(cardID, memberID string) error {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
+	}
+
+	resp, err := config.Consumer.Post(
+		c.url("/cards/%s/idMembers", cardID),
+		map[string]string{"value": memberID},
+		token,
+	)
+	if err != nil {
+		return fmt.Errorf("error adding card member: %v", apiError(err))
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// RemoveCardMember removes a member from a card
+func (c *Client) RemoveCardMember(cardID, memberID string) error {
+	token := &oauth.AccessToken{
+		Token:  c.AccessToken,
+		Secret: c.AccessSecret,
+	}
+
+	resp, err := config.Consumer.Delete(
+		c.url("/cards/%s/idMembers/%s", cardID, memberID),
+		map[string]string{},
+		token,
+	)
+	if err != nil {
+		return fmt.Errorf("error removing card member: %v", apiError(err))
+	}
+	resp.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package trello_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"agents_go/services/trello"
+	"agents_go/services/trello/trellotest"
+)
+
+// actionsFixture returns the default fixture with count actions on its
+// board, one a minute apart, action-0 the newest
+func actionsFixture(now time.Time, count int) *trellotest.Fixture {
+	fixture := trellotest.DefaultFixture()
+	actions := make([]map[string]interface{}, count)
+	for i := range actions {
+		actions[i] = map[string]interface{}{
+			"id":   fmt.Sprintf("action-%d", i),
+			"type": "commentCard",
+			"date": now.Add(-time.Duration(i) * time.Minute).Format(time.RFC3339),
+		}
+	}
+	fixture.Boards[0].Actions = actions
+	return fixture
+}
+
+func TestGetBoardActionsPagination(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		actions int
+		// since and until are in minutes before now; zero leaves them open
+		since, until int
+		// want is the number of actions returned, from the newest in range
+		want      int
+		wantFirst string
+		pages     int
+	}{
+		{name: "no actions", actions: 0, want: 0, pages: 1},
+		{name: "short page", actions: 49, want: 49, wantFirst: "action-0", pages: 1},
+		{name: "exactly one page", actions: 50, want: 50, wantFirst: "action-0", pages: 2},
+		{name: "several pages", actions: 120, want: 120, wantFirst: "action-0", pages: 3},
+		{name: "capped at the page limit", actions: 600, want: 500, wantFirst: "action-0", pages: 10},
+		{name: "since", actions: 120, since: 70, want: 71, wantFirst: "action-0", pages: 2},
+		{name: "until", actions: 120, until: 30, want: 89, wantFirst: "action-31", pages: 2},
+		{name: "since and until", actions: 120, since: 100, until: 10, want: 90, wantFirst: "action-11", pages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := actionsFixture(now, tt.actions)
+			server := trellotest.NewServer(fixture)
+			defer server.Close()
+			server.Configure()
+			client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+			var since, until time.Time
+			if tt.since > 0 {
+				since = now.Add(-time.Duration(tt.since) * time.Minute).Add(-time.Second)
+			}
+			if tt.until > 0 {
+				until = now.Add(-time.Duration(tt.until) * time.Minute).Add(-time.Second)
+			}
+
+			actions, err := client.GetBoardActions("board-1", since, until)
+			if err != nil {
+				t.Fatalf("GetBoardActions: %v", err)
+			}
+			if len(actions) != tt.want {
+				t.Fatalf("got %d actions, want %d", len(actions), tt.want)
+			}
+			if tt.want > 0 && actions[0]["id"] != tt.wantFirst {
+				t.Errorf("first action is %v, want %s", actions[0]["id"], tt.wantFirst)
+			}
+
+			// Pages must continue where the previous one ended, without
+			// repeating or skipping actions
+			seen := make(map[string]bool)
+			for i, action := range actions {
+				id, _ := action["id"].(string)
+				if seen[id] {
+					t.Fatalf("action %s returned twice", id)
+				}
+				seen[id] = true
+				if i > 0 {
+					var prev, cur int
+					fmt.Sscanf(actions[i-1]["id"].(string), "action-%d", &prev)
+					fmt.Sscanf(id, "action-%d", &cur)
+					if cur != prev+1 {
+						t.Fatalf("action %s follows %s", id, actions[i-1]["id"])
+					}
+				}
+			}
+
+			pages := 0
+			for _, request := range server.Requests() {
+				if strings.HasSuffix(request, "/boards/board-1/actions") {
+					pages++
+				}
+			}
+			if pages != tt.pages {
+				t.Errorf("fetched %d pages, want %d", pages, tt.pages)
+			}
+		})
+	}
+}
+
+func TestGetBoardActionsError(t *testing.T) {
+	fixture := actionsFixture(time.Now().UTC(), 120)
+	server := trellotest.NewServer(fixture)
+	defer server.Close()
+	server.Configure()
+	client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+	// A failed page fails the whole call rather than returning part of it
+	server.FailNext(http.StatusInternalServerError, 1)
+	actions, err := client.GetBoardActions("board-1", time.Time{}, time.Time{})
+	if err == nil {
+		t.Fatalf("got %d actions, want an error", len(actions))
+	}
+	if strings.Contains(err.Error(), "\n") {
+		t.Errorf("error spans several lines: %q", err)
+	}
+}
+
+func TestGetBoardDataActivityTruncated(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		actions int
+		want    bool
+	}{
+		{name: "under the limit", actions: 120, want: false},
+		{name: "exactly the limit", actions: trello.ActivityLimit, want: true},
+		{name: "over the limit", actions: 600, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := actionsFixture(now, tt.actions)
+			server := trellotest.NewServer(fixture)
+			defer server.Close()
+			server.Configure()
+			client := trello.NewClient(fixture.AccessToken, fixture.AccessSecret)
+
+			data, err := client.GetBoardData("board-1", now.AddDate(0, 0, -30))
+			if err != nil {
+				t.Fatalf("GetBoardData: %v", err)
+			}
+			// A full last page can't tell whether older actions remain, so
+			// exactly the limit counts as capped
+			if got, _ := data["activities_truncated"].(bool); got != tt.want {
+				t.Errorf("activities_truncated is %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
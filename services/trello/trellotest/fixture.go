@@ -0,0 +1,193 @@
+package trellotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"agents_go/services/trello"
+)
+
+// Fixture describes the data served by the fake Trello API
+type Fixture struct {
+	// Member is the authenticated user returned by /members/me
+	Member trello.Member `json:"member"`
+	// AccessToken and AccessSecret are accepted without going through the OAuth flow
+	AccessToken  string         `json:"access_token"`
+	AccessSecret string         `json:"access_secret"`
+	Boards       []BoardFixture `json:"boards"`
+}
+
+// BoardFixture holds everything the fake serves for a single board
+type BoardFixture struct {
+	Board   trello.Board    `json:"board"`
+	Lists   []trello.List   `json:"lists"`
+	Cards   []trello.Card   `json:"cards"`
+	Members []trello.Member `json:"members"`
+	// Actions are returned newest first, in the same shape as the Trello API
+	Actions []map[string]interface{} `json:"actions"`
+}
+
+// LoadFixture reads a fixture from a JSON file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture file: %v", err)
+	}
+
+	return ParseFixture(data)
+}
+
+// ParseFixture parses a fixture from JSON
+func ParseFixture(data []byte) (*Fixture, error) {
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("error parsing fixture: %v", err)
+	}
+
+	if fixture.AccessToken == "" {
+		fixture.AccessToken = DefaultAccessToken
+		fixture.AccessSecret = DefaultAccessSecret
+	}
+
+	return &fixture, nil
+}
+
+// Board returns the fixture for a board ID, or nil if it does not exist
+func (f *Fixture) Board(boardID string) *BoardFixture {
+	for i := range f.Boards {
+		if f.Boards[i].Board.ID == boardID {
+			return &f.Boards[i]
+		}
+	}
+	return nil
+}
+
+const (
+	// DefaultAccessToken is the access token accepted by DefaultFixture
+	DefaultAccessToken = "fake-access-token"
+	// DefaultAccessSecret is the access secret accepted by DefaultFixture
+	DefaultAccessSecret = "fake-access-secret"
+)
+
+// DefaultFixture returns a small board with a handful of lists, cards,
+// members and recent actions
+func DefaultFixture() *Fixture {
+	now := time.Now().UTC().Truncate(time.Second)
+	due := now.AddDate(0, 0, 3)
+	overdue := now.AddDate(0, 0, -2)
+
+	members := []trello.Member{
+		{ID: "member-1", FullName: "Priya Sharma", Username: "priya"},
+		{ID: "member-2", FullName: "Jonas Weber", Username: "jonas"},
+		{ID: "member-3", FullName: "Lucia Gomez", Username: "lucia"},
+	}
+
+	lists := []trello.List{
+		{ID: "list-todo", Name: "To Do", BoardID: "board-1", Pos: 1},
+		{ID: "list-doing", Name: "In Progress", BoardID: "board-1", Pos: 2},
+		{ID: "list-done", Name: "Done", BoardID: "board-1", Pos: 3},
+	}
+
+	cards := []trello.Card{
+		{
+			ID: "card-1", Name: "Redesign landing page", Description: "New hero section and pricing table",
+			BoardID: "board-1", ListID: "list-doing", Due: &due, Members: []string{"member-1"},
+			Labels:  []trello.Label{{ID: "label-1", Name: "Design", Color: "purple", BoardID: "board-1"}},
+			Created: now.AddDate(0, 0, -1),
+		},
+		{
+			ID: "card-2", Name: "Fix payment webhook retries", Description: "Stripe webhooks are retried too aggressively",
+			BoardID: "board-1", ListID: "list-todo", Due: &overdue, Members: []string{"member-2"},
+			Labels:  []trello.Label{{ID: "label-2", Name: "Bug", Color: "red", BoardID: "board-1"}},
+			Created: now.AddDate(0, 0, -4),
+		},
+		{
+			ID: "card-3", Name: "Write launch blog post", BoardID: "board-1", ListID: "list-done",
+			Members: []string{"member-3"}, Created: now.AddDate(0, 0, -2),
+		},
+		{
+			ID: "card-4", Name: "Set up analytics dashboard", BoardID: "board-1", ListID: "list-todo",
+			Created: now.AddDate(0, 0, -6),
+		},
+	}
+
+	actions := []map[string]interface{}{
+		{
+			"id": "action-4", "type": "updateCard", "date": now.Add(-2 * time.Hour).Format(time.RFC3339),
+			"memberCreator": map[string]interface{}{"id": "member-3", "fullName": "Lucia Gomez"},
+			"data": map[string]interface{}{
+				"card":       map[string]interface{}{"id": "card-3", "name": "Write launch blog post"},
+				"listBefore": map[string]interface{}{"id": "list-doing", "name": "In Progress"},
+				"listAfter":  map[string]interface{}{"id": "list-done", "name": "Done"},
+			},
+		},
+		{
+			"id": "action-3", "type": "commentCard", "date": now.Add(-26 * time.Hour).Format(time.RFC3339),
+			"memberCreator": map[string]interface{}{"id": "member-2", "fullName": "Jonas Weber"},
+			"data": map[string]interface{}{
+				"card": map[string]interface{}{"id": "card-2", "name": "Fix payment webhook retries"},
+				"text": "Blocked until we get access to the Stripe dashboard.",
+			},
+		},
+		{
+			"id": "action-2", "type": "createCard", "date": now.Add(-50 * time.Hour).Format(time.RFC3339),
+			"memberCreator": map[string]interface{}{"id": "member-1", "fullName": "Priya Sharma"},
+			"data": map[string]interface{}{
+				"card": map[string]interface{}{"id": "card-1", "name": "Redesign landing page"},
+				"list": map[string]interface{}{"id": "list-todo", "name": "To Do"},
+			},
+		},
+		{
+			"id": "action-1", "type": "updateCard", "date": now.Add(-74 * time.Hour).Format(time.RFC3339),
+			"memberCreator": map[string]interface{}{"id": "member-1", "fullName": "Priya Sharma"},
+			"data": map[string]interface{}{
+				"card":       map[string]interface{}{"id": "card-1", "name": "Redesign landing page"},
+				"listBefore": map[string]interface{}{"id": "list-todo", "name": "To Do"},
+				"listAfter":  map[string]interface{}{"id": "list-doing", "name": "In Progress"},
+			},
+		},
+	}
+
+	return &Fixture{
+		Member:       members[0],
+		AccessToken:  DefaultAccessToken,
+		AccessSecret: DefaultAccessSecret,
+		Boards: []BoardFixture{
+			{
+				Board: trello.Board{
+					ID:          "board-1",
+					Name:        "Website Relaunch",
+					Description: "Marketing website relaunch project",
+					URL:         "https://trello.com/b/board-1/website-relaunch",
+					ShortURL:    "https://trello.com/b/board-1",
+				},
+				Lists:   lists,
+				Cards:   cards,
+				Members: members,
+				Actions: actions,
+			},
+		},
+	}
+}
+
+// list returns the board's list with the given ID, or nil
+func (b *BoardFixture) list(listID string) *trello.List {
+	for i := range b.Lists {
+		if b.Lists[i].ID == listID {
+			return &b.Lists[i]
+		}
+	}
+	return nil
+}
+
+// hasMember reports whether a member belongs to the board
+func (b *BoardFixture) hasMember(memberID string) bool {
+	for _, member := range b.Members {
+		if member.ID == memberID {
+			return true
+		}
+	}
+	return false
+}
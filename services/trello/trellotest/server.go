@@ -0,0 +1,710 @@
+// Package trellotest provides an in-process fake of the Trello API for local
+// development and tests. It serves board fixtures over the same REST
+// endpoints trello.Client and the dashboard handlers use, implements the
+// OAuth 1.0a token endpoints, and can simulate pagination, error responses
+// and latency.
+package trellotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agents_go/config"
+	"agents_go/services/trello"
+
+	"github.com/gorilla/mux"
+)
+
+// maxActionsLimit mirrors the largest page size Trello accepts for actions
+const maxActionsLimit = 1000
+
+// defaultActionsLimit is the page size used when no limit is given
+const defaultActionsLimit = 50
+
+// Server is a fake Trello API server
+type Server struct {
+	// URL is the base URL of the running server, e.g. http://127.0.0.1:1234
+	URL string
+
+	fixture    *Fixture
+	router     *mux.Router
+	httpServer *httptest.Server
+
+	mu              sync.Mutex
+	latency         time.Duration
+	failures        []int
+	rateLimit       int
+	rateLimitWindow time.Duration
+	requestTimes    []time.Time
+	requestTokens   map[string]requestToken
+	verifiers       map[string]string
+	accessTokens    map[string]string
+	requests        []string
+
+	// fixtureMu guards the fixture, which write requests modify
+	fixtureMu sync.RWMutex
+}
+
+// requestToken is an OAuth request token issued by the fake
+type requestToken struct {
+	secret   string
+	callback string
+}
+
+// NewServer starts a fake Trello API server serving the given fixture.
+// A nil fixture serves DefaultFixture.
+func NewServer(fixture *Fixture) *Server {
+	s := NewHandler(fixture)
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// NewHandler creates a fake Trello API without starting a listener, so it
+// can be mounted on any http.Server
+func NewHandler(fixture *Fixture) *Server {
+	if fixture == nil {
+		fixture = DefaultFixture()
+	}
+
+	s := &Server{
+		fixture:         fixture,
+		rateLimitWindow: 10 * time.Second,
+		requestTokens:   make(map[string]requestToken),
+		verifiers:       make(map[string]string),
+		accessTokens:    make(map[string]string),
+	}
+
+	// The fixture's own token is always valid
+	if fixture.AccessToken != "" {
+		s.accessTokens[fixture.AccessToken] = fixture.AccessSecret
+	}
+
+	s.router = mux.NewRouter()
+
+	// OAuth 1.0a endpoints
+	s.router.HandleFunc("/1/OAuthGetRequestToken", s.handleRequestToken)
+	s.router.HandleFunc("/1/OAuthAuthorizeToken", s.handleAuthorizeToken)
+	s.router.HandleFunc("/1/OAuthGetAccessToken", s.handleAccessToken)
+
+	// REST API endpoints
+	api := s.router.PathPrefix("/1").Subrouter()
+	api.Use(s.authenticate)
+	api.HandleFunc("/members/me", s.handleMe).Methods("GET")
+	api.HandleFunc("/members/me/boards", s.handleMyBoards).Methods("GET")
+	api.HandleFunc("/boards/{id}", s.handleBoard).Methods("GET")
+	api.HandleFunc("/boards/{id}/lists", s.handleLists).Methods("GET")
+	api.HandleFunc("/boards/{id}/cards", s.handleCards).Methods("GET")
+	api.HandleFunc("/boards/{id}/members", s.handleMembers).Methods("GET")
+	api.HandleFunc("/boards/{id}/actions", s.handleActions).Methods("GET")
+	api.HandleFunc("/cards/{id}", s.handleCard).Methods("GET")
+	api.HandleFunc("/cards/{id}/actions", s.handleCardActions).Methods("GET")
+	api.HandleFunc("/cards/{id}", s.handleUpdateCard).Methods("PUT")
+	api.HandleFunc("/cards/{id}/idMembers", s.handleAddCardMember).Methods("POST")
+	api.HandleFunc("/cards/{id}/idMembers/{member}", s.handleRemoveCardMember).Methods("DELETE")
+
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// APIURL returns the base URL of the fake REST API
+func (s *Server) APIURL() string {
+	return s.URL + "/1"
+}
+
+// OAuthURL returns the base URL of the fake OAuth endpoints
+func (s *Server) OAuthURL() string {
+	return s.URL + "/1"
+}
+
+// Configure points the application's config at this server and
+// re-initializes the OAuth consumer
+func (s *Server) Configure() {
+	config.TrelloAPIURL = s.APIURL()
+	config.TrelloOAuthURL = s.OAuthURL()
+	config.Init()
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext makes the next count API requests fail with the given HTTP status
+func (s *Server) FailNext(status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// SetRateLimit allows at most limit API requests per window before responding
+// with 429 Too Many Requests. A limit of zero disables rate limiting.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+	s.rateLimitWindow = window
+	s.requestTimes = nil
+}
+
+// Requests returns the method and path of every API request served so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latency := s.latency
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// Serialize writes to the fixture against reads
+	if r.Method == http.MethodGet {
+		s.fixtureMu.RLock()
+		defer s.fixtureMu.RUnlock()
+	} else {
+		s.fixtureMu.Lock()
+		defer s.fixtureMu.Unlock()
+	}
+
+	s.router.ServeHTTP(w, r)
+}
+
+// authenticate checks the OAuth access token and applies simulated failures
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+
+		// Queued failures take precedence over everything else
+		if len(s.failures) > 0 {
+			status := s.failures[0]
+			s.failures = s.failures[1:]
+			s.mu.Unlock()
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		// Enforce the rate limit over a sliding window
+		if s.rateLimit > 0 {
+			now := time.Now()
+			cutoff := now.Add(-s.rateLimitWindow)
+			recent := s.requestTimes[:0]
+			for _, t := range s.requestTimes {
+				if t.After(cutoff) {
+					recent = append(recent, t)
+				}
+			}
+			s.requestTimes = recent
+			if len(s.requestTimes) >= s.rateLimit {
+				s.mu.Unlock()
+				w.Header().Set("Retry-After", strconv.Itoa(int(s.rateLimitWindow.Seconds())+1))
+				http.Error(w, "API_TOKEN_LIMIT_EXCEEDED", http.StatusTooManyRequests)
+				return
+			}
+			s.requestTimes = append(s.requestTimes, now)
+		}
+
+		// Check the access token
+		params := oauthParams(r)
+		_, ok := s.accessTokens[params["oauth_token"]]
+		s.mu.Unlock()
+
+		if !ok {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleRequestToken issues an OAuth request token
+func (s *Server) handleRequestToken(w http.ResponseWriter, r *http.Request) {
+	params := oauthParams(r)
+	if params["oauth_consumer_key"] == "" {
+		http.Error(w, "missing consumer key", http.StatusUnauthorized)
+		return
+	}
+
+	token, secret := randomToken(), randomToken()
+
+	s.mu.Lock()
+	s.requestTokens[token] = requestToken{secret: secret, callback: params["oauth_callback"]}
+	s.mu.Unlock()
+
+	writeForm(w, url.Values{
+		"oauth_token":              {token},
+		"oauth_token_secret":       {secret},
+		"oauth_callback_confirmed": {"true"},
+	})
+}
+
+// handleAuthorizeToken approves a request token immediately and redirects to
+// the callback, as if the user had clicked "Allow"
+func (s *Server) handleAuthorizeToken(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("oauth_token")
+
+	s.mu.Lock()
+	rt, ok := s.requestTokens[token]
+	verifier := randomToken()
+	if ok {
+		s.verifiers[token] = verifier
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "invalid request token", http.StatusUnauthorized)
+		return
+	}
+
+	callback := rt.callback
+	if callback == "" || callback == "oob" {
+		callback = r.URL.Query().Get("callback_url")
+	}
+	if callback == "" {
+		// Out-of-band flow: show the verifier to the user
+		fmt.Fprintf(w, "Verification code: %s", verifier)
+		return
+	}
+
+	target, err := url.Parse(callback)
+	if err != nil {
+		http.Error(w, "invalid callback URL", http.StatusBadRequest)
+		return
+	}
+	q := target.Query()
+	q.Set("oauth_token", token)
+	q.Set("oauth_verifier", verifier)
+	target.RawQuery = q.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// handleAccessToken exchanges an authorized request token for an access token
+func (s *Server) handleAccessToken(w http.ResponseWriter, r *http.Request) {
+	params := oauthParams(r)
+	token := params["oauth_token"]
+
+	s.mu.Lock()
+	verifier, ok := s.verifiers[token]
+	if !ok || verifier != params["oauth_verifier"] {
+		s.mu.Unlock()
+		http.Error(w, "invalid verifier", http.StatusUnauthorized)
+		return
+	}
+	delete(s.verifiers, token)
+	delete(s.requestTokens, token)
+
+	accessToken, accessSecret := randomToken(), randomToken()
+	s.accessTokens[accessToken] = accessSecret
+	s.mu.Unlock()
+
+	writeForm(w, url.Values{
+		"oauth_token":        {accessToken},
+		"oauth_token_secret": {accessSecret},
+	})
+}
+
+// handleMe returns the authenticated member
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.fixture.Member)
+}
+
+// handleMyBoards returns all boards in the fixture
+func (s *Server) handleMyBoards(w http.ResponseWriter, r *http.Request) {
+	boards := make([]interface{}, 0, len(s.fixture.Boards))
+	for _, b := range s.fixture.Boards {
+		boards = append(boards, b.Board)
+	}
+	writeJSON(w, boards)
+}
+
+// handleBoard returns a single board
+func (s *Server) handleBoard(w http.ResponseWriter, r *http.Request) {
+	board := s.boardOr404(w, r)
+	if board == nil {
+		return
+	}
+	writeJSON(w, board.Board)
+}
+
+// handleLists returns the open lists of a board
+func (s *Server) handleLists(w http.ResponseWriter, r *http.Request) {
+	board := s.boardOr404(w, r)
+	if board == nil {
+		return
+	}
+	writeJSON(w, board.Lists)
+}
+
+// handleCards returns the cards of a board
+func (s *Server) handleCards(w http.ResponseWriter, r *http.Request) {
+	board := s.boardOr404(w, r)
+	if board == nil {
+		return
+	}
+	writeJSON(w, board.Cards)
+}
+
+// handleMembers returns the members of a board
+func (s *Server) handleMembers(w http.ResponseWriter, r *http.Request) {
+	board := s.boardOr404(w, r)
+	if board == nil {
+		return
+	}
+	writeJSON(w, board.Members)
+}
+
+// handleActions returns a page of board actions, newest first. It supports
+// the limit, since, before and page parameters of the real API.
+func (s *Server) handleActions(w http.ResponseWriter, r *http.Request) {
+	board := s.boardOr404(w, r)
+	if board == nil {
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit := defaultActionsLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxActionsLimit {
+			http.Error(w, "invalid value for limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	page := 0
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid value for page", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+
+	actions := sortedActions(board.Actions)
+
+	// Apply the since/before bounds, which may be action IDs or dates
+	filtered := make([]map[string]interface{}, 0, len(actions))
+	for _, action := range actions {
+		if !actionAfter(actions, action, query.Get("since")) {
+			continue
+		}
+		if !actionBefore(actions, action, query.Get("before")) {
+			continue
+		}
+		filtered = append(filtered, action)
+	}
+
+	start := page * limit
+	if start > len(filtered) {
+		start = len(filtered)
+	}
+	end := start + limit
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+
+	writeJSON(w, filtered[start:end])
+}
+
+// handleCard returns a single card from any board
+func (s *Server) handleCard(w http.ResponseWriter, r *http.Request) {
+	card, _ := s.cardOr404(w, r)
+	if card == nil {
+		return
+	}
+	writeJSON(w, card)
+}
+
+// handleCardActions returns the actions on a card, newest first, optionally
+// limited to the action types in the filter parameter
+func (s *Server) handleCardActions(w http.ResponseWriter, r *http.Request) {
+	card, board := s.cardOr404(w, r)
+	if card == nil {
+		return
+	}
+
+	types := map[string]bool{}
+	for _, t := range strings.Split(r.URL.Query().Get("filter"), ",") {
+		if t != "" && t != "all" {
+			types[t] = true
+		}
+	}
+
+	actions := []map[string]interface{}{}
+	for _, action := range sortedActions(board.Actions) {
+		data, _ := action["data"].(map[string]interface{})
+		actionCard, _ := data["card"].(map[string]interface{})
+		if id, _ := actionCard["id"].(string); id != card.ID {
+			continue
+		}
+		if actionType, _ := action["type"].(string); len(types) > 0 && !types[actionType] {
+			continue
+		}
+		actions = append(actions, action)
+	}
+
+	writeJSON(w, actions)
+}
+
+// handleUpdateCard updates a card's list, due date or archived state, and
+// records a move in the board's actions like the real API
+func (s *Server) handleUpdateCard(w http.ResponseWriter, r *http.Request) {
+	card, board := s.cardOr404(w, r)
+	if card == nil {
+		return
+	}
+
+	if listID := r.FormValue("idList"); listID != "" && listID != card.ListID {
+		listBefore, listAfter := board.list(card.ListID), board.list(listID)
+		if listAfter == nil {
+			http.Error(w, "invalid value for idList", http.StatusBadRequest)
+			return
+		}
+		card.ListID = listID
+
+		action := map[string]interface{}{
+			"id":   fmt.Sprintf("action-%d", len(board.Actions)+1),
+			"type": "updateCard",
+			"date": time.Now().UTC().Format(time.RFC3339),
+			"memberCreator": map[string]interface{}{
+				"id":       s.fixture.Member.ID,
+				"fullName": s.fixture.Member.FullName,
+			},
+			"data": map[string]interface{}{
+				"card":      map[string]interface{}{"id": card.ID, "name": card.Name},
+				"listAfter": map[string]interface{}{"id": listAfter.ID, "name": listAfter.Name},
+			},
+		}
+		if listBefore != nil {
+			action["data"].(map[string]interface{})["listBefore"] = map[string]interface{}{"id": listBefore.ID, "name": listBefore.Name}
+		}
+		board.Actions = append(board.Actions, action)
+	}
+
+	if _, ok := r.Form["due"]; ok {
+		due := r.FormValue("due")
+		if due == "" || due == "null" {
+			card.Due = nil
+		} else {
+			t, err := time.Parse(time.RFC3339, due)
+			if err != nil {
+				http.Error(w, "invalid value for due", http.StatusBadRequest)
+				return
+			}
+			card.Due = &t
+		}
+	}
+
+	if closed := r.FormValue("closed"); closed != "" {
+		card.Closed = closed == "true"
+	}
+
+	writeJSON(w, card)
+}
+
+// handleAddCardMember assigns a board member to a card
+func (s *Server) handleAddCardMember(w http.ResponseWriter, r *http.Request) {
+	card, board := s.cardOr404(w, r)
+	if card == nil {
+		return
+	}
+
+	memberID := r.FormValue("value")
+	if !board.hasMember(memberID) {
+		http.Error(w, "invalid value for value", http.StatusBadRequest)
+		return
+	}
+	for _, id := range card.Members {
+		if id == memberID {
+			http.Error(w, "member is already on the card", http.StatusBadRequest)
+			return
+		}
+	}
+
+	card.Members = append(card.Members, memberID)
+	writeJSON(w, card.Members)
+}
+
+// handleRemoveCardMember removes a member from a card
+func (s *Server) handleRemoveCardMember(w http.ResponseWriter, r *http.Request) {
+	card, _ := s.cardOr404(w, r)
+	if card == nil {
+		return
+	}
+
+	memberID := mux.Vars(r)["member"]
+	for i, id := range card.Members {
+		if id == memberID {
+			card.Members = append(card.Members[:i:i], card.Members[i+1:]...)
+			writeJSON(w, card.Members)
+			return
+		}
+	}
+
+	http.Error(w, "member is not on the card", http.StatusBadRequest)
+}
+
+// cardOr404 looks up the card in the request path and its board, writing a
+// 404 if missing
+func (s *Server) cardOr404(w http.ResponseWriter, r *http.Request) (*trello.Card, *BoardFixture) {
+	cardID := mux.Vars(r)["id"]
+	for i := range s.fixture.Boards {
+		board := &s.fixture.Boards[i]
+		for j := range board.Cards {
+			if board.Cards[j].ID == cardID {
+				return &board.Cards[j], board
+			}
+		}
+	}
+	http.Error(w, "The requested resource was not found.", http.StatusNotFound)
+	return nil, nil
+}
+
+// boardOr404 looks up the board in the request path, writing a 404 if missing
+func (s *Server) boardOr404(w http.ResponseWriter, r *http.Request) *BoardFixture {
+	board := s.fixture.Board(mux.Vars(r)["id"])
+	if board == nil {
+		http.Error(w, "The requested resource was not found.", http.StatusNotFound)
+		return nil
+	}
+	return board
+}
+
+// sortedActions returns the actions ordered newest first
+func sortedActions(actions []map[string]interface{}) []map[string]interface{} {
+	sorted := append([]map[string]interface{}(nil), actions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return actionDate(sorted[i]).After(actionDate(sorted[j]))
+	})
+	return sorted
+}
+
+// actionDate parses the date of an action
+func actionDate(action map[string]interface{}) time.Time {
+	date, _ := action["date"].(string)
+	t, _ := time.Parse(time.RFC3339, date)
+	return t
+}
+
+// boundDate resolves a since/before bound, which is either an action ID or a date
+func boundDate(actions []map[string]interface{}, bound string) (time.Time, bool) {
+	if bound == "" {
+		return time.Time{}, false
+	}
+	for _, action := range actions {
+		if id, _ := action["id"].(string); id == bound {
+			return actionDate(action), true
+		}
+	}
+	t, err := time.Parse(time.RFC3339, bound)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// actionAfter reports whether the action is newer than the since bound
+func actionAfter(actions []map[string]interface{}, action map[string]interface{}, since string) bool {
+	t, ok := boundDate(actions, since)
+	if !ok {
+		return true
+	}
+	return actionDate(action).After(t)
+}
+
+// actionBefore reports whether the action is older than the before bound
+func actionBefore(actions []map[string]interface{}, action map[string]interface{}, before string) bool {
+	t, ok := boundDate(actions, before)
+	if !ok {
+		return true
+	}
+	return actionDate(action).Before(t)
+}
+
+// oauthParams extracts OAuth parameters from the Authorization header,
+// falling back to the query string
+func oauthParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		if strings.HasPrefix(key, "oauth_") && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "OAuth ") {
+		return params
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(header, "OAuth "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := url.QueryUnescape(strings.Trim(kv[1], `"`))
+		if err != nil {
+			continue
+		}
+		params[kv[0]] = value
+	}
+
+	return params
+}
+
+// randomToken returns a random hex token
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeForm writes an OAuth form-encoded response
+func writeForm(w http.ResponseWriter, values url.Values) {
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	w.Write([]byte(values.Encode()))
+}